@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// Agent is an AI participant in a multi-agent chat
+type Agent struct {
+	Name         string
+	Model        string
+	Instructions string
+}
+
+// Turn-taking modes for multi-agent chats
+const (
+	agentModeRoundRobin = "One agent per message"
+	agentModeAll        = "All agents respond"
+)
+
+// agentBar holds the moderator controls shown above the input for multi-agent chats
+var agentBar *fyne.Container
+
+// agentNames returns the names of the chat's agents
+func agentNames(chat *Chat) []string {
+	names := make([]string, len(chat.Agents))
+	for i, agent := range chat.Agents {
+		names[i] = agent.Name
+	}
+	return names
+}
+
+// chatTranscript returns the chat messages as a multi-agent transcript
+func chatTranscript(chat *Chat) []llm.Turn {
+	turns := make([]llm.Turn, 0, len(chat.Messages))
+	for _, msg := range chat.Messages {
+		if msg.Sender == "System" {
+			continue
+		}
+		turns = append(turns, llm.Turn{Speaker: msg.Sender, Text: msg.Text})
+	}
+	return turns
+}
+
+// runAgentTurns lets agents respond after a user message or a moderator action.
+// In round robin mode only the next agent speaks, otherwise every agent speaks once.
+func runAgentTurns(chatID int, all bool) {
+	chat := findChat(chatID)
+	if chat == nil || len(chat.Agents) == 0 {
+		return
+	}
+
+	count := 1
+	if all || chat.AgentMode == agentModeAll {
+		count = len(chat.Agents)
+	}
+
+	for i := 0; i < count; i++ {
+		chat = findChat(chatID)
+		if chat == nil {
+			return
+		}
+		agent := chat.Agents[chat.NextAgent%len(chat.Agents)]
+		chat.NextAgent = (chat.NextAgent + 1) % len(chat.Agents)
+
+		model := agent.Model
+		if model == "" {
+			model = currentModel
+		}
+		reply, err := llm.AgentReply(appCtx, model, agent.Name, agent.Instructions,
+			append(agentNames(chat), "You"), chatTranscript(chat))
+		if err != nil {
+			AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
+			return
+		}
+		AddMessage(chatID, reply, agent.Name, true)
+	}
+}
+
+// refreshAgentBar shows the moderator controls when the current chat has agents
+func refreshAgentBar() {
+	if agentBar == nil {
+		return
+	}
+	chat := currentChatEntry()
+	if chat == nil || len(chat.Agents) == 0 {
+		agentBar.Hide()
+		return
+	}
+
+	chatID := chat.ID
+	label := widget.NewLabel("Agents: " + strings.Join(agentNames(chat), ", "))
+	modeSelect := widget.NewSelect([]string{agentModeRoundRobin, agentModeAll}, func(mode string) {
+		if c := findChat(chatID); c != nil {
+			c.AgentMode = mode
+		}
+	})
+	if chat.AgentMode == "" {
+		chat.AgentMode = agentModeRoundRobin
+	}
+	modeSelect.SetSelected(chat.AgentMode)
+
+	nextBtn := widget.NewButtonWithIcon("Next turn", theme.MediaPlayIcon(), func() {
+		go runAgentTurns(chatID, false)
+	})
+	debateBtn := widget.NewButtonWithIcon("Debate round", theme.MediaFastForwardIcon(), func() {
+		go runAgentTurns(chatID, true)
+	})
+	copyBtn := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		var transcript strings.Builder
+		for _, turn := range chatTranscript(findChat(chatID)) {
+			fmt.Fprintf(&transcript, "%s: %s\n\n", turn.Speaker, turn.Text)
+		}
+		mainWindow.Clipboard().SetContent(transcript.String())
+	})
+
+	agentBar.Objects = []fyne.CanvasObject{
+		container.NewHBox(label, layout.NewSpacer(), modeSelect, nextBtn, debateBtn, copyBtn),
+	}
+	agentBar.Refresh()
+	agentBar.Show()
+}
+
+// showAgentsDialog lets the user add and remove AI participants of the current chat
+func showAgentsDialog(w fyne.Window) {
+	chat := currentChatEntry()
+	if chat == nil {
+		return
+	}
+	chatID := chat.ID
+
+	var modelNames []string
+	if settings, err := database.GetSettings(appCtx); err == nil && settings != nil {
+		if models, err := database.GetModelsByCompany(appCtx, settings.CompanyID); err == nil {
+			for _, model := range models {
+				modelNames = append(modelNames, model.Name)
+			}
+		}
+	}
+
+	list := container.NewVBox()
+	var refreshList func()
+	refreshList = func() {
+		list.Objects = nil
+		c := findChat(chatID)
+		for i, agent := range c.Agents {
+			index := i
+			model := agent.Model
+			if model == "" {
+				model = "current model"
+			}
+			removeBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				c := findChat(chatID)
+				c.Agents = append(c.Agents[:index], c.Agents[index+1:]...)
+				c.NextAgent = 0
+				refreshList()
+				refreshAgentBar()
+			})
+			list.Add(container.NewHBox(widget.NewLabel(fmt.Sprintf("%s (%s)", agent.Name, model)), layout.NewSpacer(), removeBtn))
+		}
+		if len(c.Agents) == 0 {
+			list.Add(widget.NewLabel("No agents, the chat uses a single assistant"))
+		}
+		list.Refresh()
+	}
+	refreshList()
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Name, e.g. Optimist")
+	modelSelect := widget.NewSelect(modelNames, nil)
+	modelSelect.PlaceHolder = "Current model"
+	instructionsEntry := widget.NewMultiLineEntry()
+	instructionsEntry.SetPlaceHolder("Persona and point of view")
+	instructionsEntry.Wrapping = fyne.TextWrapWord
+
+	addBtn := widget.NewButtonWithIcon("Add agent", theme.ContentAddIcon(), func() {
+		name := strings.TrimSpace(nameEntry.Text)
+		if name == "" || name == "You" || name == "AI" || name == "System" {
+			dialog.ShowError(fmt.Errorf("Please enter a unique agent name"), w)
+			return
+		}
+		c := findChat(chatID)
+		for _, agent := range c.Agents {
+			if agent.Name == name {
+				dialog.ShowError(fmt.Errorf("An agent named %s already exists", name), w)
+				return
+			}
+		}
+		c.Agents = append(c.Agents, Agent{
+			Name:         name,
+			Model:        modelSelect.Selected,
+			Instructions: instructionsEntry.Text,
+		})
+		nameEntry.SetText("")
+		instructionsEntry.SetText("")
+		modelSelect.ClearSelected()
+		refreshList()
+		refreshAgentBar()
+	})
+
+	content := container.NewVBox(
+		list,
+		widget.NewSeparator(),
+		widget.NewForm(
+			&widget.FormItem{Text: "Name", Widget: nameEntry},
+			&widget.FormItem{Text: "Model", Widget: modelSelect},
+			&widget.FormItem{Text: "Instructions", Widget: instructionsEntry},
+		),
+		container.NewHBox(layout.NewSpacer(), addBtn),
+	)
+
+	d := dialog.NewCustom("Agents", "Close", container.NewVScroll(content), w)
+	d.Resize(fyne.NewSize(500, 450))
+	d.Show()
+}
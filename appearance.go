@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/themes/dracula"
+)
+
+// Line spacing choices, as multiples of the text size
+var lineSpacings = map[string]float32{
+	"Normal": 1,
+	"1.25×":  1.25,
+	"1.5×":   1.5,
+	"2×":     2,
+}
+
+// lineSpacingNames lists the line spacing choices in order
+var lineSpacingNames = []string{"Normal", "1.25×", "1.5×", "2×"}
+
+// Reading fonts
+const (
+	fontDefault      = "Default"
+	fontAtkinson     = "Atkinson Hyperlegible"
+	fontOpenDyslexic = "OpenDyslexic"
+)
+
+// readingFonts lists the font choices in order
+var readingFonts = []string{fontDefault, fontAtkinson, fontOpenDyslexic}
+
+// fontFileWords are the words every file name of a reading font contains
+var fontFileWords = map[string][]string{
+	fontAtkinson:     {"atkinson", "hyperlegible"},
+	fontOpenDyslexic: {"opendyslexic"},
+}
+
+// Color schemes
+const (
+	schemeDracula   = "Dracula"
+	schemeSafeDark  = "Color-blind safe, dark"
+	schemeSafeLight = "Color-blind safe, light"
+)
+
+// colorSchemeNames lists the color schemes in order
+var colorSchemeNames = []string{schemeDracula, schemeSafeDark, schemeSafeLight}
+
+// colorScheme overrides colors of the Dracula theme, or of Fyne's light theme
+type colorScheme struct {
+	light  bool
+	colors map[fyne.ThemeColorName]color.Color
+}
+
+// colorSchemes maps the schemes to their colors. The color-blind safe ones
+// take their accents from the Okabe-Ito palette, whose colors stay apart with
+// deuteranopia and protanopia; errors are vermillion and successes bluish
+// green instead of red and green.
+var colorSchemes = map[string]colorScheme{
+	schemeDracula: {},
+	schemeSafeDark: {colors: map[fyne.ThemeColorName]color.Color{
+		theme.ColorNamePrimary:   color.NRGBA{0x56, 0xb4, 0xe9, 0xff}, // Sky blue
+		theme.ColorNameHyperlink: color.NRGBA{0x56, 0xb4, 0xe9, 0xff},
+		theme.ColorNameFocus:     color.NRGBA{0x56, 0xb4, 0xe9, 0x7f},
+		theme.ColorNameSelection: color.NRGBA{0x56, 0xb4, 0xe9, 0x3f},
+		theme.ColorNameError:     color.NRGBA{0xd5, 0x5e, 0x00, 0xff}, // Vermillion
+		theme.ColorNameSuccess:   color.NRGBA{0x00, 0x9e, 0x73, 0xff}, // Bluish green
+		theme.ColorNameWarning:   color.NRGBA{0xf0, 0xe4, 0x42, 0xff}, // Yellow
+	}},
+	schemeSafeLight: {light: true, colors: map[fyne.ThemeColorName]color.Color{
+		theme.ColorNamePrimary:   color.NRGBA{0x00, 0x72, 0xb2, 0xff}, // Blue
+		theme.ColorNameHyperlink: color.NRGBA{0x00, 0x72, 0xb2, 0xff},
+		theme.ColorNameFocus:     color.NRGBA{0x00, 0x72, 0xb2, 0x7f},
+		theme.ColorNameSelection: color.NRGBA{0x00, 0x72, 0xb2, 0x3f},
+		theme.ColorNameError:     color.NRGBA{0xd5, 0x5e, 0x00, 0xff}, // Vermillion
+		theme.ColorNameSuccess:   color.NRGBA{0x00, 0x9e, 0x73, 0xff}, // Bluish green
+		theme.ColorNameWarning:   color.NRGBA{0xe6, 0x9f, 0x00, 0xff}, // Orange
+	}},
+}
+
+// readableTheme applies the color scheme, line spacing and font preferences
+// on top of the app's theme
+type readableTheme struct {
+	fyne.Theme
+	scheme      colorScheme
+	lineSpacing float32                          // Multiple of the text size
+	fonts       map[fyne.TextStyle]fyne.Resource // Regular, bold, italic and bold italic
+}
+
+func (t readableTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if c, ok := t.scheme.colors[name]; ok {
+		return c
+	}
+	if t.scheme.light {
+		return theme.DefaultTheme().Color(name, theme.VariantLight)
+	}
+	return t.Theme.Color(name, variant)
+}
+
+func (t readableTheme) Size(name fyne.ThemeSizeName) float32 {
+	size := t.Theme.Size(name)
+	if name == theme.SizeNameLineSpacing {
+		size += (t.lineSpacing - 1) * t.Theme.Size(theme.SizeNameText)
+	}
+	return size
+}
+
+func (t readableTheme) Font(style fyne.TextStyle) fyne.Resource {
+	if style.Monospace || style.Symbol || len(t.fonts) == 0 {
+		return t.Theme.Font(style)
+	}
+	key := fyne.TextStyle{Bold: style.Bold, Italic: style.Italic}
+	if font, ok := t.fonts[key]; ok {
+		return font
+	}
+	return t.fonts[fyne.TextStyle{}]
+}
+
+// applyAppearance sets the theme with the reading preferences. A font that is
+// not installed leaves the default font and is reported.
+func applyAppearance() error {
+	t := readableTheme{Theme: &dracula.DraculaTheme{}, lineSpacing: 1}
+	if value, err := database.GetPreference(appCtx, database.PrefColorScheme); err == nil {
+		t.scheme = colorSchemes[value]
+	}
+	if value, err := database.GetPreference(appCtx, database.PrefLineSpacing); err == nil {
+		if spacing, ok := lineSpacings[value]; ok {
+			t.lineSpacing = spacing
+		}
+	}
+
+	var fontErr error
+	if name, err := database.GetPreference(appCtx, database.PrefReadingFont); err == nil && name != "" && name != fontDefault {
+		t.fonts, fontErr = loadReadingFont(name)
+	}
+	fyne.CurrentApp().Settings().SetTheme(t)
+	return fontErr
+}
+
+// fontDirs returns the folders searched for reading fonts: the fonts folder of
+// the data directory first, then the user's and the system's font folders
+func fontDirs() []string {
+	dirs := []string{filepath.Join(database.DataDir(), "fonts")}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs,
+			filepath.Join(home, ".local", "share", "fonts"),
+			filepath.Join(home, ".fonts"),
+			filepath.Join(home, "Library", "Fonts"),
+		)
+	}
+	if local := os.Getenv("LOCALAPPDATA"); local != "" {
+		dirs = append(dirs, filepath.Join(local, "Microsoft", "Windows", "Fonts"))
+	}
+	if windows := os.Getenv("WINDIR"); windows != "" {
+		dirs = append(dirs, filepath.Join(windows, "Fonts"))
+	}
+	return append(dirs, "/usr/share/fonts", "/usr/local/share/fonts", "/Library/Fonts")
+}
+
+// loadReadingFont reads the styles of a reading font from the first font
+// folder that has its regular style
+func loadReadingFont(name string) (map[fyne.TextStyle]fyne.Resource, error) {
+	words, ok := fontFileWords[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown font %q", name)
+	}
+	for _, dir := range fontDirs() {
+		fonts := make(map[fyne.TextStyle]fyne.Resource)
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			style, ok := fontFileStyle(d.Name(), words)
+			if !ok || fonts[style] != nil {
+				return nil
+			}
+			font, err := fyne.LoadResourceFromPath(path)
+			if err != nil {
+				log.Printf("Failed to load font %s: %v", path, err)
+				return nil
+			}
+			fonts[style] = font
+			return nil
+		})
+		if fonts[fyne.TextStyle{}] != nil {
+			return fonts, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not installed; put its .ttf or .otf files in %s", name, filepath.Join(database.DataDir(), "fonts"))
+}
+
+// fontFileStyle tells whether file is a font file of the font named by words
+// and which style it holds
+func fontFileStyle(file string, words []string) (fyne.TextStyle, bool) {
+	lower := strings.ToLower(file)
+	ext := filepath.Ext(lower)
+	if ext != ".ttf" && ext != ".otf" {
+		return fyne.TextStyle{}, false
+	}
+	for _, word := range words {
+		if !strings.Contains(lower, word) {
+			return fyne.TextStyle{}, false
+		}
+	}
+	style := fyne.TextStyle{
+		Bold:   strings.Contains(lower, "bold"),
+		Italic: strings.Contains(lower, "italic"),
+	}
+	// Mono, light and other variants are left out; version numbers are not
+	rest := strings.TrimSuffix(lower, ext)
+	for _, word := range append([]string{"regular", "bold", "italic"}, words...) {
+		rest = strings.ReplaceAll(rest, word, "")
+	}
+	rest = strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || strings.ContainsRune("-_ ", r) {
+			return -1
+		}
+		return r
+	}, rest)
+	return style, rest == ""
+}
+
+// roleIconsEnabled reports whether senders are shown with an icon of their
+// role, which is on unless turned off in the settings
+func roleIconsEnabled() bool {
+	value, _ := database.GetPreference(appCtx, database.PrefRoleIcons)
+	return value != "false"
+}
+
+// roleStyle returns the icon and color of the sender of a message, so roles
+// differ by shape and name, not by color alone
+func roleStyle(sender, text string) (fyne.Resource, widget.Importance) {
+	switch {
+	case sender == "You":
+		return theme.AccountIcon(), widget.HighImportance
+	case sender == "AI":
+		return theme.ComputerIcon(), widget.SuccessImportance
+	case sender == "System" && strings.HasPrefix(text, "Error"):
+		return theme.ErrorIcon(), widget.DangerImportance
+	case sender == "System":
+		return theme.InfoIcon(), widget.WarningImportance
+	default:
+		// Agents, test runs and other tools
+		return theme.SettingsIcon(), widget.MediumImportance
+	}
+}
+
+// reducedMotion reports whether motion is reduced for users sensitive to it:
+// answers appear a paragraph at a time without following the stream, and
+// busy indicators stand still. It is off unless turned on in the settings.
+func reducedMotion() bool {
+	value, _ := database.GetPreference(appCtx, database.PrefReducedMotion)
+	return value == "true"
+}
+
+// newBusyIndicator returns an infinite progress bar, or a still label when
+// motion is reduced
+func newBusyIndicator() fyne.CanvasObject {
+	if reducedMotion() {
+		label := widget.NewLabel("Working…")
+		label.Importance = widget.LowImportance
+		return label
+	}
+	return widget.NewProgressBarInfinite()
+}
+
+// newSenderLabel returns the role icon and the name of the sender of a message
+func newSenderLabel(sender, text string) (*widget.Icon, *widget.Label) {
+	resource, importance := roleStyle(sender, text)
+	icon := widget.NewIcon(resource)
+	if !roleIconsEnabled() {
+		icon.Hide()
+	}
+	label := widget.NewLabel(sender)
+	label.TextStyle = fyne.TextStyle{Italic: true}
+	label.Importance = importance
+	return icon, label
+}
+
+// forEachMessageItem calls f with every message shown in the opened chats
+func forEachMessageItem(f func(*messageItem)) {
+	for _, msgContainer := range chatContainers {
+		for _, object := range msgContainer.Objects {
+			if item, ok := object.(*messageItem); ok {
+				f(item)
+			}
+		}
+	}
+}
+
+// refreshRoleIcons shows or hides the role icons of the opened chats after
+// the setting changed
+func refreshRoleIcons() {
+	enabled := roleIconsEnabled()
+	forEachMessageItem(func(item *messageItem) {
+		if item.icon == nil {
+			return
+		}
+		if enabled {
+			item.icon.Show()
+		} else {
+			item.icon.Hide()
+		}
+	})
+}
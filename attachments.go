@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// showAttachmentsDialog lists the attachments of the current chat with
+// buttons to open them in their default application
+func showAttachmentsDialog(w fyne.Window) {
+	chat := currentChatEntry()
+	if chat == nil {
+		return
+	}
+	attachments, err := database.GetChatAttachments(appCtx, chat.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load attachments: %v", err), w)
+		return
+	}
+
+	var d dialog.Dialog
+	list := container.NewVBox()
+	for _, a := range attachments {
+		attachment := a
+		openBtn := widget.NewButtonWithIcon("", theme.FileIcon(), func() {
+			if err := openAttachment(attachment); err != nil {
+				dialog.ShowError(err, w)
+			}
+		})
+		name := widget.NewLabel(attachment.Name)
+		name.Truncation = fyne.TextTruncateEllipsis
+		info := widget.NewLabel(fmt.Sprintf("%s, %s, %s", attachment.MimeType, formatBytes(attachment.Size), attachment.CreatedAt.Local().Format("2006-01-02 15:04")))
+		info.Importance = widget.LowImportance
+		details := container.NewVBox(name, info)
+		if attachment.Description != "" {
+			description := widget.NewLabel(attachment.Description)
+			description.Wrapping = fyne.TextWrapWord
+			details.Add(description)
+		}
+		buttons := container.NewHBox(openBtn)
+		if isGoSource(attachment) {
+			buttons.Add(widget.NewButton("Generate tests", func() {
+				d.Hide()
+				generateTests(w, chat.ID, attachment)
+			}))
+		}
+		list.Add(container.NewBorder(nil, nil, nil, buttons, details))
+	}
+	if len(attachments) == 0 {
+		list.Add(widget.NewLabel("No attachments in this chat"))
+	}
+
+	d = dialog.NewCustom("Attachments", "Close", container.NewVScroll(list), w)
+	d.Resize(fyne.NewSize(500, 400))
+	d.Show()
+}
+
+// openAttachment opens an attachment with the default application of its type
+func openAttachment(a database.Attachment) error {
+	path, err := a.File()
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(storage.NewFileURI(path).String())
+	if err != nil {
+		return err
+	}
+	return fyne.CurrentApp().OpenURL(u)
+}
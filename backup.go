@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// backupDatabase asks where to save a backup of the database and writes it
+func backupDatabase(w fyne.Window) {
+	picker := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		// The backup is written by SQLite, which refuses to overwrite a file
+		path := writer.URI().Path()
+		writer.Close()
+		os.Remove(path)
+
+		if err := database.Backup(appCtx, path); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("Backup", fmt.Sprintf("Chats, settings and attachments saved to %s", path), w)
+	}, w)
+	picker.SetFileName(database.BackupName())
+	picker.Show()
+}
+
+// restoreDatabase asks for a backup file and replaces the database with it
+// after confirmation
+func restoreDatabase(w fyne.Window) {
+	picker := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		path := reader.URI().Path()
+		reader.Close()
+
+		dialog.ShowConfirm("Restore backup",
+			"Replace all chats and settings with the backup? A copy of the current data is kept in the backups folder.",
+			func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				safety, err := database.Restore(appCtx, path)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				reloadChats()
+				restartBridges()
+				dialog.ShowInformation("Restore", fmt.Sprintf("Backup restored. The previous data was saved to %s", safety), w)
+			}, w)
+	}, w)
+	picker.SetFilter(storage.NewExtensionFileFilter([]string{".db"}))
+	picker.Show()
+}
+
+// reloadChats replaces the chats in the sidebar with the ones in the database
+func reloadChats() {
+	stopSelecting()
+	chatsMu.Lock()
+	chats = nil
+	chatsMu.Unlock()
+	chatContainers = make(map[int]*fyne.Container)
+	currentChat = nil
+	mainContainer.Objects = []fyne.CanvasObject{container.NewVBox()}
+	mainContainer.Refresh()
+
+	loadChats()
+	chatList.UnselectAll()
+	chatList.Refresh()
+	if len(chats) == 0 {
+		createNewChat()
+	} else {
+		selectChat(chats[len(chats)-1].ID)
+	}
+	refreshChatBars()
+}
+
+// exportDatabaseJSON asks where to save the chats and settings as JSON and
+// writes them there
+func exportDatabaseJSON(w fyne.Window) {
+	picker := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		if err := database.ExportAll(appCtx, writer); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to export: %v", err), w)
+			return
+		}
+		dialog.ShowInformation("Export", "Chats and settings exported. API keys, tokens and passwords are not included.", w)
+	}, w)
+	picker.SetFileName("llmschat-export.json")
+	picker.Show()
+}
+
+// importDatabaseJSON asks for a JSON export and adds its chats and settings
+// to the current ones
+func importDatabaseJSON(w fyne.Window) {
+	picker := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+		imported, err := database.ImportAll(appCtx, reader)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to import: %v", err), w)
+			return
+		}
+		reloadChats()
+		dialog.ShowInformation("Import", fmt.Sprintf("Imported %d chats. Enter the API keys again in the settings.", imported), w)
+	}, w)
+	picker.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	picker.Show()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// benchModel collects the runs of a model in a benchmark
+type benchModel struct {
+	name   string
+	runs   []llm.BenchResult
+	failed int
+}
+
+// cliBench sends the same prompt to every model a number of times, in turns
+// so a slow minute of the network hits them all, and compares the answers'
+// latency, throughput and cost
+func cliBench(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	modelList := flags.String("models", "", "comma separated models, optionally as Company/model")
+	promptFile := flags.String("prompt", "", "file with the prompt, - for stdin")
+	runs := flags.Int("runs", 3, "runs per model")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *modelList == "" || *promptFile == "" {
+		return fmt.Errorf("bench needs --models and --prompt")
+	}
+	if *runs < 1 {
+		return fmt.Errorf("bench needs at least one run")
+	}
+
+	var data []byte
+	var err error
+	if *promptFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*promptFile)
+	}
+	if err != nil {
+		return err
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return fmt.Errorf("the prompt is empty")
+	}
+
+	var models []*benchModel
+	for _, name := range strings.Split(*modelList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			models = append(models, &benchModel{name: name})
+		}
+	}
+	defer llm.StopLocalServer()
+
+	for run := 1; run <= *runs; run++ {
+		for _, m := range models {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			result, err := llm.Bench(ctx, m.name, prompt)
+			if err != nil {
+				m.failed++
+				fmt.Fprintf(os.Stderr, "run %d/%d %s: %v\n", run, *runs, m.name, err)
+				continue
+			}
+			m.runs = append(m.runs, result)
+			fmt.Fprintf(os.Stderr, "run %d/%d %s: %s to first token, %.1f tokens/s\n",
+				run, *runs, m.name, result.TimeToFirstToken.Round(time.Millisecond), result.TokensPerSecond)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tRUNS\tFAILED\tFIRST TOKEN\tTOKENS/S\tTOKENS\tCOST/RUN")
+	succeeded := false
+	for _, m := range models {
+		if len(m.runs) == 0 {
+			fmt.Fprintf(w, "%s\t0\t%d\t-\t-\t-\t-\n", m.name, m.failed)
+			continue
+		}
+		succeeded = true
+		var firsts []time.Duration
+		var rates []float64
+		tokens, cost, priced := 0, 0.0, true
+		for _, r := range m.runs {
+			firsts = append(firsts, r.TimeToFirstToken)
+			rates = append(rates, r.TokensPerSecond)
+			tokens += r.CompletionTokens
+			cost += r.Cost
+			priced = priced && r.Priced
+		}
+		costText := "n/a"
+		if priced {
+			costText = fmt.Sprintf("$%.5f", cost/float64(len(m.runs)))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%.1f\t%d\t%s\n", m.name, len(m.runs), m.failed,
+			median(firsts).Round(time.Millisecond), median(rates), tokens/len(m.runs), costText)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println("\nFirst token and tokens/s are medians, tokens and cost are averages per run.")
+	if !succeeded {
+		return fmt.Errorf("every run failed")
+	}
+	return nil
+}
+
+// median returns the middle value of values, or the mean of the two middle ones
+func median[T time.Duration | float64](values []T) T {
+	sorted := append([]T(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+	"github.com/devalexandre/llmschat/telegram"
+)
+
+// telegramChatTitle is the title of the chat that logs the bot conversation
+const telegramChatTitle = "Telegram"
+
+// telegramRetryDelay is how long the bridge waits after a failed poll
+const telegramRetryDelay = 10 * time.Second
+
+// telegramBridge holds the cancel function of the running bridge
+var telegramBridge struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// restartBridges restarts every chat bridge after the settings changed
+func restartBridges() {
+	restartTelegramBridge()
+	restartTeamBridges()
+}
+
+// restartTelegramBridge stops the running bridge and starts a new one when a
+// bot token is configured
+func restartTelegramBridge() {
+	telegramBridge.Lock()
+	defer telegramBridge.Unlock()
+
+	if telegramBridge.cancel != nil {
+		telegramBridge.cancel()
+		telegramBridge.cancel = nil
+	}
+
+	token, err := database.GetPreference(appCtx, database.PrefTelegramToken)
+	if err != nil || token == "" {
+		return
+	}
+	ctx, cancel := context.WithCancel(appCtx)
+	telegramBridge.cancel = cancel
+	go runTelegramBridge(ctx, token)
+}
+
+// runTelegramBridge answers the bot's messages until ctx is cancelled
+func runTelegramBridge(ctx context.Context, token string) {
+	log.Printf("Telegram bridge started")
+	for ctx.Err() == nil {
+		client, err := llm.HTTPClient(appCtx)
+		if err != nil {
+			log.Printf("Telegram bridge: %v", err)
+			return
+		}
+		bot := &telegram.Bot{Token: token, HTTP: client}
+
+		for ctx.Err() == nil {
+			updates, err := bot.Updates(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Telegram bridge: %v", err)
+					time.Sleep(telegramRetryDelay)
+				}
+				break
+			}
+			for _, update := range updates {
+				if update.Message != nil && update.Message.From != nil && update.Message.Text != "" {
+					handleTelegramMessage(ctx, bot, update.Message)
+				}
+			}
+		}
+	}
+	log.Printf("Telegram bridge stopped")
+}
+
+// handleTelegramMessage answers one message from an allowed user and logs the
+// exchange in the Telegram chat
+func handleTelegramMessage(ctx context.Context, bot *telegram.Bot, msg *telegram.Message) {
+	if !telegramUserAllowed(msg.From) {
+		log.Printf("Telegram bridge: ignoring message from %s (%d)", msg.From.Name(), msg.From.ID)
+		reply := fmt.Sprintf("You are not allowed to use this bot. Add your user ID %d to the allowed users in the app settings.", msg.From.ID)
+		if err := bot.Send(ctx, msg.Chat.ID, reply); err != nil {
+			log.Printf("Telegram bridge: %v", err)
+		}
+		return
+	}
+
+	chat := bridgeChat(database.PrefTelegramChatID, telegramChatTitle)
+	answer := bridgeAnswer(chat, "You", msg.Text, systemPrompt(chat))
+	if err := bot.Send(ctx, msg.Chat.ID, answer); err != nil {
+		log.Printf("Telegram bridge: %v", err)
+	}
+}
+
+// telegramUserAllowed reports whether user is in the allowed list, given as
+// comma-separated user IDs or @usernames
+func telegramUserAllowed(user *telegram.User) bool {
+	allowed, err := database.GetPreference(appCtx, database.PrefTelegramAllowed)
+	if err != nil {
+		return false
+	}
+	for _, entry := range strings.Split(allowed, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == strconv.FormatInt(user.ID, 10) || (user.Username != "" && strings.EqualFold(strings.TrimPrefix(entry, "@"), user.Username)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bridgeChat returns the chat whose ID is stored under key, creating it with
+// title when needed
+func bridgeChat(key, title string) *Chat {
+	if value, err := database.GetPreference(appCtx, key); err == nil {
+		if id, err := strconv.Atoi(value); err == nil {
+			if chat := findChat(id); chat != nil {
+				return chat
+			}
+		}
+	}
+
+	chat := newChat(title)
+	if err := database.SetPreference(appCtx, key, strconv.Itoa(chat.ID)); err != nil {
+		log.Printf("Failed to save %s chat: %v", title, err)
+	}
+	chatList.Refresh()
+	return chat
+}
+
+// bridgeAnswer archives a message of sender in chat, asks the current model and
+// archives and returns its answer
+func bridgeAnswer(chat *Chat, sender, text, system string) string {
+	AddMessage(chat.ID, text, sender, false)
+	notify(chat.ID, database.EventBridge, sender+": "+text)
+
+	prompt := text
+	if sender != "You" {
+		prompt = sender + ": " + text
+	}
+	answer, err := llm.GetResponse(appCtx, llm.Request{
+		Prompt:       prompt,
+		Model:        currentModel,
+		SystemPrompt: system,
+		ChatID:       chat.ID,
+	})
+	if err != nil {
+		answer = fmt.Sprintf("Error: %v", err)
+		AddMessage(chat.ID, answer, "System", true)
+		return answer
+	}
+	AddMessage(chat.ID, answer, "AI", true)
+	return answer
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/calendar"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// calendarDays is how far ahead the model can see the calendar
+const calendarDays = 14
+
+// calendarRefresh is how long loaded events are reused before reloading
+const calendarRefresh = 5 * time.Minute
+
+// calendarCache keeps the last loaded events so every message does not refetch them
+var calendarCache struct {
+	sync.Mutex
+	source string
+	loaded time.Time
+	events []calendar.Event
+}
+
+// calendarEvents loads the events of the configured calendar source
+func calendarEvents() ([]calendar.Event, bool) {
+	source, err := database.GetPreference(appCtx, database.PrefCalendarSource)
+	if err != nil || source == "" {
+		return nil, false
+	}
+
+	calendarCache.Lock()
+	defer calendarCache.Unlock()
+	if calendarCache.source == source && time.Since(calendarCache.loaded) < calendarRefresh {
+		return calendarCache.events, true
+	}
+
+	client, err := llm.HTTPClient(appCtx)
+	if err != nil {
+		log.Printf("Failed to load calendar: %v", err)
+		return nil, false
+	}
+	events, err := calendar.Load(appCtx, client, source)
+	if err != nil {
+		log.Printf("Failed to load calendar: %v", err)
+		return nil, false
+	}
+	calendarCache.source = source
+	calendarCache.loaded = time.Now()
+	calendarCache.events = events
+	return events, true
+}
+
+// calendarPrompt returns the read-only calendar tool for the system prompt, or
+// an empty string when no calendar is configured
+func calendarPrompt() string {
+	events, ok := calendarEvents()
+	if !ok {
+		return ""
+	}
+	return calendar.ToolPrompt(events, time.Now(), calendarDays)
+}
+
+// calendarTarget returns where confirmed events are written: the configured
+// ICS file or CalDAV collection, falling back to a local source file
+func calendarTarget() string {
+	if target, err := database.GetPreference(appCtx, database.PrefCalendarTarget); err == nil && target != "" {
+		return target
+	}
+	source, err := database.GetPreference(appCtx, database.PrefCalendarSource)
+	if err != nil || source == "" || calendar.IsURL(source) {
+		return ""
+	}
+	return source
+}
+
+// addEventProposals adds a confirm button for every event proposed in reply
+func addEventProposals(box *fyne.Container, reply string) {
+	for _, event := range calendar.ParseProposals(reply) {
+		event := event
+		var btn *widget.Button
+		btn = widget.NewButtonWithIcon("Add to calendar: "+calendar.Describe(event), theme.ContentAddIcon(), func() {
+			confirmEvent(event, btn)
+		})
+		box.Add(btn)
+	}
+}
+
+// confirmEvent asks the user before writing a proposed event to the calendar
+func confirmEvent(event calendar.Event, btn *widget.Button) {
+	target := calendarTarget()
+	if target == "" {
+		dialog.ShowError(fmt.Errorf("Please set a calendar file or CalDAV URL to save events in settings"), mainWindow)
+		return
+	}
+
+	dialog.ShowConfirm("Add event",
+		fmt.Sprintf("Add \"%s\" to %s?", calendar.Describe(event), target),
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			client, err := llm.HTTPClient(appCtx)
+			if err != nil {
+				dialog.ShowError(err, mainWindow)
+				return
+			}
+			username, _ := database.GetPreference(appCtx, database.PrefCalDAVUser)
+			password, _ := database.GetPreference(appCtx, database.PrefCalDAVPassword)
+			if err := calendar.Save(appCtx, client, target, username, password, event); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to save event: %v", err), mainWindow)
+				return
+			}
+
+			// Reload on the next message so the model sees the new event
+			calendarCache.Lock()
+			calendarCache.loaded = time.Time{}
+			calendarCache.Unlock()
+
+			btn.SetText("Added: " + calendar.Describe(event))
+			btn.SetIcon(theme.ConfirmIcon())
+			btn.Disable()
+		}, mainWindow)
+}
@@ -0,0 +1,135 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event is a single calendar entry
+type Event struct {
+	UID         string
+	Summary     string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Location    string
+	Description string
+}
+
+// IsURL reports whether location points to a remote calendar
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// Load reads the events of an ICS file or an ICS feed URL
+func Load(ctx context.Context, client *http.Client, source string) ([]Event, error) {
+	if !IsURL(source) {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open calendar: %v", err)
+		}
+		defer f.Close()
+		return ParseICS(f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch calendar: status %d", resp.StatusCode)
+	}
+	return ParseICS(resp.Body)
+}
+
+// Between returns the events overlapping [from, to), sorted by start time
+func Between(events []Event, from, to time.Time) []Event {
+	var result []Event
+	for _, event := range events {
+		if event.Start.Before(to) && event.End.After(from) {
+			result = append(result, event)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result
+}
+
+// NewUID returns a unique identifier for a new event
+func NewUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b) + "@llmschat"
+}
+
+// Save writes event to an ICS file, creating it if needed, or PUTs it into a
+// CalDAV collection when target is a URL
+func Save(ctx context.Context, client *http.Client, target, username, password string, event Event) error {
+	if event.UID == "" {
+		event.UID = NewUID()
+	}
+	if IsURL(target) {
+		return putCalDAV(ctx, client, target, username, password, event)
+	}
+	return appendToFile(target, event)
+}
+
+// appendToFile inserts event before the closing VCALENDAR of an ICS file
+func appendToFile(path string, event Event) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return os.WriteFile(path, []byte(FormatCalendar(event)), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read calendar file: %v", err)
+	}
+
+	content := string(data)
+	end := strings.LastIndex(content, "END:VCALENDAR")
+	if end < 0 {
+		return fmt.Errorf("%s is not an iCalendar file", path)
+	}
+	content = content[:end] + FormatEvent(event) + content[end:]
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write calendar file: %v", err)
+	}
+	return nil
+}
+
+// putCalDAV creates event as a new resource in a CalDAV collection
+func putCalDAV(ctx context.Context, client *http.Client, collection, username, password string, event Event) error {
+	url := strings.TrimRight(collection, "/") + "/" + strings.TrimSuffix(event.UID, "@llmschat") + ".ics"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader([]byte(FormatCalendar(event))))
+	if err != nil {
+		return fmt.Errorf("failed to create CalDAV request: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.Header.Set("If-None-Match", "*")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("CalDAV request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("CalDAV request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
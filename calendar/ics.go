@@ -0,0 +1,177 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICS date-time layouts
+const (
+	icsUTC   = "20060102T150405Z"
+	icsLocal = "20060102T150405"
+	icsDate  = "20060102"
+)
+
+// ParseICS reads the VEVENTs of an iCalendar stream. Recurrence rules are not
+// expanded, so only the first occurrence of a recurring event is returned.
+func ParseICS(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	for _, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &Event{}
+		case name == "END" && value == "VEVENT":
+			if current != nil && !current.Start.IsZero() {
+				if current.End.IsZero() {
+					current.End = current.Start
+					if current.AllDay {
+						current.End = current.Start.AddDate(0, 0, 1)
+					}
+				}
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescapeText(value)
+		case name == "LOCATION":
+			current.Location = unescapeText(value)
+		case name == "DESCRIPTION":
+			current.Description = unescapeText(value)
+		case name == "DTSTART":
+			current.Start, current.AllDay, err = parseDateTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+		case name == "DTEND":
+			current.End, _, err = parseDateTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return events, nil
+}
+
+// unfold joins continuation lines, which start with a space or a tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %v", err)
+	}
+	return lines, nil
+}
+
+// splitProperty splits "NAME;PARAM=x:value" into its name, parameters and value
+func splitProperty(line string) (string, map[string]string, string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		if key, val, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// parseDateTime parses a DTSTART/DTEND value, honouring VALUE=DATE and TZID
+func parseDateTime(value string, params map[string]string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(icsDate) {
+		t, err := time.ParseInLocation(icsDate, value, time.Local)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date %q: %v", value, err)
+		}
+		return t, true, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsUTC, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date-time %q: %v", value, err)
+		}
+		return t.Local(), false, nil
+	}
+
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(icsLocal, value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date-time %q: %v", value, err)
+	}
+	return t.Local(), false, nil
+}
+
+// unescapeText reverses iCalendar TEXT escaping
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// escapeText applies iCalendar TEXT escaping
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// FormatEvent encodes event as a VEVENT block with CRLF line endings
+func FormatEvent(event Event) string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\r\n", args...)
+	}
+	line("BEGIN:VEVENT")
+	line("UID:%s", event.UID)
+	line("DTSTAMP:%s", time.Now().UTC().Format(icsUTC))
+	if event.AllDay {
+		line("DTSTART;VALUE=DATE:%s", event.Start.Format(icsDate))
+		line("DTEND;VALUE=DATE:%s", event.End.Format(icsDate))
+	} else {
+		line("DTSTART:%s", event.Start.UTC().Format(icsUTC))
+		line("DTEND:%s", event.End.UTC().Format(icsUTC))
+	}
+	line("SUMMARY:%s", escapeText(event.Summary))
+	if event.Location != "" {
+		line("LOCATION:%s", escapeText(event.Location))
+	}
+	if event.Description != "" {
+		line("DESCRIPTION:%s", escapeText(event.Description))
+	}
+	line("END:VEVENT")
+	return b.String()
+}
+
+// FormatCalendar wraps events in a VCALENDAR
+func FormatCalendar(events ...Event) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//llmschat//EN\r\n")
+	for _, event := range events {
+		b.WriteString(FormatEvent(event))
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+	return b.String()
+}
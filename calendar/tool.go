@@ -0,0 +1,99 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// toolPrompt exposes the calendar to the model and explains how to propose events
+const toolPrompt = `You can read the user's calendar. Now is %s. Their events for the next %d days are:
+%s
+To suggest a new event, add a fenced block like this to your reply, one block per event,
+and tell the user they can confirm it. Never claim an event was added yourself.
+` + "```event" + `
+{"summary": "Dentist", "start": "2024-05-02T15:00", "end": "2024-05-02T16:00", "location": "", "description": ""}
+` + "```"
+
+// proposalPattern matches the fenced event blocks of a reply
+var proposalPattern = regexp.MustCompile("(?s)```event\\s*\\n(.*?)```")
+
+// proposalLayouts are the accepted start/end formats of a proposed event
+var proposalLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// ToolPrompt describes the events in the days after now for the system prompt
+func ToolPrompt(events []Event, now time.Time, days int) string {
+	var list strings.Builder
+	upcoming := Between(events, now, now.AddDate(0, 0, days))
+	for _, event := range upcoming {
+		fmt.Fprintf(&list, "- %s\n", Describe(event))
+	}
+	if len(upcoming) == 0 {
+		list.WriteString("(no events)\n")
+	}
+	return fmt.Sprintf(toolPrompt, now.Format("Monday 2006-01-02 15:04"), days, list.String())
+}
+
+// Describe formats event on one line, e.g. "Mon 2024-05-02 15:00-16:00 Dentist (Main St)"
+func Describe(event Event) string {
+	var when string
+	if event.AllDay {
+		when = event.Start.Format("Mon 2006-01-02") + " all day"
+	} else {
+		when = event.Start.Format("Mon 2006-01-02 15:04") + "-" + event.End.Format("15:04")
+	}
+	text := when + " " + event.Summary
+	if event.Location != "" {
+		text += " (" + event.Location + ")"
+	}
+	return text
+}
+
+// ParseProposals returns the events the model proposed in reply
+func ParseProposals(reply string) []Event {
+	var events []Event
+	for _, match := range proposalPattern.FindAllStringSubmatch(reply, -1) {
+		var proposal struct {
+			Summary     string `json:"summary"`
+			Start       string `json:"start"`
+			End         string `json:"end"`
+			Location    string `json:"location"`
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal([]byte(match[1]), &proposal); err != nil || proposal.Summary == "" {
+			continue
+		}
+		start, allDay, ok := parseProposalTime(proposal.Start)
+		if !ok {
+			continue
+		}
+		end, _, ok := parseProposalTime(proposal.End)
+		if !ok || !end.After(start) {
+			end = start.Add(time.Hour)
+			if allDay {
+				end = start.AddDate(0, 0, 1)
+			}
+		}
+		events = append(events, Event{
+			Summary:     proposal.Summary,
+			Start:       start,
+			End:         end,
+			AllDay:      allDay,
+			Location:    proposal.Location,
+			Description: proposal.Description,
+		})
+	}
+	return events
+}
+
+// parseProposalTime parses a proposed time in local time; date-only values are all day
+func parseProposalTime(value string) (time.Time, bool, bool) {
+	for _, layout := range proposalLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t, layout == "2006-01-02", true
+		}
+	}
+	return time.Time{}, false, false
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// changelogDays is the period a changelog covers by default
+const changelogDays = 14
+
+// folderChats returns the chats in a folder and its subfolders
+func folderChats(folderID int) []*Chat {
+	var found []*Chat
+	for i := range chats {
+		if chats[i].FolderID == folderID {
+			found = append(found, chats[i])
+		}
+	}
+	for _, folder := range folders {
+		if folder.ParentID == folderID {
+			found = append(found, folderChats(folder.ID)...)
+		}
+	}
+	return found
+}
+
+// showChangelog asks for a period and drafts a changelog from the chats of a
+// folder in it, shown in an editor to correct before it is exported
+func showChangelog(w fyne.Window, folderID int) {
+	folder := findFolder(folderID)
+	if folder == nil {
+		return
+	}
+	fromEntry := widget.NewEntry()
+	fromEntry.SetText(time.Now().AddDate(0, 0, -changelogDays).Format("2006-01-02"))
+	toEntry := widget.NewEntry()
+	toEntry.SetText(time.Now().Format("2006-01-02"))
+
+	dialog.ShowForm(fmt.Sprintf("Changelog of %s", folder.Name), "Draft", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("From", fromEntry),
+		widget.NewFormItem("To", toEntry),
+	}, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		from, err := time.ParseInLocation("2006-01-02", fromEntry.Text, time.Local)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Invalid start date %q, use YYYY-MM-DD", fromEntry.Text), w)
+			return
+		}
+		to, err := time.ParseInLocation("2006-01-02", toEntry.Text, time.Local)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Invalid end date %q, use YYYY-MM-DD", toEntry.Text), w)
+			return
+		}
+		draftChangelog(w, folder.Name, folderID, from, to.AddDate(0, 0, 1))
+	}, w)
+}
+
+// draftChangelog has the model draft the changelog of the messages sent in the
+// folder's chats from start until end
+func draftChangelog(w fyne.Window, name string, folderID int, start, end time.Time) {
+	var sources []llm.ChangelogChat
+	for _, chat := range folderChats(folderID) {
+		messages, err := database.GetMessages(appCtx, chat.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load messages: %v", err), w)
+			return
+		}
+		source := llm.ChangelogChat{Title: chat.Title}
+		for _, msg := range messages {
+			if msg.Sender == "System" || msg.CreatedAt.Before(start) || !msg.CreatedAt.Before(end) {
+				continue
+			}
+			source.Transcript = append(source.Transcript, llm.Turn{Speaker: msg.Sender, Text: msg.Text})
+		}
+		if len(source.Transcript) > 0 {
+			sources = append(sources, source)
+		}
+	}
+	period := fmt.Sprintf("%s from %s to %s", name, start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	if len(sources) == 0 {
+		dialog.ShowInformation("Changelog", fmt.Sprintf("No messages were sent in %s.", period), w)
+		return
+	}
+
+	progress := dialog.NewCustomWithoutButtons("Changelog", container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("Drafting the changelog from %d chats...", len(sources))),
+		newBusyIndicator(),
+	), w)
+	progress.Show()
+	go func() {
+		draft, err := llm.DraftChangelog(appCtx, currentModel, period, sources)
+		progress.Hide()
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		editChangelog(w, draft, "changelog-"+end.AddDate(0, 0, -1).Format("2006-01-02")+".md")
+	}()
+}
+
+// editChangelog shows a changelog draft in an editor with a button saving it
+// as Markdown
+func editChangelog(w fyne.Window, draft, fileName string) {
+	editor := widget.NewMultiLineEntry()
+	editor.Wrapping = fyne.TextWrapWord
+	editor.SetText(draft)
+	preview := widget.NewRichTextFromMarkdown(draft)
+	preview.Wrapping = fyne.TextWrapWord
+	editor.OnChanged = func(text string) {
+		preview.ParseMarkdown(text)
+	}
+
+	exportBtn := widget.NewButton("Export", func() {
+		picker := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+			if _, err := writer.Write([]byte(editor.Text)); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to export changelog: %v", err), w)
+			}
+		}, w)
+		picker.SetFileName(fileName)
+		picker.SetFilter(storage.NewExtensionFileFilter([]string{".md"}))
+		picker.Show()
+	})
+	exportBtn.Importance = widget.HighImportance
+
+	split := container.NewHSplit(editor, container.NewScroll(preview))
+	d := dialog.NewCustom("Changelog", "Close", container.NewBorder(nil, container.NewHBox(exportBtn), nil, nil, split), w)
+	d.Resize(fyne.NewSize(1000, 650))
+	d.Show()
+}
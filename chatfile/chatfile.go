@@ -0,0 +1,97 @@
+package chatfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Extension is the file extension of exported chats
+const Extension = ".llmchat"
+
+// MimeType is the MIME type registered for exported chats
+const MimeType = "application/x-llmchat"
+
+// version is the format version written by Write
+const version = 1
+
+// Chat is an exported chat
+type Chat struct {
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	Messages  []Message `json:"messages"`
+}
+
+// Message is a message of an exported chat
+type Message struct {
+	Sender      string       `json:"sender"`
+	Text        string       `json:"text"`
+	IsAI        bool         `json:"is_ai"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment describes a file of a message; the content is not exported
+type Attachment struct {
+	Name        string `json:"name"`
+	MimeType    string `json:"mime_type"`
+	Description string `json:"description,omitempty"`
+}
+
+// Write encodes chat as an indented JSON document
+func Write(w io.Writer, chat Chat) error {
+	chat.Version = version
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(chat)
+}
+
+// Archive holds several exported chats in one file
+type Archive struct {
+	Version int    `json:"version"`
+	Chats   []Chat `json:"chats"`
+}
+
+// Read decodes a chat written by Write
+func Read(r io.Reader) (Chat, error) {
+	chats, err := ReadAll(r)
+	if err != nil {
+		return Chat{}, err
+	}
+	if len(chats) != 1 {
+		return Chat{}, fmt.Errorf("the export holds %d chats, expected one", len(chats))
+	}
+	return chats[0], nil
+}
+
+// ReadAll decodes a single exported chat or an archive of chats
+func ReadAll(r io.Reader) ([]Chat, error) {
+	var doc struct {
+		Chat
+		Chats []Chat `json:"chats"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("not a chat export: %v", err)
+	}
+	if doc.Version < 1 || doc.Version > version {
+		return nil, fmt.Errorf("unsupported chat export version %d", doc.Version)
+	}
+	if doc.Chats != nil {
+		return doc.Chats, nil
+	}
+	return []Chat{doc.Chat}, nil
+}
+
+// Fingerprint identifies the conversation of a chat by its messages, so the
+// same chat is recognized after a rename or a round trip through an export
+func (c Chat) Fingerprint() string {
+	h := sha256.New()
+	for _, msg := range c.Messages {
+		fmt.Fprintf(h, "%s\x00%s\x00", msg.Sender, msg.Text)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// cliCommand describes a command line subcommand for the help, the shell
+// completions and the man page
+type cliCommand struct {
+	name    string   // One word or a group and a word, like "chats list"
+	args    string   // Arguments and flags shown in the usage
+	summary string   // One sentence
+	words   []string // Flags and fixed arguments offered by shell completion
+}
+
+// cliCommands lists the subcommands in the order they are documented
+var cliCommands = []cliCommand{
+	{"chat", "[--stdin-format text|jsonl] [--model NAME]",
+		`Send stdin to the model and stream the answer to stdout. With jsonl every line is a message like {"role":"user","content":"Hi"}; roles are system, user and assistant.`,
+		[]string{"--stdin-format", "--model"}},
+	{"bench", "--models A,B,C --prompt FILE [--runs N]",
+		"Send the prompt in FILE, or stdin for -, to every model N times and compare the time to the first token, tokens per second and cost. Models of other providers are used with their stored API key; write Company/model to pick the provider.",
+		[]string{"--models", "--prompt", "--runs"}},
+	{"chats list", "", "List the stored chats.", nil},
+	{"chats export", "ID | --all [--dir DIR]", "Print a chat as Markdown, or write every chat as a Markdown file into DIR.", []string{"--all", "--dir"}},
+	{"chats search", "QUERY", "Find the messages containing every word of QUERY.", nil},
+	{"db backup", "[PATH]", "Write a copy of the database and attachments to PATH.", nil},
+	{"db export", "[PATH]", "Write the chats and settings, without secrets, as JSON to PATH or stdout.", nil},
+	{"db import", "PATH", "Add the chats and settings of a JSON export.", nil},
+	{"completion", "bash|zsh|fish", "Print the shell completion script.", []string{"bash", "zsh", "fish"}},
+	{"man", "", "Print the manual page.", nil},
+	{"help", "", "Show this help.", nil},
+}
+
+// cliFlagValues are the values offered by shell completion after a flag
+var cliFlagValues = map[string][]string{
+	"--stdin-format": {"text", "jsonl"},
+}
+
+// cliUsage returns the help listing the subcommands
+func cliUsage() string {
+	var b strings.Builder
+	b.WriteString("Usage: llmschat [command]\n\nWithout a command the desktop app is started.\n\nCommands:\n")
+	for _, c := range cliCommands {
+		fmt.Fprintf(&b, "  %s\n      %s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	return b.String()
+}
+
+// runCLI runs a command line subcommand and returns the process exit code
+func runCLI(args []string) int {
+	// Commands that only print documentation
+	switch args[0] {
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage())
+		return 0
+	case "man":
+		fmt.Print(manPage())
+		return 0
+	case "completion":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "llmschat: completion needs a shell: bash, zsh or fish")
+			return 2
+		}
+		script, err := completionScript(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "llmschat: %v\n", err)
+			return 2
+		}
+		fmt.Print(script)
+		return 0
+	}
+
+	// Keep the output scriptable; errors are reported on stderr
+	log.SetOutput(io.Discard)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := database.InitDB(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "llmschat: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	// Commands are one word or a group and a word, like "chats list"
+	command, rest := args[0], args[1:]
+	if command != "chat" && command != "bench" && len(rest) > 0 {
+		command, rest = command+" "+rest[0], rest[1:]
+	}
+
+	var err error
+	switch command {
+	case "chat":
+		err = cliChat(ctx, rest)
+	case "bench":
+		err = cliBench(ctx, rest)
+	case "chats list":
+		err = cliListChats(ctx)
+	case "chats export":
+		err = cliExportChats(ctx, rest)
+	case "chats search":
+		err = cliSearchChats(ctx, rest)
+	case "db backup":
+		err = cliBackup(ctx, rest)
+	case "db export":
+		err = cliExport(ctx, rest)
+	case "db import":
+		err = cliImport(ctx, rest)
+	default:
+		fmt.Fprint(os.Stderr, cliUsage())
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llmschat: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// cliListChats prints the ID, creation date, message count and title of every chat
+func cliListChats(ctx context.Context) error {
+	chats, err := database.GetChats(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tMESSAGES\tTITLE")
+	for _, chat := range chats {
+		messages, err := database.GetMessages(ctx, chat.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", chat.ID, chat.CreatedAt.Local().Format("2006-01-02 15:04"), len(messages), chat.Title)
+	}
+	return w.Flush()
+}
+
+// cliExportChats prints one chat as Markdown, or writes all of them into a directory
+func cliExportChats(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("chats export", flag.ContinueOnError)
+	all := flags.Bool("all", false, "export every chat")
+	dir := flags.String("dir", ".", "directory for the exported files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	chats, err := database.GetChats(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !*all {
+		if flags.NArg() != 1 {
+			return fmt.Errorf("chats export needs a chat ID or --all")
+		}
+		id, err := strconv.Atoi(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid chat ID %q", flags.Arg(0))
+		}
+		for _, chat := range chats {
+			if chat.ID == id {
+				return writeChatMarkdown(ctx, os.Stdout, chat)
+			}
+		}
+		return fmt.Errorf("no chat with ID %d", id)
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		path := filepath.Join(*dir, fmt.Sprintf("chat-%d.md", chat.ID))
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = writeChatMarkdown(ctx, f, chat)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+// writeChatMarkdown writes the title and messages of chat as Markdown
+func writeChatMarkdown(ctx context.Context, w io.Writer, chat database.ChatRecord) error {
+	messages, err := database.GetMessages(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	attachments, err := database.GetChatAttachments(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	writeMessagesMarkdown(w, chat.Title, chat.CreatedAt, messages, attachments)
+	return nil
+}
+
+// writeMessagesMarkdown writes a title and messages of a chat as Markdown;
+// the descriptions of images found in attachments are quoted under their
+// message
+func writeMessagesMarkdown(w io.Writer, title string, started time.Time, messages []database.MessageRecord, attachments []database.Attachment) {
+	fmt.Fprintf(w, "# %s\n\n", title)
+	fmt.Fprintf(w, "_Started %s_\n", started.Local().Format("2006-01-02 15:04"))
+	for _, msg := range messages {
+		fmt.Fprintf(w, "\n**%s** (%s):\n\n%s\n", msg.Sender, msg.CreatedAt.Local().Format("15:04"), msg.Text)
+		// Images are exported as their description
+		for _, a := range attachments {
+			if a.MessageID == msg.ID && a.Description != "" {
+				fmt.Fprintf(w, "\n> %s: %s\n", a.Name, strings.ReplaceAll(a.Description, "\n", "\n> "))
+			}
+		}
+	}
+}
+
+// cliSearchChats prints the messages matching the query with their chat
+func cliSearchChats(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("chats search needs a query")
+	}
+	chats, err := database.GetChats(ctx)
+	if err != nil {
+		return err
+	}
+	titles := make(map[int]string, len(chats))
+	for _, chat := range chats {
+		titles[chat.ID] = chat.Title
+	}
+
+	messages, err := database.SearchMessages(ctx, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHAT\tTITLE\tSENDER\tMESSAGE")
+	for _, msg := range messages {
+		text := strings.Join(strings.Fields(msg.Text), " ")
+		if runes := []rune(text); len(runes) > 80 {
+			text = string(runes[:80]) + "…"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", msg.ChatID, titles[msg.ChatID], msg.Sender, text)
+	}
+	return w.Flush()
+}
+
+// cliBackup copies the database with the attachments to the given path or a
+// timestamped file
+func cliBackup(ctx context.Context, args []string) error {
+	path := database.BackupName()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if err := database.Backup(ctx, path); err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
+
+// cliExport writes the JSON export of the database to the given path or stdout
+func cliExport(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return database.ExportAll(ctx, os.Stdout)
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if err := database.ExportAll(ctx, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// cliImport adds the chats and settings of a JSON export to the database
+func cliImport(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("db import needs the path of an export")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	imported, err := database.ImportAll(ctx, f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d chats\n", imported)
+	return nil
+}
+
+// cliChat reads a conversation from stdin and streams the model's answer to stdout
+func cliChat(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("chat", flag.ContinueOnError)
+	format := flags.String("stdin-format", "text", "text sends stdin as one user message, jsonl reads one role-tagged message per line")
+	model := flags.String("model", "", "model to use instead of the one in the settings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var messages []llm.Message
+	switch *format {
+	case "text":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, llm.Message{Role: "user", Content: string(data)})
+	case "jsonl":
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var msg llm.Message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			messages = append(messages, msg)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown stdin format %q, use text or jsonl", *format)
+	}
+
+	if *model == "" {
+		name, err := settingsModel(ctx)
+		if err != nil {
+			return err
+		}
+		*model = name
+	}
+	defer llm.StopLocalServer()
+
+	_, err := llm.StreamConversation(ctx, *model, messages, func(chunk string) error {
+		_, err := io.WriteString(os.Stdout, chunk)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
+
+// settingsModel returns the name of the model chosen in the settings
+func settingsModel(ctx context.Context) (string, error) {
+	settings, err := database.GetSettings(ctx)
+	if err != nil {
+		return "", err
+	}
+	if settings == nil {
+		return "", fmt.Errorf("no settings found, configure a model in the app first")
+	}
+	models, err := database.GetModelsByCompany(ctx, settings.CompanyID)
+	if err != nil {
+		return "", err
+	}
+	for _, model := range models {
+		if model.ID == settings.ModelID {
+			return model.Name, nil
+		}
+	}
+	return "", fmt.Errorf("the model in the settings no longer exists")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/highlight"
+)
+
+// codeColors are the theme colors of the kinds of code tokens
+var codeColors = map[highlight.Kind]fyne.ThemeColorName{
+	highlight.Plain:   theme.ColorNameForeground,
+	highlight.Keyword: theme.ColorNamePrimary,
+	highlight.String:  theme.ColorNameSuccess,
+	highlight.Comment: theme.ColorNamePlaceHolder,
+	highlight.Number:  theme.ColorNameWarning,
+}
+
+// newMarkdownView renders a message's Markdown with its fenced code blocks
+// highlighted, each with a button copying its code
+func newMarkdownView(markdown string) fyne.CanvasObject {
+	parts := highlight.Split(markdown)
+	if len(parts) == 1 && !parts[0].Code {
+		return newMarkdownText(markdown)
+	}
+	box := container.NewVBox()
+	for _, part := range parts {
+		if part.Code {
+			box.Add(newCodeBlock(part.Lang, part.Text))
+		} else {
+			box.Add(newMarkdownText(part.Text))
+		}
+	}
+	return box
+}
+
+func newMarkdownText(markdown string) *widget.RichText {
+	text := widget.NewRichTextFromMarkdown(markdown)
+	text.Wrapping = fyne.TextWrapWord
+	return text
+}
+
+// newCodeBlock shows code in a monospace font colored by its language, under
+// a bar with the language and a copy button. Blocks that name no language
+// are colored as the one detected from their code.
+func newCodeBlock(lang, code string) fyne.CanvasObject {
+	langName := lang
+	if lang == "" {
+		if lang = highlight.Detect(code); lang != "" {
+			langName = lang + " (detected)"
+		}
+	}
+	var segments []widget.RichTextSegment
+	for _, token := range highlight.Tokens(lang, code) {
+		segments = append(segments, &widget.TextSegment{
+			Text: token.Text,
+			Style: widget.RichTextStyle{
+				Inline:    true,
+				ColorName: codeColors[token.Kind],
+				TextStyle: fyne.TextStyle{Monospace: true},
+			},
+		})
+	}
+	text := widget.NewRichText(segments...)
+
+	langLabel := widget.NewLabel(langName)
+	langLabel.Importance = widget.LowImportance
+	copyBtn := widget.NewButtonWithIcon("Copy code", theme.ContentCopyIcon(), func() {
+		mainWindow.Clipboard().SetContent(code)
+		showToast("Copied code")
+	})
+	copyBtn.Importance = widget.LowImportance
+
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	background.CornerRadius = theme.InputRadiusSize()
+	// Long lines scroll sideways instead of wrapping, as in an editor
+	return container.NewStack(background, container.NewBorder(
+		container.NewHBox(langLabel, layout.NewSpacer(), copyBtn), nil, nil, nil,
+		container.NewHScroll(text),
+	))
+}
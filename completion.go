@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+)
+
+// completionScript returns the completion script for shell, generated from cliCommands
+func completionScript(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(), nil
+	case "zsh":
+		return "#compdef llmschat\n\nautoload -U +X bashcompinit && bashcompinit\n\n" + bashCompletion(), nil
+	case "fish":
+		return fishCompletion(), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q, use bash, zsh or fish", shell)
+	}
+}
+
+// cliGroups returns the first words of the commands in order and, for groups
+// like "chats", the commands in them
+func cliGroups() ([]string, map[string][]string) {
+	var top []string
+	groups := make(map[string][]string)
+	for _, c := range cliCommands {
+		first, second, isGroup := strings.Cut(c.name, " ")
+		if _, seen := groups[first]; !seen {
+			top = append(top, first)
+			groups[first] = nil
+		}
+		if isGroup {
+			groups[first] = append(groups[first], second)
+		}
+	}
+	return top, groups
+}
+
+// bashCompletion completes the commands, their flags and the flag values in bash
+func bashCompletion() string {
+	top, groups := cliGroups()
+
+	var b strings.Builder
+	b.WriteString("# bash completion for llmschat\n")
+	b.WriteString("_llmschat() {\n")
+	b.WriteString("\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} words\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	flags := make([]string, 0, len(cliFlagValues))
+	for flag := range cliFlagValues {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	for _, flag := range flags {
+		fmt.Fprintf(&b, "\t%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\t\t;;\n", flag, strings.Join(cliFlagValues[flag], " "))
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tcase \"${COMP_WORDS[*]:1:COMP_CWORD-1}\" in\n")
+	fmt.Fprintf(&b, "\t\"\") words=%q ;;\n", strings.Join(top, " "))
+	for _, name := range top {
+		if len(groups[name]) > 0 {
+			fmt.Fprintf(&b, "\t%q) words=%q ;;\n", name, strings.Join(groups[name], " "))
+		}
+	}
+	for _, c := range cliCommands {
+		if len(c.words) > 0 {
+			fmt.Fprintf(&b, "\t%q | %q*) words=%q ;;\n", c.name, c.name+" ", strings.Join(c.words, " "))
+		}
+	}
+	b.WriteString("\t*) words=\"\" ;;\n")
+	b.WriteString("\tesac\n")
+	b.WriteString("\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o default -F _llmschat llmschat\n")
+	return b.String()
+}
+
+// fishCompletion completes the commands, their flags and the flag values in fish
+func fishCompletion() string {
+	top, groups := cliGroups()
+
+	var b strings.Builder
+	b.WriteString("# fish completion for llmschat\n")
+	b.WriteString("complete -c llmschat -f\n")
+	for _, name := range top {
+		description := name + " commands"
+		for _, c := range cliCommands {
+			if c.name == name {
+				description = firstSentence(c.summary)
+			}
+		}
+		fmt.Fprintf(&b, "complete -c llmschat -n __fish_use_subcommand -a %s -d %s\n", name, fishQuote(description))
+	}
+	for _, name := range top {
+		if len(groups[name]) == 0 {
+			continue
+		}
+		condition := fmt.Sprintf("__fish_seen_subcommand_from %s; and not __fish_seen_subcommand_from %s", name, strings.Join(groups[name], " "))
+		for _, c := range cliCommands {
+			if group, sub, ok := strings.Cut(c.name, " "); ok && group == name {
+				fmt.Fprintf(&b, "complete -c llmschat -n %s -a %s -d %s\n", fishQuote(condition), sub, fishQuote(firstSentence(c.summary)))
+			}
+		}
+	}
+	for _, c := range cliCommands {
+		if len(c.words) == 0 {
+			continue
+		}
+		var conditions []string
+		for _, word := range strings.Fields(c.name) {
+			conditions = append(conditions, "__fish_seen_subcommand_from "+word)
+		}
+		condition := fishQuote(strings.Join(conditions, "; and "))
+		for _, word := range c.words {
+			flag, isFlag := strings.CutPrefix(word, "--")
+			switch {
+			case !isFlag:
+				fmt.Fprintf(&b, "complete -c llmschat -n %s -a %s\n", condition, word)
+			case len(cliFlagValues[word]) > 0:
+				fmt.Fprintf(&b, "complete -c llmschat -n %s -l %s -x -a %s\n", condition, flag, fishQuote(strings.Join(cliFlagValues[word], " ")))
+			default:
+				fmt.Fprintf(&b, "complete -c llmschat -n %s -l %s\n", condition, flag)
+			}
+		}
+	}
+	return b.String()
+}
+
+// fishQuote quotes s as a single-quoted fish string
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// firstSentence returns the first sentence of s without its period
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSuffix(s, ".")
+}
+
+// manPage returns the llmschat(1) manual page in roff, generated from cliCommands
+func manPage() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH LLMSCHAT 1 %q \"llmschat\" \"User Commands\"\n", time.Now().Format("January 2006"))
+	b.WriteString(".SH NAME\nllmschat \\- desktop chat client for OpenAI, Anthropic and local models\n")
+	b.WriteString(".SH SYNOPSIS\n.B llmschat\n[\\fIcommand\\fR] [\\fIargs\\fR]\n")
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString("Without a command the desktop app is started. The commands below work on the same\n")
+	b.WriteString("chats and settings from the shell, printing their results to standard output and\n")
+	b.WriteString("errors to standard error.\n")
+	b.WriteString(".SH COMMANDS\n")
+	for _, c := range cliCommands {
+		fmt.Fprintf(&b, ".TP\n\\fB%s\\fR \\fI%s\\fR\n", roffEscape(c.name), roffEscape(c.args))
+		fmt.Fprintf(&b, "%s\n", roffEscape(c.summary))
+	}
+	b.WriteString(".SH ENVIRONMENT\n.TP\n")
+	fmt.Fprintf(&b, ".B %s\n", database.DataDirEnv)
+	b.WriteString("Directory holding the database and generated files, overriding the data folder\n")
+	b.WriteString("chosen in the settings.\n")
+	b.WriteString(".SH FILES\n.TP\n")
+	fmt.Fprintf(&b, ".I %s\n", roffEscape(database.DefaultDataDir()+"/chat.db"))
+	b.WriteString("The chats, settings and encrypted API keys.\n")
+	b.WriteString(".SH EXIT STATUS\n")
+	b.WriteString("0 on success, 1 when a command fails and 2 for invalid usage.\n")
+	return b.String()
+}
+
+// roffEscape escapes text so roff prints it literally
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// addContinueButton fills bar with a button asking the model to pick up an
+// answer it cut off at the token limit. The continuation streams into body
+// after text, so the answer reads as one message; meta is how it was
+// generated so far.
+func addContinueButton(chatID int, item *messageItem, body, bar *fyne.Container, text *string, meta database.MessageMeta) {
+	var btn *widget.Button
+	btn = widget.NewButtonWithIcon("Continue", theme.MediaPlayIcon(), func() {
+		if !isLatestMessage(chatID, item) {
+			dialog.ShowInformation("Continue", "Only the latest answer of a chat can be continued.", mainWindow)
+			return
+		}
+		if waitForStream(chatID, "Continue") {
+			return
+		}
+		btn.Hide()
+		go func() {
+			meta = continueAnswer(chatID, item, body, text, meta)
+			if llm.Truncated(meta.FinishReason) {
+				btn.Show()
+			}
+		}()
+	})
+	bar.Objects = []fyne.CanvasObject{btn}
+	bar.Refresh()
+}
+
+// isLatestMessage reports whether item is the last stored message of a chat
+func isLatestMessage(chatID int, item *messageItem) bool {
+	chat := findChat(chatID)
+	if chat == nil || item.messageID == 0 || len(chat.Messages) == 0 {
+		return false
+	}
+	return chat.Messages[len(chat.Messages)-1].ID == item.messageID
+}
+
+// continueAnswer streams the continuation of an answer into body, appends it
+// to the stored message and returns the message's updated meta
+func continueAnswer(chatID int, item *messageItem, body *fyne.Container, text *string, meta database.MessageMeta) database.MessageMeta {
+	chat := findChat(chatID)
+	if chat == nil {
+		return meta
+	}
+	model := meta.Model
+	if model == "" {
+		model = currentModel
+	}
+	req := llm.Request{
+		Prompt:        llm.ContinuePrompt,
+		Model:         model,
+		SystemPrompt:  systemPrompt(chat),
+		ChatID:        chatID,
+		NoCache:       true,
+		Continue:      true,
+		Temperature:   chat.Temperature,
+		MaxTokens:     chat.MaxTokens,
+		ContextTokens: chat.ContextTokens,
+	}
+	var result llm.Result
+	req.OnFinish = func(r llm.Result) {
+		result = r
+	}
+	ctx, cancel := context.WithCancel(appCtx)
+	active := startStream(chatID, cancel)
+	stream, err := llm.GetResponseStream(ctx, req)
+	if err != nil {
+		endStream(chatID, active)
+		dialog.ShowError(fmt.Errorf("Failed to continue the answer: %v", err), mainWindow)
+		return meta
+	}
+
+	base := *text
+	label := widget.NewRichText()
+	label.Wrapping = fyne.TextWrapWord
+	label.ParseMarkdown(base)
+	body.Objects = []fyne.CanvasObject{label}
+	body.Refresh()
+	continuation := ""
+	for chunk := range stream {
+		continuation += chunk
+		*text = base + continuation
+		publishLiveView(chatID, *text)
+		label.ParseMarkdown(*text)
+		label.Refresh()
+		if !reducedMotion() && currentChat != nil && currentChat.ID == chatID {
+			mainScroll.ScrollToBottom()
+		}
+	}
+	endStream(chatID, active)
+
+	if result.Err != nil && ctx.Err() == nil {
+		*text = base
+		continuation = ""
+		dialog.ShowError(fmt.Errorf("Failed to continue the answer: %v", result.Err), mainWindow)
+	}
+	body.Objects = []fyne.CanvasObject{newMarkdownView(*text)}
+	body.Refresh()
+	if continuation == "" {
+		return meta
+	}
+
+	meta.PromptTokens += result.PromptTokens
+	meta.CompletionTokens += result.CompletionTokens
+	meta.Latency += result.Latency
+	meta.FinishReason = result.FinishReason
+	if err := database.AppendMessage(appCtx, item.messageID, continuation); err != nil {
+		log.Printf("Failed to save the continued answer: %v", err)
+	}
+	if err := database.SetMessageMeta(appCtx, item.messageID, meta); err != nil {
+		log.Printf("Failed to save message details: %v", err)
+	}
+	for i := range chat.Messages {
+		if chat.Messages[i].ID == item.messageID {
+			chat.Messages[i].Text = *text
+			chat.Messages[i].MessageMeta = meta
+		}
+	}
+	if item.footer != nil {
+		item.footer.SetText(formatMessageStats(meta))
+	}
+	publishLiveView(chatID, "")
+	return meta
+}
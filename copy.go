@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// toastDuration is how long a toast stays on screen
+const toastDuration = 1500 * time.Millisecond
+
+// newCopyButton returns a button copying the Markdown of a message
+func newCopyButton(item *messageItem) *widget.Button {
+	btn := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		copyMessage(item)
+	})
+	btn.Importance = widget.LowImportance
+	return btn
+}
+
+// copyMessage puts the Markdown of a message, as sent or received, on the
+// clipboard
+func copyMessage(item *messageItem) {
+	mainWindow.Clipboard().SetContent(item.text())
+	showToast("Copied")
+}
+
+// showToast shows a short note at the bottom of the window that goes away by
+// itself
+func showToast(text string) {
+	c := mainWindow.Canvas()
+	label := widget.NewLabel(text)
+	label.Importance = widget.HighImportance
+	toast := widget.NewPopUp(container.NewPadded(label), c)
+	size := toast.MinSize()
+	toast.ShowAtPosition(fyne.NewPos((c.Size().Width-size.Width)/2, c.Size().Height-size.Height-theme.Padding()*8))
+	time.AfterFunc(toastDuration, toast.Hide)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// sendButton sends the input, and shows the estimated cost of sending it in
+// a tooltip while the pointer is over it
+type sendButton struct {
+	widget.Button
+	costText func() (string, bool) // The estimate and whether it is above the warning threshold, "" when unknown
+	tip      *widget.PopUp
+}
+
+func newSendButton(tapped func()) *sendButton {
+	b := &sendButton{}
+	b.Text = "Send"
+	b.Icon = theme.MailSendIcon()
+	b.OnTapped = tapped
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// MouseIn shows the cost tooltip above the button
+func (b *sendButton) MouseIn(e *desktop.MouseEvent) {
+	b.Button.MouseIn(e)
+	if b.costText == nil || (currentChat != nil && isStreaming(currentChat.ID)) {
+		return
+	}
+	text, expensive := b.costText()
+	if text == "" {
+		return
+	}
+	label := widget.NewLabel(text)
+	if expensive {
+		label.Importance = widget.WarningImportance
+	}
+	c := fyne.CurrentApp().Driver().CanvasForObject(b)
+	if c == nil {
+		return
+	}
+	b.tip = widget.NewPopUp(container.NewPadded(label), c)
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(b)
+	size := b.tip.MinSize()
+	b.tip.ShowAtPosition(fyne.NewPos(pos.X+b.Size().Width-size.Width, pos.Y-size.Height))
+}
+
+// MouseOut hides the cost tooltip
+func (b *sendButton) MouseOut() {
+	b.Button.MouseOut()
+	b.hideTip()
+}
+
+// Tapped hides the cost tooltip before sending
+func (b *sendButton) Tapped(e *fyne.PointEvent) {
+	b.hideTip()
+	b.Button.Tapped(e)
+}
+
+func (b *sendButton) hideTip() {
+	if b.tip != nil {
+		b.tip.Hide()
+		b.tip = nil
+	}
+}
+
+// costPreview returns the estimated cost of sending text with files with the
+// current model, and whether it is above the warning threshold
+func costPreview(text string, files []pastedFile) (string, bool) {
+	if text == "" {
+		return "", false
+	}
+	cost, ok := llm.EstimateCost(currentModel, requestTokens(text, files), 0)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("~$%.4f with %s", cost, currentModel), cost >= costWarningThreshold()
+}
+
+// historyTokens holds the past turns of a chat for cost estimates, so they do
+// not read the chat's memory each time. It is dropped when an answer ends or
+// another chat is shown.
+var historyTokens struct {
+	sync.Mutex
+	chatID int
+	turns  []llm.TurnTokens
+}
+
+// chatHistoryTokens returns the past turns of a chat, loading them once
+func chatHistoryTokens(chatID int) []llm.TurnTokens {
+	historyTokens.Lock()
+	defer historyTokens.Unlock()
+	if historyTokens.chatID == chatID && historyTokens.turns != nil {
+		return historyTokens.turns
+	}
+	turns, err := llm.HistoryTokens(appCtx, chatID)
+	if err != nil {
+		log.Printf("Failed to load history: %v", err)
+		return nil
+	}
+	historyTokens.chatID, historyTokens.turns = chatID, turns
+	return turns
+}
+
+// forgetHistoryTokens drops the past turns held for a chat, or for any chat
+// when chatID is 0
+func forgetHistoryTokens(chatID int) {
+	historyTokens.Lock()
+	if chatID == 0 || historyTokens.chatID == chatID {
+		historyTokens.chatID, historyTokens.turns = 0, nil
+	}
+	historyTokens.Unlock()
+}
@@ -0,0 +1,329 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blobMu keeps CollectBlobs from deleting a blob that is being saved
+var blobMu sync.Mutex
+
+// Attachment is an image or document attached to a chat message. Its content
+// is stored once per distinct file in the blobs folder, named by its SHA-256.
+type Attachment struct {
+	ID          int
+	ChatID      int
+	MessageID   int // 0 until the attachment is linked to a message
+	Name        string
+	MimeType    string
+	Size        int64
+	SHA256      string // Hex checksum of the content
+	Description string // Alt text of an image, so it can be searched and exported as text
+	CreatedAt   time.Time
+}
+
+// attachmentsDir returns the folder holding the named copies written by File
+func attachmentsDir() string {
+	return filepath.Join(DataDir(), "attachments")
+}
+
+// blobsDir returns the folder holding the content of the attachments
+func blobsDir() string {
+	return filepath.Join(DataDir(), "blobs")
+}
+
+// blobPath returns the file holding the content with the given checksum
+func blobPath(sum string) string {
+	return filepath.Join(blobsDir(), sum[:2], sum)
+}
+
+// writeBlob stores data in the blobs folder unless it is already there and
+// returns its checksum
+func writeBlob(data []byte) (string, error) {
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+	path := blobPath(sum)
+	if info, err := os.Stat(path); err == nil && info.Size() == int64(len(data)) {
+		return sum, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	// Write under a temporary name so a blob is never seen half written
+	tmp, err := os.CreateTemp(filepath.Dir(path), sum+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return sum, nil
+}
+
+// SaveAttachment stores data as an attachment of a chat, and of a message
+// when messageID is not 0. The MIME type is detected when empty.
+func SaveAttachment(ctx context.Context, chatID, messageID int, name, mimeType string, data []byte) (Attachment, error) {
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	name = filepath.Base(name)
+	if name == "." || name == string(filepath.Separator) {
+		name = "attachment"
+	}
+	a := Attachment{ChatID: chatID, MessageID: messageID, Name: name, MimeType: mimeType, Size: int64(len(data))}
+
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	sum, err := writeBlob(data)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to save attachment: %v", err)
+	}
+	a.SHA256 = sum
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO attachments (chat_id, message_id, name, mime_type, size, sha256)
+		VALUES (?, NULLIF(?, 0), ?, ?, ?, ?)
+	`, chatID, messageID, a.Name, a.MimeType, a.Size, a.SHA256)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to save attachment: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Attachment{}, err
+	}
+	a.ID = int(id)
+	return a, nil
+}
+
+// LinkAttachment attaches a stored attachment to a message
+func LinkAttachment(ctx context.Context, id, messageID int) error {
+	_, err := db.ExecContext(ctx, "UPDATE attachments SET message_id = ? WHERE id = ?", messageID, id)
+	return err
+}
+
+// SetAttachmentDescription stores the alt text of an attachment
+func SetAttachmentDescription(ctx context.Context, id int, description string) error {
+	_, err := db.ExecContext(ctx, "UPDATE attachments SET description = ? WHERE id = ?", description, id)
+	return err
+}
+
+// GetAttachment returns an attachment by ID
+func GetAttachment(ctx context.Context, id int) (Attachment, error) {
+	attachments, err := queryAttachments(ctx, "WHERE id = ?", id)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if len(attachments) == 0 {
+		return Attachment{}, fmt.Errorf("attachment %d not found", id)
+	}
+	return attachments[0], nil
+}
+
+// GetChatAttachments returns the attachments of a chat, oldest first
+func GetChatAttachments(ctx context.Context, chatID int) ([]Attachment, error) {
+	return queryAttachments(ctx, "WHERE chat_id = ? ORDER BY id", chatID)
+}
+
+// GetMessageAttachments returns the attachments of a message, oldest first
+func GetMessageAttachments(ctx context.Context, messageID int) ([]Attachment, error) {
+	return queryAttachments(ctx, "WHERE message_id = ? ORDER BY id", messageID)
+}
+
+// queryAttachments returns the attachments selected by the where clause
+func queryAttachments(ctx context.Context, where string, args ...interface{}) ([]Attachment, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, chat_id, COALESCE(message_id, 0), name, mime_type, size, COALESCE(sha256, ''), description, created_at
+		FROM attachments `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.ChatID, &a.MessageID, &a.Name, &a.MimeType, &a.Size, &a.SHA256, &a.Description, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// Data returns the content of the attachment, checking it against its checksum
+func (a Attachment) Data() ([]byte, error) {
+	if a.SHA256 == "" {
+		return nil, fmt.Errorf("the content of attachment %s is missing", a.Name)
+	}
+	data, err := os.ReadFile(blobPath(a.SHA256))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment %s: %v", a.Name, err)
+	}
+	if hash := sha256.Sum256(data); hex.EncodeToString(hash[:]) != a.SHA256 {
+		return nil, fmt.Errorf("attachment %s is damaged, its checksum does not match", a.Name)
+	}
+	return data, nil
+}
+
+// File returns a file named after the attachment holding its content, so it
+// can be shown or opened with another application
+func (a Attachment) File() (string, error) {
+	path := filepath.Join(attachmentsDir(), fmt.Sprintf("%d-%s", a.ID, a.Name))
+	if info, err := os.Stat(path); err == nil && info.Size() == a.Size {
+		return path, nil
+	}
+	data, err := a.Data()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(attachmentsDir(), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write attachment %s: %v", a.Name, err)
+	}
+	return path, nil
+}
+
+// IsImage reports whether the attachment is an image
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.MimeType, "image/")
+}
+
+// deleteChatAttachments removes the attachments of a chat in tx and returns
+// the named copies to delete once tx is committed. Their blobs are left for
+// CollectBlobs, since other attachments may share them.
+func deleteChatAttachments(ctx context.Context, tx *sql.Tx, chatID int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, name FROM attachments WHERE chat_id = ?", chatID)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		files = append(files, filepath.Join(attachmentsDir(), fmt.Sprintf("%d-%s", id, name)))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM attachments WHERE chat_id = ?", chatID)
+	return files, err
+}
+
+// CollectBlobs deletes the blobs no attachment refers to anymore and returns
+// how many were deleted
+func CollectBlobs(ctx context.Context) (int, error) {
+	blobMu.Lock()
+	defer blobMu.Unlock()
+
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT sha256 FROM attachments WHERE sha256 IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var sum string
+		if err := rows.Scan(&sum); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		referenced[sum] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	err = filepath.WalkDir(blobsDir(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || referenced[entry.Name()] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// moveAttachmentsToBlobs moves the content of the attachments stored in the
+// database or in the attachments folder into the blobs folder. The files in the
+// attachments folder are named like the copies written by File and stay as such.
+func moveAttachmentsToBlobs(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, data, COALESCE(path, '') FROM attachments")
+	if err != nil {
+		return err
+	}
+	type content struct {
+		data []byte
+		path string
+	}
+	contents := make(map[int]content)
+	for rows.Next() {
+		var id int
+		var c content
+		if err := rows.Scan(&id, &c.data, &c.path); err != nil {
+			rows.Close()
+			return err
+		}
+		contents[id] = c
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, c := range contents {
+		data := c.data
+		if c.path != "" {
+			if data, err = os.ReadFile(c.path); err != nil {
+				log.Printf("Content of attachment %d is missing: %v", id, err)
+				continue
+			}
+		}
+		sum, err := writeBlob(data)
+		if err != nil {
+			return fmt.Errorf("failed to move attachment %d: %v", id, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE attachments SET sha256 = ?, data = NULL, path = NULL WHERE id = ?", sum, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
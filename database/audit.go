@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AuditEntry is an outbound request to a model provider. The bodies are only
+// kept when content auditing is enabled.
+type AuditEntry struct {
+	ID            int
+	Method        string
+	URL           string // Without the query string
+	Model         string
+	Status        int    // HTTP status, 0 when no response arrived
+	Error         string // Why the request failed
+	Latency       time.Duration
+	RequestBytes  int64
+	ResponseBytes int64
+	RequestBody   string
+	ResponseBody  string
+	CreatedAt     time.Time
+}
+
+// SaveAuditEntry records an outbound request
+func SaveAuditEntry(ctx context.Context, e AuditEntry) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO audit_log (method, url, model, status, error, latency_ms, request_bytes, response_bytes, request_body, response_body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.Method, e.URL, e.Model, e.Status, e.Error, e.Latency.Milliseconds(), e.RequestBytes, e.ResponseBytes,
+		sql.NullString{String: e.RequestBody, Valid: e.RequestBody != ""},
+		sql.NullString{String: e.ResponseBody, Valid: e.ResponseBody != ""})
+	return err
+}
+
+// GetAuditLog returns the most recent audited requests, newest first
+func GetAuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, method, url, model, status, error, latency_ms, request_bytes, response_bytes,
+			COALESCE(request_body, ''), COALESCE(response_body, ''), created_at
+		FROM audit_log ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var latency int64
+		if err := rows.Scan(&e.ID, &e.Method, &e.URL, &e.Model, &e.Status, &e.Error, &latency, &e.RequestBytes, &e.ResponseBytes,
+			&e.RequestBody, &e.ResponseBody, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Latency = time.Duration(latency) * time.Millisecond
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ClearAuditLog deletes every audited request
+func ClearAuditLog(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM audit_log")
+	return err
+}
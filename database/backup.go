@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// backupBlobsTable is the table of a backup holding the content of its
+// attachments, which lives outside the database in the blobs folder
+const backupBlobsTable = "backup_blobs"
+
+// Backup writes a consistent copy of the database to path, which must not
+// exist, with the blobs of its attachments so the file is a complete backup
+func Backup(ctx context.Context, path string) error {
+	// The blobs are kept from being collected until the copy has them
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database: %v", err)
+	}
+	if err := backupBlobs(ctx, path); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to back up attachments: %v", err)
+	}
+	return nil
+}
+
+// backupBlobs copies the blobs the attachments of the backup at path refer to
+// into it. A blob missing from the folder is logged and left out.
+func backupBlobs(ctx context.Context, path string) error {
+	backup, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+
+	tx, err := backup.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE "+backupBlobsTable+" (sha256 TEXT PRIMARY KEY, data BLOB NOT NULL)"); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT sha256 FROM attachments WHERE sha256 IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	var sums []string
+	for rows.Next() {
+		var sum string
+		if err := rows.Scan(&sum); err != nil {
+			rows.Close()
+			return err
+		}
+		sums = append(sums, sum)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, sum := range sums {
+		data, err := os.ReadFile(blobPath(sum))
+		if err != nil {
+			log.Printf("Failed to back up attachment blob %s: %v", sum, err)
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO "+backupBlobsTable+" (sha256, data) VALUES (?, ?)", sum, data); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// restoreBlobs writes the blobs kept in the backup at path to the blobs
+// folder; backups made before they kept blobs have none
+func restoreBlobs(ctx context.Context, path string) error {
+	backup, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+
+	var count int
+	if err := backup.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", backupBlobsTable).Scan(&count); err != nil || count == 0 {
+		return err
+	}
+	rows, err := backup.QueryContext(ctx, "SELECT data FROM "+backupBlobsTable)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if _, err := writeBlob(data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// BackupName returns a timestamped file name for a backup
+func BackupName() string {
+	return fmt.Sprintf("llmschat-backup-%s.db", time.Now().Format("20060102-150405"))
+}
+
+// freePath returns path, or when a file is already there the same name with
+// the first free number added, like backup-2.db
+func freePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
+
+// Restore replaces the database with the backup at path and reopens it, and
+// puts the blobs of its attachments back. The current database is first copied
+// to the backups folder of the data directory; the path of that safety copy is
+// returned.
+func Restore(ctx context.Context, path string) (string, error) {
+	if err := checkBackup(ctx, path); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(DataDir(), "backups")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backups folder: %v", err)
+	}
+	safety := freePath(filepath.Join(dir, "pre-restore-"+BackupName()))
+	if err := Backup(ctx, safety); err != nil {
+		return "", err
+	}
+	// Blobs are only added, so the safety copy keeps the ones it refers to
+	if err := restoreBlobs(ctx, path); err != nil {
+		return safety, fmt.Errorf("failed to restore attachments: %v", err)
+	}
+
+	log.Printf("Restoring database from %s", path)
+	Close()
+	restoreErr := copyFile(path, databasePath())
+	removeJournals()
+	if restoreErr != nil {
+		// Put the previous database back
+		if err := copyFile(safety, databasePath()); err != nil {
+			log.Printf("Failed to put back the previous database: %v", err)
+		}
+	}
+	if err := InitDB(ctx); err != nil {
+		return safety, err
+	}
+	// The blobs are back in their folder, the restored database does not
+	// need its copy of them
+	if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+backupBlobsTable); err != nil {
+		log.Printf("Failed to drop the blobs of the backup: %v", err)
+	}
+	if restoreErr != nil {
+		return safety, fmt.Errorf("failed to restore database: %v", restoreErr)
+	}
+	return safety, nil
+}
+
+// checkBackup verifies that path is an intact database of this app
+func checkBackup(ctx context.Context, path string) error {
+	backup, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+
+	var result string
+	if err := backup.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("%s is not a database backup: %v", filepath.Base(path), err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%s is damaged: %s", filepath.Base(path), result)
+	}
+	var count int
+	if err := backup.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('settings', 'chats')").Scan(&count); err != nil || count < 2 {
+		return fmt.Errorf("%s is not a backup of this app", filepath.Base(path))
+	}
+	return nil
+}
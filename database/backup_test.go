@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := openTestDB(t)
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.db")
+	if err := os.WriteFile(existing, []byte("taken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"new file", filepath.Join(dir, "backup.db"), false},
+		{"existing file", existing, true},
+		{"missing folder", filepath.Join(dir, "missing", "backup.db"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Backup(ctx, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Backup() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := checkBackup(ctx, tt.path); err != nil {
+				t.Errorf("the backup does not pass its own check: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ctx := openTestDB(t)
+	kept, err := CreateChat(ctx, "Backed up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachment, err := SaveAttachment(ctx, kept, 0, "restore.txt", "text/plain", []byte("content of the backed up attachment"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), BackupName())
+	if err := Backup(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes after the backup, with the blob lost
+	dropped, err := CreateChat(ctx, "Not backed up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(blobPath(attachment.SHA256)); err != nil {
+		t.Fatal(err)
+	}
+
+	safety, err := Restore(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(safety); err != nil {
+		t.Errorf("safety copy: %v", err)
+	}
+	tests := []struct {
+		id   int
+		want int
+	}{
+		{kept, 1},
+		{dropped, 0},
+	}
+	for _, tt := range tests {
+		if got := countWhere(t, ctx, "chats", "id = ?", tt.id); got != tt.want {
+			t.Errorf("chat %d rows = %d, want %d", tt.id, got, tt.want)
+		}
+	}
+	restored, err := GetAttachment(ctx, attachment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := restored.Data(); err != nil || string(data) != "content of the backed up attachment" {
+		t.Errorf("attachment data = %q, %v, want the backed up content", data, err)
+	}
+	if got := countWhere(t, ctx, "sqlite_master", "type = 'table' AND name = ?", backupBlobsTable); got != 0 {
+		t.Errorf("the restored database still has the %s table", backupBlobsTable)
+	}
+}
+
+func TestRestoreRejects(t *testing.T) {
+	ctx := openTestDB(t)
+	dir := t.TempDir()
+	notDatabase := filepath.Join(dir, "notes.db")
+	if err := os.WriteFile(notDatabase, []byte("not a database at all, just some text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	otherApp := filepath.Join(dir, "other.db")
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", otherApp); err != nil {
+		t.Fatal(err)
+	}
+	other, err := sql.Open("sqlite3", otherApp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = other.ExecContext(ctx, "DROP TABLE settings")
+	other.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"not a database", notDatabase},
+		{"missing file", filepath.Join(dir, "missing.db")},
+		{"database of another app", otherApp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat, err := CreateChat(ctx, "Before "+tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Restore(ctx, tt.path); err == nil {
+				t.Fatal("Restore() succeeded")
+			}
+			if got := countWhere(t, ctx, "chats", "id = ?", chat); got != 1 {
+				t.Errorf("the database was replaced")
+			}
+		})
+	}
+}
+
+func TestFreePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"taken.db", "twice.db", "twice-2.db"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"free.db", "free.db"},
+		{"taken.db", "taken-2.db"},
+		{"twice.db", "twice-3.db"},
+	}
+	for _, tt := range tests {
+		if got := freePath(filepath.Join(dir, tt.name)); got != filepath.Join(dir, tt.want) {
+			t.Errorf("freePath(%q) = %q, want %q", tt.name, filepath.Base(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// GetCachedResponse returns the cached response stored under key
+func GetCachedResponse(ctx context.Context, key string) (string, bool, error) {
+	var response string
+	err := db.QueryRowContext(ctx, "SELECT response FROM response_cache WHERE key = ?", key).Scan(&response)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return response, true, nil
+}
+
+// SaveCachedResponse stores response under key, replacing any previous entry
+func SaveCachedResponse(ctx context.Context, key, model, response string) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO response_cache (key, model, response) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET response = excluded.response, created_at = CURRENT_TIMESTAMP
+	`, key, model, response)
+	return err
+}
+
+// ClearResponseCache removes every cached response
+func ClearResponseCache(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM response_cache")
+	return err
+}
@@ -0,0 +1,534 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChatRecord is a stored chat without its messages
+type ChatRecord struct {
+	ID        int
+	Title     string
+	CreatedAt time.Time
+	FolderID  int // 0 when the chat is not in a folder
+	ChatConfig
+}
+
+// ChatConfig is the model and generation parameters a chat was set up with,
+// restored when it is reopened
+type ChatConfig struct {
+	Model         string // Empty for the model in the settings
+	SystemPrompt  string
+	Temperature   *float64 // Nil for the model's default
+	MaxTokens     int      // Longest answer, 0 for the model's default
+	ContextTokens int      // Most tokens of past messages sent, 0 for no limit
+}
+
+// chatColumns are the columns read by scanChat
+const chatColumns = "id, title, created_at, folder_id, model, system_prompt, temperature, max_tokens, context_tokens"
+
+// scanChat reads a row starting with chatColumns; the columns after them are
+// scanned into dest
+func scanChat(rows *sql.Rows, dest ...any) (ChatRecord, error) {
+	var c ChatRecord
+	var folderID sql.NullInt64
+	var temperature sql.NullFloat64
+	err := rows.Scan(append([]any{&c.ID, &c.Title, &c.CreatedAt, &folderID, &c.Model, &c.SystemPrompt, &temperature, &c.MaxTokens, &c.ContextTokens}, dest...)...)
+	c.FolderID = int(folderID.Int64)
+	if temperature.Valid {
+		c.Temperature = &temperature.Float64
+	}
+	return c, err
+}
+
+// MessageRecord is a stored chat message
+type MessageRecord struct {
+	ID        int
+	ChatID    int
+	Sender    string
+	Text      string
+	IsAI      bool
+	CreatedAt time.Time
+	MemoryID  int64 // Row of the message in the model's memory of the chat, 0 when unknown
+	MessageMeta
+}
+
+// MessageMeta describes how an AI message was generated, whether it was
+// edited since and whether it is the error of a request that failed
+type MessageMeta struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	FinishReason     string // Why the model stopped, like "stop" or "length"
+	Latency          time.Duration
+	Edited           bool
+	Failed           bool // The message is the error of a failed answer, which can be retried
+}
+
+// ChatRepo stores chats and their messages
+type ChatRepo struct {
+	statements
+	create      *sql.Stmt
+	list        *sql.Stmt
+	rename      *sql.Stmt
+	setConfig   *sql.Stmt
+	saveMessage *sql.Stmt
+	setMeta     *sql.Stmt
+	setMemory   *sql.Stmt
+	editMsg     *sql.Stmt
+	appendMsg   *sql.Stmt
+	deleteMsg   *sql.Stmt
+	truncate    *sql.Stmt
+	messages    *sql.Stmt
+}
+
+// NewChatRepo prepares the chat statements on db
+func NewChatRepo(ctx context.Context, db *sql.DB) (*ChatRepo, error) {
+	r := &ChatRepo{}
+	r.create = r.prepare(ctx, db, "INSERT INTO chats (title) VALUES (?)")
+	r.list = r.prepare(ctx, db, "SELECT "+chatColumns+" FROM chats WHERE deleted_at IS NULL ORDER BY id")
+	r.rename = r.prepare(ctx, db, "UPDATE chats SET title = ? WHERE id = ?")
+	r.setConfig = r.prepare(ctx, db, `
+		UPDATE chats SET model = ?, system_prompt = ?, temperature = ?, max_tokens = ?, context_tokens = ?
+		WHERE id = ?
+	`)
+	r.saveMessage = r.prepare(ctx, db, `
+		INSERT INTO messages (chat_id, sender, text, is_ai)
+		VALUES (?, ?, ?, ?)
+	`)
+	r.setMeta = r.prepare(ctx, db, `
+		UPDATE messages SET model = ?, prompt_tokens = ?, completion_tokens = ?, finish_reason = ?, latency_ms = ?, edited = ?, failed = ?
+		WHERE id = ?
+	`)
+	r.setMemory = r.prepare(ctx, db, "UPDATE messages SET memory_id = ? WHERE id = ?")
+	r.editMsg = r.prepare(ctx, db, "UPDATE messages SET text = ?, edited = 1 WHERE id = ?")
+	r.appendMsg = r.prepare(ctx, db, "UPDATE messages SET text = text || ? WHERE id = ?")
+	r.deleteMsg = r.prepare(ctx, db, "DELETE FROM messages WHERE id = ?")
+	r.truncate = r.prepare(ctx, db, `
+		DELETE FROM messages WHERE chat_id = ? AND (datetime(created_at), id) >
+			(SELECT datetime(created_at), id FROM messages WHERE id = ?)
+	`)
+	r.messages = r.prepare(ctx, db, `
+		SELECT id, chat_id, sender, text, is_ai, created_at, COALESCE(memory_id, 0),
+			model, prompt_tokens, completion_tokens, finish_reason, latency_ms, edited, failed
+		FROM messages WHERE chat_id = ? ORDER BY datetime(created_at), id
+	`)
+	return r, r.done("prepare chat statements")
+}
+
+// Create stores a new chat and returns its ID
+func (r *ChatRepo) Create(ctx context.Context, title string) (int, error) {
+	result, err := r.create.ExecContext(ctx, title)
+	if err != nil {
+		return 0, opError("create chat", err)
+	}
+	id, err := result.LastInsertId()
+	return int(id), opError("create chat", err)
+}
+
+// List returns all chats not in the trash, oldest first
+func (r *ChatRepo) List(ctx context.Context) ([]ChatRecord, error) {
+	rows, err := r.list.QueryContext(ctx)
+	if err != nil {
+		return nil, opError("list chats", err)
+	}
+	defer rows.Close()
+
+	var chats []ChatRecord
+	for rows.Next() {
+		c, err := scanChat(rows)
+		if err != nil {
+			return nil, opError("list chats", err)
+		}
+		chats = append(chats, c)
+	}
+	return chats, opError("list chats", rows.Err())
+}
+
+// Rename changes the title of a chat
+func (r *ChatRepo) Rename(ctx context.Context, id int, title string) error {
+	result, err := r.rename.ExecContext(ctx, title, id)
+	return expectRow("rename chat", result, err)
+}
+
+// SetConfig stores the model and generation parameters of a chat
+func (r *ChatRepo) SetConfig(ctx context.Context, id int, config ChatConfig) error {
+	result, err := r.setConfig.ExecContext(ctx, config.Model, config.SystemPrompt, config.Temperature, config.MaxTokens, config.ContextTokens, id)
+	return expectRow("set chat config", result, err)
+}
+
+// SaveMessage appends a message to a chat and returns its ID
+func (r *ChatRepo) SaveMessage(ctx context.Context, chatID int, sender, text string, isAI bool) (int, error) {
+	result, err := r.saveMessage.ExecContext(ctx, chatID, sender, text, isAI)
+	if err != nil {
+		return 0, opError("save message", err)
+	}
+	id, err := result.LastInsertId()
+	return int(id), opError("save message", err)
+}
+
+// SetMessageMeta stores how a message was generated
+func (r *ChatRepo) SetMessageMeta(ctx context.Context, id int, meta MessageMeta) error {
+	result, err := r.setMeta.ExecContext(ctx, meta.Model, meta.PromptTokens, meta.CompletionTokens, meta.FinishReason, meta.Latency.Milliseconds(), meta.Edited, meta.Failed, id)
+	return expectRow("set message meta", result, err)
+}
+
+// SetMessageMemory links a message to its row in the model's memory of the chat
+func (r *ChatRepo) SetMessageMemory(ctx context.Context, id int, memoryID int64) error {
+	result, err := r.setMemory.ExecContext(ctx, memoryID, id)
+	return expectRow("set message memory", result, err)
+}
+
+// EditMessage replaces the text of a message and marks it as edited
+func (r *ChatRepo) EditMessage(ctx context.Context, id int, text string) error {
+	result, err := r.editMsg.ExecContext(ctx, text, id)
+	return expectRow("edit message", result, err)
+}
+
+// AppendMessage adds text to the end of a message without marking it as edited
+func (r *ChatRepo) AppendMessage(ctx context.Context, id int, text string) error {
+	result, err := r.appendMsg.ExecContext(ctx, text, id)
+	return expectRow("append to message", result, err)
+}
+
+// DeleteMessagesAfter removes the messages of a chat sent after a message and
+// returns how many were removed
+func (r *ChatRepo) DeleteMessagesAfter(ctx context.Context, chatID, messageID int) (int, error) {
+	result, err := r.truncate.ExecContext(ctx, chatID, messageID)
+	if err != nil {
+		return 0, opError("delete messages", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), opError("delete messages", err)
+}
+
+// DeleteMessage removes a message; its attachments stay with the chat
+func (r *ChatRepo) DeleteMessage(ctx context.Context, id int) error {
+	result, err := r.deleteMsg.ExecContext(ctx, id)
+	return expectRow("delete message", result, err)
+}
+
+// Messages returns the messages of a chat in the order they were sent
+func (r *ChatRepo) Messages(ctx context.Context, chatID int) ([]MessageRecord, error) {
+	rows, err := r.messages.QueryContext(ctx, chatID)
+	if err != nil {
+		return nil, opError("get messages", err)
+	}
+	defer rows.Close()
+
+	var messages []MessageRecord
+	for rows.Next() {
+		var m MessageRecord
+		var latency int64
+		if err := rows.Scan(&m.ID, &m.ChatID, &m.Sender, &m.Text, &m.IsAI, &m.CreatedAt, &m.MemoryID,
+			&m.Model, &m.PromptTokens, &m.CompletionTokens, &m.FinishReason, &latency, &m.Edited, &m.Failed); err != nil {
+			return nil, opError("get messages", err)
+		}
+		m.Latency = time.Duration(latency) * time.Millisecond
+		messages = append(messages, m)
+	}
+	return messages, opError("get messages", rows.Err())
+}
+
+// CreateChat stores a new chat in the open database and returns its ID
+func CreateChat(ctx context.Context, title string) (int, error) {
+	return chatRepo.Create(ctx, title)
+}
+
+// GetChats returns all chats of the open database not in the trash, oldest first
+func GetChats(ctx context.Context) ([]ChatRecord, error) {
+	return chatRepo.List(ctx)
+}
+
+// UpdateChatTitle renames a chat of the open database
+func UpdateChatTitle(ctx context.Context, id int, title string) error {
+	return chatRepo.Rename(ctx, id, title)
+}
+
+// SetChatConfig stores the model and generation parameters of a chat of the
+// open database
+func SetChatConfig(ctx context.Context, id int, config ChatConfig) error {
+	return chatRepo.SetConfig(ctx, id, config)
+}
+
+// SaveMessage appends a message to a chat of the open database and returns its ID
+func SaveMessage(ctx context.Context, chatID int, sender, text string, isAI bool) (int, error) {
+	return chatRepo.SaveMessage(ctx, chatID, sender, text, isAI)
+}
+
+// SetMessageMeta stores how a message of the open database was generated
+func SetMessageMeta(ctx context.Context, id int, meta MessageMeta) error {
+	return chatRepo.SetMessageMeta(ctx, id, meta)
+}
+
+// SetMessageMemory links a message of the open database to its row in the
+// model's memory of the chat
+func SetMessageMemory(ctx context.Context, id int, memoryID int64) error {
+	return chatRepo.SetMessageMemory(ctx, id, memoryID)
+}
+
+// EditMessage replaces the text of a message of the open database and marks
+// it as edited
+func EditMessage(ctx context.Context, id int, text string) error {
+	return chatRepo.EditMessage(ctx, id, text)
+}
+
+// AppendMessage adds text to the end of a message of the open database, like
+// the continuation of an answer that was cut off
+func AppendMessage(ctx context.Context, id int, text string) error {
+	return chatRepo.AppendMessage(ctx, id, text)
+}
+
+// DeleteMessagesAfter removes the messages of a chat of the open database sent
+// after a message and returns how many were removed
+func DeleteMessagesAfter(ctx context.Context, chatID, messageID int) (int, error) {
+	return chatRepo.DeleteMessagesAfter(ctx, chatID, messageID)
+}
+
+// DeleteMessage removes a message of the open database
+func DeleteMessage(ctx context.Context, id int) error {
+	return chatRepo.DeleteMessage(ctx, id)
+}
+
+// LatestMemoryID returns the row of the latest prompt, or answer with isAI, in
+// the model's memory of a chat, 0 when there is none
+func LatestMemoryID(ctx context.Context, chatID int, isAI bool) (int64, error) {
+	kind := "human"
+	if isAI {
+		kind = "ai"
+	}
+	// langchaingo keeps the model's memory of the chat under the session chat-N
+	var id int64
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(id), 0) FROM langchaingo_messages WHERE session = ? AND type = ?
+	`, fmt.Sprintf("chat-%d", chatID), kind).Scan(&id)
+	return id, err
+}
+
+// ForgetMessage removes a user message or answer from the model's memory of a
+// chat, so it is no longer sent with later prompts. The row linked to the
+// message is removed when memoryID is not 0. Messages saved before they were
+// linked are matched by text instead: the memory holds prompts with their
+// pasted files and answers as streamed, before formatting, so the latest entry
+// of the same type that starts with text, or that text starts with, is
+// removed; exact and longer matches win over the latest one.
+func ForgetMessage(ctx context.Context, chatID int, memoryID int64, text string, isAI bool) error {
+	session := fmt.Sprintf("chat-%d", chatID)
+	if memoryID != 0 {
+		_, err := db.ExecContext(ctx, "DELETE FROM langchaingo_messages WHERE id = ? AND session = ?", memoryID, session)
+		if err != nil && !strings.Contains(err.Error(), "no such table") {
+			return err
+		}
+		return nil
+	}
+
+	kind := "human"
+	if isAI {
+		kind = "ai"
+	}
+	// langchaingo keeps the model's memory of the chat under the session chat-N
+	_, err := db.ExecContext(ctx, `
+		DELETE FROM langchaingo_messages WHERE id = (
+			SELECT id FROM langchaingo_messages
+			WHERE session = ? AND type = ? AND content <> ''
+				AND (substr(content, 1, length(?3)) = ?3 OR substr(?3, 1, length(content)) = content)
+			ORDER BY content = ?3 DESC, min(length(content), length(?3)) DESC, id DESC LIMIT 1
+		)
+	`, session, kind, text)
+	if err != nil && !strings.Contains(err.Error(), "no such table") {
+		return err
+	}
+	return nil
+}
+
+// ExtendAnswer adds text to the latest answer in the model's memory of a
+// chat, so a continuation is sent with later prompts as part of the answer it
+// continues
+func ExtendAnswer(ctx context.Context, chatID int, text string) error {
+	// langchaingo keeps the model's memory of the chat under the session chat-N
+	_, err := db.ExecContext(ctx, `
+		UPDATE langchaingo_messages SET content = content || ? WHERE id = (
+			SELECT id FROM langchaingo_messages WHERE session = ? AND type = 'ai'
+			ORDER BY id DESC LIMIT 1
+		)
+	`, text, fmt.Sprintf("chat-%d", chatID))
+	if err != nil && !strings.Contains(err.Error(), "no such table") {
+		return err
+	}
+	return nil
+}
+
+// GetMessages returns the messages of a chat of the open database in the order
+// they were sent
+func GetMessages(ctx context.Context, chatID int) ([]MessageRecord, error) {
+	return chatRepo.Messages(ctx, chatID)
+}
+
+// ImportChat stores a chat with its messages, keeping their timestamps, and
+// returns its ID
+func ImportChat(ctx context.Context, title string, createdAt time.Time, messages []MessageRecord) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO chats (title, created_at) VALUES (?, ?)", title, createdAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range messages {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO messages (chat_id, sender, text, is_ai, created_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, id, m.Sender, m.Text, m.IsAI, m.CreatedAt.UTC())
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int(id), tx.Commit()
+}
+
+// MergeMessages adds the messages a chat does not have yet, keeping their
+// timestamps so they are listed in time order, and returns how many were added.
+// A message is already there when its sender, text and second match.
+func MergeMessages(ctx context.Context, chatID int, messages []MessageRecord) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT sender, text, CAST(strftime('%s', created_at) AS INTEGER) FROM messages WHERE chat_id = ?", chatID)
+	if err != nil {
+		return 0, err
+	}
+	stored := make(map[string]bool)
+	for rows.Next() {
+		var sender, text string
+		var unix int64
+		if err := rows.Scan(&sender, &text, &unix); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stored[fmt.Sprintf("%s\x00%s\x00%d", sender, text, unix)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, m := range messages {
+		key := fmt.Sprintf("%s\x00%s\x00%d", m.Sender, m.Text, m.CreatedAt.Unix())
+		if stored[key] {
+			continue
+		}
+		stored[key] = true
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO messages (chat_id, sender, text, is_ai, created_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, chatID, m.Sender, m.Text, m.IsAI, m.CreatedAt.UTC())
+		if err != nil {
+			return 0, err
+		}
+		added++
+	}
+	return added, tx.Commit()
+}
+
+// GetMessagesPerDay counts the messages of the chats not in the trash per local
+// day since the given time, keyed by the day as 2006-01-02
+func GetMessagesPerDay(ctx context.Context, since time.Time) (map[string]int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT date(m.created_at, 'localtime'), COUNT(*)
+		FROM messages m JOIN chats c ON c.id = m.chat_id
+		WHERE c.deleted_at IS NULL AND datetime(m.created_at) >= ?
+		GROUP BY 1
+	`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, err
+		}
+		counts[day] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetChatsOnDay returns the chats not in the trash with messages sent on a
+// local day, given as 2006-01-02, oldest first
+func GetChatsOnDay(ctx context.Context, day string) ([]ChatRecord, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+chatColumns+` FROM chats
+		WHERE deleted_at IS NULL AND id IN (
+			SELECT chat_id FROM messages WHERE date(created_at, 'localtime') = ?
+		)
+		ORDER BY id
+	`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []ChatRecord
+	for rows.Next() {
+		c, err := scanChat(rows)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, c)
+	}
+	return chats, rows.Err()
+}
+
+// ChatActivity is a chat with the number of messages sent in a period
+type ChatActivity struct {
+	ChatRecord
+	Messages int
+}
+
+// GetTopChats returns the chats not in the trash with the most messages sent
+// since the given time, busiest first
+func GetTopChats(ctx context.Context, since time.Time, limit int) ([]ChatActivity, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+chatColumns+`, counts.messages FROM chats
+		JOIN (
+			SELECT chat_id, COUNT(*) AS messages FROM messages
+			WHERE datetime(created_at) >= ? GROUP BY chat_id
+		) counts ON counts.chat_id = chats.id
+		WHERE deleted_at IS NULL
+		ORDER BY counts.messages DESC, id
+		LIMIT ?
+	`, since.UTC().Format("2006-01-02 15:04:05"), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []ChatActivity
+	for rows.Next() {
+		var c ChatActivity
+		record, err := scanChat(rows, &c.Messages)
+		if err != nil {
+			return nil, err
+		}
+		c.ChatRecord = record
+		chats = append(chats, c)
+	}
+	return chats, rows.Err()
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeMessages(t *testing.T) {
+	ctx := openTestDB(t)
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	message := func(sender, text string, after time.Duration) MessageRecord {
+		return MessageRecord{Sender: sender, Text: text, IsAI: sender == "AI", CreatedAt: base.Add(after)}
+	}
+	existing := []MessageRecord{
+		message("You", "question", 0),
+		message("AI", "answer", time.Second),
+	}
+
+	tests := []struct {
+		name     string
+		incoming []MessageRecord
+		added    int
+		want     []string // Texts of the merged chat in order
+	}{
+		{"nothing new", existing, 0, []string{"question", "answer"}},
+		{"newer message", []MessageRecord{message("You", "more", 2 * time.Second)}, 1, []string{"question", "answer", "more"}},
+		{"older message goes first", []MessageRecord{message("You", "earlier", -time.Minute)}, 1, []string{"earlier", "question", "answer"}},
+		{"same text another second", []MessageRecord{message("You", "question", 5 * time.Second)}, 1, []string{"question", "answer", "question"}},
+		{"same text within the second", []MessageRecord{message("You", "question", 500 * time.Millisecond)}, 0, []string{"question", "answer"}},
+		{"same text other sender", []MessageRecord{message("AI", "question", 0)}, 1, []string{"question", "question", "answer"}},
+		{"repeated in the import", []MessageRecord{message("You", "twice", 3 * time.Second), message("You", "twice", 3 * time.Second)}, 1, []string{"question", "answer", "twice"}},
+		{"empty import", nil, 0, []string{"question", "answer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID, err := ImportChat(ctx, "Merge", base, existing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			added, err := MergeMessages(ctx, chatID, tt.incoming)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added != tt.added {
+				t.Errorf("MergeMessages() added %d, want %d", added, tt.added)
+			}
+			messages, err := GetMessages(ctx, chatID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var texts []string
+			for _, m := range messages {
+				texts = append(texts, m.Text)
+			}
+			if len(texts) != len(tt.want) {
+				t.Fatalf("messages = %q, want %q", texts, tt.want)
+			}
+			for i := range texts {
+				if texts[i] != tt.want[i] {
+					t.Fatalf("messages = %q, want %q", texts, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encryptedPrefix marks values encrypted by encryptSecret
+const encryptedPrefix = "enc:v1:"
+
+// machineIDFiles hold a stable per-installation identifier on Linux and BSD
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id", "/etc/hostid"}
+
+// secretKeyFile holds the random key used where the OS has no machine identifier
+const secretKeyFile = "secret.key"
+
+// machineIDTimeout bounds the commands that read the machine identifier
+const machineIDTimeout = 5 * time.Second
+
+var (
+	platformUUID = regexp.MustCompile(`"IOPlatformUUID"\s*=\s*"([^"]+)"`)
+	machineGUID  = regexp.MustCompile(`MachineGuid\s+REG_SZ\s+(\S+)`)
+)
+
+// machineID returns the identifier the OS gives this installation, which
+// survives a host name change, or "" when there is none
+func machineID() string {
+	switch runtime.GOOS {
+	case "darwin":
+		if m := platformUUID.FindStringSubmatch(commandOutput("ioreg", "-rd1", "-c", "IOPlatformExpertDevice")); m != nil {
+			return m[1]
+		}
+	case "windows":
+		if m := machineGUID.FindStringSubmatch(commandOutput("reg", "query", `HKLM\SOFTWARE\Microsoft\Cryptography`, "/v", "MachineGuid")); m != nil {
+			return m[1]
+		}
+	default:
+		return machineIDFile()
+	}
+	return ""
+}
+
+// machineIDFile returns the content of the first machine ID file, or ""
+func machineIDFile() string {
+	for _, path := range machineIDFiles {
+		if data, err := os.ReadFile(path); err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// commandOutput runs a command and returns its output, or "" when it is not
+// installed or fails
+func commandOutput(name string, args ...string) string {
+	if _, err := exec.LookPath(name); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), machineIDTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// localKey returns the random key stored in the data directory, generating it
+// on first use
+func localKey() (string, error) {
+	path := filepath.Join(DataDir(), secretKeyFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if key := strings.TrimSpace(string(data)); key != "" {
+			return key, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(random)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// deriveKey derives an AES-256 key from a machine identifier and the current
+// user, so a copied chat.db cannot be decrypted on another machine or by
+// another account
+func deriveKey(machineID string) []byte {
+	var username string
+	if u, err := user.Current(); err == nil {
+		username = u.Uid + ":" + u.Username
+	}
+	key := sha256.Sum256([]byte("llmschat/secrets/v1\x00" + machineID + "\x00" + username))
+	return key[:]
+}
+
+// secretKeys returns the keys for secrets: the first encrypts, and all are
+// tried in turn to decrypt. Older versions used the host name where there was
+// no machine ID file, so that key is kept to read what they stored until the
+// migration encrypts it again.
+var secretKeys = sync.OnceValue(func() [][]byte {
+	id := machineID()
+	if id == "" {
+		key, err := localKey()
+		if err != nil {
+			log.Printf("Failed to read the secret key file: %v", err)
+		}
+		id = key
+	}
+	keys := [][]byte{deriveKey(id)}
+	if machineIDFile() == "" {
+		if host, err := os.Hostname(); err == nil {
+			keys = append(keys, deriveKey(host))
+		}
+	}
+	return keys
+})
+
+// newGCM returns the AEAD used for secrets with the given key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecret encrypts plaintext for storage; empty values stay empty
+func encryptSecret(plaintext string) (string, error) {
+	if plaintext == "" || strings.HasPrefix(plaintext, encryptedPrefix) {
+		return plaintext, nil
+	}
+	gcm, err := newGCM(secretKeys()[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %v", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %v", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret reverses encryptSecret. Values stored before encryption was
+// introduced are returned unchanged.
+func decryptSecret(stored string) (string, error) {
+	plaintext, _, err := openSecret(stored)
+	return plaintext, err
+}
+
+// openSecret decrypts a stored secret and reports whether it was encrypted with
+// a key other than the current one
+func openSecret(stored string) (plaintext string, stale bool, err error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedPrefix)
+	if !ok {
+		return stored, false, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decrypt secret: %v", err)
+	}
+	for i, key := range secretKeys() {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decrypt secret: %v", err)
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return "", false, fmt.Errorf("failed to decrypt secret: value too short")
+		}
+		opened, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+		if err == nil {
+			return string(opened), i > 0, nil
+		}
+	}
+	return "", false, fmt.Errorf("failed to decrypt secret, it was stored on another machine or account")
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		encrypted bool
+	}{
+		{"empty stays empty", "", false},
+		{"api key", "sk-test-1234567890", true},
+		{"unicode", "pässwörd 🔑", true},
+		{"long token", strings.Repeat("token", 200), true},
+		{"already encrypted", encryptedPrefix + "abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, err := encryptSecret(tt.plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.encrypted {
+				if stored != tt.plaintext {
+					t.Errorf("encryptSecret(%q) = %q, want it unchanged", tt.plaintext, stored)
+				}
+				return
+			}
+			if !strings.HasPrefix(stored, encryptedPrefix) || strings.Contains(stored, tt.plaintext) {
+				t.Errorf("encryptSecret(%q) = %q, want an encrypted value", tt.plaintext, stored)
+			}
+			again, err := encryptSecret(tt.plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again == stored {
+				t.Error("encrypting twice gave the same value, the nonce is not random")
+			}
+			got, err := decryptSecret(stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.plaintext {
+				t.Errorf("decryptSecret() = %q, want %q", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptSecret(t *testing.T) {
+	valid, err := encryptSecret("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a bit of the sealed value so authentication fails
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(valid, encryptedPrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	tampered := encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name    string
+		stored  string
+		want    string
+		wantErr bool
+	}{
+		{"encrypted value", valid, "secret", false},
+		{"plaintext from before encryption", "sk-legacy", "sk-legacy", false},
+		{"empty", "", "", false},
+		{"invalid base64", encryptedPrefix + "not base64!", "", true},
+		{"too short", encryptedPrefix + "AAAA", "", true},
+		{"tampered", tampered, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptSecret(tt.stored)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decryptSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decryptSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenSecretOlderKey(t *testing.T) {
+	current, older := deriveKey("machine"), deriveKey("old-hostname")
+	old := secretKeys
+	t.Cleanup(func() { secretKeys = old })
+
+	secretKeys = func() [][]byte { return [][]byte{older} }
+	stored, err := encryptSecret("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keys    [][]byte
+		stale   bool
+		wantErr bool
+	}{
+		{"current key", [][]byte{older}, false, false},
+		{"older key", [][]byte{current, older}, true, false},
+		{"key is gone", [][]byte{current}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secretKeys = func() [][]byte { return tt.keys }
+			got, stale, err := openSecret(stored)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("openSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != "secret" || stale != tt.stale {
+				t.Errorf("openSecret() = %q, %v, want %q, %v", got, stale, "secret", tt.stale)
+			}
+		})
+	}
+}
+
+func TestLocalKey(t *testing.T) {
+	key, err := localKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(DataDir(), secretKeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		t.Errorf("key file mode = %v, want it private", info.Mode().Perm())
+	}
+	again, err := localKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key == "" || again != key {
+		t.Errorf("localKey() = %q then %q, want the same key", key, again)
+	}
+}
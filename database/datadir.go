@@ -0,0 +1,186 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DataDirEnv overrides the data directory when set
+const DataDirEnv = "LLMSCHAT_DATA_DIR"
+
+// legacyDataDir is where older versions kept their data, relative to the
+// working directory
+const legacyDataDir = "data"
+
+// dataDirFile, kept in the default data directory, names a custom data
+// directory chosen in the settings on its first line and the directory in use
+// when it was chosen on the second. It cannot live in the database since it
+// decides where the database is.
+const dataDirFile = "data_dir"
+
+var (
+	dataDirOnce sync.Once
+	dataDir     string
+)
+
+// DataDir returns the directory holding the database and generated files: the
+// LLMSCHAT_DATA_DIR environment variable, the directory chosen in the settings
+// or the llmschat folder of the user configuration directory
+func DataDir() string {
+	dataDirOnce.Do(func() {
+		dataDir = os.Getenv(DataDirEnv)
+		if dataDir == "" {
+			dataDir = ConfiguredDataDir()
+		}
+		if dataDir == "" {
+			dataDir = DefaultDataDir()
+		}
+	})
+	return dataDir
+}
+
+// DefaultDataDir returns the data directory following the OS conventions,
+// e.g. ~/.config/llmschat on Linux
+func DefaultDataDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Printf("No user configuration directory, using %s: %v", legacyDataDir, err)
+		return legacyDataDir
+	}
+	return filepath.Join(dir, "llmschat")
+}
+
+// ConfiguredDataDir returns the data directory chosen in the settings, if any
+func ConfiguredDataDir() string {
+	return dataDirLine(0)
+}
+
+// dataDirLine returns line n of the data directory file
+func dataDirLine(n int) string {
+	data, err := os.ReadFile(filepath.Join(DefaultDataDir(), dataDirFile))
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(data), "\n")
+	if n >= len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[n])
+}
+
+// SetDataDir records dir as the data directory used from the next start; an
+// empty dir restores the default. The current data is moved there on startup.
+func SetDataDir(dir string) error {
+	path := filepath.Join(DefaultDataDir(), dataDirFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to save data directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(dir+"\n"+DataDir()+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save data directory: %v", err)
+	}
+	return nil
+}
+
+// migrateDataDir moves the database from the directory in use when another
+// one was chosen with SetDataDir into dir, when dir has none yet. Nothing is
+// moved for a directory set with LLMSCHAT_DATA_DIR, which may point anywhere,
+// e.g. at an empty folder for a test instance. The database older versions
+// kept in the working directory is only migrated when the user agrees, see
+// LegacyDatabase.
+func migrateDataDir(dir string) error {
+	if os.Getenv(DataDirEnv) != "" {
+		return nil
+	}
+	old := dataDirLine(1)
+	if old == "" {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "chat.db")); err == nil {
+		return nil
+	}
+	target, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	source, err := filepath.Abs(old)
+	if err != nil || source == target {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(source, "chat.db")); err != nil {
+		return nil
+	}
+
+	log.Printf("Moving data from %s to %s", source, target)
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == dataDirFile {
+			continue
+		}
+		if err := moveEntry(filepath.Join(source, entry.Name()), filepath.Join(target, entry.Name())); err != nil {
+			return fmt.Errorf("failed to move %s: %v", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// moveEntry moves a file or directory, copying it when a rename is not
+// possible (e.g. across file systems)
+func moveEntry(source, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return nil
+	}
+	if err := copyTree(source, target); err != nil {
+		return err
+	}
+	return os.RemoveAll(source)
+}
+
+// copyTree copies a file or directory, merging directories into existing ones
+func copyTree(source, target string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		return copyFile(path, dest)
+	})
+}
+
+// copyFile copies a regular file, keeping its permissions
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
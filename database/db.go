@@ -1,11 +1,13 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -22,62 +24,211 @@ type Model struct {
 	CompanyID int
 }
 
+// Settings is a profile with its own provider, model and API key; the active
+// profile is used for every request
 type Settings struct {
 	ID        int
+	Profile   string
 	Name      string
 	CompanyID int
 	ModelID   int
-	APIKey    string
+	APIKey    string // The key requests use: the profile's own, else the company's
+	OwnKey    string // The profile's own key, empty when it uses the company's
+	Active    bool
 }
 
+// Preference keys
+const (
+	PrefWhisperURL   = "whisper_url"
+	PrefWorkspaceDir = "workspace_dir"
+	PrefTTSVoice     = "tts_voice"
+	PrefCostWarning  = "cost_warning"
+
+	PrefContextCompression = "context_compression"
+	PrefRelevantTurns      = "relevant_turns"
+	PrefModeration         = "moderation"
+	PrefDriftDetection     = "drift_detection"
+	PrefMessageStats       = "message_stats"
+
+	PrefLineSpacing   = "line_spacing"
+	PrefReadingFont   = "reading_font"
+	PrefColorScheme   = "color_scheme"
+	PrefRoleIcons     = "role_icons"
+	PrefReducedMotion = "reduced_motion"
+
+	PrefQuietHoursStart = "quiet_hours_start"
+	PrefQuietHoursEnd   = "quiet_hours_end"
+
+	PrefPowerAware   = "power_aware"
+	PrefEconomyModel = "economy_model"
+
+	PrefProxyURL       = "proxy_url"
+	PrefRequestTimeout = "request_timeout"
+	PrefCACertFile     = "ca_cert_file"
+
+	PrefGlobalInstructions  = "global_instructions"
+	PrefProjectInstructions = "project_instructions"
+
+	PrefResponseCache = "response_cache"
+	PrefUseKeyring    = "use_keyring"
+
+	PrefAuditLog     = "audit_log"
+	PrefAuditContent = "audit_content"
+
+	PrefStripImageMetadata = "strip_image_metadata"
+
+	PrefGoFormat = "go_format"
+	PrefGoVet    = "go_vet"
+
+	PrefKeybindings = "keybindings"
+
+	PrefMaintenanceLastRun = "maintenance_last_run"
+	PrefLegacyMigration    = "legacy_migration"
+
+	PrefReportDir     = "report_dir"
+	PrefReportLastRun = "report_last_run"
+
+	PrefLocalModelPath = "local_model_path"
+	PrefLlamaServer    = "llama_server"
+	PrefWarmUpAtStart  = "warm_up_at_start"
+	PrefWarmUpTime     = "warm_up_time"
+	PrefWarmUpLastRun  = "warm_up_last_run"
+
+	PrefCalendarSource = "calendar_source"
+	PrefCalendarTarget = "calendar_target"
+	PrefCalDAVUser     = "caldav_user"
+	PrefCalDAVPassword = "caldav_password"
+
+	PrefHomeAssistantURL      = "home_assistant_url"
+	PrefHomeAssistantToken    = "home_assistant_token"
+	PrefHomeAssistantEntities = "home_assistant_entities"
+
+	PrefDigestLength   = "digest_length"
+	PrefDigestLanguage = "digest_language"
+	PrefDigestLastRun  = "digest_last_run"
+	PrefNewsChatID     = "news_chat_id"
+
+	PrefTelegramToken   = "telegram_token"
+	PrefTelegramAllowed = "telegram_allowed"
+	PrefTelegramChatID  = "telegram_chat_id"
+
+	PrefBridgePersona = "bridge_persona"
+
+	PrefIRCServer   = "irc_server"
+	PrefIRCTLS      = "irc_tls"
+	PrefIRCNick     = "irc_nick"
+	PrefIRCPassword = "irc_password"
+	PrefIRCChannel  = "irc_channel"
+	PrefIRCChatID   = "irc_chat_id"
+
+	PrefMatrixHomeserver = "matrix_homeserver"
+	PrefMatrixToken      = "matrix_token"
+	PrefMatrixRoom       = "matrix_room"
+	PrefMatrixChatID     = "matrix_chat_id"
+
+	PrefLiveViewPort = "live_view_port"
+)
+
 var db *sql.DB
 
-func InitDB() error {
+// busyTimeout is how long a connection waits for another one to finish writing
+const busyTimeout = 5 * time.Second
+
+// databasePath returns the path of the SQLite database file
+func databasePath() string {
+	return filepath.Join(DataDir(), "chat.db")
+}
+
+// DB returns the connection pool shared by the whole app. It changes when the
+// database is restored or repaired, so it should not be kept around.
+func DB() *sql.DB {
+	return db
+}
+
+// removeJournals deletes the write-ahead log and journal files left next to the
+// database file, which must be closed, before it is replaced
+func removeJournals() {
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(databasePath() + suffix)
+	}
+}
+
+func InitDB(ctx context.Context) error {
 	// Create database directory if it doesn't exist
-	dbDir := "data"
+	dbDir := DataDir()
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
 		log.Printf("Failed to create database directory: %v", err)
 		return fmt.Errorf("failed to create database directory: %v", err)
 	}
 
-	dbPath := filepath.Join(dbDir, "chat.db")
+	// Bring over the database after another data directory was chosen
+	if err := migrateDataDir(dbDir); err != nil {
+		log.Printf("Failed to migrate data directory: %v", err)
+		return fmt.Errorf("failed to migrate data directory: %v", err)
+	}
+
+	dbPath := databasePath()
 	log.Printf("Opening database at: %s", dbPath)
 
-	// Open database connection
+	// Open database connection. The write-ahead log lets readers go on while
+	// a message is written, and writers wait for each other instead of failing.
 	var err error
-	db, err = sql.Open("sqlite3", dbPath)
+	db, err = sql.Open("sqlite3", fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=%d", dbPath, busyTimeout.Milliseconds()))
 	if err != nil {
 		log.Printf("Failed to open database: %v", err)
 		return fmt.Errorf("failed to open database: %v", err)
 	}
 
 	// Check if database is accessible
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		log.Printf("Failed to ping database: %v", err)
 		return fmt.Errorf("failed to ping database: %v", err)
 	}
 
 	// Create tables
-	if err := createTables(); err != nil {
+	if err := createTables(ctx); err != nil {
 		log.Printf("Failed to create tables: %v", err)
 		return fmt.Errorf("failed to create tables: %v", err)
 	}
 
+	// Apply schema migrations
+	if err := migrate(ctx); err != nil {
+		log.Printf("Failed to migrate database: %v", err)
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	// Index message text for search
+	if err := ensureSearchIndex(ctx); err != nil {
+		log.Printf("Failed to create search index: %v", err)
+		return fmt.Errorf("failed to create search index: %v", err)
+	}
+
+	// Prepare the statements of the repositories
+	if err := openRepos(ctx); err != nil {
+		log.Printf("Failed to prepare statements: %v", err)
+		return fmt.Errorf("failed to prepare statements: %v", err)
+	}
+
 	// Initialize default data
-	if err := initializeDefaultData(); err != nil {
+	if err := initializeDefaultData(ctx); err != nil {
 		log.Printf("Failed to initialize default data: %v", err)
 		return fmt.Errorf("failed to initialize default data: %v", err)
 	}
 
+	// Compact the database once in a while
+	if err := maintainIfDue(ctx); err != nil {
+		log.Printf("Database maintenance failed: %v", err)
+	}
+
 	log.Printf("Database initialization completed successfully")
 	return nil
 }
 
-func createTables() error {
+func createTables(ctx context.Context) error {
 	log.Printf("Creating/updating tables...")
 
 	// Companies table
-	_, err := db.Exec(`
+	_, err := db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS companies (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL UNIQUE,
@@ -91,7 +242,7 @@ func createTables() error {
 	log.Printf("Companies table created/verified successfully")
 
 	// Models table
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS models (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL,
@@ -107,7 +258,7 @@ func createTables() error {
 	log.Printf("Models table created/verified successfully")
 
 	// Settings table
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS settings (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL,
@@ -124,10 +275,88 @@ func createTables() error {
 	}
 	log.Printf("Settings table created/verified successfully")
 
+	// Preferences table
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS preferences (
+			key TEXT PRIMARY KEY,
+			value TEXT
+		)
+	`)
+	if err != nil {
+		log.Printf("Failed to create preferences table: %v", err)
+		return fmt.Errorf("failed to create preferences table: %v", err)
+	}
+	log.Printf("Preferences table created/verified successfully")
+
+	// Stream metrics table
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS stream_metrics (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			model TEXT NOT NULL,
+			ttft_ms INTEGER,
+			duration_ms INTEGER,
+			tokens INTEGER,
+			tokens_per_sec REAL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Printf("Failed to create stream_metrics table: %v", err)
+		return fmt.Errorf("failed to create stream_metrics table: %v", err)
+	}
+	log.Printf("Stream metrics table created/verified successfully")
+
+	// Response cache table
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS response_cache (
+			key TEXT PRIMARY KEY,
+			model TEXT NOT NULL,
+			response TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Printf("Failed to create response_cache table: %v", err)
+		return fmt.Errorf("failed to create response_cache table: %v", err)
+	}
+	log.Printf("Response cache table created/verified successfully")
+
+	// Chats and messages tables
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS chats (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Printf("Failed to create chats table: %v", err)
+		return fmt.Errorf("failed to create chats table: %v", err)
+	}
+	log.Printf("Chats table created/verified successfully")
+
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			sender TEXT NOT NULL,
+			text TEXT NOT NULL,
+			is_ai BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (chat_id) REFERENCES chats (id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_messages_chat ON messages (chat_id, id);
+	`)
+	if err != nil {
+		log.Printf("Failed to create messages table: %v", err)
+		return fmt.Errorf("failed to create messages table: %v", err)
+	}
+	log.Printf("Messages table created/verified successfully")
+
 	return nil
 }
 
-func initializeDefaultData() error {
+func initializeDefaultData(ctx context.Context) error {
 	log.Printf("Initializing default data...")
 	
 	// Default companies and their models
@@ -160,10 +389,17 @@ func initializeDefaultData() error {
 			"deepseek-chat",
 			"deepseek-reasoner",
 		},
+		"Local": {
+			"gguf",
+		},
+		"Demo": {
+			"demo",
+			"demo-instant",
+		},
 	}
 
 	// Begin transaction
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("Failed to begin transaction: %v", err)
 		return fmt.Errorf("failed to begin transaction: %v", err)
@@ -187,7 +423,7 @@ func initializeDefaultData() error {
 			baseURL = "https://generativelanguage.googleapis.com"
 		}
 		
-		result, err := tx.Exec("INSERT OR IGNORE INTO companies (name, base_url) VALUES (?, ?)", companyName, baseURL)
+		result, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO companies (name, base_url) VALUES (?, ?)", companyName, baseURL)
 		if err != nil {
 			log.Printf("Failed to insert company %s: %v", companyName, err)
 			return fmt.Errorf("failed to insert company %s: %v", companyName, err)
@@ -201,7 +437,7 @@ func initializeDefaultData() error {
 		} else {
 			// If company already exists, get its ID
 			var id int64
-			err := tx.QueryRow("SELECT id FROM companies WHERE name = ?", companyName).Scan(&id)
+			err := tx.QueryRowContext(ctx, "SELECT id FROM companies WHERE name = ?", companyName).Scan(&id)
 			if err != nil {
 				log.Printf("Failed to get ID for existing company %s: %v", companyName, err)
 				return fmt.Errorf("failed to get ID for existing company %s: %v", companyName, err)
@@ -212,7 +448,7 @@ func initializeDefaultData() error {
 
 		// Insert models for this company
 		for _, modelName := range models {
-			_, err = tx.Exec("INSERT OR IGNORE INTO models (name, company_id) VALUES (?, ?)",
+			_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO models (name, company_id) VALUES (?, ?)",
 				modelName, companyID)
 			if err != nil {
 				log.Printf("Failed to insert model %s for company %s: %v", modelName, companyName, err)
@@ -231,11 +467,28 @@ func initializeDefaultData() error {
 	return nil
 }
 
-// GetCompanies returns all companies
-func GetCompanies() ([]Company, error) {
-	rows, err := db.Query("SELECT id, name, base_url FROM companies ORDER BY name")
+// ModelRepo reads the providers and their models
+type ModelRepo struct {
+	statements
+	companies *sql.Stmt
+	company   *sql.Stmt
+	byCompany *sql.Stmt
+}
+
+// NewModelRepo prepares the provider and model statements on db
+func NewModelRepo(ctx context.Context, db *sql.DB) (*ModelRepo, error) {
+	r := &ModelRepo{}
+	r.companies = r.prepare(ctx, db, "SELECT id, name, base_url FROM companies ORDER BY name")
+	r.company = r.prepare(ctx, db, "SELECT id, name, base_url FROM companies WHERE id = ?")
+	r.byCompany = r.prepare(ctx, db, "SELECT id, name FROM models WHERE company_id = ? ORDER BY name")
+	return r, r.done("prepare model statements")
+}
+
+// Companies returns all companies
+func (r *ModelRepo) Companies(ctx context.Context) ([]Company, error) {
+	rows, err := r.companies.QueryContext(ctx)
 	if err != nil {
-		return nil, err
+		return nil, opError("get companies", err)
 	}
 	defer rows.Close()
 
@@ -243,18 +496,27 @@ func GetCompanies() ([]Company, error) {
 	for rows.Next() {
 		var c Company
 		if err := rows.Scan(&c.ID, &c.Name, &c.BaseURL); err != nil {
-			return nil, err
+			return nil, opError("get companies", err)
 		}
 		companies = append(companies, c)
 	}
-	return companies, nil
+	return companies, opError("get companies", rows.Err())
+}
+
+// Company returns the company with the given ID
+func (r *ModelRepo) Company(ctx context.Context, id int) (*Company, error) {
+	var c Company
+	if err := r.company.QueryRowContext(ctx, id).Scan(&c.ID, &c.Name, &c.BaseURL); err != nil {
+		return nil, opError(fmt.Sprintf("get company %d", id), err)
+	}
+	return &c, nil
 }
 
-// GetModelsByCompany returns all models for a given company
-func GetModelsByCompany(companyID int) ([]Model, error) {
-	rows, err := db.Query("SELECT id, name FROM models WHERE company_id = ? ORDER BY name", companyID)
+// ByCompany returns all models for a given company
+func (r *ModelRepo) ByCompany(ctx context.Context, companyID int) ([]Model, error) {
+	rows, err := r.byCompany.QueryContext(ctx, companyID)
 	if err != nil {
-		return nil, err
+		return nil, opError("get models", err)
 	}
 	defer rows.Close()
 
@@ -262,48 +524,220 @@ func GetModelsByCompany(companyID int) ([]Model, error) {
 	for rows.Next() {
 		var m Model
 		if err := rows.Scan(&m.ID, &m.Name); err != nil {
-			return nil, err
+			return nil, opError("get models", err)
 		}
 		m.CompanyID = companyID
 		models = append(models, m)
 	}
-	return models, nil
+	return models, opError("get models", rows.Err())
+}
+
+// GetCompanies returns all companies of the open database
+func GetCompanies(ctx context.Context) ([]Company, error) {
+	return modelRepo.Companies(ctx)
+}
+
+// GetCompany returns the company of the open database with the given ID
+func GetCompany(ctx context.Context, id int) (*Company, error) {
+	return modelRepo.Company(ctx, id)
+}
+
+// GetModelsByCompany returns all models of the open database for a given company
+func GetModelsByCompany(ctx context.Context, companyID int) ([]Model, error) {
+	return modelRepo.ByCompany(ctx, companyID)
+}
+
+// SettingsRepo stores the active profile's settings, the API keys of the
+// companies and the preferences
+type SettingsRepo struct {
+	statements
+	activeID      *sql.Stmt
+	createProfile *sql.Stmt
+	update        *sql.Stmt
+	get           *sql.Stmt
+	apiKey        *sql.Stmt
+	saveAPIKey    *sql.Stmt
+	preference    *sql.Stmt
+	setPreference *sql.Stmt
+}
+
+// NewSettingsRepo prepares the settings statements on db
+func NewSettingsRepo(ctx context.Context, db *sql.DB) (*SettingsRepo, error) {
+	r := &SettingsRepo{}
+	r.activeID = r.prepare(ctx, db, "SELECT id FROM settings ORDER BY active DESC, id LIMIT 1")
+	r.createProfile = r.prepare(ctx, db, "INSERT INTO settings (profile, name, active) VALUES (?, '', 1)")
+	r.update = r.prepare(ctx, db, `
+		UPDATE settings SET name = ?, company_id = ?, model_id = ?, api_key = ?
+		WHERE id = ?
+	`)
+	r.get = r.prepare(ctx, db, `
+		SELECT s.id, s.profile, s.name, COALESCE(s.company_id, 0), COALESCE(s.model_id, 0), COALESCE(s.api_key, ''), k.api_key, s.active
+		FROM settings s LEFT JOIN api_keys k ON k.company_id = s.company_id
+		ORDER BY s.active DESC, s.id LIMIT 1
+	`)
+	r.apiKey = r.prepare(ctx, db, "SELECT api_key FROM api_keys WHERE company_id = ?")
+	r.saveAPIKey = r.prepare(ctx, db, `
+		INSERT INTO api_keys (company_id, api_key) VALUES (?, ?)
+		ON CONFLICT(company_id) DO UPDATE SET api_key = excluded.api_key
+	`)
+	r.preference = r.prepare(ctx, db, "SELECT value FROM preferences WHERE key = ?")
+	r.setPreference = r.prepare(ctx, db, `
+		INSERT INTO preferences (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`)
+	return r, r.done("prepare settings statements")
+}
+
+// Save saves the settings of the active profile, creating a first profile
+// when there is none
+func (r *SettingsRepo) Save(ctx context.Context, name string, companyID, modelID int, apiKey string) error {
+	var id int
+	err := r.activeID.QueryRowContext(ctx).Scan(&id)
+	if err == sql.ErrNoRows {
+		result, err := r.createProfile.ExecContext(ctx, DefaultProfile)
+		if err != nil {
+			return opError("save settings", err)
+		}
+		id64, err := result.LastInsertId()
+		if err != nil {
+			return opError("save settings", err)
+		}
+		id = int(id64)
+	} else if err != nil {
+		return opError("save settings", err)
+	}
+
+	stored, err := storeSecret(r.keyringEnabled(ctx), profileKeyAccount(id), apiKey)
+	if err != nil {
+		return opError("save settings", err)
+	}
+	_, err = r.update.ExecContext(ctx, name, companyID, modelID, stored, id)
+	return opError("save settings", err)
 }
 
-// SaveSettings saves user settings
-func SaveSettings(name string, companyID, modelID int, apiKey string) error {
-	// Delete existing settings first (we only keep one settings record)
-	_, err := db.Exec("DELETE FROM settings")
+// APIKey returns the API key stored for a company, or an empty string
+func (r *SettingsRepo) APIKey(ctx context.Context, companyID int) (string, error) {
+	var key string
+	err := r.apiKey.QueryRowContext(ctx, companyID).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
 	if err != nil {
-		return err
+		return "", opError("get API key", err)
 	}
+	key, err = loadSecret(apiKeyAccount(companyID), key)
+	return key, opError("get API key", err)
+}
 
-	// Insert new settings
-	_, err = db.Exec(`
-		INSERT INTO settings (name, company_id, model_id, api_key)
-		VALUES (?, ?, ?, ?)
-	`, name, companyID, modelID, apiKey)
-	return err
+// SetAPIKey stores the API key of a company in the OS keyring when enabled,
+// otherwise encrypted in the database
+func (r *SettingsRepo) SetAPIKey(ctx context.Context, companyID int, apiKey string) error {
+	stored, err := storeSecret(r.keyringEnabled(ctx), apiKeyAccount(companyID), apiKey)
+	if err != nil {
+		return opError("set API key", err)
+	}
+	_, err = r.saveAPIKey.ExecContext(ctx, companyID, stored)
+	return opError("set API key", err)
 }
 
-// GetSettings retrieves the current settings
-func GetSettings() (*Settings, error) {
+// Get retrieves the settings of the active profile, with its own API key or
+// else the one of the selected company, or nil when nothing was saved yet
+func (r *SettingsRepo) Get(ctx context.Context) (*Settings, error) {
 	var s Settings
-	err := db.QueryRow(`
-		SELECT id, name, company_id, model_id, api_key
-		FROM settings LIMIT 1
-	`).Scan(&s.ID, &s.Name, &s.CompanyID, &s.ModelID, &s.APIKey)
+	var companyKey sql.NullString
+	err := r.get.QueryRowContext(ctx).Scan(&s.ID, &s.Profile, &s.Name, &s.CompanyID, &s.ModelID, &s.APIKey, &companyKey, &s.Active)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, err
+		return nil, opError("get settings", err)
+	}
+
+	// A key that cannot be read has to be entered again
+	if s.OwnKey, err = loadSecret(profileKeyAccount(s.ID), s.APIKey); err != nil {
+		log.Printf("Failed to read API key: %v", err)
+		s.OwnKey = ""
+	}
+	s.APIKey = s.OwnKey
+	if s.APIKey == "" && companyKey.Valid {
+		if s.APIKey, err = loadSecret(apiKeyAccount(s.CompanyID), companyKey.String); err != nil {
+			log.Printf("Failed to read API key: %v", err)
+			s.APIKey = ""
+		}
 	}
 	return &s, nil
 }
 
+// Preference returns the stored value for key, or an empty string if unset
+func (r *SettingsRepo) Preference(ctx context.Context, key string) (string, error) {
+	var value string
+	err := r.preference.QueryRowContext(ctx, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", opError("get preference "+key, err)
+	}
+	if secretPreferences[key] {
+		value, err = loadSecret(preferenceAccount(key), value)
+		return value, opError("get preference "+key, err)
+	}
+	return value, nil
+}
+
+// SetPreference stores value under key, replacing any previous value. Secret
+// preferences go to the OS keyring when enabled, otherwise encrypted.
+func (r *SettingsRepo) SetPreference(ctx context.Context, key, value string) error {
+	if secretPreferences[key] {
+		var err error
+		if value, err = storeSecret(r.keyringEnabled(ctx), preferenceAccount(key), value); err != nil {
+			return opError("set preference "+key, err)
+		}
+	}
+	_, err := r.setPreference.ExecContext(ctx, key, value)
+	return opError("set preference "+key, err)
+}
+
+// keyringEnabled reports whether secrets should be stored in the OS keyring
+func (r *SettingsRepo) keyringEnabled(ctx context.Context) bool {
+	value, err := r.Preference(ctx, PrefUseKeyring)
+	return err == nil && value == "true"
+}
+
+// SaveSettings saves the settings of the active profile of the open database
+func SaveSettings(ctx context.Context, name string, companyID, modelID int, apiKey string) error {
+	return settingsRepo.Save(ctx, name, companyID, modelID, apiKey)
+}
+
+// GetAPIKey returns the API key stored in the open database for a company
+func GetAPIKey(ctx context.Context, companyID int) (string, error) {
+	return settingsRepo.APIKey(ctx, companyID)
+}
+
+// SetAPIKey stores the API key of a company in the open database
+func SetAPIKey(ctx context.Context, companyID int, apiKey string) error {
+	return settingsRepo.SetAPIKey(ctx, companyID, apiKey)
+}
+
+// GetSettings retrieves the settings of the active profile of the open database
+func GetSettings(ctx context.Context) (*Settings, error) {
+	return settingsRepo.Get(ctx)
+}
+
+// GetPreference returns the value stored in the open database for key, or an
+// empty string if unset
+func GetPreference(ctx context.Context, key string) (string, error) {
+	return settingsRepo.Preference(ctx, key)
+}
+
+// SetPreference stores value under key in the open database
+func SetPreference(ctx context.Context, key, value string) error {
+	return settingsRepo.SetPreference(ctx, key, value)
+}
+
 // Close closes the database connection
 func Close() {
+	closeRepos()
 	if db != nil {
 		if err := db.Close(); err != nil {
 			log.Printf("Error closing database: %v", err)
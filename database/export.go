@@ -0,0 +1,358 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportVersion is the format version written by ExportAll
+const exportVersion = 1
+
+// Export is the JSON document written by ExportAll and read by ImportAll. It
+// holds everything needed to move to another machine except secrets: API keys,
+// tokens and passwords have to be entered again after an import.
+type Export struct {
+	Version     int               `json:"version"` // Format version, currently 1
+	ExportedAt  time.Time         `json:"exported_at"`
+	Companies   []ExportCompany   `json:"companies"`
+	Profiles    []ExportProfile   `json:"profiles"`
+	Preferences map[string]string `json:"preferences"` // Preference keys and values, without secrets
+	Chats       []ExportChat      `json:"chats"`       // Chats not in the trash, oldest first
+}
+
+// ExportCompany is a provider with the names of its models
+type ExportCompany struct {
+	Name    string   `json:"name"`
+	BaseURL string   `json:"base_url,omitempty"`
+	Models  []string `json:"models"`
+}
+
+// ExportProfile is a settings profile; its provider and model are given by name
+type ExportProfile struct {
+	Profile string `json:"profile"`
+	Name    string `json:"name"`
+	Company string `json:"company,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Active  bool   `json:"active"`
+}
+
+// ExportChat is a chat with its configuration, organization and messages
+type ExportChat struct {
+	ID            int             `json:"id"` // ID on the exporting machine, referenced by preferences
+	Title         string          `json:"title"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Folder        string          `json:"folder,omitempty"` // Folder path, like "Work/Reports"
+	Tags          []string        `json:"tags,omitempty"`
+	Model         string          `json:"model,omitempty"`
+	SystemPrompt  string          `json:"system_prompt,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
+	MaxTokens     int             `json:"max_tokens,omitempty"`
+	ContextTokens int             `json:"context_tokens,omitempty"`
+	Messages      []ExportMessage `json:"messages"`
+}
+
+// ExportMessage is a message of a chat with how it was generated
+type ExportMessage struct {
+	Sender           string    `json:"sender"`
+	Text             string    `json:"text"`
+	IsAI             bool      `json:"is_ai"`
+	CreatedAt        time.Time `json:"created_at"`
+	Model            string    `json:"model,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+	FinishReason     string    `json:"finish_reason,omitempty"`
+	LatencyMS        int64     `json:"latency_ms,omitempty"`
+	Edited           bool      `json:"edited,omitempty"`
+	Failed           bool      `json:"failed,omitempty"`
+}
+
+// chatPreferences are the preferences holding a chat ID, remapped on import
+var chatPreferences = []string{PrefNewsChatID, PrefTelegramChatID, PrefIRCChatID, PrefMatrixChatID}
+
+// ExportAll writes the companies, models, profiles, preferences and chats as
+// an indented Export document
+func ExportAll(ctx context.Context, w io.Writer) error {
+	export := Export{Version: exportVersion, ExportedAt: time.Now().UTC(), Preferences: make(map[string]string)}
+
+	companies, err := GetCompanies(ctx)
+	if err != nil {
+		return err
+	}
+	companyNames := make(map[int]string)
+	modelNames := make(map[int]string)
+	for _, company := range companies {
+		companyNames[company.ID] = company.Name
+		models, err := GetModelsByCompany(ctx, company.ID)
+		if err != nil {
+			return err
+		}
+		c := ExportCompany{Name: company.Name, BaseURL: company.BaseURL, Models: []string{}}
+		for _, model := range models {
+			modelNames[model.ID] = model.Name
+			c.Models = append(c.Models, model.Name)
+		}
+		export.Companies = append(export.Companies, c)
+	}
+
+	profiles, err := GetProfiles(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		export.Profiles = append(export.Profiles, ExportProfile{
+			Profile: p.Profile,
+			Name:    p.Name,
+			Company: companyNames[p.CompanyID],
+			Model:   modelNames[p.ModelID],
+			Active:  p.Active,
+		})
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT key, COALESCE(value, '') FROM preferences ORDER BY key")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		if !secretPreferences[key] {
+			export.Preferences[key] = value
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	chats, err := GetChats(ctx)
+	if err != nil {
+		return err
+	}
+	tags, err := GetChatTags(ctx)
+	if err != nil {
+		return err
+	}
+	folders, err := GetFolders(ctx)
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		c := ExportChat{
+			ID:            chat.ID,
+			Title:         chat.Title,
+			CreatedAt:     chat.CreatedAt,
+			Folder:        folderPath(folders, chat.FolderID),
+			Tags:          tags[chat.ID],
+			Model:         chat.Model,
+			SystemPrompt:  chat.SystemPrompt,
+			Temperature:   chat.Temperature,
+			MaxTokens:     chat.MaxTokens,
+			ContextTokens: chat.ContextTokens,
+			Messages:      []ExportMessage{},
+		}
+		messages, err := GetMessages(ctx, chat.ID)
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			c.Messages = append(c.Messages, ExportMessage{
+				Sender:           m.Sender,
+				Text:             m.Text,
+				IsAI:             m.IsAI,
+				CreatedAt:        m.CreatedAt,
+				Model:            m.Model,
+				PromptTokens:     m.PromptTokens,
+				CompletionTokens: m.CompletionTokens,
+				FinishReason:     m.FinishReason,
+				LatencyMS:        m.Latency.Milliseconds(),
+				Edited:           m.Edited,
+				Failed:           m.Failed,
+			})
+		}
+		export.Chats = append(export.Chats, c)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// folderPath returns the names of a folder and its parents joined by slashes
+func folderPath(folders []Folder, id int) string {
+	var names []string
+	for depth := 0; id != 0 && depth < len(folders); depth++ {
+		found := false
+		for _, folder := range folders {
+			if folder.ID == id {
+				names = append([]string{folder.Name}, names...)
+				id, found = folder.ParentID, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// ImportAll reads a document written by ExportAll and merges it into the
+// database: missing companies, models and profiles are added, preferences are
+// overwritten and every chat is added as a new chat. It returns how many chats
+// were imported.
+func ImportAll(ctx context.Context, r io.Reader) (int, error) {
+	var export Export
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return 0, fmt.Errorf("not a database export: %v", err)
+	}
+	if export.Version < 1 || export.Version > exportVersion {
+		return 0, fmt.Errorf("unsupported export version %d", export.Version)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, company := range export.Companies {
+		_, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO companies (name, base_url) VALUES (?, ?)", company.Name, company.BaseURL)
+		if err != nil {
+			return 0, err
+		}
+		for _, model := range company.Models {
+			_, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO models (name, company_id)
+				SELECT ?, id FROM companies WHERE name = ?
+			`, model, company.Name)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for _, p := range export.Profiles {
+		// The first profile becomes active, the others are added alongside
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO settings (profile, name, company_id, model_id, api_key, active)
+			SELECT ?, ?, c.id, m.id, '', NOT EXISTS (SELECT 1 FROM settings)
+			FROM (SELECT 1) LEFT JOIN companies c ON c.name = ? LEFT JOIN models m ON m.company_id = c.id AND m.name = ?
+		`, p.Profile, p.Name, p.Company, p.Model)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	chatIDs := make(map[int]int)
+	folderIDs := make(map[string]int)
+	for _, chat := range export.Chats {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO chats (title, created_at, model, system_prompt, temperature, max_tokens, context_tokens)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, chat.Title, chat.CreatedAt.UTC(), chat.Model, chat.SystemPrompt, chat.Temperature, chat.MaxTokens, chat.ContextTokens)
+		if err != nil {
+			return 0, err
+		}
+		id64, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		id := int(id64)
+		chatIDs[chat.ID] = id
+
+		for _, m := range chat.Messages {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO messages (chat_id, sender, text, is_ai, created_at,
+					model, prompt_tokens, completion_tokens, finish_reason, latency_ms, edited, failed)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, id, m.Sender, m.Text, m.IsAI, m.CreatedAt.UTC(),
+				m.Model, m.PromptTokens, m.CompletionTokens, m.FinishReason, m.LatencyMS, m.Edited, m.Failed)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if chat.Folder != "" {
+			folderID, err := importFolder(ctx, tx, folderIDs, chat.Folder)
+			if err != nil {
+				return 0, err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE chats SET folder_id = ? WHERE id = ?", folderID, id); err != nil {
+				return 0, err
+			}
+		}
+		for _, tag := range chat.Tags {
+			if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+				return 0, err
+			}
+			_, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO chat_tags (chat_id, tag_id) SELECT ?, id FROM tags WHERE name = ?", id, tag)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for key, value := range export.Preferences {
+		if secretPreferences[key] {
+			continue
+		}
+		for _, chatKey := range chatPreferences {
+			if key != chatKey {
+				continue
+			}
+			old, err := strconv.Atoi(value)
+			if err != nil || chatIDs[old] == 0 {
+				value = ""
+			} else {
+				value = strconv.Itoa(chatIDs[old])
+			}
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO preferences (key, value) VALUES (?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value
+		`, key, value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(export.Chats), tx.Commit()
+}
+
+// importFolder returns the ID of the folder at a slash separated path,
+// creating the folders that are missing
+func importFolder(ctx context.Context, tx *sql.Tx, ids map[string]int, path string) (int, error) {
+	parentID := 0
+	prefix := ""
+	for _, name := range strings.Split(path, "/") {
+		prefix += "/" + name
+		if id, ok := ids[prefix]; ok {
+			parentID = id
+			continue
+		}
+		err := tx.QueryRowContext(ctx, "SELECT id FROM folders WHERE name = ? AND COALESCE(parent_id, 0) = ?", name, parentID).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			result, err := tx.ExecContext(ctx, "INSERT INTO folders (parent_id, name) VALUES (?, ?)", nullID(parentID), name)
+			if err != nil {
+				return 0, err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return 0, err
+			}
+			parentID = int(id)
+		} else if err != nil {
+			return 0, err
+		}
+		ids[prefix] = parentID
+	}
+	return parentID, nil
+}
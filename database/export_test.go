@@ -0,0 +1,199 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFolderPath(t *testing.T) {
+	folders := []Folder{
+		{ID: 1, Name: "Work"},
+		{ID: 2, ParentID: 1, Name: "Reports"},
+		{ID: 3, ParentID: 2, Name: "2024"},
+		{ID: 4, ParentID: 99, Name: "Orphan"},
+		{ID: 5, ParentID: 6, Name: "Loop A"},
+		{ID: 6, ParentID: 5, Name: "Loop B"},
+	}
+	tests := []struct {
+		name string
+		id   int
+		want string
+	}{
+		{"no folder", 0, ""},
+		{"top level", 1, "Work"},
+		{"nested", 3, "Work/Reports/2024"},
+		{"missing parent", 4, "Orphan"},
+		{"missing folder", 42, ""},
+		{"cycle stops after as many steps as folders", 5, "Loop B/Loop A/Loop B/Loop A/Loop B/Loop A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := folderPath(folders, tt.id); got != tt.want {
+				t.Errorf("folderPath(%d) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportAllRejects(t *testing.T) {
+	ctx := openTestDB(t)
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not JSON", "chats: []"},
+		{"empty", ""},
+		{"no version", `{"chats": []}`},
+		{"newer version", `{"version": 2, "chats": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if n, err := ImportAll(ctx, strings.NewReader(tt.doc)); err == nil {
+				t.Errorf("ImportAll() = %d, want an error", n)
+			}
+		})
+	}
+}
+
+func TestExportImportAll(t *testing.T) {
+	ctx := openTestDB(t)
+	chatID, err := CreateChat(ctx, "Exported chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	temperature := 0.7
+	if err := SetChatConfig(ctx, chatID, ChatConfig{Model: "llama3", SystemPrompt: "Be brief", Temperature: &temperature}); err != nil {
+		t.Fatal(err)
+	}
+	work, err := CreateFolder(ctx, 0, "Export work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := CreateFolder(ctx, work, "Reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetChatFolder(ctx, chatID, reports); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetChatTags(ctx, chatID, []string{"export", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveMessage(ctx, chatID, "You", "question", false); err != nil {
+		t.Fatal(err)
+	}
+	answerID, err := SaveMessage(ctx, chatID, "AI", "answer", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := MessageMeta{Model: "llama3", PromptTokens: 3, CompletionTokens: 5, FinishReason: "stop", Latency: 250 * time.Millisecond}
+	if err := SetMessageMeta(ctx, answerID, meta); err != nil {
+		t.Fatal(err)
+	}
+	prefs := map[string]string{
+		PrefWorkspaceDir: "/tmp/exported",
+		PrefNewsChatID:   strconv.Itoa(chatID),
+		PrefMatrixToken:  "secret-token",
+	}
+	for key, value := range prefs {
+		if err := SetPreference(ctx, key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ExportAll(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "secret-token") {
+		t.Error("the export holds a secret")
+	}
+	var export Export
+	if err := json.Unmarshal(buf.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	var exported *ExportChat
+	for i := range export.Chats {
+		if export.Chats[i].ID == chatID {
+			exported = &export.Chats[i]
+		}
+	}
+	if exported == nil {
+		t.Fatalf("chat %d is not in the export", chatID)
+	}
+
+	// Importing into the same database adds every chat again
+	imported, err := ImportAll(ctx, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != len(export.Chats) {
+		t.Errorf("ImportAll() = %d, want %d", imported, len(export.Chats))
+	}
+	newsChat, err := GetPreference(ctx, PrefNewsChatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newID, err := strconv.Atoi(newsChat)
+	if err != nil || newID == chatID {
+		t.Fatalf("news chat preference = %q, want the ID of the imported copy", newsChat)
+	}
+	if token, err := GetPreference(ctx, PrefMatrixToken); err != nil || token != "secret-token" {
+		t.Errorf("secret preference = %q, %v, want it untouched", token, err)
+	}
+
+	chats, err := GetChats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var copied *ChatRecord
+	for i := range chats {
+		if chats[i].ID == newID {
+			copied = &chats[i]
+		}
+	}
+	if copied == nil {
+		t.Fatalf("imported chat %d not found", newID)
+	}
+	tags, err := GetChatTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders, err := GetFolders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		field string
+		got   any
+		want  any
+	}{
+		{"title", copied.Title, "Exported chat"},
+		{"model", copied.Model, "llama3"},
+		{"system prompt", copied.SystemPrompt, "Be brief"},
+		{"temperature", *copied.Temperature, temperature},
+		{"folder", copied.FolderID, reports},
+		{"folder path", folderPath(folders, copied.FolderID), "Export work/Reports"},
+		{"tags", strings.Join(tags[newID], ","), "export,json"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+
+	messages, err := GetMessages(ctx, newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Text != "question" || messages[1].Text != "answer" {
+		t.Fatalf("imported messages = %+v, want the question and the answer", messages)
+	}
+	if messages[1].MessageMeta != meta {
+		t.Errorf("imported meta = %+v, want %+v", messages[1].MessageMeta, meta)
+	}
+}
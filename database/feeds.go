@@ -0,0 +1,65 @@
+package database
+
+import "context"
+
+// Feed is a registered RSS or Atom feed
+type Feed struct {
+	ID    int
+	URL   string
+	Title string
+}
+
+// AddFeed registers a feed; adding a known URL is a no-op
+func AddFeed(ctx context.Context, url, title string) error {
+	_, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO feeds (url, title) VALUES (?, ?)", url, title)
+	return err
+}
+
+// GetFeeds returns the registered feeds in the order they were added
+func GetFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, url, title FROM feeds ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []Feed
+	for rows.Next() {
+		var f Feed
+		if err := rows.Scan(&f.ID, &f.URL, &f.Title); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// DeleteFeed removes a feed and forgets which of its items were seen
+func DeleteFeed(ctx context.Context, id int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM feed_items WHERE feed_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM feeds WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FeedItemSeen reports whether an item of a feed was already included in a digest
+func FeedItemSeen(ctx context.Context, feedID int, guid string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM feed_items WHERE feed_id = ? AND guid = ?", feedID, guid).Scan(&count)
+	return count > 0, err
+}
+
+// MarkFeedItemSeen records that an item of a feed was included in a digest
+func MarkFeedItemSeen(ctx context.Context, feedID int, guid string) error {
+	_, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO feed_items (feed_id, guid) VALUES (?, ?)", feedID, guid)
+	return err
+}
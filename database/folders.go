@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Folder groups chats in the sidebar; folders can hold other folders
+type Folder struct {
+	ID        int
+	ParentID  int // 0 for a top level folder
+	Name      string
+	Collapsed bool
+}
+
+// nullID stores the ID 0 as NULL
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// CreateFolder stores a new folder inside parentID, or at the top level when
+// parentID is 0, and returns its ID
+func CreateFolder(ctx context.Context, parentID int, name string) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("the folder needs a name")
+	}
+	result, err := db.ExecContext(ctx, "INSERT INTO folders (parent_id, name) VALUES (?, ?)", nullID(parentID), name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// GetFolders returns every folder sorted by name
+func GetFolders(ctx context.Context) ([]Folder, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, parent_id, name, collapsed FROM folders ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []Folder
+	for rows.Next() {
+		var f Folder
+		var parentID sql.NullInt64
+		if err := rows.Scan(&f.ID, &parentID, &f.Name, &f.Collapsed); err != nil {
+			return nil, err
+		}
+		f.ParentID = int(parentID.Int64)
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// RenameFolder renames a folder
+func RenameFolder(ctx context.Context, id int, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("the folder needs a name")
+	}
+	_, err := db.ExecContext(ctx, "UPDATE folders SET name = ? WHERE id = ?", name, id)
+	return err
+}
+
+// SetFolderCollapsed stores whether a folder is shown collapsed in the sidebar
+func SetFolderCollapsed(ctx context.Context, id int, collapsed bool) error {
+	_, err := db.ExecContext(ctx, "UPDATE folders SET collapsed = ? WHERE id = ?", collapsed, id)
+	return err
+}
+
+// DeleteFolder removes a folder, moving its chats and folders to its parent
+func DeleteFolder(ctx context.Context, id int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	if err := tx.QueryRowContext(ctx, "SELECT parent_id FROM folders WHERE id = ?", id).Scan(&parentID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE chats SET folder_id = ? WHERE folder_id = ?", parentID, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE folders SET parent_id = ? WHERE parent_id = ?", parentID, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM folders WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetChatFolder moves a chat into a folder, or out of every folder when
+// folderID is 0
+func SetChatFolder(ctx context.Context, chatID, folderID int) error {
+	_, err := db.ExecContext(ctx, "UPDATE chats SET folder_id = ? WHERE id = ?", nullID(folderID), chatID)
+	return err
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// Followup is the result of a follow-up action on an AI message, like a
+// translation; it hangs under the message and is not part of the chat
+type Followup struct {
+	ID        int
+	MessageID int
+	Action    string
+	Text      string
+	CreatedAt time.Time
+}
+
+// SaveFollowup stores the result of a follow-up action on a message and
+// returns its ID
+func SaveFollowup(ctx context.Context, messageID int, action, text string) (int, error) {
+	result, err := db.ExecContext(ctx, "INSERT INTO message_followups (message_id, action, text) VALUES (?, ?, ?)", messageID, action, text)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// DeleteFollowup removes the result of a follow-up action
+func DeleteFollowup(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM message_followups WHERE id = ?", id)
+	return err
+}
+
+// GetFollowups returns the follow-up results of a message, oldest first
+func GetFollowups(ctx context.Context, messageID int) ([]Followup, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, message_id, action, text, created_at FROM message_followups
+		WHERE message_id = ? ORDER BY id
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var followups []Followup
+	for rows.Next() {
+		var f Followup
+		if err := rows.Scan(&f.ID, &f.MessageID, &f.Action, &f.Text, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		followups = append(followups, f)
+	}
+	return followups, rows.Err()
+}
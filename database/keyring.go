@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name secrets are filed under in the OS keyring
+const keyringService = "llmschat"
+
+// keyringMarker is stored in the database in place of a secret kept in the keyring
+const keyringMarker = "keyring:"
+
+// KeyringEnabled reports whether secrets should be stored in the OS keyring
+func KeyringEnabled(ctx context.Context) bool {
+	return settingsRepo.keyringEnabled(ctx)
+}
+
+// storeSecret saves value under account in the OS keyring when useKeyring is
+// set and returns the value to store in the database: a marker when the
+// keyring took the secret, otherwise the encrypted secret itself
+func storeSecret(useKeyring bool, account, value string) (string, error) {
+	if value != "" && useKeyring {
+		err := keyring.Set(keyringService, account, value)
+		if err == nil {
+			return keyringMarker, nil
+		}
+		log.Printf("OS keyring unavailable, storing %s in the database: %v", account, err)
+	}
+	return encryptSecret(value)
+}
+
+// loadSecret returns the secret of account given the value stored in the database
+func loadSecret(account, stored string) (string, error) {
+	if stored != keyringMarker {
+		return decryptSecret(stored)
+	}
+	value, err := keyring.Get(keyringService, account)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from the OS keyring: %v", account, err)
+	}
+	return value, nil
+}
+
+// deleteSecret removes the secret of account from the OS keyring, if it is there
+func deleteSecret(account string) {
+	if err := keyring.Delete(keyringService, account); err != nil && err != keyring.ErrNotFound {
+		log.Printf("Failed to delete %s from the OS keyring: %v", account, err)
+	}
+}
+
+// apiKeyAccount names the keyring entry of a company's API key
+func apiKeyAccount(companyID int) string {
+	return fmt.Sprintf("api_key_%d", companyID)
+}
+
+// secretPreferences are the preferences holding passwords and tokens, stored
+// like API keys and left out of exports
+var secretPreferences = map[string]bool{
+	PrefCalDAVPassword:     true,
+	PrefHomeAssistantToken: true,
+	PrefTelegramToken:      true,
+	PrefIRCPassword:        true,
+	PrefMatrixToken:        true,
+}
+
+// preferenceAccount names the keyring entry of a secret preference
+func preferenceAccount(key string) string {
+	return "preference_" + key
+}
+
+// profileKeyAccount names the keyring entry of a settings profile's API key
+func profileKeyAccount(profileID int) string {
+	return fmt.Sprintf("profile_key_%d", profileID)
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TableCount compares the rows of a table of the legacy database with the
+// rows found after migrating it
+type TableCount struct {
+	Table  string
+	Legacy int
+	Now    int
+}
+
+// LegacyDatabase returns the path of the database older versions kept in the
+// data folder of the working directory, or an empty string when there is none
+// or it was already migrated or declined
+func LegacyDatabase(ctx context.Context) string {
+	dir, err := filepath.Abs(legacyDataDir)
+	if err != nil {
+		return ""
+	}
+	if current, err := filepath.Abs(DataDir()); err != nil || current == dir {
+		return ""
+	}
+	path := filepath.Join(dir, "chat.db")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	if handled, err := GetPreference(ctx, PrefLegacyMigration); err != nil || handled == path {
+		return ""
+	}
+	return path
+}
+
+// SkipLegacyDatabase records that the legacy database at path should not be
+// offered again
+func SkipLegacyDatabase(ctx context.Context, path string) error {
+	return SetPreference(ctx, PrefLegacyMigration, path)
+}
+
+// MigrateLegacy replaces the database with the legacy one at path, upgrading
+// its schema, and copies its attachments. The current database is first saved
+// to the backups folder, whose path is returned with the row counts of every
+// table before and after. The legacy files are left untouched; an error is
+// returned when a table lost rows.
+func MigrateLegacy(ctx context.Context, path string) (string, []TableCount, error) {
+	legacy, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer legacy.Close()
+	counts, err := countRows(ctx, legacy)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	// A consistent copy includes what is still in the legacy write-ahead log
+	snapshot := filepath.Join(os.TempDir(), "llmschat-legacy-"+BackupName())
+	if _, err := legacy.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		return "", nil, fmt.Errorf("failed to copy %s: %v", path, err)
+	}
+	defer os.Remove(snapshot)
+
+	log.Printf("Migrating the database of an older version from %s", path)
+	safety, err := Restore(ctx, snapshot)
+	if err != nil {
+		return safety, nil, err
+	}
+	for _, name := range []string{"attachments", "blobs"} {
+		source := filepath.Join(filepath.Dir(path), name)
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+		if err := copyTree(source, filepath.Join(DataDir(), name)); err != nil {
+			return safety, nil, fmt.Errorf("failed to copy %s: %v", name, err)
+		}
+	}
+
+	// Migrations may add rows, like default models, but never drop any
+	migrated, err := countRows(ctx, db)
+	if err != nil {
+		return safety, nil, err
+	}
+	tables := make([]string, 0, len(counts))
+	for table := range counts {
+		// Tables merged into others by a migration are gone
+		if _, ok := migrated[table]; ok {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+	var result []TableCount
+	var lost []string
+	for _, table := range tables {
+		count := TableCount{Table: table, Legacy: counts[table], Now: migrated[table]}
+		result = append(result, count)
+		if count.Now < count.Legacy {
+			lost = append(lost, fmt.Sprintf("%s has %d of %d rows", table, count.Now, count.Legacy))
+		}
+	}
+	if len(lost) > 0 {
+		return safety, result, fmt.Errorf("the migrated database is missing rows: %s", strings.Join(lost, ", "))
+	}
+	return safety, result, SkipLegacyDatabase(ctx, path)
+}
+
+// countRows counts the rows of every table of a database, leaving out the
+// internal tables of SQLite and the search index
+func countRows(ctx context.Context, conn *sql.DB) (map[string]int, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name NOT LIKE 'messages_fts%'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(tables))
+	for _, table := range tables {
+		var n int
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %q", table)).Scan(&n); err != nil {
+			return nil, err
+		}
+		counts[table] = n
+	}
+	return counts, nil
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacy(t *testing.T) {
+	ctx := openTestDB(t)
+
+	// The working directory of an older version, with its data folder
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := t.TempDir()
+	if err := os.Chdir(old); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	legacyDir := filepath.Join(old, legacyDataDir)
+	if err := os.MkdirAll(filepath.Join(legacyDir, "blobs", "ab"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacyDir, "blobs", "ab", "abcdef"), []byte("legacy blob"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := LegacyDatabase(ctx); got != "" {
+		t.Fatalf("LegacyDatabase() without a legacy database = %q", got)
+	}
+
+	// The legacy database has a chat the current one does not
+	legacyChat, err := CreateChat(ctx, "Legacy chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveMessage(ctx, legacyChat, "You", "from the old version", false); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(legacyDir, "chat.db")
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := TrashChat(ctx, legacyChat); err != nil {
+		t.Fatal(err)
+	}
+	if err := PurgeChat(ctx, legacyChat); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := LegacyDatabase(ctx); got != path {
+		t.Fatalf("LegacyDatabase() = %q, want %q", got, path)
+	}
+	safety, counts, err := MigrateLegacy(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(safety); err != nil {
+		t.Errorf("safety copy: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		check func() bool
+	}{
+		{"chat is migrated", func() bool { return countWhere(t, ctx, "chats", "id = ?", legacyChat) == 1 }},
+		{"messages are migrated", func() bool { return countWhere(t, ctx, "messages", "chat_id = ?", legacyChat) == 1 }},
+		{"blobs are copied", func() bool {
+			data, err := os.ReadFile(filepath.Join(DataDir(), "blobs", "ab", "abcdef"))
+			return err == nil && string(data) == "legacy blob"
+		}},
+		{"legacy files are kept", func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}},
+		{"no table lost rows", func() bool {
+			for _, c := range counts {
+				if c.Now < c.Legacy {
+					return false
+				}
+			}
+			return len(counts) > 0
+		}},
+		{"not offered again", func() bool { return LegacyDatabase(ctx) == "" }},
+	}
+	for _, tt := range tests {
+		if !tt.check() {
+			t.Errorf("%s: failed", tt.name)
+		}
+	}
+}
+
+func TestMigrateLegacyRejects(t *testing.T) {
+	ctx := openTestDB(t)
+	dir := t.TempDir()
+	notDatabase := filepath.Join(dir, "chat.db")
+	if err := os.WriteFile(notDatabase, []byte("not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"not a database", notDatabase},
+		{"missing file", filepath.Join(dir, "missing", "chat.db")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat, err := CreateChat(ctx, "Kept "+tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := MigrateLegacy(ctx, tt.path); err == nil {
+				t.Fatal("MigrateLegacy() succeeded")
+			}
+			if got := countWhere(t, ctx, "chats", "id = ?", chat); got != 1 {
+				t.Error("the database was replaced")
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"log"
+	"os"
+	"testing"
+)
+
+// TestMain keeps the tests' database in a temporary data directory, away
+// from the user's
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "llmschat-test-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("HOME", dir)
+	os.Setenv(DataDirEnv, dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
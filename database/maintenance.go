@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maintenanceInterval is how often the database is compacted on startup
+const maintenanceInterval = 7 * 24 * time.Hour
+
+// DatabaseSize describes the space used by the database
+type DatabaseSize struct {
+	File        int64 // Bytes of the database file and its write-ahead log
+	Reclaimable int64 // Bytes of free pages a VACUUM would give back
+}
+
+// Maintain checkpoints the write-ahead log, compacts the database, refreshes
+// the query planner statistics and deletes unused attachment blobs
+func Maintain(ctx context.Context) error {
+	log.Printf("Running database maintenance...")
+	if deleted, err := CollectBlobs(ctx); err != nil {
+		log.Printf("Failed to delete unused attachments: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Deleted %d unused attachment blobs", deleted)
+	}
+	for _, statement := range []string{
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+		"VACUUM",
+		"PRAGMA optimize",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%s failed: %v", statement, err)
+		}
+	}
+	return SetPreference(ctx, PrefMaintenanceLastRun, time.Now().Format(time.RFC3339))
+}
+
+// maintainIfDue runs Maintain when the last run is older than maintenanceInterval
+func maintainIfDue(ctx context.Context) error {
+	value, err := GetPreference(ctx, PrefMaintenanceLastRun)
+	if err != nil {
+		return err
+	}
+	if last, err := time.Parse(time.RFC3339, value); err == nil && time.Since(last) < maintenanceInterval {
+		return nil
+	}
+	return Maintain(ctx)
+}
+
+// CheckIntegrity runs SQLite's integrity check and returns the problems found,
+// or an empty string when the database is intact
+func CheckIntegrity(ctx context.Context) (string, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	return strings.Join(problems, "\n"), rows.Err()
+}
+
+// Repair tries to fix a damaged database, first by rebuilding the indexes and
+// then by copying everything SQLite can still read into a new file. A raw copy
+// of the damaged file is kept in the backups folder; its path is returned.
+func Repair(ctx context.Context) (string, error) {
+	dir := filepath.Join(DataDir(), "backups")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backups folder: %v", err)
+	}
+	damaged := filepath.Join(dir, fmt.Sprintf("damaged-%s.db", time.Now().Format("20060102-150405")))
+	if err := copyFile(databasePath(), damaged); err != nil {
+		return "", fmt.Errorf("failed to copy the damaged database: %v", err)
+	}
+
+	log.Printf("Repairing database, damaged copy saved to %s", damaged)
+	if _, err := db.ExecContext(ctx, "REINDEX"); err == nil {
+		if problems, err := CheckIntegrity(ctx); err == nil && problems == "" {
+			return damaged, nil
+		}
+	}
+
+	rebuilt := databasePath() + ".rebuilt"
+	os.Remove(rebuilt)
+	// A plain copy, the blobs stay in their folder
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", rebuilt); err != nil {
+		return damaged, fmt.Errorf("the database could not be repaired, restore a backup instead: %v", err)
+	}
+	Close()
+	removeJournals()
+	if err := os.Rename(rebuilt, databasePath()); err != nil {
+		InitDB(ctx)
+		return damaged, fmt.Errorf("failed to replace the database: %v", err)
+	}
+	if err := InitDB(ctx); err != nil {
+		return damaged, err
+	}
+	problems, err := CheckIntegrity(ctx)
+	if err != nil {
+		return damaged, err
+	}
+	if problems != "" {
+		return damaged, fmt.Errorf("the database is still damaged, restore a backup instead:\n%s", problems)
+	}
+	return damaged, nil
+}
+
+// Size returns the space used by the database
+func Size(ctx context.Context) (DatabaseSize, error) {
+	var size DatabaseSize
+	for _, suffix := range []string{"", "-wal"} {
+		if info, err := os.Stat(databasePath() + suffix); err == nil {
+			size.File += info.Size()
+		}
+	}
+	var free, pageSize int64
+	if err := db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&free); err != nil {
+		return size, err
+	}
+	if err := db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return size, err
+	}
+	size.Reclaimable = free * pageSize
+	return size, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// StreamMetric is the measured performance of one streamed response
+type StreamMetric struct {
+	ID           int
+	Model        string
+	TTFT         time.Duration
+	Duration     time.Duration
+	Tokens       int
+	TokensPerSec float64
+	CreatedAt    time.Time
+}
+
+// ModelPerformance aggregates the stream metrics of a model
+type ModelPerformance struct {
+	Model           string
+	Responses       int
+	AvgTTFT         time.Duration
+	AvgTokensPerSec float64
+}
+
+// SaveStreamMetric records the performance of a streamed response
+func SaveStreamMetric(ctx context.Context, m StreamMetric) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO stream_metrics (model, ttft_ms, duration_ms, tokens, tokens_per_sec)
+		VALUES (?, ?, ?, ?, ?)
+	`, m.Model, m.TTFT.Milliseconds(), m.Duration.Milliseconds(), m.Tokens, m.TokensPerSec)
+	return err
+}
+
+// GetStreamMetrics returns the most recent stream metrics, newest first
+func GetStreamMetrics(ctx context.Context, limit int) ([]StreamMetric, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, model, ttft_ms, duration_ms, tokens, tokens_per_sec, created_at
+		FROM stream_metrics ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []StreamMetric
+	for rows.Next() {
+		var m StreamMetric
+		var ttft, duration int64
+		if err := rows.Scan(&m.ID, &m.Model, &ttft, &duration, &m.Tokens, &m.TokensPerSec, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.TTFT = time.Duration(ttft) * time.Millisecond
+		m.Duration = time.Duration(duration) * time.Millisecond
+		metrics = append(metrics, m)
+	}
+	return metrics, rows.Err()
+}
+
+// GetModelPerformance returns average stream metrics per model
+func GetModelPerformance(ctx context.Context) ([]ModelPerformance, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT model, COUNT(*), AVG(ttft_ms), AVG(tokens_per_sec)
+		FROM stream_metrics GROUP BY model ORDER BY model
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perf []ModelPerformance
+	for rows.Next() {
+		var p ModelPerformance
+		var ttft float64
+		if err := rows.Scan(&p.Model, &p.Responses, &ttft, &p.AvgTokensPerSec); err != nil {
+			return nil, err
+		}
+		p.AvgTTFT = time.Duration(ttft) * time.Millisecond
+		perf = append(perf, p)
+	}
+	return perf, rows.Err()
+}
@@ -0,0 +1,464 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration is one versioned change to the schema
+type migration struct {
+	version     int
+	description string
+	up          func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations are applied in order on top of the tables created by createTables.
+// Append new migrations with the next version number and never change one that
+// has been released, since databases already at that version will not rerun it.
+var migrations = []migration{
+	{1, "news feeds", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE feeds (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				url TEXT NOT NULL UNIQUE,
+				title TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE feed_items (
+				feed_id INTEGER NOT NULL,
+				guid TEXT NOT NULL,
+				seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (feed_id, guid),
+				FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+			);
+		`)
+		return err
+	}},
+	{2, "api keys per company", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE api_keys (
+				company_id INTEGER PRIMARY KEY,
+				api_key TEXT NOT NULL,
+				FOREIGN KEY (company_id) REFERENCES companies (id)
+			);
+			INSERT INTO api_keys (company_id, api_key)
+				SELECT company_id, api_key FROM settings WHERE api_key != '';
+		`)
+		return err
+	}},
+	{3, "encrypt api keys", func(ctx context.Context, tx *sql.Tx) error {
+		for _, table := range []string{"api_keys", "settings"} {
+			if err := encryptColumn(ctx, tx, table, "api_key"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{4, "chat trash", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "ALTER TABLE chats ADD COLUMN deleted_at DATETIME")
+		return err
+	}},
+	{5, "attachments", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE attachments (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				chat_id INTEGER NOT NULL,
+				message_id INTEGER,
+				name TEXT NOT NULL,
+				mime_type TEXT NOT NULL,
+				size INTEGER NOT NULL,
+				data BLOB,
+				path TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (chat_id) REFERENCES chats (id) ON DELETE CASCADE,
+				FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_attachments_chat ON attachments (chat_id);
+			CREATE INDEX idx_attachments_message ON attachments (message_id);
+		`)
+		return err
+	}},
+	{6, "usage", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE usage (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				model TEXT NOT NULL,
+				prompt_tokens INTEGER NOT NULL,
+				completion_tokens INTEGER NOT NULL,
+				cost REAL NOT NULL,
+				latency_ms INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_usage_created ON usage (created_at);
+		`)
+		return err
+	}},
+	{7, "content-addressed attachments", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			ALTER TABLE attachments ADD COLUMN sha256 TEXT;
+			CREATE INDEX idx_attachments_sha256 ON attachments (sha256);
+		`)
+		if err != nil {
+			return err
+		}
+		return moveAttachmentsToBlobs(ctx, tx)
+	}},
+	{8, "prompt templates", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE prompts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE,
+				text TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)
+		`)
+		return err
+	}},
+	{9, "settings profiles", func(ctx context.Context, tx *sql.Tx) error {
+		// The key of the single settings row is also in api_keys, filed under
+		// the company, so the first profile starts without a key of its own
+		_, err := tx.ExecContext(ctx, `
+			ALTER TABLE settings ADD COLUMN profile TEXT NOT NULL DEFAULT 'Default';
+			ALTER TABLE settings ADD COLUMN active INTEGER NOT NULL DEFAULT 0;
+			DELETE FROM settings WHERE id != (SELECT MAX(id) FROM settings);
+			UPDATE settings SET active = 1, api_key = '';
+			CREATE UNIQUE INDEX idx_settings_profile ON settings (profile);
+		`)
+		return err
+	}},
+	{10, "attachment descriptions", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "ALTER TABLE attachments ADD COLUMN description TEXT NOT NULL DEFAULT ''")
+		return err
+	}},
+	{11, "per-chat model and parameters", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			ALTER TABLE chats ADD COLUMN model TEXT NOT NULL DEFAULT '';
+			ALTER TABLE chats ADD COLUMN system_prompt TEXT NOT NULL DEFAULT '';
+			ALTER TABLE chats ADD COLUMN temperature REAL;
+			ALTER TABLE chats ADD COLUMN max_tokens INTEGER NOT NULL DEFAULT 0;
+		`)
+		return err
+	}},
+	{12, "per-chat context size", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "ALTER TABLE chats ADD COLUMN context_tokens INTEGER NOT NULL DEFAULT 0")
+		return err
+	}},
+	{13, "message metadata", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			ALTER TABLE messages ADD COLUMN model TEXT NOT NULL DEFAULT '';
+			ALTER TABLE messages ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE messages ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE messages ADD COLUMN finish_reason TEXT NOT NULL DEFAULT '';
+			ALTER TABLE messages ADD COLUMN latency_ms INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE messages ADD COLUMN edited INTEGER NOT NULL DEFAULT 0;
+		`)
+		return err
+	}},
+	{14, "chat tags", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE tags (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE COLLATE NOCASE
+			);
+			CREATE TABLE chat_tags (
+				chat_id INTEGER NOT NULL,
+				tag_id INTEGER NOT NULL,
+				PRIMARY KEY (chat_id, tag_id),
+				FOREIGN KEY (chat_id) REFERENCES chats (id) ON DELETE CASCADE,
+				FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_chat_tags_tag ON chat_tags (tag_id);
+		`)
+		return err
+	}},
+	{15, "chat folders", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE folders (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				parent_id INTEGER,
+				name TEXT NOT NULL,
+				collapsed INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (parent_id) REFERENCES folders (id)
+			);
+			ALTER TABLE chats ADD COLUMN folder_id INTEGER REFERENCES folders (id);
+		`)
+		return err
+	}},
+	{16, "audit log", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				method TEXT NOT NULL,
+				url TEXT NOT NULL,
+				model TEXT NOT NULL DEFAULT '',
+				status INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				latency_ms INTEGER NOT NULL,
+				request_bytes INTEGER NOT NULL,
+				response_bytes INTEGER NOT NULL,
+				request_body TEXT,
+				response_body TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_audit_log_created ON audit_log (created_at);
+		`)
+		return err
+	}},
+	{17, "personas", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE personas (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE,
+				avatar TEXT NOT NULL DEFAULT '',
+				system_prompt TEXT NOT NULL DEFAULT '',
+				model TEXT NOT NULL DEFAULT '',
+				temperature REAL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`)
+		return err
+	}},
+	{18, "notification rules", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE event_notifications (
+				event TEXT PRIMARY KEY,
+				mode TEXT NOT NULL,
+				sound BOOLEAN NOT NULL DEFAULT 0
+			);
+			CREATE TABLE chat_notifications (
+				chat_id INTEGER PRIMARY KEY,
+				mode TEXT NOT NULL,
+				sound BOOLEAN NOT NULL DEFAULT 0,
+				FOREIGN KEY (chat_id) REFERENCES chats (id) ON DELETE CASCADE
+			);
+		`)
+		return err
+	}},
+	{19, "starter prompt templates", func(ctx context.Context, tx *sql.Tx) error {
+		// Prompts the user already named the same are kept
+		for _, p := range starterPrompts {
+			if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO prompts (name, text) VALUES (?, ?)", p.Name, p.Text); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{20, "message follow-ups", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE message_followups (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				message_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				text TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_message_followups_message ON message_followups (message_id);
+			CREATE TRIGGER message_followups_delete AFTER DELETE ON messages BEGIN
+				DELETE FROM message_followups WHERE message_id = old.id;
+			END;
+		`)
+		return err
+	}},
+	{21, "message threads", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE threads (
+				message_id INTEGER PRIMARY KEY,
+				in_context BOOLEAN NOT NULL DEFAULT 0,
+				FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+			);
+			CREATE TABLE thread_messages (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				message_id INTEGER NOT NULL,
+				sender TEXT NOT NULL,
+				text TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (message_id) REFERENCES threads (message_id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_thread_messages_message ON thread_messages (message_id);
+			CREATE TRIGGER threads_delete AFTER DELETE ON messages BEGIN
+				DELETE FROM thread_messages WHERE message_id = old.id;
+				DELETE FROM threads WHERE message_id = old.id;
+			END;
+		`)
+		return err
+	}},
+	{22, "starred messages", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TABLE starred_messages (
+				message_id INTEGER PRIMARY KEY,
+				starred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+			);
+			CREATE TRIGGER starred_messages_delete AFTER DELETE ON messages BEGIN
+				DELETE FROM starred_messages WHERE message_id = old.id;
+			END;
+		`)
+		return err
+	}},
+	{23, "message memory rows", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "ALTER TABLE messages ADD COLUMN memory_id INTEGER")
+		return err
+	}},
+	{24, "failed requests", func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE messages ADD COLUMN failed INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+		// Errors saved before the column existed are System messages
+		_, err := tx.ExecContext(ctx, "UPDATE messages SET failed = 1 WHERE sender = 'System' AND text LIKE 'Error%'")
+		return err
+	}},
+	{25, "encrypt secret preferences", func(ctx context.Context, tx *sql.Tx) error {
+		for key := range secretPreferences {
+			var value string
+			err := tx.QueryRowContext(ctx, "SELECT COALESCE(value, '') FROM preferences WHERE key = ?", key).Scan(&value)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			encrypted, err := encryptSecret(value)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE preferences SET value = ? WHERE key = ?", encrypted, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{26, "encrypt secrets with the machine key", func(ctx context.Context, tx *sql.Tx) error {
+		for _, column := range [][2]string{{"api_keys", "api_key"}, {"settings", "api_key"}, {"preferences", "value"}} {
+			if err := reencryptColumn(ctx, tx, column[0], column[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+}
+
+// encryptColumn encrypts the plaintext secrets stored in a column
+func encryptColumn(ctx context.Context, tx *sql.Tx, table, column string) error {
+	values, err := columnValues(ctx, tx, table, column)
+	if err != nil {
+		return err
+	}
+	for id, value := range values {
+		encrypted, err := encryptSecret(value)
+		if err != nil {
+			return err
+		}
+		if encrypted == value {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column), encrypted, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reencryptColumn encrypts again with the current key the secrets of a column
+// stored with an older one. Secrets that no key opens are left as they are.
+func reencryptColumn(ctx context.Context, tx *sql.Tx, table, column string) error {
+	values, err := columnValues(ctx, tx, table, column)
+	if err != nil {
+		return err
+	}
+	for id, value := range values {
+		plaintext, stale, err := openSecret(value)
+		if err != nil || !stale {
+			continue
+		}
+		encrypted, err := encryptSecret(plaintext)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column), encrypted, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnValues returns the values of a text column by rowid
+func columnValues(ctx context.Context, tx *sql.Tx, table, column string) (map[int64]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT rowid, COALESCE(%s, '') FROM %s", column, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		values[id] = value
+	}
+	return values, rows.Err()
+}
+
+// migrate applies the migrations newer than the recorded schema version, each
+// in its own transaction
+func migrate(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Printf("Failed to create schema_version table: %v", err)
+		return fmt.Errorf("failed to create schema_version table: %v", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+
+	last := 0
+	for _, m := range migrations {
+		if m.version <= last {
+			return fmt.Errorf("migration %d is out of order", m.version)
+		}
+		last = m.version
+		if m.version <= current {
+			continue
+		}
+
+		log.Printf("Applying migration %d: %s", m.version, m.description)
+		if err := applyMigration(ctx, m); err != nil {
+			log.Printf("Failed to apply migration %d: %v", m.version, err)
+			return fmt.Errorf("failed to apply migration %d (%s): %v", m.version, m.description, err)
+		}
+	}
+
+	log.Printf("Database schema is at version %d", max(current, last))
+	return nil
+}
+
+// applyMigration runs m and records its version atomically
+func applyMigration(ctx context.Context, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_version (version, description) VALUES (?, ?)", m.version, m.description); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMigrationVersions(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.version, i+1)
+		}
+		if strings.TrimSpace(m.description) == "" {
+			t.Errorf("migration %d has no description", m.version)
+		}
+	}
+}
+
+// openTestDB opens the database of the test data directory until the test ends
+func openTestDB(t *testing.T) context.Context {
+	t.Helper()
+	ctx := context.Background()
+	if err := InitDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Close)
+	return ctx
+}
+
+// schemaVersion returns the latest applied migration
+func schemaVersion(t *testing.T, ctx context.Context) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := openTestDB(t)
+	latest := len(migrations)
+	if got := schemaVersion(t, ctx); got != latest {
+		t.Fatalf("schema version = %d, want %d", got, latest)
+	}
+
+	// Objects added along the chain
+	tests := []struct {
+		table  string
+		column string
+	}{
+		{"feeds", "url"},
+		{"api_keys", "api_key"},
+		{"chats", "deleted_at"},
+		{"attachments", "sha256"},
+		{"starred_messages", "message_id"},
+		{"messages", "memory_id"},
+		{"messages", "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table+"."+tt.column, func(t *testing.T) {
+			var count int
+			err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", tt.table, tt.column).Scan(&count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Errorf("%s.%s is missing", tt.table, tt.column)
+			}
+		})
+	}
+
+	// Running the chain again changes nothing
+	if err := migrate(ctx); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if got := schemaVersion(t, ctx); got != latest {
+		t.Fatalf("schema version after second migrate = %d, want %d", got, latest)
+	}
+}
+
+func TestMigrateFromOlderVersion(t *testing.T) {
+	ctx := openTestDB(t)
+
+	// A database from before secret preferences were encrypted
+	const token = "plaintext-token"
+	if _, err := db.ExecContext(ctx, "INSERT OR REPLACE INTO preferences (key, value) VALUES (?, ?)", PrefMatrixToken, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM schema_version WHERE version >= 25"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, latest := schemaVersion(t, ctx), len(migrations); got != latest {
+		t.Fatalf("schema version = %d, want %d", got, latest)
+	}
+	var stored string
+	if err := db.QueryRowContext(ctx, "SELECT value FROM preferences WHERE key = ?", PrefMatrixToken).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		t.Errorf("stored token = %q, want it encrypted", stored)
+	}
+	value, err := GetPreference(ctx, PrefMatrixToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != token {
+		t.Errorf("GetPreference() = %q, want %q", value, token)
+	}
+}
+
+func TestMigrateMarksFailedRequests(t *testing.T) {
+	ctx := openTestDB(t)
+	chatID, err := CreateChat(ctx, "Errors from an older version")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sender, text string
+		failed       bool
+	}{
+		{"System", "Error: connection refused", true},
+		{"System", "Chat renamed", false},
+		{"AI", "Error handling in Go uses values", false},
+		{"You", "Error?", false},
+	}
+	ids := make([]int, len(tests))
+	for i, tt := range tests {
+		if ids[i], err = SaveMessage(ctx, chatID, tt.sender, tt.text, tt.sender == "AI"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A database from before the failed column
+	if _, err := db.ExecContext(ctx, "ALTER TABLE messages DROP COLUMN failed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM schema_version WHERE version >= 24"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range tests {
+		var failed bool
+		if err := db.QueryRowContext(ctx, "SELECT failed FROM messages WHERE id = ?", ids[i]).Scan(&failed); err != nil {
+			t.Fatal(err)
+		}
+		if failed != tt.failed {
+			t.Errorf("%s %q: failed = %v, want %v", tt.sender, tt.text, failed, tt.failed)
+		}
+	}
+}
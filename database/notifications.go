@@ -0,0 +1,91 @@
+package database
+
+import "context"
+
+// Notification modes
+const (
+	NotifyAlways    = "always"
+	NotifyUnfocused = "unfocused" // Only when the window is not focused
+	NotifyNever     = "never"
+)
+
+// Events that can be notified
+const (
+	EventAnswer = "answer" // An answer finished streaming
+	EventError  = "error"  // A request failed
+	EventBridge = "bridge" // A message arrived through a bridge
+)
+
+// NotificationRule is how an event, or any event of a chat, is notified
+type NotificationRule struct {
+	Mode  string
+	Sound bool
+}
+
+// GetEventNotifications returns the rules of the events that have one
+func GetEventNotifications(ctx context.Context) (map[string]NotificationRule, error) {
+	rows, err := db.QueryContext(ctx, "SELECT event, mode, sound FROM event_notifications")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make(map[string]NotificationRule)
+	for rows.Next() {
+		var event string
+		var rule NotificationRule
+		if err := rows.Scan(&event, &rule.Mode, &rule.Sound); err != nil {
+			return nil, err
+		}
+		rules[event] = rule
+	}
+	return rules, rows.Err()
+}
+
+// SetEventNotification stores the rule of an event
+func SetEventNotification(ctx context.Context, event string, rule NotificationRule) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO event_notifications (event, mode, sound) VALUES (?, ?, ?)
+		ON CONFLICT(event) DO UPDATE SET mode = excluded.mode, sound = excluded.sound
+	`, event, rule.Mode, rule.Sound)
+	return err
+}
+
+// GetChatNotifications returns the rules of the chats that override the
+// event rules, by chat ID
+func GetChatNotifications(ctx context.Context) (map[int]NotificationRule, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT n.chat_id, n.mode, n.sound FROM chat_notifications n
+		JOIN chats c ON c.id = n.chat_id WHERE c.deleted_at IS NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make(map[int]NotificationRule)
+	for rows.Next() {
+		var chatID int
+		var rule NotificationRule
+		if err := rows.Scan(&chatID, &rule.Mode, &rule.Sound); err != nil {
+			return nil, err
+		}
+		rules[chatID] = rule
+	}
+	return rules, rows.Err()
+}
+
+// SetChatNotification makes a chat follow rule instead of the event rules
+func SetChatNotification(ctx context.Context, chatID int, rule NotificationRule) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO chat_notifications (chat_id, mode, sound) VALUES (?, ?, ?)
+		ON CONFLICT(chat_id) DO UPDATE SET mode = excluded.mode, sound = excluded.sound
+	`, chatID, rule.Mode, rule.Sound)
+	return err
+}
+
+// DeleteChatNotification makes a chat follow the event rules again
+func DeleteChatNotification(ctx context.Context, chatID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM chat_notifications WHERE chat_id = ?", chatID)
+	return err
+}
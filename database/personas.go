@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Persona is a named preset new chats can start from
+type Persona struct {
+	ID           int
+	Name         string
+	Avatar       string // An emoji or a few letters
+	SystemPrompt string
+	Model        string   // Empty for the model in the settings
+	Temperature  *float64 // Nil for the model's default
+}
+
+// SavePersona stores persona, as a new one when its ID is 0, and returns its ID
+func SavePersona(ctx context.Context, persona Persona) (int, error) {
+	persona.Name = strings.TrimSpace(persona.Name)
+	if persona.Name == "" {
+		return 0, fmt.Errorf("the persona needs a name")
+	}
+	var err error
+	if persona.ID == 0 {
+		var id int64
+		result, insertErr := db.ExecContext(ctx, `
+			INSERT INTO personas (name, avatar, system_prompt, model, temperature) VALUES (?, ?, ?, ?, ?)
+		`, persona.Name, persona.Avatar, persona.SystemPrompt, persona.Model, persona.Temperature)
+		if err = insertErr; err == nil {
+			id, err = result.LastInsertId()
+			persona.ID = int(id)
+		}
+	} else {
+		_, err = db.ExecContext(ctx, `
+			UPDATE personas SET name = ?, avatar = ?, system_prompt = ?, model = ?, temperature = ? WHERE id = ?
+		`, persona.Name, persona.Avatar, persona.SystemPrompt, persona.Model, persona.Temperature, persona.ID)
+	}
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return 0, fmt.Errorf("a persona named %q already exists", persona.Name)
+	}
+	return persona.ID, err
+}
+
+// DeletePersona removes a persona; chats started from it keep their settings
+func DeletePersona(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM personas WHERE id = ?", id)
+	return err
+}
+
+// GetPersonas returns the personas sorted by name
+func GetPersonas(ctx context.Context) ([]Persona, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, avatar, system_prompt, model, temperature FROM personas ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var personas []Persona
+	for rows.Next() {
+		var p Persona
+		var temperature sql.NullFloat64
+		if err := rows.Scan(&p.ID, &p.Name, &p.Avatar, &p.SystemPrompt, &p.Model, &temperature); err != nil {
+			return nil, err
+		}
+		if temperature.Valid {
+			p.Temperature = &temperature.Float64
+		}
+		personas = append(personas, p)
+	}
+	return personas, rows.Err()
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DefaultProfile names the profile created with the first settings
+const DefaultProfile = "Default"
+
+// GetProfiles returns the settings profiles sorted by name, without their keys
+func GetProfiles(ctx context.Context) ([]Settings, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, profile, name, COALESCE(company_id, 0), COALESCE(model_id, 0), active
+		FROM settings ORDER BY profile COLLATE NOCASE
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []Settings
+	for rows.Next() {
+		var p Settings
+		if err := rows.Scan(&p.ID, &p.Profile, &p.Name, &p.CompanyID, &p.ModelID, &p.Active); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// CreateProfile adds a profile with the provider and model of the active one
+// and returns its ID. It uses the company's API key until it is given its own.
+func CreateProfile(ctx context.Context, profile string) (int, error) {
+	profile = strings.TrimSpace(profile)
+	if profile == "" {
+		return 0, fmt.Errorf("the profile needs a name")
+	}
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO settings (profile, name, company_id, model_id, api_key, active)
+		SELECT ?, COALESCE(MAX(name), ''), MAX(company_id), MAX(model_id), '', 0
+		FROM (SELECT * FROM settings ORDER BY active DESC, id LIMIT 1)
+	`, profile)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return 0, fmt.Errorf("a profile named %q already exists", profile)
+		}
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// RenameProfile changes the name of a profile
+func RenameProfile(ctx context.Context, id int, profile string) error {
+	profile = strings.TrimSpace(profile)
+	if profile == "" {
+		return fmt.Errorf("the profile needs a name")
+	}
+	_, err := db.ExecContext(ctx, "UPDATE settings SET profile = ? WHERE id = ?", profile, id)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return fmt.Errorf("a profile named %q already exists", profile)
+	}
+	return err
+}
+
+// SwitchProfile makes a profile the active one; an unknown profile leaves the
+// active one as it is
+func SwitchProfile(ctx context.Context, id int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM settings WHERE id = ?)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("profile %d not found", id)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE settings SET active = (id = ?)", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteProfile removes a profile that is not the active one
+func DeleteProfile(ctx context.Context, id int) error {
+	result, err := db.ExecContext(ctx, "DELETE FROM settings WHERE id = ? AND active = 0", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("the active profile cannot be deleted, switch to another one first")
+	}
+	if KeyringEnabled(ctx) {
+		deleteSecret(profileKeyAccount(id))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Prompt is a named prompt template; its text may hold {{variables}}
+type Prompt struct {
+	ID        int
+	Name      string
+	Text      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// starterPrompts are the templates a new library starts with
+var starterPrompts = []Prompt{
+	{Name: "Explain code", Text: "Explain code: {{code}}"},
+	{Name: "Review code", Text: "Review this code for bugs, unclear names and missing tests. Quote the lines you comment on.\n\n{{code}}"},
+	{Name: "Summarize", Text: "Summarize the following in {{length}}:\n\n{{text}}"},
+	{Name: "Translate", Text: "Translate into {{language}}, keeping the tone and formatting:\n\n{{text}}"},
+	{Name: "Write a commit message", Text: "Write a commit message for this diff. Use a short subject line, then explain why in the body.\n\n{{diff}}"},
+}
+
+// CreatePrompt stores a new prompt template and returns its ID
+func CreatePrompt(ctx context.Context, name, text string) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("the prompt needs a name")
+	}
+	result, err := db.ExecContext(ctx, "INSERT INTO prompts (name, text) VALUES (?, ?)", name, text)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return 0, fmt.Errorf("a prompt named %q already exists", name)
+		}
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// UpdatePrompt renames a prompt template and replaces its text
+func UpdatePrompt(ctx context.Context, id int, name, text string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("the prompt needs a name")
+	}
+	_, err := db.ExecContext(ctx, "UPDATE prompts SET name = ?, text = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", name, text, id)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return fmt.Errorf("a prompt named %q already exists", name)
+	}
+	return err
+}
+
+// DeletePrompt removes a prompt template
+func DeletePrompt(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM prompts WHERE id = ?", id)
+	return err
+}
+
+// GetPrompts returns the prompt templates sorted by name
+func GetPrompts(ctx context.Context) ([]Prompt, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, text, created_at, updated_at FROM prompts ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prompts []Prompt
+	for rows.Next() {
+		var p Prompt
+		if err := rows.Scan(&p.ID, &p.Name, &p.Text, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, rows.Err()
+}
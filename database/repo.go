@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrNotFound is returned when the chat, message, company or model asked for
+// does not exist
+var ErrNotFound = errors.New("not found")
+
+// Error is a failed repository operation
+type Error struct {
+	Op  string // What was being done, like "rename chat"
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// opError wraps err in an Error for op, turning sql.ErrNoRows into ErrNotFound
+func opError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return &Error{Op: op, Err: err}
+}
+
+// expectRow returns ErrNotFound for op when a statement changed no row
+func expectRow(op string, result sql.Result, err error) error {
+	if err != nil {
+		return opError(op, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return opError(op, ErrNotFound)
+	}
+	return nil
+}
+
+// statements prepares the statements of a repository and closes them with it
+type statements struct {
+	list []*sql.Stmt
+	err  error
+}
+
+// prepare prepares query on db; after the first failure it does nothing and
+// the error is kept for done
+func (s *statements) prepare(ctx context.Context, db *sql.DB, query string) *sql.Stmt {
+	if s.err != nil {
+		return nil
+	}
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		s.err = err
+		return nil
+	}
+	s.list = append(s.list, stmt)
+	return stmt
+}
+
+// done returns the first preparation error, closing what was prepared
+func (s *statements) done(op string) error {
+	if s.err != nil {
+		s.Close()
+		return opError(op, s.err)
+	}
+	return nil
+}
+
+// Close closes the prepared statements
+func (s *statements) Close() error {
+	var first error
+	for _, stmt := range s.list {
+		if err := stmt.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	s.list = nil
+	return first
+}
+
+// The repositories of the open database, used by the package-level functions
+var (
+	chatRepo     *ChatRepo
+	settingsRepo *SettingsRepo
+	modelRepo    *ModelRepo
+)
+
+// openRepos prepares the repositories of the open database
+func openRepos(ctx context.Context) error {
+	var err error
+	if chatRepo, err = NewChatRepo(ctx, db); err != nil {
+		return err
+	}
+	if settingsRepo, err = NewSettingsRepo(ctx, db); err != nil {
+		return err
+	}
+	modelRepo, err = NewModelRepo(ctx, db)
+	return err
+}
+
+// closeRepos closes the statements of the repositories before the database
+func closeRepos() {
+	if chatRepo != nil {
+		chatRepo.Close()
+	}
+	if settingsRepo != nil {
+		settingsRepo.Close()
+	}
+	if modelRepo != nil {
+		modelRepo.Close()
+	}
+	chatRepo, settingsRepo, modelRepo = nil, nil, nil
+}
@@ -0,0 +1,343 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryDB returns an in-memory database with the full schema. The schema
+// is created through the package's open database, which is put back after.
+func memoryDB(t *testing.T) (context.Context, *sql.DB) {
+	t.Helper()
+	ctx := context.Background()
+	mem, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own
+	mem.SetMaxOpenConns(1)
+	t.Cleanup(func() { mem.Close() })
+
+	open := db
+	db = mem
+	defer func() { db = open }()
+	if err := createTables(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return ctx, mem
+}
+
+func newChatRepo(t *testing.T) (context.Context, *sql.DB, *ChatRepo) {
+	t.Helper()
+	ctx, mem := memoryDB(t)
+	r, err := NewChatRepo(ctx, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return ctx, mem, r
+}
+
+func TestChatRepoMessages(t *testing.T) {
+	ctx, _, r := newChatRepo(t)
+	chatID, err := r.Create(ctx, "Repo chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, m := range []struct {
+		sender string
+		text   string
+		isAI   bool
+	}{
+		{"You", "question", false},
+		{"AI", "answer", true},
+		{"You", "follow-up", false},
+		{"AI", "second answer", true},
+	} {
+		id, err := r.SaveMessage(ctx, chatID, m.sender, m.text, m.isAI)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	meta := MessageMeta{Model: "gpt-4o-mini", PromptTokens: 12, CompletionTokens: 34, FinishReason: "stop", Latency: 1500 * time.Millisecond}
+	if err := r.SetMessageMeta(ctx, ids[1], meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetMessageMemory(ctx, ids[1], 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AppendMessage(ctx, ids[1], ", continued"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EditMessage(ctx, ids[0], "edited question"); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := r.Messages(ctx, chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("got %d messages, want 4", len(messages))
+	}
+	if m := messages[0]; m.Text != "edited question" || !m.Edited || m.IsAI {
+		t.Errorf("first message = %+v, want the edited question", m)
+	}
+	if m := messages[1]; m.Text != "answer, continued" || m.MemoryID != 7 || m.MessageMeta != meta || !m.IsAI {
+		t.Errorf("second message = %+v, want the continued answer with its meta", m)
+	}
+
+	n, err := r.DeleteMessagesAfter(ctx, chatID, ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteMessagesAfter() removed %d messages, want 2", n)
+	}
+	if err := r.DeleteMessage(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	messages, err = r.Messages(ctx, chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].ID != ids[1] {
+		t.Errorf("messages after deleting = %+v, want only the first answer", messages)
+	}
+}
+
+func TestChatRepoList(t *testing.T) {
+	ctx, mem, r := newChatRepo(t)
+	first, err := r.Create(ctx, "First")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trashed, err := r.Create(ctx, "Trashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.ExecContext(ctx, "UPDATE chats SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", trashed); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Rename(ctx, first, "Renamed"); err != nil {
+		t.Fatal(err)
+	}
+	temperature := 0.3
+	config := ChatConfig{Model: "llama3", SystemPrompt: "Be brief", Temperature: &temperature, MaxTokens: 200, ContextTokens: 1000}
+	if err := r.SetConfig(ctx, first, config); err != nil {
+		t.Fatal(err)
+	}
+
+	chats, err := r.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chats) != 1 {
+		t.Fatalf("List() returned %d chats, want only the one outside the trash", len(chats))
+	}
+	c := chats[0]
+	if c.ID != first || c.Title != "Renamed" {
+		t.Errorf("chat = %d %q, want %d %q", c.ID, c.Title, first, "Renamed")
+	}
+	if c.Temperature == nil || *c.Temperature != temperature {
+		t.Errorf("temperature = %v, want %v", c.Temperature, temperature)
+	}
+	c.Temperature = config.Temperature
+	if c.ChatConfig != config {
+		t.Errorf("config = %+v, want %+v", c.ChatConfig, config)
+	}
+}
+
+func TestChatRepoNotFound(t *testing.T) {
+	ctx, _, r := newChatRepo(t)
+	const missing = 4242
+	tests := []struct {
+		op  string
+		run func() error
+	}{
+		{"rename chat", func() error { return r.Rename(ctx, missing, "x") }},
+		{"set chat config", func() error { return r.SetConfig(ctx, missing, ChatConfig{}) }},
+		{"set message meta", func() error { return r.SetMessageMeta(ctx, missing, MessageMeta{}) }},
+		{"set message memory", func() error { return r.SetMessageMemory(ctx, missing, 1) }},
+		{"edit message", func() error { return r.EditMessage(ctx, missing, "x") }},
+		{"append to message", func() error { return r.AppendMessage(ctx, missing, "x") }},
+		{"delete message", func() error { return r.DeleteMessage(ctx, missing) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			err := tt.run()
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("error = %v, want ErrNotFound", err)
+			}
+			var opErr *Error
+			if !errors.As(err, &opErr) || opErr.Op != tt.op {
+				t.Errorf("error = %v, want it to name %q", err, tt.op)
+			}
+		})
+	}
+}
+
+func TestSettingsRepo(t *testing.T) {
+	ctx, mem := memoryDB(t)
+	r, err := NewSettingsRepo(ctx, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if s, err := r.Get(ctx); err != nil || s != nil {
+		t.Fatalf("Get() before saving = %+v, %v, want nil", s, err)
+	}
+	if _, err := mem.ExecContext(ctx, "INSERT INTO companies (id, name) VALUES (1, 'OpenAI'), (2, 'Anthropic')"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetAPIKey(ctx, 1, "company-key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetAPIKey(ctx, 2, "company-key-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		companyID int
+		ownKey    string
+		want      string
+	}{
+		{"company key without own key", 1, "", "company-key-1"},
+		{"own key wins", 1, "profile-key", "profile-key"},
+		{"switching company swaps the key", 2, "", "company-key-2"},
+		{"company without a key", 3, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Save(ctx, "Me", tt.companyID, 0, tt.ownKey); err != nil {
+				t.Fatal(err)
+			}
+			s, err := r.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Profile != DefaultProfile || s.CompanyID != tt.companyID {
+				t.Errorf("profile = %q with company %d, want %q with %d", s.Profile, s.CompanyID, DefaultProfile, tt.companyID)
+			}
+			if s.APIKey != tt.want || s.OwnKey != tt.ownKey {
+				t.Errorf("keys = %q (own %q), want %q (own %q)", s.APIKey, s.OwnKey, tt.want, tt.ownKey)
+			}
+		})
+	}
+
+	// Keys are not stored in the clear
+	var stored string
+	if err := mem.QueryRowContext(ctx, "SELECT api_key FROM api_keys WHERE company_id = 1").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		t.Errorf("stored company key = %q, want it encrypted", stored)
+	}
+	if key, err := r.APIKey(ctx, 1); err != nil || key != "company-key-1" {
+		t.Errorf("APIKey(1) = %q, %v, want %q", key, err, "company-key-1")
+	}
+}
+
+func TestSettingsRepoPreferences(t *testing.T) {
+	ctx, mem := memoryDB(t)
+	r, err := NewSettingsRepo(ctx, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tests := []struct {
+		key       string
+		value     string
+		encrypted bool
+	}{
+		{PrefWorkspaceDir, "/tmp/project", false},
+		{PrefMatrixToken, "matrix-token", true},
+		{PrefCalDAVPassword, "caldav-password", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got, err := r.Preference(ctx, tt.key); err != nil || got != "" {
+				t.Fatalf("Preference() before setting = %q, %v, want empty", got, err)
+			}
+			if err := r.SetPreference(ctx, tt.key, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := r.Preference(ctx, tt.key); err != nil || got != tt.value {
+				t.Errorf("Preference() = %q, %v, want %q", got, err, tt.value)
+			}
+			var stored string
+			if err := mem.QueryRowContext(ctx, "SELECT value FROM preferences WHERE key = ?", tt.key).Scan(&stored); err != nil {
+				t.Fatal(err)
+			}
+			if encrypted := strings.HasPrefix(stored, encryptedPrefix); encrypted != tt.encrypted {
+				t.Errorf("stored %q, encrypted = %v, want %v", stored, encrypted, tt.encrypted)
+			}
+		})
+	}
+}
+
+func TestModelRepo(t *testing.T) {
+	ctx, mem := memoryDB(t)
+	r, err := NewModelRepo(ctx, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := mem.ExecContext(ctx, `
+		INSERT INTO companies (id, name, base_url) VALUES (1, 'Ollama', 'http://localhost:11434'), (2, 'Anthropic', '');
+		INSERT INTO models (name, company_id) VALUES ('llama3', 1), ('mistral', 1), ('claude', 2);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	companies, err := r.Companies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(companies) != 2 || companies[0].Name != "Anthropic" || companies[1].BaseURL != "http://localhost:11434" {
+		t.Errorf("Companies() = %+v, want both sorted by name", companies)
+	}
+	if _, err := r.Company(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Company(99) error = %v, want ErrNotFound", err)
+	}
+
+	tests := []struct {
+		companyID int
+		want      []string
+	}{
+		{1, []string{"llama3", "mistral"}},
+		{2, []string{"claude"}},
+		{3, nil},
+	}
+	for _, tt := range tests {
+		models, err := r.ByCompany(ctx, tt.companyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, m := range models {
+			if m.CompanyID != tt.companyID {
+				t.Errorf("model %q has company %d, want %d", m.Name, m.CompanyID, tt.companyID)
+			}
+			names = append(names, m.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ByCompany(%d) = %v, want %v", tt.companyID, names, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// searchIndexed reports whether the messages_fts full-text index is available.
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag; without
+// it SearchMessages falls back to a substring scan.
+var searchIndexed bool
+
+// dropSearchTriggers removes the triggers keeping the full-text index up to date
+const dropSearchTriggers = `
+	DROP TRIGGER IF EXISTS messages_fts_insert;
+	DROP TRIGGER IF EXISTS messages_fts_delete;
+	DROP TRIGGER IF EXISTS messages_fts_update;
+`
+
+// ensureSearchIndex creates the full-text index over message text and its
+// triggers, filling it from the existing messages when they are missing.
+// Without FTS5 the triggers of a database opened by a build with it are
+// dropped, since they would fail every write to messages; the index is then
+// rebuilt the next time FTS5 is available.
+func ensureSearchIndex(ctx context.Context) error {
+	var available bool
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&available); err != nil {
+		return err
+	}
+	if !available {
+		log.Printf("SQLite was built without FTS5 (build with -tags sqlite_fts5), message search scans all messages")
+		_, err := db.ExecContext(ctx, dropSearchTriggers)
+		return err
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE name IN ('messages_fts', 'messages_fts_insert', 'messages_fts_delete', 'messages_fts_update')
+	`).Scan(&count); err != nil {
+		return err
+	}
+	if count < 4 {
+		log.Printf("Building message search index...")
+		_, err := db.ExecContext(ctx, dropSearchTriggers+`
+			DROP TABLE IF EXISTS messages_fts;
+			CREATE VIRTUAL TABLE messages_fts USING fts5 (
+				text, content = 'messages', content_rowid = 'id'
+			);
+			CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+				INSERT INTO messages_fts (rowid, text) VALUES (new.id, new.text);
+			END;
+			CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+				INSERT INTO messages_fts (messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			END;
+			CREATE TRIGGER messages_fts_update AFTER UPDATE OF text ON messages BEGIN
+				INSERT INTO messages_fts (messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+				INSERT INTO messages_fts (rowid, text) VALUES (new.id, new.text);
+			END;
+			INSERT INTO messages_fts (messages_fts) VALUES ('rebuild');
+		`)
+		if err != nil {
+			return err
+		}
+	}
+	searchIndexed = true
+	return nil
+}
+
+// SearchMessages returns the messages matching every word of query outside the
+// trash, best matches first; the last word also matches as a prefix
+func SearchMessages(ctx context.Context, query string) ([]MessageRecord, error) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var messages []MessageRecord
+	var err error
+	if searchIndexed {
+		messages, err = queryMessages(ctx, `
+			SELECT m.id, m.chat_id, m.sender, m.text, m.is_ai, m.created_at
+			FROM messages_fts
+			JOIN messages m ON m.id = messages_fts.rowid
+			JOIN chats c ON c.id = m.chat_id AND c.deleted_at IS NULL
+			WHERE messages_fts MATCH ? ORDER BY rank
+		`, matchExpression(words))
+	} else {
+		where, args := likeConditions("m.text", words)
+		messages, err = queryMessages(ctx, `
+			SELECT m.id, m.chat_id, m.sender, m.text, m.is_ai, m.created_at
+			FROM messages m JOIN chats c ON c.id = m.chat_id AND c.deleted_at IS NULL
+			WHERE `+where+` ORDER BY m.id DESC
+		`, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Images are found by the description generated for them
+	where, args := likeConditions("a.description", words)
+	described, err := queryMessages(ctx, `
+		SELECT m.id, m.chat_id, m.sender, m.text, m.is_ai, m.created_at
+		FROM messages m JOIN chats c ON c.id = m.chat_id AND c.deleted_at IS NULL
+		WHERE EXISTS (SELECT 1 FROM attachments a WHERE a.message_id = m.id AND `+where+`)
+		ORDER BY m.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int]bool, len(messages))
+	for _, m := range messages {
+		found[m.ID] = true
+	}
+	for _, m := range described {
+		if !found[m.ID] {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+// likeConditions returns a condition matching column against every word and
+// its arguments
+func likeConditions(column string, words []string) (string, []interface{}) {
+	conditions := make([]string, len(words))
+	args := make([]interface{}, len(words))
+	for i, word := range words {
+		conditions[i] = column + ` LIKE '%' || ? || '%' ESCAPE '\'`
+		args[i] = escapeLike(word)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// queryMessages returns the messages selected by query
+func queryMessages(ctx context.Context, query string, args ...interface{}) ([]MessageRecord, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []MessageRecord
+	for rows.Next() {
+		var m MessageRecord
+		if err := rows.Scan(&m.ID, &m.ChatID, &m.Sender, &m.Text, &m.IsAI, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// matchExpression quotes each word as an FTS5 string so user input cannot
+// form query syntax, and lets the last word match as a prefix
+func matchExpression(words []string) string {
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	terms[len(terms)-1] += "*"
+	return strings.Join(terms, " ")
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,26 @@
+package database
+
+import "testing"
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  string
+	}{
+		{"one word is a prefix", []string{"go"}, `"go"*`},
+		{"only the last word is a prefix", []string{"error", "handling"}, `"error" "handling"*`},
+		{"quotes are doubled", []string{`say"hi`}, `"say""hi"*`},
+		{"operators are quoted", []string{"a", "OR", "b"}, `"a" "OR" "b"*`},
+		{"query syntax is quoted", []string{"col:x", "-y", "(z)"}, `"col:x" "-y" "(z)"*`},
+		{"star is quoted", []string{"x*"}, `"x*"*`},
+		{"unicode", []string{"café", "日本"}, `"café" "日本"*`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchExpression(tt.words); got != tt.want {
+				t.Errorf("matchExpression(%q) = %s, want %s", tt.words, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// StarredMessage is a message starred to find it again in the Saved view
+type StarredMessage struct {
+	MessageRecord
+	ChatTitle string
+	StarredAt time.Time
+}
+
+// SetStarred stars or unstars a message
+func SetStarred(ctx context.Context, messageID int, starred bool) error {
+	if !starred {
+		_, err := db.ExecContext(ctx, "DELETE FROM starred_messages WHERE message_id = ?", messageID)
+		return err
+	}
+	_, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO starred_messages (message_id) VALUES (?)", messageID)
+	return err
+}
+
+// IsStarred reports whether a message is starred
+func IsStarred(ctx context.Context, messageID int) (bool, error) {
+	var starred bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM starred_messages WHERE message_id = ?)", messageID).Scan(&starred)
+	return starred, err
+}
+
+// GetStarredMessages returns the starred messages of the chats not in the
+// trash, most recently starred first
+func GetStarredMessages(ctx context.Context) ([]StarredMessage, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.id, m.chat_id, m.sender, m.text, m.is_ai, m.created_at, c.title, s.starred_at
+		FROM starred_messages s
+		JOIN messages m ON m.id = s.message_id
+		JOIN chats c ON c.id = m.chat_id
+		WHERE c.deleted_at IS NULL
+		ORDER BY s.starred_at DESC, s.message_id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []StarredMessage
+	for rows.Next() {
+		var m StarredMessage
+		if err := rows.Scan(&m.ID, &m.ChatID, &m.Sender, &m.Text, &m.IsAI, &m.CreatedAt, &m.ChatTitle, &m.StarredAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// GetChatTags returns the tags of every chat, sorted by name and keyed by chat ID
+func GetChatTags(ctx context.Context) (map[int][]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT ct.chat_id, t.name FROM chat_tags ct JOIN tags t ON t.id = ct.tag_id
+		ORDER BY t.name COLLATE NOCASE
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]string)
+	for rows.Next() {
+		var chatID int
+		var name string
+		if err := rows.Scan(&chatID, &name); err != nil {
+			return nil, err
+		}
+		tags[chatID] = append(tags[chatID], name)
+	}
+	return tags, rows.Err()
+}
+
+// SetChatTags replaces the tags of a chat, creating the tags that do not exist
+// yet and deleting the ones no chat uses anymore
+func SetChatTags(ctx context.Context, chatID int, tags []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM chat_tags WHERE chat_id = ?", chatID); err != nil {
+		return err
+	}
+	for _, name := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO tags (name) VALUES (?)", name); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO chat_tags (chat_id, tag_id)
+			SELECT ?, id FROM tags WHERE name = ?
+		`, chatID, name)
+		if err != nil {
+			return err
+		}
+	}
+	if err := deleteUnusedTags(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteChatTags removes the tags of a chat being purged
+func deleteChatTags(ctx context.Context, tx *sql.Tx, chatID int) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM chat_tags WHERE chat_id = ?", chatID); err != nil {
+		return err
+	}
+	return deleteUnusedTags(ctx, tx)
+}
+
+// deleteUnusedTags removes the tags no chat uses
+func deleteUnusedTags(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM chat_tags)")
+	return err
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// Thread is a side discussion on a message. It is stored with the chat but
+// left out of the chat's context unless InContext is set.
+type Thread struct {
+	MessageID int
+	Parent    string // Text of the message the thread is on
+	InContext bool
+	Messages  []ThreadMessage
+}
+
+// ThreadMessage is a question or an answer of a thread
+type ThreadMessage struct {
+	ID        int
+	Sender    string
+	Text      string
+	CreatedAt time.Time
+}
+
+// AddThreadMessage appends a message to the thread on a message, starting the
+// thread if needed, and returns its ID
+func AddThreadMessage(ctx context.Context, messageID int, sender, text string) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO threads (message_id) VALUES (?)", messageID); err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "INSERT INTO thread_messages (message_id, sender, text) VALUES (?, ?, ?)", messageID, sender, text)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
+}
+
+// SetThreadInContext chooses whether the thread on a message is sent with the
+// chat's prompts
+func SetThreadInContext(ctx context.Context, messageID int, inContext bool) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO threads (message_id, in_context) VALUES (?, ?)
+		ON CONFLICT(message_id) DO UPDATE SET in_context = excluded.in_context
+	`, messageID, inContext)
+	return err
+}
+
+// GetThread returns the thread on a message; a message without one has a
+// thread with no messages
+func GetThread(ctx context.Context, messageID int) (Thread, error) {
+	thread := Thread{MessageID: messageID}
+	err := db.QueryRowContext(ctx, `
+		SELECT m.text, COALESCE(t.in_context, 0) FROM messages m
+		LEFT JOIN threads t ON t.message_id = m.id WHERE m.id = ?
+	`, messageID).Scan(&thread.Parent, &thread.InContext)
+	if err != nil {
+		return thread, err
+	}
+	thread.Messages, err = threadMessages(ctx, messageID)
+	return thread, err
+}
+
+// GetContextThreads returns the threads of a chat that are sent with its
+// prompts, in the order of their messages
+func GetContextThreads(ctx context.Context, chatID int) ([]Thread, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.id, m.text FROM threads t JOIN messages m ON m.id = t.message_id
+		WHERE m.chat_id = ? AND t.in_context ORDER BY m.id
+	`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	var threads []Thread
+	for rows.Next() {
+		thread := Thread{InContext: true}
+		if err := rows.Scan(&thread.MessageID, &thread.Parent); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range threads {
+		if threads[i].Messages, err = threadMessages(ctx, threads[i].MessageID); err != nil {
+			return nil, err
+		}
+	}
+	return threads, nil
+}
+
+// threadMessages returns the messages of the thread on a message, oldest first
+func threadMessages(ctx context.Context, messageID int) ([]ThreadMessage, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, sender, text, created_at FROM thread_messages
+		WHERE message_id = ? ORDER BY id
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []ThreadMessage
+	for rows.Next() {
+		var m ThreadMessage
+		if err := rows.Scan(&m.ID, &m.Sender, &m.Text, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
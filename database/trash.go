@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// TrashRetention is how long deleted chats stay recoverable in the trash
+const TrashRetention = 30 * 24 * time.Hour
+
+// TrashedChat is a chat in the trash
+type TrashedChat struct {
+	ChatRecord
+	DeletedAt time.Time
+}
+
+// TrashChat moves a chat to the trash
+func TrashChat(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, "UPDATE chats SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+	return err
+}
+
+// RestoreChat takes a chat out of the trash
+func RestoreChat(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, "UPDATE chats SET deleted_at = NULL WHERE id = ?", id)
+	return err
+}
+
+// GetTrashedChats returns the chats in the trash, most recently deleted first
+func GetTrashedChats(ctx context.Context) ([]TrashedChat, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+chatColumns+`, deleted_at
+		FROM chats WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []TrashedChat
+	for rows.Next() {
+		var c TrashedChat
+		record, err := scanChat(rows, &c.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		c.ChatRecord = record
+		chats = append(chats, c)
+	}
+	return chats, rows.Err()
+}
+
+// PurgeChat permanently deletes a chat in the trash with its messages,
+// attachments, tags and conversation memory
+func PurgeChat(ctx context.Context, id int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM chats WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM messages WHERE chat_id = ?", id); err != nil {
+		return err
+	}
+	if err := deleteChatTags(ctx, tx, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM chat_notifications WHERE chat_id = ?", id); err != nil {
+		return err
+	}
+	files, err := deleteChatAttachments(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	// langchaingo keeps the model's memory of the chat under the session chat-N
+	_, err = tx.ExecContext(ctx, "DELETE FROM langchaingo_messages WHERE session = ?", fmt.Sprintf("chat-%d", id))
+	if err != nil && !strings.Contains(err.Error(), "no such table") {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, file := range files {
+		os.Remove(file)
+	}
+	if _, err := CollectBlobs(ctx); err != nil {
+		log.Printf("Failed to delete unused attachments: %v", err)
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes the chats that have been in the trash longer
+// than TrashRetention and returns how many were deleted
+func PurgeTrash(ctx context.Context) (int, error) {
+	chats, err := GetTrashedChats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, chat := range chats {
+		if time.Since(chat.DeletedAt) < TrashRetention {
+			continue
+		}
+		if err := PurgeChat(ctx, chat.ID); err != nil {
+			return purged, fmt.Errorf("failed to purge chat %d: %v", chat.ID, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// countWhere counts the rows of table matching where
+func countWhere(t *testing.T, ctx context.Context, table, where string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE "+where, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPurgeChat(t *testing.T) {
+	ctx := openTestDB(t)
+	// The model's memory of the chats, created by langchaingo outside the tests
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS langchaingo_messages (id INTEGER PRIMARY KEY, session TEXT, content TEXT, type TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// newChat creates a chat with a message, a tag, an attachment and memory
+	newChat := func(t *testing.T, attachment string) int {
+		t.Helper()
+		id, err := CreateChat(ctx, "Purge")
+		if err != nil {
+			t.Fatal(err)
+		}
+		messageID, err := SaveMessage(ctx, id, "You", "hello", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := SetChatTags(ctx, id, []string{fmt.Sprintf("purge-%d", id)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := SaveAttachment(ctx, id, messageID, "notes.txt", "text/plain", []byte(attachment)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO langchaingo_messages (session, content, type) VALUES (?, 'hello', 'human')", fmt.Sprintf("chat-%d", id)); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	tests := []struct {
+		name     string
+		trashed  bool
+		shared   bool // Another chat has the same attachment
+		purged   bool
+		blobKept bool
+	}{
+		{"chat in the trash", true, false, true, false},
+		{"chat not in the trash is kept", false, false, false, true},
+		{"blob of another chat is kept", true, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "attachment of " + tt.name
+			id := newChat(t, content)
+			if tt.shared {
+				newChat(t, content)
+			}
+			if tt.trashed {
+				if err := TrashChat(ctx, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			attachments, err := GetChatAttachments(ctx, id)
+			if err != nil || len(attachments) != 1 {
+				t.Fatalf("GetChatAttachments() = %v, %v, want one attachment", attachments, err)
+			}
+			blob := blobPath(attachments[0].SHA256)
+
+			if err := PurgeChat(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+
+			want := 1
+			if tt.purged {
+				want = 0
+			}
+			for _, table := range []struct{ name, where string }{
+				{"chats", "id = ?"},
+				{"messages", "chat_id = ?"},
+				{"chat_tags", "chat_id = ?"},
+				{"attachments", "chat_id = ?"},
+			} {
+				if got := countWhere(t, ctx, table.name, table.where, id); got != want {
+					t.Errorf("%s rows of the chat = %d, want %d", table.name, got, want)
+				}
+			}
+			if got := countWhere(t, ctx, "langchaingo_messages", "session = ?", fmt.Sprintf("chat-%d", id)); got != want {
+				t.Errorf("memory rows of the chat = %d, want %d", got, want)
+			}
+			if got := countWhere(t, ctx, "tags", "name = ?", fmt.Sprintf("purge-%d", id)); got != want {
+				t.Errorf("tags of the chat = %d, want %d", got, want)
+			}
+			if _, err := os.Stat(blob); (err == nil) != tt.blobKept {
+				t.Errorf("blob exists = %v, want %v", err == nil, tt.blobKept)
+			}
+		})
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	ctx := openTestDB(t)
+	tests := []struct {
+		name      string
+		deletedAt string // Modifier of the deletion time, empty for a chat not in the trash
+		purged    bool
+	}{
+		{"past the retention", "-31 days", true},
+		{"long past the retention", "-1 years", true},
+		{"within the retention", "-29 days", false},
+		{"just deleted", "-0 seconds", false},
+		{"not in the trash", "", false},
+	}
+	ids := make([]int, len(tests))
+	for i, tt := range tests {
+		id, err := CreateChat(ctx, "Trash "+tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.deletedAt != "" {
+			if _, err := db.ExecContext(ctx, "UPDATE chats SET deleted_at = datetime('now', ?) WHERE id = ?", tt.deletedAt, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids[i] = id
+	}
+
+	purged, err := PurgeTrash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 {
+		t.Errorf("PurgeTrash() = %d, want 2", purged)
+	}
+	for i, tt := range tests {
+		if exists := countWhere(t, ctx, "chats", "id = ?", ids[i]) == 1; exists == tt.purged {
+			t.Errorf("%s: chat exists = %v, want %v", tt.name, exists, !tt.purged)
+		}
+	}
+
+	// A restored chat leaves the trash, the others stay in it
+	if err := RestoreChat(ctx, ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	trashed, err := GetTrashedChats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inTrash := make(map[int]bool)
+	for _, c := range trashed {
+		inTrash[c.ID] = true
+	}
+	if inTrash[ids[0]] || inTrash[ids[2]] || !inTrash[ids[3]] {
+		t.Errorf("trash = %v, want chat %d in it and not %d or %d", trashed, ids[3], ids[0], ids[2])
+	}
+}
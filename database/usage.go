@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Usage is one request sent to a model
+type Usage struct {
+	ID               int
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // USD, from the prices at the time of the request
+	Latency          time.Duration
+	CreatedAt        time.Time
+}
+
+// UsageTotal aggregates the requests of a day or a model
+type UsageTotal struct {
+	Key              string // The day as 2006-01-02 in local time, or the model
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	AvgLatency       time.Duration
+}
+
+// SaveUsage records a request
+func SaveUsage(ctx context.Context, u Usage) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO usage (model, prompt_tokens, completion_tokens, cost, latency_ms)
+		VALUES (?, ?, ?, ?, ?)
+	`, u.Model, u.PromptTokens, u.CompletionTokens, u.Cost, u.Latency.Milliseconds())
+	return err
+}
+
+// GetUsageByDay returns the usage per local day since the given time, oldest first
+func GetUsageByDay(ctx context.Context, since time.Time) ([]UsageTotal, error) {
+	return usageTotals(ctx, "date(created_at, 'localtime')", since)
+}
+
+// GetUsageByModel returns the usage per model since the given time, most
+// expensive first
+func GetUsageByModel(ctx context.Context, since time.Time) ([]UsageTotal, error) {
+	return usageTotals(ctx, "model", since)
+}
+
+// usageTotals aggregates the usage since the given time by a column expression
+func usageTotals(ctx context.Context, key string, since time.Time) ([]UsageTotal, error) {
+	order := key
+	if key == "model" {
+		order = "SUM(cost) DESC, model"
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens), SUM(cost), AVG(latency_ms)
+		FROM usage WHERE created_at >= ? GROUP BY 1 ORDER BY %s
+	`, key, order), since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var t UsageTotal
+		var latency float64
+		if err := rows.Scan(&t.Key, &t.Requests, &t.PromptTokens, &t.CompletionTokens, &t.Cost, &latency); err != nil {
+			return nil, err
+		}
+		t.AvgLatency = time.Duration(latency) * time.Millisecond
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// newDeleteButton returns a button deleting a message after confirmation
+func newDeleteButton(chatID int, item *messageItem) *widget.Button {
+	btn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		confirmDeleteMessage(chatID, item)
+	})
+	btn.Importance = widget.LowImportance
+	return btn
+}
+
+// confirmDeleteMessage asks before deleting a message
+func confirmDeleteMessage(chatID int, item *messageItem) {
+	if item.messageID == 0 {
+		dialog.ShowInformation("Delete message", "Wait until the answer is complete.", mainWindow)
+		return
+	}
+	dialog.ShowConfirm("Delete message", "Delete this message? The model will no longer see it in this chat.", func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := deleteMessage(chatID, item); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to delete message: %v", err), mainWindow)
+		}
+	}, mainWindow)
+}
+
+// deleteMessage removes a message from the chat, the database and the model's
+// memory of the chat
+func deleteMessage(chatID int, item *messageItem) error {
+	chat := findChat(chatID)
+	if chat == nil {
+		return nil
+	}
+	for i, msg := range chat.Messages {
+		if msg.ID != item.messageID {
+			continue
+		}
+		if err := database.DeleteMessage(appCtx, msg.ID); err != nil {
+			return err
+		}
+		forgetMessage(chatID, msg)
+		chat.Messages = append(chat.Messages[:i], chat.Messages[i+1:]...)
+		// The interview keeps starting at the same message
+		if i < chat.InterviewStart {
+			chat.InterviewStart--
+		}
+		publishLiveView(chatID, "")
+		break
+	}
+	if msgContainer := chatContainers[chatID]; msgContainer != nil {
+		removeMessageItem(msgContainer, item)
+	}
+	clearMessageCursor()
+	return nil
+}
+
+// forgetMessage removes a prompt or answer from the model's memory of a chat;
+// other messages are never sent to the model
+func forgetMessage(chatID int, msg ChatMessage) {
+	if msg.Sender != "You" && msg.Sender != "AI" {
+		return
+	}
+	if err := database.ForgetMessage(appCtx, chatID, msg.MemoryID, msg.Text, msg.IsAI); err != nil {
+		log.Printf("Failed to update the chat memory: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// auditLogLimit is how many recent requests the diagnostics screen lists
+const auditLogLimit = 500
+
+// showDiagnostics lists the audited requests to model providers; selecting one
+// shows its bodies when they were recorded
+func showDiagnostics(w fyne.Window) {
+	entries, err := database.GetAuditLog(appCtx, auditLogLimit)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load audit log: %v", err), w)
+		return
+	}
+
+	details := widget.NewLabel("Select a request to see its details")
+	details.Wrapping = fyne.TextWrapWord
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			e := entries[id]
+			status := fmt.Sprintf("%d", e.Status)
+			if e.Error != "" {
+				status = "failed"
+			}
+			item.(*widget.Label).SetText(fmt.Sprintf("%s  %s  %s  %s  %s · %d B sent · %d B received",
+				e.CreatedAt.Local().Format("2006-01-02 15:04:05"), status, e.Model, e.URL,
+				e.Latency.Round(10*time.Millisecond), e.RequestBytes, e.ResponseBytes))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		e := entries[id]
+		text := fmt.Sprintf("%s %s\nModel: %s\nStatus: %d\nLatency: %s", e.Method, e.URL, e.Model, e.Status, e.Latency)
+		if e.Error != "" {
+			text += "\nError: " + e.Error
+		}
+		if e.RequestBody == "" && e.ResponseBody == "" {
+			text += "\n\nContent was not recorded."
+		} else {
+			text += "\n\nRequest:\n" + e.RequestBody + "\n\nResponse:\n" + e.ResponseBody
+		}
+		details.SetText(text)
+	}
+
+	clearBtn := widget.NewButtonWithIcon("Clear", theme.DeleteIcon(), func() {
+		dialog.ShowConfirm("Clear audit log", "Delete every recorded request?", func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := database.ClearAuditLog(appCtx); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to clear audit log: %v", err), w)
+				return
+			}
+			entries = nil
+			list.UnselectAll()
+			list.Refresh()
+			details.SetText("")
+		}, w)
+	})
+
+	enabled, _ := database.GetPreference(appCtx, database.PrefAuditLog)
+	state := widget.NewLabel("Recording is off, turn it on in Settings > Features")
+	if enabled == "true" {
+		state.SetText(fmt.Sprintf("%d most recent requests to model providers", len(entries)))
+	}
+	content := container.NewVSplit(list, container.NewVScroll(details))
+	content.SetOffset(0.6)
+
+	d := dialog.NewCustom("Diagnostics", "Close", container.NewBorder(container.NewBorder(nil, nil, nil, clearBtn, state), nil, nil, nil, content), w)
+	d.Resize(fyne.NewSize(900, 600))
+	d.Show()
+}
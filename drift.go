@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// driftDetectionEnabled reports whether topic drift suggestions are turned on
+func driftDetectionEnabled() bool {
+	value, err := database.GetPreference(appCtx, database.PrefDriftDetection)
+	return err == nil && value == "true"
+}
+
+// checkTopicDrift asks the model whether the user message at index started a new
+// topic and, if so, offers to continue it in a new chat
+func checkTopicDrift(chatID, index int) {
+	chat := findChat(chatID)
+	if chat == nil || index <= 0 || index >= len(chat.Messages) {
+		return
+	}
+
+	previous := make([]string, 0, index)
+	for _, msg := range chat.Messages[:index] {
+		previous = append(previous, fmt.Sprintf("%s: %s", msg.Sender, msg.Text))
+	}
+	latest := chat.Messages[index].Text
+
+	topic, drifted, err := llm.DetectTopicDrift(appCtx, currentModel, previous, latest)
+	if err != nil {
+		log.Printf("Failed to detect topic drift: %v", err)
+		return
+	}
+	if !drifted {
+		return
+	}
+
+	msgContainer := chatContainers[chatID]
+	if msgContainer == nil {
+		return
+	}
+
+	var suggestion *fyne.Container
+	label := widget.NewLabel(fmt.Sprintf("Start a new chat about %q?", topic))
+	startBtn := widget.NewButtonWithIcon("New chat", theme.ContentAddIcon(), func() {
+		msgContainer.Remove(suggestion)
+		forkChat(chatID, index, topic)
+	})
+	dismissBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		msgContainer.Remove(suggestion)
+	})
+	dismissBtn.Importance = widget.LowImportance
+	suggestion = container.NewHBox(label, layout.NewSpacer(), startBtn, dismissBtn)
+	msgContainer.Add(suggestion)
+
+	if currentChat != nil && currentChat.ID == chatID {
+		mainScroll.ScrollToBottom()
+	}
+}
+
+// forkChat creates a new chat titled title holding a copy of the messages of
+// chat sourceID starting at index from, and switches to it
+func forkChat(sourceID, from int, title string) {
+	source := findChat(sourceID)
+	if source == nil || from >= len(source.Messages) {
+		return
+	}
+	messages := append([]ChatMessage(nil), source.Messages[from:]...)
+
+	newID := newChat(title).ID
+	chatContainers[newID] = container.NewVBox()
+	for _, msg := range messages {
+		AddMessage(newID, msg.Text, msg.Sender, msg.IsAI)
+	}
+
+	chatList.Refresh()
+	switchToChat(findChat(newID))
+}
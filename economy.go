@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/power"
+)
+
+// powerCheckInterval is how often the power and network state is read
+const powerCheckInterval = time.Minute
+
+// Economy overrides of the status bar
+const (
+	economyAuto = "Automatic"
+	economyOn   = "Save power"
+	economyOff  = "Full power"
+)
+
+// economy is the last detected power state and the override of the status
+// bar, which lasts until the app quits
+var economy struct {
+	sync.Mutex
+	state    power.State
+	override string
+}
+
+// powerBar shows the economy mode and its override above the input
+var powerBar *fyne.Container
+
+// powerAwareEnabled reports whether battery saver and metered connections
+// switch to economy mode, which is on unless turned off in the settings
+func powerAwareEnabled() bool {
+	value, _ := database.GetPreference(appCtx, database.PrefPowerAware)
+	return value != "false"
+}
+
+// economyActive reports whether answers use the economy model and background
+// jobs wait: when forced in the status bar, or when the machine is on battery,
+// saving power or on a metered connection
+func economyActive() bool {
+	economy.Lock()
+	defer economy.Unlock()
+	switch economy.override {
+	case economyOn:
+		return true
+	case economyOff:
+		return false
+	}
+	return powerAwareEnabled() && economy.state.Constrained()
+}
+
+// answerModel returns the model that answers instead of model: the economy
+// model of the settings while economy mode is active
+func answerModel(model string) string {
+	if !economyActive() {
+		return model
+	}
+	if name, err := database.GetPreference(appCtx, database.PrefEconomyModel); err == nil && name != "" {
+		return name
+	}
+	return model
+}
+
+// runPowerMonitor follows the power and network state while the app is running
+func runPowerMonitor() {
+	for {
+		state := power.Detect(appCtx)
+		economy.Lock()
+		changed := state != economy.state
+		economy.state = state
+		economy.Unlock()
+		if changed {
+			refreshPowerBar()
+		}
+		time.Sleep(powerCheckInterval)
+	}
+}
+
+// refreshPowerBar shows the economy mode in the status bar while power is
+// constrained or overridden, and hides it otherwise
+func refreshPowerBar() {
+	if powerBar == nil {
+		return
+	}
+	economy.Lock()
+	state, override := economy.state, economy.override
+	economy.Unlock()
+	if override == "" && (!state.Constrained() || !powerAwareEnabled()) {
+		powerBar.Hide()
+		return
+	}
+
+	var status string
+	if economyActive() {
+		status = "Saving power: background jobs paused"
+		if name, err := database.GetPreference(appCtx, database.PrefEconomyModel); err == nil && name != "" {
+			status += ", answering with " + name
+		}
+	} else {
+		status = "Full power"
+	}
+	if state.Constrained() {
+		status = fmt.Sprintf("%s (%s)", status, state)
+	}
+	label := widget.NewLabel(status)
+	label.Importance = widget.LowImportance
+	label.Truncation = fyne.TextTruncateEllipsis
+
+	overrideSelect := widget.NewSelect([]string{economyAuto, economyOn, economyOff}, nil)
+	if override == "" {
+		overrideSelect.SetSelected(economyAuto)
+	} else {
+		overrideSelect.SetSelected(override)
+	}
+	overrideSelect.OnChanged = func(value string) {
+		economy.Lock()
+		if value == economyAuto {
+			value = ""
+		}
+		economy.override = value
+		economy.Unlock()
+		refreshPowerBar()
+	}
+
+	powerBar.Objects = []fyne.CanvasObject{
+		container.NewBorder(nil, nil, widget.NewIcon(theme.InfoIcon()), container.NewHBox(layout.NewSpacer(), overrideSelect), label),
+	}
+	powerBar.Refresh()
+	powerBar.Show()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// What happens to the messages after an edited message
+const (
+	editDiscard = "Discard the later messages"
+	editFork    = "Keep them and fork into a new chat"
+)
+
+// newEditButton returns a button to edit a user message and send it again
+func newEditButton(chatID int, item *messageItem) *widget.Button {
+	return widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+		showEditMessage(chatID, item)
+	})
+}
+
+// showEditMessage lets the user change a message and send it again. When the
+// chat goes on after it, the user picks whether the later messages are
+// discarded or kept in this chat while the edit continues in a fork.
+func showEditMessage(chatID int, item *messageItem) {
+	chat := findChat(chatID)
+	if chat == nil || item.messageID == 0 {
+		return
+	}
+	index := -1
+	for i, msg := range chat.Messages {
+		if msg.ID == item.messageID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return
+	}
+
+	entry := widget.NewMultiLineEntry()
+	entry.Wrapping = fyne.TextWrapWord
+	entry.SetText(chat.Messages[index].Text)
+	entry.SetMinRowsVisible(6)
+	content := container.NewVBox(entry)
+
+	later := len(chat.Messages) - index - 1
+	mode := widget.NewRadioGroup([]string{editDiscard, editFork}, nil)
+	mode.SetSelected(editDiscard)
+	mode.Required = true
+	if later > 0 {
+		content.Add(widget.NewLabel(fmt.Sprintf("%d later messages:", later)))
+		content.Add(mode)
+	}
+
+	d := dialog.NewCustomConfirm("Edit message", "Resend", "Cancel", content, func(ok bool) {
+		if !ok || entry.Text == "" {
+			return
+		}
+		if later > 0 && mode.Selected == editFork {
+			forkEditedMessage(chat, index, entry.Text)
+		} else {
+			resendEditedMessage(chat, index, entry.Text)
+		}
+	}, mainWindow)
+	d.Resize(fyne.NewSize(550, 0))
+	d.Show()
+}
+
+// resendEditedMessage replaces the text of a message, discards the messages
+// after it and streams a new answer
+func resendEditedMessage(chat *Chat, index int, text string) {
+	if waitForStream(chat.ID, "Edit message") {
+		return
+	}
+	msg := chat.Messages[index]
+	files, images, err := messageAttachments(msg.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load attachments: %v", err), mainWindow)
+		return
+	}
+	if _, err := database.DeleteMessagesAfter(appCtx, chat.ID, msg.ID); err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to delete messages: %v", err), mainWindow)
+		return
+	}
+	if err := database.EditMessage(appCtx, msg.ID, text); err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to save message: %v", err), mainWindow)
+		return
+	}
+
+	// The old prompt and what followed leave the model's memory; the edit is
+	// added when it is sent
+	for _, later := range chat.Messages[index:] {
+		forgetMessage(chat.ID, later)
+	}
+	chat.Messages = chat.Messages[:index+1]
+	if chat.InterviewStart > len(chat.Messages) {
+		chat.InterviewStart = len(chat.Messages)
+	}
+	chat.Messages[index].Text = text
+	chat.Messages[index].Edited = true
+	rerenderChat(chat)
+	go streamAnswer(chat.ID, msg.ID, text, files, images, currentModel)
+}
+
+// forkEditedMessage copies the messages before an edited message into a new
+// chat with the same settings and folder, opens it and sends the edit there.
+// Attachments of the copied messages stay with the original chat.
+func forkEditedMessage(chat *Chat, index int, text string) {
+	files, images, err := messageAttachments(chat.Messages[index].ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load attachments: %v", err), mainWindow)
+		return
+	}
+
+	records := make([]database.MessageRecord, index)
+	for i, msg := range chat.Messages[:index] {
+		records[i] = database.MessageRecord{Sender: msg.Sender, Text: msg.Text, IsAI: msg.IsAI, CreatedAt: msg.CreatedAt}
+	}
+	title := chat.Title + " (fork)"
+	id, err := database.ImportChat(appCtx, title, time.Now(), records)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to fork chat: %v", err), mainWindow)
+		return
+	}
+	// The copied turns are what the model remembers of the fork
+	if copied, err := database.GetMessages(appCtx, id); err != nil {
+		log.Printf("Failed to load messages: %v", err)
+	} else if err := llm.SeedMemory(appCtx, id, copied); err != nil {
+		log.Printf("Failed to copy the chat history: %v", err)
+	}
+	if err := database.SetChatConfig(appCtx, id, chat.ChatConfig); err != nil {
+		log.Printf("Failed to save chat settings: %v", err)
+	}
+	if chat.FolderID != 0 {
+		if err := database.SetChatFolder(appCtx, id, chat.FolderID); err != nil {
+			log.Printf("Failed to move chat: %v", err)
+		}
+	}
+	addChat(&Chat{ID: id, Title: title, FolderID: chat.FolderID, ChatConfig: chat.ChatConfig})
+	chatList.Refresh()
+	selectChat(id)
+
+	messageID := AddMessage(id, text, "You", false)
+	saveFiles(id, messageID, files)
+	for _, image := range images {
+		if _, err := database.SaveAttachment(appCtx, id, messageID, image.Name, image.MimeType, image.Data); err != nil {
+			log.Printf("Failed to save image: %v", err)
+		}
+	}
+	go streamAnswer(id, messageID, text, files, images, currentModel)
+}
@@ -0,0 +1,154 @@
+package feeds
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Item is one entry of a feed
+type Item struct {
+	GUID      string
+	Title     string
+	Link      string
+	Summary   string
+	Published time.Time
+}
+
+// Feed is a fetched RSS or Atom feed
+type Feed struct {
+	Title string
+	Items []Item
+}
+
+// document covers both RSS 2.0 (<rss><channel><item>) and Atom (<feed><entry>)
+type document struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Updated   string `xml:"updated"`
+	Published string `xml:"published"`
+}
+
+// tagPattern matches HTML tags in item summaries
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// maxSummary bounds the length of an item summary
+const maxSummary = 500
+
+// Fetch downloads and parses the feed at url
+func Fetch(ctx context.Context, client *http.Client, url string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feed request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feed: status %d", resp.StatusCode)
+	}
+	return Parse(resp.Body)
+}
+
+// Parse reads an RSS 2.0 or Atom document
+func Parse(r io.Reader) (*Feed, error) {
+	var doc document
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid feed: %v", err)
+	}
+
+	feed := &Feed{Title: strings.TrimSpace(doc.Channel.Title)}
+	for _, item := range doc.Channel.Items {
+		feed.Items = append(feed.Items, Item{
+			GUID:      firstNonEmpty(item.GUID, item.Link, item.Title),
+			Title:     strings.TrimSpace(item.Title),
+			Link:      strings.TrimSpace(item.Link),
+			Summary:   cleanSummary(item.Description),
+			Published: parseTime(item.PubDate),
+		})
+	}
+
+	if feed.Title == "" {
+		feed.Title = strings.TrimSpace(doc.Title)
+	}
+	for _, entry := range doc.Entries {
+		var link string
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		feed.Items = append(feed.Items, Item{
+			GUID:      firstNonEmpty(entry.ID, link, entry.Title),
+			Title:     strings.TrimSpace(entry.Title),
+			Link:      link,
+			Summary:   cleanSummary(firstNonEmpty(entry.Summary, entry.Content)),
+			Published: parseTime(firstNonEmpty(entry.Published, entry.Updated)),
+		})
+	}
+	return feed, nil
+}
+
+// cleanSummary strips HTML and truncates a summary
+func cleanSummary(s string) string {
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, " "))
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxSummary {
+		s = s[:maxSummary] + "..."
+	}
+	return s
+}
+
+// parseTime parses the RFC 822 dates of RSS and the RFC 3339 dates of Atom
+func parseTime(s string) time.Time {
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// firstNonEmpty returns the first value that is not blank
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/workspace"
+)
+
+// fileRefPattern matches the @file:path references of a message, which send
+// the workspace files along with it
+var fileRefPattern = regexp.MustCompile(`@file:(\S+)`)
+
+// fileQueryPattern matches a reference being typed at the end of the input
+var fileQueryPattern = regexp.MustCompile(`@file:(\S*)$`)
+
+// fileSuggestionCount is how many paths are offered while a reference is typed
+const fileSuggestionCount = 8
+
+// fileIndexAge is how long the list of workspace files is reused before the
+// folder is walked again
+const fileIndexAge = 30 * time.Second
+
+// fileRefMaxSize is the size in bytes above which a referenced file is not sent
+const fileRefMaxSize = 1024 * 1024
+
+// fileIndex caches the files of the workspace for the completion
+var fileIndex struct {
+	sync.Mutex
+	root  string
+	files []string
+	built time.Time
+}
+
+// workspaceDir returns the configured workspace folder, "" when there is none
+func workspaceDir() string {
+	dir, err := database.GetPreference(appCtx, database.PrefWorkspaceDir)
+	if err != nil {
+		log.Printf("Failed to get workspace preference: %v", err)
+		return ""
+	}
+	return dir
+}
+
+// workspaceFiles returns the files of the workspace root not ignored by git
+func workspaceFiles(root string) []string {
+	fileIndex.Lock()
+	defer fileIndex.Unlock()
+	if fileIndex.root == root && time.Since(fileIndex.built) < fileIndexAge {
+		return fileIndex.files
+	}
+	files, err := workspace.Files(root)
+	if err != nil {
+		log.Printf("Failed to list workspace files: %v", err)
+	}
+	fileIndex.root, fileIndex.files, fileIndex.built = root, files, time.Now()
+	return files
+}
+
+// refreshFileSuggestions offers the workspace paths matching the @file:
+// reference typed at the end of the input; picking one completes it
+func refreshFileSuggestions(bar *fyne.Container, input *CustomEntry) {
+	m := fileQueryPattern.FindStringSubmatch(input.Text)
+	root := workspaceDir()
+	if m == nil || root == "" {
+		bar.Hide()
+		return
+	}
+	matches := workspace.Match(workspaceFiles(root), m[1], fileSuggestionCount)
+	if len(matches) == 0 {
+		bar.Hide()
+		return
+	}
+
+	typed := utf8.RuneCountInString(m[1])
+	buttons := container.NewHBox()
+	for _, match := range matches {
+		btn := widget.NewButton(match, func() {
+			input.replaceBeforeCursor(typed, match+" ")
+			bar.Hide()
+			mainWindow.Canvas().Focus(input)
+		})
+		btn.Importance = widget.LowImportance
+		buttons.Add(btn)
+	}
+	bar.Objects = []fyne.CanvasObject{container.NewHScroll(buttons)}
+	bar.Refresh()
+	bar.Show()
+}
+
+// replaceBeforeCursor replaces the n characters before the cursor with text,
+// as one undo step
+func (e *CustomEntry) replaceBeforeCursor(n int, text string) {
+	e.edit(false, func() {
+		for i := 0; i < n; i++ {
+			e.Entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+		}
+		e.Entry.TypedShortcut(&fyne.ShortcutPaste{Clipboard: textClipboard(text)})
+	})
+}
+
+// referencedFiles reads the workspace files a message references with
+// @file:path, to send them along like pasted files. Paths outside the
+// workspace, missing, binary and oversized files are skipped with a note.
+func referencedFiles(message string) ([]pastedFile, []string) {
+	refs := fileRefPattern.FindAllStringSubmatch(message, -1)
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	root := workspaceDir()
+	if root == "" {
+		return nil, []string{"@file: references need a workspace folder in the settings"}
+	}
+
+	var files []pastedFile
+	var problems []string
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		name := strings.TrimRight(ref[1], ".,;:!?)")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			problems = append(problems, fmt.Sprintf("%s is outside the workspace", name))
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s was not found in the workspace", name))
+			continue
+		}
+		if info.IsDir() || info.Size() > fileRefMaxSize {
+			problems = append(problems, fmt.Sprintf("%s is not a file of at most %d KB", name, fileRefMaxSize/1024))
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || !utf8.Valid(data) {
+			problems = append(problems, fmt.Sprintf("%s could not be read as text", name))
+			continue
+		}
+		lang := strings.TrimPrefix(filepath.Ext(name), ".")
+		if lang == "txt" {
+			lang = ""
+		}
+		files = append(files, pastedFile{Name: name, Lang: lang, Text: string(data)})
+	}
+	return files, problems
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// Sidebar tree node IDs are a prefix followed by the folder or chat ID; the
+// root node is ""
+const (
+	folderPrefix = "folder-"
+	chatPrefix   = "chat-"
+)
+
+func folderNode(id int) widget.TreeNodeID { return folderPrefix + strconv.Itoa(id) }
+func chatNode(id int) widget.TreeNodeID   { return chatPrefix + strconv.Itoa(id) }
+
+// nodeID returns the folder or chat ID of a sidebar tree node
+func nodeID(uid widget.TreeNodeID) int {
+	id, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(uid, folderPrefix), chatPrefix))
+	return id
+}
+
+// loadFolders reads the stored folders and opens the ones that were expanded
+func loadFolders() {
+	records, err := database.GetFolders(appCtx)
+	if err != nil {
+		log.Printf("Failed to load folders: %v", err)
+		return
+	}
+	folders = records
+	if chatList == nil {
+		return
+	}
+	for _, folder := range folders {
+		if !folder.Collapsed {
+			chatList.OpenBranch(folderNode(folder.ID))
+		}
+	}
+}
+
+// findFolder returns the folder with the given ID, or nil if there is none
+func findFolder(id int) *database.Folder {
+	for i := range folders {
+		if folders[i].ID == id {
+			return &folders[i]
+		}
+	}
+	return nil
+}
+
+// sidebarChildren returns the folders and then the chats inside a sidebar
+// node. Chats whose folder was deleted are shown at the top level.
+func sidebarChildren(uid widget.TreeNodeID) []widget.TreeNodeID {
+	parentID := nodeID(uid)
+	var nodes []widget.TreeNodeID
+	for _, folder := range folders {
+		if folder.ParentID == parentID && folderListed(folder.ID) {
+			nodes = append(nodes, folderNode(folder.ID))
+		}
+	}
+	for i := range chats {
+		folderID := chats[i].FolderID
+		if findFolder(folderID) == nil {
+			folderID = 0
+		}
+		if folderID == parentID && chatListed(chats[i]) {
+			nodes = append(nodes, chatNode(chats[i].ID))
+		}
+	}
+	return nodes
+}
+
+// folderListed reports whether a folder is shown in the sidebar: always
+// without a tag filter, otherwise only when it holds a chat with the tag
+func folderListed(id int) bool {
+	if tagFilter == nil || tagFilter.Selected == allTags {
+		return true
+	}
+	for i := range chats {
+		if chats[i].FolderID == id && chatListed(chats[i]) {
+			return true
+		}
+	}
+	for _, folder := range folders {
+		if folder.ParentID == id && folderListed(folder.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFolderCollapsed stores whether a folder is collapsed when it changes
+func setFolderCollapsed(id int, collapsed bool) {
+	folder := findFolder(id)
+	if folder == nil || folder.Collapsed == collapsed {
+		return
+	}
+	folder.Collapsed = collapsed
+	if err := database.SetFolderCollapsed(appCtx, id, collapsed); err != nil {
+		log.Printf("Failed to save folder state: %v", err)
+	}
+}
+
+// showNewFolder asks for the name of a folder to create inside parentID, or at
+// the top level when parentID is 0
+func showNewFolder(w fyne.Window, parentID int) {
+	entry := widget.NewEntry()
+	dialog.ShowForm("New folder", "Create", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Name", entry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		if _, err := database.CreateFolder(appCtx, parentID, entry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to create folder: %v", err), w)
+			return
+		}
+		if parentID != 0 {
+			chatList.OpenBranch(folderNode(parentID))
+		}
+		loadFolders()
+		chatList.Refresh()
+	}, w)
+}
+
+// showRenameFolder asks for a new name for a folder
+func showRenameFolder(w fyne.Window, id int) {
+	folder := findFolder(id)
+	if folder == nil {
+		return
+	}
+	entry := widget.NewEntry()
+	entry.SetText(folder.Name)
+	dialog.ShowForm("Rename folder", "Save", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Name", entry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := database.RenameFolder(appCtx, id, entry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to rename folder: %v", err), w)
+			return
+		}
+		loadFolders()
+		chatList.Refresh()
+	}, w)
+}
+
+// deleteFolder removes a folder after confirmation; its chats and folders move
+// up to its parent
+func deleteFolder(w fyne.Window, id int) {
+	folder := findFolder(id)
+	if folder == nil {
+		return
+	}
+	message := fmt.Sprintf("Delete the folder %q? Its chats and folders are kept.", folder.Name)
+	dialog.ShowConfirm("Delete folder", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		parentID := folder.ParentID
+		if err := database.DeleteFolder(appCtx, id); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to delete folder: %v", err), w)
+			return
+		}
+		for i := range chats {
+			if chats[i].FolderID == id {
+				chats[i].FolderID = parentID
+			}
+		}
+		loadFolders()
+		chatList.Refresh()
+	}, w)
+}
+
+// moveChatToFolder moves a chat into a folder, or to the top level when
+// folderID is 0
+func moveChatToFolder(chatID, folderID int) {
+	chat := findChat(chatID)
+	if chat == nil || chat.FolderID == folderID {
+		return
+	}
+	if err := database.SetChatFolder(appCtx, chatID, folderID); err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to move chat: %v", err), mainWindow)
+		return
+	}
+	chat.FolderID = folderID
+	if folderID != 0 {
+		chatList.OpenBranch(folderNode(folderID))
+	}
+	chatList.Refresh()
+	if currentChat != nil && currentChat.ID == chatID {
+		selectChat(chatID)
+	}
+}
+
+// sidebarRows are the rows created by the sidebar tree, searched for the row a
+// chat is dropped on
+var sidebarRows []*sidebarRow
+
+// sidebarRow is a row of the sidebar tree; chat rows can be dragged onto a
+// folder, or onto a chat in another folder, to move the chat there
+type sidebarRow struct {
+	widget.BaseWidget
+	content fyne.CanvasObject
+	node    widget.TreeNodeID
+	dropAt  fyne.Position // Where the dragged row is, in window coordinates
+}
+
+func newSidebarRow(content fyne.CanvasObject) *sidebarRow {
+	row := &sidebarRow{content: content}
+	row.ExtendBaseWidget(row)
+	sidebarRows = append(sidebarRows, row)
+	return row
+}
+
+func (r *sidebarRow) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(r.content)
+}
+
+// Dragged follows a chat being dragged
+func (r *sidebarRow) Dragged(e *fyne.DragEvent) {
+	r.dropAt = e.AbsolutePosition
+}
+
+// DragEnd moves the dragged chat into the folder of the row it was dropped on
+func (r *sidebarRow) DragEnd() {
+	if !strings.HasPrefix(r.node, chatPrefix) {
+		return
+	}
+	target := rowAt(r.dropAt)
+	switch {
+	case target == nil || target == r:
+	case strings.HasPrefix(target.node, folderPrefix):
+		moveChatToFolder(nodeID(r.node), nodeID(target.node))
+	case strings.HasPrefix(target.node, chatPrefix):
+		if chat := findChat(nodeID(target.node)); chat != nil {
+			moveChatToFolder(nodeID(r.node), chat.FolderID)
+		}
+	}
+}
+
+// rowAt returns the sidebar row shown at a position in window coordinates
+func rowAt(pos fyne.Position) *sidebarRow {
+	driver := fyne.CurrentApp().Driver()
+	if !inside(pos, driver.AbsolutePositionForObject(chatList), chatList.Size()) {
+		return nil
+	}
+	for _, row := range sidebarRows {
+		// Rows the tree is not showing have no position
+		origin := driver.AbsolutePositionForObject(row)
+		if !origin.IsZero() && row.Visible() && inside(pos, origin, row.Size()) {
+			return row
+		}
+	}
+	return nil
+}
+
+// inside reports whether pos is in the rectangle at origin of the given size
+func inside(pos, origin fyne.Position, size fyne.Size) bool {
+	return pos.X >= origin.X && pos.Y >= origin.Y && pos.X < origin.X+size.Width && pos.Y < origin.Y+size.Height
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+	"github.com/devalexandre/llmschat/templates"
+)
+
+// followUpAction re-prompts the model with an AI message; {{variables}} in
+// the instruction are asked for first
+type followUpAction struct {
+	name        string
+	instruction string
+}
+
+// followUpActions are the actions of the "…" menu of AI messages
+var followUpActions = []followUpAction{
+	{"Translate", "Translate the text into {{language}}, keeping its formatting."},
+	{"Simplify", "Rewrite the text in plain words for someone new to the subject. Keep it short."},
+	{"Expand", "Expand the text with more detail, examples and the reasoning behind it."},
+	{"Convert to email", "Turn the text into a clear, polite email with a subject line."},
+	{"Extract action items", "List the action items in the text as a Markdown checklist, with owners and dates when they are mentioned."},
+}
+
+// newFollowUpButton returns the "…" button opening the follow-up actions of
+// an AI message
+func newFollowUpButton(item *messageItem) *widget.Button {
+	var btn *widget.Button
+	btn = widget.NewButtonWithIcon("", theme.MoreHorizontalIcon(), func() {
+		menu := fyne.NewMenu("")
+		for _, action := range followUpActions {
+			menu.Items = append(menu.Items, fyne.NewMenuItem(action.name, func() {
+				runFollowUp(item, action)
+			}))
+		}
+		widget.ShowPopUpMenuAtRelativePosition(menu, mainWindow.Canvas(), fyne.NewPos(0, btn.Size().Height), btn)
+	})
+	btn.Importance = widget.LowImportance
+	return btn
+}
+
+// runFollowUp asks for the variables of action, if any, and runs it on a message
+func runFollowUp(item *messageItem, action followUpAction) {
+	if item.messageID == 0 {
+		dialog.ShowInformation(action.name, "Wait until the answer is complete.", mainWindow)
+		return
+	}
+	names := templates.Variables(action.instruction)
+	if len(names) == 0 {
+		startFollowUp(item, action.name, action.instruction)
+		return
+	}
+
+	entries := make(map[string]*widget.Entry, len(names))
+	form := widget.NewForm()
+	for _, name := range names {
+		entry := widget.NewEntry()
+		entries[name] = entry
+		form.Append(name, entry)
+	}
+	d := dialog.NewCustomConfirm(action.name, "Run", "Cancel", form, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		values := make(map[string]string, len(entries))
+		for name, entry := range entries {
+			values[name] = strings.TrimSpace(entry.Text)
+		}
+		startFollowUp(item, action.name, templates.Fill(action.instruction, values))
+	}, mainWindow)
+	d.Resize(fyne.NewSize(400, 0))
+	d.Show()
+}
+
+// startFollowUp shows a follow-up under the message while the model works on
+// it, then stores and shows the result
+func startFollowUp(item *messageItem, name, instruction string) {
+	pending := newFollowupBox(name, newBusyIndicator(), nil)
+	item.followups.Add(pending)
+
+	go func() {
+		text, err := llm.FollowUp(appCtx, currentModel, instruction, item.text())
+		item.followups.Remove(pending)
+		if err != nil {
+			errLabel := widget.NewLabel(fmt.Sprintf("Error: %v", err))
+			errLabel.Importance = widget.DangerImportance
+			errLabel.Wrapping = fyne.TextWrapWord
+			var failed fyne.CanvasObject
+			failed = newFollowupBox(name, errLabel, func() { item.followups.Remove(failed) })
+			item.followups.Add(failed)
+			return
+		}
+		id, err := database.SaveFollowup(appCtx, item.messageID, name, text)
+		if err != nil {
+			log.Printf("Failed to save follow-up: %v", err)
+		}
+		addFollowup(item, database.Followup{ID: id, MessageID: item.messageID, Action: name, Text: text})
+	}()
+}
+
+// loadFollowups shows the stored follow-ups of a message under it
+func loadFollowups(item *messageItem) {
+	followups, err := database.GetFollowups(appCtx, item.messageID)
+	if err != nil {
+		log.Printf("Failed to load follow-ups: %v", err)
+		return
+	}
+	for _, f := range followups {
+		addFollowup(item, f)
+	}
+}
+
+// addFollowup shows a follow-up result under its message, with buttons to
+// copy and delete it
+func addFollowup(item *messageItem, f database.Followup) {
+	var box fyne.CanvasObject
+	copyBtn := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		mainWindow.Clipboard().SetContent(f.Text)
+		showToast("Copied")
+	})
+	copyBtn.Importance = widget.LowImportance
+	box = newFollowupBox(f.Action, newMarkdownView(f.Text), func() {
+		if f.ID != 0 {
+			if err := database.DeleteFollowup(appCtx, f.ID); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to delete follow-up: %v", err), mainWindow)
+				return
+			}
+		}
+		item.followups.Remove(box)
+	}, copyBtn)
+	item.followups.Add(box)
+}
+
+// newFollowupBox lays out a follow-up indented under its message, behind a
+// bar in the primary color; remove, when not nil, adds a delete button
+func newFollowupBox(name string, body fyne.CanvasObject, remove func(), buttons ...fyne.CanvasObject) fyne.CanvasObject {
+	title := widget.NewLabel(name)
+	title.TextStyle = fyne.TextStyle{Italic: true}
+	title.Importance = widget.LowImportance
+	header := container.NewHBox(widget.NewIcon(theme.MailReplyIcon()), title, layout.NewSpacer())
+	for _, btn := range buttons {
+		header.Add(btn)
+	}
+	if remove != nil {
+		deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), remove)
+		deleteBtn.Importance = widget.LowImportance
+		header.Add(deleteBtn)
+	}
+	bar := canvas.NewRectangle(theme.Color(theme.ColorNamePrimary))
+	bar.SetMinSize(fyne.NewSize(3, 0))
+	indent := canvas.NewRectangle(color.Transparent)
+	indent.SetMinSize(fyne.NewSize(theme.Padding()*4, 0))
+	return container.NewBorder(nil, nil, container.NewHBox(indent, bar), nil, container.NewVBox(header, body))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// formSchemaExample is shown as the placeholder of the schema editor
+const formSchemaExample = `- name: full_name
+  type: string
+- name: start_date
+  type: date
+- name: plan
+  options: [basic, pro]`
+
+// showFormFillDialog maps the current chat onto a user-supplied form schema
+// and lets the user copy the values or POST them to a webhook
+func showFormFillDialog(w fyne.Window) {
+	chat := currentChatEntry()
+	if chat == nil {
+		return
+	}
+	chatID := chat.ID
+
+	schemaEntry := widget.NewMultiLineEntry()
+	schemaEntry.SetPlaceHolder(formSchemaExample)
+	schemaEntry.SetMinRowsVisible(8)
+
+	resultEntry := widget.NewMultiLineEntry()
+	resultEntry.SetPlaceHolder("Extracted values appear here")
+	resultEntry.SetMinRowsVisible(8)
+
+	webhookEntry := widget.NewEntry()
+	webhookEntry.SetPlaceHolder("https://example.com/webhook")
+
+	status := widget.NewLabel("")
+
+	openBtn := widget.NewButtonWithIcon("Open schema", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to read schema: %v", err), w)
+				return
+			}
+			schemaEntry.SetText(string(data))
+		}, w)
+	})
+
+	var fillBtn *widget.Button
+	fillBtn = widget.NewButtonWithIcon("Fill", theme.MediaPlayIcon(), func() {
+		fields, err := llm.ParseFormSchema([]byte(schemaEntry.Text))
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		c := findChat(chatID)
+		if c == nil {
+			return
+		}
+		transcript := chatTranscript(c)
+
+		fillBtn.Disable()
+		status.SetText("Filling form...")
+		go func() {
+			defer fillBtn.Enable()
+			values, err := llm.FillForm(appCtx, currentModel, fields, transcript)
+			if err != nil {
+				status.SetText("")
+				dialog.ShowError(err, w)
+				return
+			}
+			data, _ := json.MarshalIndent(values, "", "  ")
+			resultEntry.SetText(string(data))
+			status.SetText(fmt.Sprintf("Filled %d fields", len(fields)))
+		}()
+	})
+	fillBtn.Importance = widget.HighImportance
+
+	copyBtn := widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(resultEntry.Text)
+		status.SetText("Copied to clipboard")
+	})
+
+	postBtn := widget.NewButtonWithIcon("POST", theme.MailSendIcon(), func() {
+		url := strings.TrimSpace(webhookEntry.Text)
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			dialog.ShowError(fmt.Errorf("Please enter a valid webhook URL"), w)
+			return
+		}
+		// Send the edited result, so the user can correct values first
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(resultEntry.Text), &values); err != nil {
+			dialog.ShowError(fmt.Errorf("Form values are not valid JSON: %v", err), w)
+			return
+		}
+		status.SetText("Sending...")
+		go func() {
+			if err := llm.PostForm(appCtx, url, values); err != nil {
+				status.SetText("")
+				dialog.ShowError(err, w)
+				return
+			}
+			status.SetText("Sent to webhook")
+		}()
+	})
+
+	content := container.NewVBox(
+		widget.NewLabel("Form schema (JSON or YAML list of fields)"),
+		schemaEntry,
+		container.NewHBox(openBtn, layout.NewSpacer(), fillBtn),
+		widget.NewSeparator(),
+		resultEntry,
+		container.NewBorder(nil, nil, nil, container.NewHBox(copyBtn, postBtn), webhookEntry),
+		status,
+	)
+
+	d := dialog.NewCustom("Fill form from conversation", "Close", container.NewVScroll(content), w)
+	d.Resize(fyne.NewSize(600, 600))
+	d.Show()
+}
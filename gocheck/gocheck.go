@@ -0,0 +1,103 @@
+package gocheck
+
+import (
+	"context"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// vetTimeout bounds a go vet run, which compiles the snippet
+const vetTimeout = 30 * time.Second
+
+// blockPattern matches a fenced Go code block of a Markdown answer
+var blockPattern = regexp.MustCompile("(?s)```(?:go|golang)[ \t]*\n(.*?)\n?```")
+
+// Problem is a Go code block of an answer that failed a check
+type Problem struct {
+	Block   int // 1 for the first Go block of the answer
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("Go block %d: %s", p.Block, p.Message)
+}
+
+// Check formats the Go code blocks of a Markdown answer with gofmt and
+// returns the answer with the formatted code, and the blocks that do not
+// parse. With vet, the blocks that are whole files are also run through go vet
+// in a throwaway module, when the go tool is installed.
+func Check(ctx context.Context, answer string, vet bool) (string, []Problem) {
+	var problems []Problem
+	block := 0
+	formatted := blockPattern.ReplaceAllStringFunc(answer, func(fenced string) string {
+		block++
+		code := blockPattern.FindStringSubmatch(fenced)[1]
+		// format.Source takes whole files as well as lists of declarations
+		// or statements, the usual shape of a snippet
+		out, err := format.Source([]byte(code))
+		if err != nil {
+			problems = append(problems, Problem{Block: block, Message: "does not parse: " + err.Error()})
+			return fenced
+		}
+		code = strings.TrimRight(string(out), "\n")
+		if vet && strings.HasPrefix(code, "package ") {
+			if message := goVet(ctx, code); message != "" {
+				problems = append(problems, Problem{Block: block, Message: "go vet: " + message})
+			}
+		}
+		return "```go\n" + code + "\n```"
+	})
+	return formatted, problems
+}
+
+// goVet runs go vet on a file in a temporary module and returns its findings.
+// Snippets importing packages that are not in the standard library cannot be
+// vetted offline and are skipped.
+func goVet(ctx context.Context, code string) string {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		return ""
+	}
+	dir, err := os.MkdirTemp("", "llmschat-vet-")
+	if err != nil {
+		return ""
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module snippet\n"), 0644); err != nil {
+		return ""
+	}
+	if err := os.WriteFile(filepath.Join(dir, "snippet.go"), []byte(code+"\n"), 0644); err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, vetTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, goTool, "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return ""
+	}
+	text := string(out)
+	if ctx.Err() != nil || strings.Contains(text, "no required module provides package") || strings.Contains(text, "is not in std") {
+		return ""
+	}
+
+	// Keep the findings, without the package header lines
+	var findings []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		findings = append(findings, strings.TrimPrefix(line, "./"))
+	}
+	return strings.Join(findings, "; ")
+}
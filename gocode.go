@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/gocheck"
+)
+
+// checkGoCode formats the Go code blocks of an answer when enabled in the
+// settings and returns the answer to show with a warning naming the blocks
+// that failed to parse or vet, empty when all passed
+func checkGoCode(answer string) (string, string) {
+	if value, err := database.GetPreference(appCtx, database.PrefGoFormat); err != nil || value != "true" {
+		return answer, ""
+	}
+	vet, _ := database.GetPreference(appCtx, database.PrefGoVet)
+	formatted, problems := gocheck.Check(appCtx, answer, vet == "true")
+	if len(problems) == 0 {
+		return formatted, ""
+	}
+	lines := make([]string, len(problems))
+	for i, problem := range problems {
+		lines[i] = problem.String()
+	}
+	return formatted, strings.Join(lines, "\n")
+}
@@ -0,0 +1,115 @@
+package gotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runTimeout bounds a go test run, which builds the package
+const runTimeout = 2 * time.Minute
+
+// Result is the outcome of running a generated test file
+type Result struct {
+	Compiled bool // The package and the tests built
+	Passed   bool
+	Output   string
+}
+
+// Locate returns the Go file of the workspace with the given source, or else
+// the one at the relative path name
+func Locate(workspace, name string, source []byte) (string, error) {
+	source = bytes.TrimSpace(source)
+	found := ""
+	err := filepath.WalkDir(workspace, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != workspace && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		if data, err := os.ReadFile(path); err == nil && bytes.Equal(bytes.TrimSpace(data), source) {
+			found = path
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if found != "" {
+		return found, nil
+	}
+	if path := filepath.Join(workspace, filepath.Clean("/"+name)); name != "" {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s was not found in the workspace %s", name, workspace)
+}
+
+// TestFile returns the path a test file for the Go file at path is proposed
+// under: its _test.go file, or a _generated_test.go file when that exists
+func TestFile(path string) string {
+	base := strings.TrimSuffix(path, ".go")
+	if _, err := os.Stat(base + "_test.go"); err == nil {
+		return base + "_generated_test.go"
+	}
+	return base + "_test.go"
+}
+
+// Run runs go test on the package of the Go file at path with the test file
+// added. The test file is only put in place through a build overlay, so the
+// workspace is not changed.
+func Run(ctx context.Context, path string, test []byte) (Result, error) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		return Result{}, fmt.Errorf("the go tool is not installed: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "llmschat-test-")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	testPath := filepath.Join(dir, "generated_test.go")
+	if err := os.WriteFile(testPath, test, 0644); err != nil {
+		return Result{}, err
+	}
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {TestFile(path): testPath},
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	overlayPath := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0644); err != nil {
+		return Result{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, goTool, "test", "-count=1", "-overlay", overlayPath, ".")
+	cmd.Dir = filepath.Dir(path)
+	out, err := cmd.CombinedOutput()
+	result := Result{Output: strings.TrimSpace(string(out))}
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("go test did not finish within %v", runTimeout)
+	}
+	result.Passed = err == nil
+	result.Compiled = result.Passed || !strings.Contains(result.Output, "[build failed]") && !strings.Contains(result.Output, "[setup failed]")
+	return result, nil
+}
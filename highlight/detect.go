@@ -0,0 +1,154 @@
+package highlight
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// marker is a sign that code is written in a language; strong markers are
+// enough on their own, weak ones need another marker of the same language
+type marker struct {
+	pattern *regexp.Regexp
+	strong  bool
+}
+
+// markers are the signs of the languages Detect knows, checked in order so
+// the first language wins a tie
+var markers = []struct {
+	lang    string
+	markers []marker
+}{
+	{"go", []marker{
+		{regexp.MustCompile(`(?m)^package \w+\s*$`), true},
+		{regexp.MustCompile(`(?m)^func (\(\w+ \*?\w+\) )?\w+\(`), true},
+		{regexp.MustCompile(`\berr != nil\b`), true},
+		{regexp.MustCompile(`\bfmt\.\w+\(`), true},
+		{regexp.MustCompile(`\w := `), false},
+		{regexp.MustCompile(`(?m)^import \($`), false},
+	}},
+	{"rust", []marker{
+		{regexp.MustCompile(`\bfn \w+(<.*>)?\(`), true},
+		{regexp.MustCompile(`\blet mut\b`), true},
+		{regexp.MustCompile(`\w+!\(`), false},
+		{regexp.MustCompile(`(?m)^use \w+(::\w+)+`), true},
+		{regexp.MustCompile(`\bimpl\b`), false},
+		{regexp.MustCompile(`&(mut )?str\b|::new\(`), false},
+	}},
+	{"python", []marker{
+		{regexp.MustCompile(`(?m)^\s*def \w+\(.*\)( -> .+)?:\s*$`), true},
+		{regexp.MustCompile(`(?m)^\s*from [\w.]+ import `), true},
+		{regexp.MustCompile(`(?m)^\s*(elif .*|else|try|except.*|finally):\s*$`), true},
+		{regexp.MustCompile(`\bself\.\w+`), false},
+		{regexp.MustCompile(`\bprint\(`), false},
+		{regexp.MustCompile(`(?m)^\s*(if|for|while|with|class) .*:\s*$`), false},
+		{regexp.MustCompile(`(?m)^import \w+(\.\w+)*( as \w+)?$`), false},
+	}},
+	{"java", []marker{
+		{regexp.MustCompile(`\bpublic (static |final |abstract )*(class|interface|void|enum)\b`), true},
+		{regexp.MustCompile(`\bSystem\.(out|err)\.`), true},
+		{regexp.MustCompile(`(?m)^import (java|javax|org|com)\.[\w.]+;`), true},
+		{regexp.MustCompile(`@Override\b`), true},
+		{regexp.MustCompile(`\b(private|protected) \w+(<.*>)? \w+`), false},
+		{regexp.MustCompile(`\bString\[\]`), false},
+	}},
+	{"c", []marker{
+		{regexp.MustCompile(`(?m)^#include\s*[<"]`), true},
+		{regexp.MustCompile(`(?m)^#define `), true},
+		{regexp.MustCompile(`\bint main\(`), true},
+		{regexp.MustCompile(`\bstd::`), true},
+		{regexp.MustCompile(`\b(printf|malloc|free|sizeof)\(`), false},
+	}},
+	{"javascript", []marker{
+		{regexp.MustCompile(`\bconsole\.\w+\(`), true},
+		{regexp.MustCompile(`(?m)^\s*import .* from ['"]`), true},
+		{regexp.MustCompile(`\brequire\(['"]`), true},
+		{regexp.MustCompile(`\bdocument\.\w+`), true},
+		{regexp.MustCompile(`\b(const|let) \w+ = `), false},
+		{regexp.MustCompile(`=> `), false},
+		{regexp.MustCompile(`===|!==`), false},
+		{regexp.MustCompile(`\bfunction\s*\w*\(`), false},
+	}},
+	{"ruby", []marker{
+		{regexp.MustCompile(`(?m)^\s*require ['"]`), true},
+		{regexp.MustCompile(`\bdo \|\w+(, \w+)*\|`), true},
+		{regexp.MustCompile(`\bputs\b`), false},
+		{regexp.MustCompile(`(?m)^\s*def \w+[^:]*$`), false},
+		{regexp.MustCompile(`(?m)^\s*end\s*$`), false},
+	}},
+	{"sql", []marker{
+		{regexp.MustCompile(`(?is)\bSELECT\b.+\bFROM\b`), true},
+		{regexp.MustCompile(`(?i)\b(CREATE (TABLE|INDEX|VIEW)|INSERT INTO|DELETE FROM|ALTER TABLE|DROP TABLE)\b`), true},
+		{regexp.MustCompile(`(?i)\bUPDATE \w+ SET\b`), true},
+	}},
+	{"shell", []marker{
+		{regexp.MustCompile(`(?m)^#!/(usr/)?bin/(env )?(ba|z)?sh\b`), true},
+		{regexp.MustCompile(`(?m)^\$ \w`), true},
+		{regexp.MustCompile(`^\s*(sudo|apt|apt-get|brew|npm|npx|yarn|pip|pip3|go|git|cd|ls|mkdir|rm|cp|mv|curl|wget|docker|kubectl|cargo|make|chmod|echo) `), true},
+		{regexp.MustCompile(`(?m)^\s*(fi|done|esac)\s*$`), false},
+		{regexp.MustCompile(`\s--?[a-zA-Z][\w-]*`), false},
+	}},
+	{"html", []marker{
+		{regexp.MustCompile(`(?i)<!doctype html|<html\b`), true},
+		{regexp.MustCompile(`(?m)^\s*<(div|span|p|a|ul|li|head|body|script|style|form|input|button|table)\b[^>]*>`), false},
+		{regexp.MustCompile(`</\w+>`), false},
+	}},
+	{"yaml", []marker{
+		{regexp.MustCompile(`(?m)^---\s*$`), false},
+		{regexp.MustCompile(`(?m)^[\w-]+:\s*$`), false},
+		{regexp.MustCompile(`(?m)^\s+[\w-]+: \S`), false},
+		{regexp.MustCompile(`(?m)^\s+- \S`), false},
+	}},
+}
+
+// Detect guesses the language of code from fences that do not name one,
+// returning the name used in fences, or "" when it looks like no language
+// in particular
+func Detect(code string) string {
+	trimmed := strings.TrimSpace(code)
+	if trimmed == "" {
+		return ""
+	}
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	best, bestScore := "", 0
+	for _, lang := range markers {
+		score := 0
+		for _, m := range lang.markers {
+			if !m.pattern.MatchString(code) {
+				continue
+			}
+			score++
+			if m.strong {
+				score++
+			}
+		}
+		if score >= 2 && score > bestScore {
+			best, bestScore = lang.lang, score
+		}
+	}
+	return best
+}
+
+// Label adds the language Detect finds to the fenced code blocks of Markdown
+// that do not name one
+func Label(markdown string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range fencePattern.FindAllStringSubmatchIndex(markdown, -1) {
+		if strings.TrimSpace(markdown[m[2]:m[3]]) != "" {
+			continue
+		}
+		lang := Detect(markdown[m[4]:m[5]])
+		if lang == "" {
+			continue
+		}
+		b.WriteString(markdown[last:m[3]])
+		b.WriteString(lang)
+		last = m[3]
+	}
+	b.WriteString(markdown[last:])
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package highlight
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"empty", "  \n", ""},
+		{"prose", "Just a sentence about nothing in particular.", ""},
+		{"json object", `{"name": "llmschat", "tags": ["go", "fyne"]}`, "json"},
+		{"json array", "[1, 2, 3]", "json"},
+		{"invalid json", `{"name": }`, ""},
+		{"go package", "package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}", "go"},
+		{"go snippet", "if err != nil {\n\treturn err\n}", "go"},
+		{"rust", "fn main() {\n    let mut v = Vec::new();\n    println!(\"{}\", v.len());\n}", "rust"},
+		{"python", "def greet(name):\n    print(f\"hi {name}\")", "python"},
+		{"python imports", "from os import path\nimport sys", "python"},
+		{"java", "public class Hello {\n    public static void main(String[] args) {\n        System.out.println(\"hi\");\n    }\n}", "java"},
+		{"c", "#include <stdio.h>\n\nint main() {\n    printf(\"hi\\n\");\n}", "c"},
+		{"cpp", "#include <vector>\nstd::vector<int> v;", "c"},
+		{"javascript", "const x = require('fs');\nconsole.log(x);", "javascript"},
+		{"javascript module", "import React from 'react';\nconst App = () => null;", "javascript"},
+		{"ruby", "require 'json'\n[1, 2].each do |n|\n  puts n\nend", "ruby"},
+		{"sql", "SELECT id, title\nFROM chats\nWHERE deleted_at IS NULL;", "sql"},
+		{"sql update", "update chats set title = 'x' where id = 1", "sql"},
+		{"shebang", "#!/bin/bash\necho hi", "shell"},
+		{"command", "go test ./...", "shell"},
+		{"prompt", "$ git status", "shell"},
+		{"html", "<!DOCTYPE html>\n<html><body></body></html>", "html"},
+		{"html fragment", "<div class=\"card\">\n  <p>hi</p>\n</div>", "html"},
+		{"yaml", "name: build\non:\n  push:\n    branches: [main]\njobs:\n  test:\n    steps:\n      - run: go test", "yaml"},
+		{"one weak marker is not enough", "x := 1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.code); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"unlabeled block", "Run:\n```\ngo test ./...\n```\n", "Run:\n```shell\ngo test ./...\n```\n"},
+		{"labeled block is kept", "```python\ngo test ./...\n```", "```python\ngo test ./...\n```"},
+		{"unknown language stays unlabeled", "```\nhello there\n```", "```\nhello there\n```"},
+		{"several blocks", "```\n{\"a\": 1}\n```\ntext\n```\nSELECT 1 FROM t\n```", "```json\n{\"a\": 1}\n```\ntext\n```sql\nSELECT 1 FROM t\n```"},
+		{"open block while streaming", "```\npackage main\n\nfunc main() {", "```go\npackage main\n\nfunc main() {"},
+		{"no blocks", "plain text", "plain text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Label(tt.markdown); got != tt.want {
+				t.Errorf("Label() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
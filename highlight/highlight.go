@@ -0,0 +1,229 @@
+package highlight
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kind is what a token of code is, which decides its color
+type Kind int
+
+const (
+	Plain Kind = iota
+	Keyword
+	String
+	Comment
+	Number
+)
+
+// Token is a run of code of one kind
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+// Part is a piece of a Markdown message: text, or a fenced code block
+type Part struct {
+	Code bool
+	Lang string // Language of the code block as written after the fence
+	Text string // Markdown, or the code without its fences
+}
+
+// fencePattern matches a fenced code block; a block still open at the end of
+// a streaming answer runs to the end
+var fencePattern = regexp.MustCompile("(?s)```([^\n`]*)\n(.*?)(?:\n?```|$)")
+
+// Split cuts Markdown into its text and fenced code blocks, in order
+func Split(markdown string) []Part {
+	var parts []Part
+	last := 0
+	for _, m := range fencePattern.FindAllStringSubmatchIndex(markdown, -1) {
+		if text := strings.TrimSpace(markdown[last:m[0]]); text != "" {
+			parts = append(parts, Part{Text: text})
+		}
+		parts = append(parts, Part{Code: true, Lang: strings.TrimSpace(markdown[m[2]:m[3]]), Text: strings.TrimSuffix(markdown[m[4]:m[5]], "\n")})
+		last = m[1]
+	}
+	if text := strings.TrimSpace(markdown[last:]); text != "" {
+		parts = append(parts, Part{Text: text})
+	}
+	return parts
+}
+
+// syntax describes what the tokenizer looks for in a language
+type syntax struct {
+	keywords     []string
+	lineComments []string
+	blockComment [2]string // Start and end, empty when there is none
+	quotes       string    // Characters that start and end a string
+}
+
+var (
+	cLike = syntax{lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: `"'`}
+	hash  = syntax{lineComments: []string{"#"}, quotes: `"'`}
+)
+
+// syntaxes are the known languages, by the names used after a fence
+var syntaxes = map[string]syntax{
+	"go": withKeywords(syntax{lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'`"},
+		"break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false"),
+	"python": withKeywords(syntax{lineComments: []string{"#"}, quotes: `"'`},
+		"and as assert async await break class continue def del elif else except False finally for from global if import in is lambda None nonlocal not or pass raise return True try while with yield self"),
+	"javascript": withKeywords(syntax{lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'`"},
+		"async await break case catch class const continue debugger default delete do else export extends false finally for function if import in instanceof let new null of return super switch this throw true try typeof undefined var void while yield interface type enum implements"),
+	"java": withKeywords(cLike,
+		"abstract boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long new null package private protected public return short static super switch this throw throws true false try void volatile while var record"),
+	"c": withKeywords(cLike,
+		"auto bool break case char class const continue default delete do double else enum extern false float for if include define inline int long namespace new nullptr private protected public return short signed sizeof static struct switch template this true typedef union unsigned using virtual void volatile while"),
+	"rust": withKeywords(cLike,
+		"as async await break const continue crate else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while Some None Ok Err"),
+	"shell": withKeywords(hash,
+		"if then else elif fi for while until do done case esac in function return export local echo exit set unset"),
+	"sql": withKeywords(syntax{lineComments: []string{"--"}, blockComment: [2]string{"/*", "*/"}, quotes: `'"`},
+		"select from where and or not insert into values update set delete create table index view drop alter add primary key foreign references join left right inner outer on group by order having limit offset as distinct null is in like between case when then else end union all exists integer text real default"),
+	"yaml": withKeywords(hash, "true false null yes no"),
+	"json": withKeywords(syntax{quotes: `"`}, "true false null"),
+	"ruby": withKeywords(hash,
+		"begin break case class def do else elsif end ensure false for if in module next nil not or redo rescue retry return self super then true undef unless until when while yield require"),
+}
+
+// aliases map other names of a language to the one in syntaxes
+var aliases = map[string]string{
+	"golang": "go", "py": "python", "js": "javascript", "jsx": "javascript", "ts": "javascript",
+	"typescript": "javascript", "tsx": "javascript", "kotlin": "java", "kt": "java", "scala": "java",
+	"cpp": "c", "c++": "c", "h": "c", "hpp": "c", "cs": "c", "csharp": "c", "rs": "rust",
+	"sh": "shell", "bash": "shell", "zsh": "shell", "console": "shell", "yml": "yaml", "rb": "ruby",
+	"postgres": "sql", "sqlite": "sql", "mysql": "sql",
+}
+
+func withKeywords(s syntax, keywords string) syntax {
+	s.keywords = strings.Fields(keywords)
+	return s
+}
+
+// lookup returns the syntax of a language; unknown languages only get their
+// strings and numbers highlighted
+func lookup(lang string) (syntax, map[string]bool) {
+	lang = strings.ToLower(lang)
+	if alias, ok := aliases[lang]; ok {
+		lang = alias
+	}
+	s, ok := syntaxes[lang]
+	if !ok {
+		s = syntax{quotes: `"`}
+	}
+	keywords := make(map[string]bool, len(s.keywords))
+	for _, k := range s.keywords {
+		keywords[k] = true
+		if lang == "sql" {
+			keywords[strings.ToUpper(k)] = true
+		}
+	}
+	return s, keywords
+}
+
+// Tokens splits code into tokens to color. The tokenizer knows comments,
+// strings, numbers and keywords, which is enough for a chat answer, and
+// never fails: whatever it does not recognize is plain.
+func Tokens(lang, code string) []Token {
+	s, keywords := lookup(lang)
+	var tokens []Token
+	add := func(kind Kind, text string) {
+		if n := len(tokens); n > 0 && tokens[n-1].Kind == kind {
+			tokens[n-1].Text += text
+			return
+		}
+		tokens = append(tokens, Token{Kind: kind, Text: text})
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if s.blockComment[0] != "" && strings.HasPrefix(rest, s.blockComment[0]) {
+			end := strings.Index(rest[len(s.blockComment[0]):], s.blockComment[1])
+			n := len(rest)
+			if end >= 0 {
+				n = len(s.blockComment[0]) + end + len(s.blockComment[1])
+			}
+			add(Comment, rest[:n])
+			i += n
+			continue
+		}
+		if lineComment(s, code, i) {
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			add(Comment, rest[:n])
+			i += n
+			continue
+		}
+
+		c := rest[0]
+		switch {
+		case strings.IndexByte(s.quotes, c) >= 0:
+			n := stringLength(rest)
+			add(String, rest[:n])
+			i += n
+		case c >= '0' && c <= '9' && (i == 0 || !isWord(rune(code[i-1]))):
+			n := 1
+			for n < len(rest) && (isWord(rune(rest[n])) || rest[n] == '.') {
+				n++
+			}
+			add(Number, rest[:n])
+			i += n
+		case isWord(rune(c)):
+			n := 1
+			for n < len(rest) && isWord(rune(rest[n])) {
+				n++
+			}
+			if keywords[rest[:n]] {
+				add(Keyword, rest[:n])
+			} else {
+				add(Plain, rest[:n])
+			}
+			i += n
+		default:
+			add(Plain, rest[:1])
+			i++
+		}
+	}
+	return tokens
+}
+
+// lineComment reports whether a line comment starts at code[i]. A # only
+// starts one at the start of a line or after a space, so "a#b" stays plain.
+func lineComment(s syntax, code string, i int) bool {
+	for _, prefix := range s.lineComments {
+		if !strings.HasPrefix(code[i:], prefix) {
+			continue
+		}
+		if prefix != "#" || i == 0 || unicode.IsSpace(rune(code[i-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringLength returns the length of the string literal text starts with,
+// up to its closing quote or, for quotes other than backticks, the line end
+func stringLength(text string) int {
+	quote := text[0]
+	for n := 1; n < len(text); n++ {
+		switch text[n] {
+		case '\\':
+			n++
+		case quote:
+			return n + 1
+		case '\n':
+			if quote != '`' {
+				return n
+			}
+		}
+	}
+	return len(text)
+}
+
+func isWord(r rune) bool {
+	return r == '_' || r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/homeassistant"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// homeRefresh is how long entity states are reused before reloading
+const homeRefresh = time.Minute
+
+// homeCache keeps the last read states so every message does not refetch them
+var homeCache struct {
+	sync.Mutex
+	loaded time.Time
+	states []homeassistant.State
+}
+
+// homeAssistantClient returns a client for the configured Home Assistant, or
+// false when the integration is not set up
+func homeAssistantClient() (*homeassistant.Client, bool) {
+	url, err := database.GetPreference(appCtx, database.PrefHomeAssistantURL)
+	if err != nil || url == "" {
+		return nil, false
+	}
+	token, err := database.GetPreference(appCtx, database.PrefHomeAssistantToken)
+	if err != nil || token == "" {
+		return nil, false
+	}
+	client, err := llm.HTTPClient(appCtx)
+	if err != nil {
+		log.Printf("Failed to create Home Assistant client: %v", err)
+		return nil, false
+	}
+	return &homeassistant.Client{URL: url, Token: token, HTTP: client}, true
+}
+
+// homePrompt returns the Home Assistant tool for the system prompt, or an empty
+// string when the integration is not set up
+func homePrompt() string {
+	client, ok := homeAssistantClient()
+	if !ok {
+		return ""
+	}
+
+	homeCache.Lock()
+	defer homeCache.Unlock()
+	if time.Since(homeCache.loaded) >= homeRefresh {
+		states, err := client.States(appCtx)
+		if err != nil {
+			log.Printf("%v", err)
+			return ""
+		}
+		homeCache.loaded = time.Now()
+		homeCache.states = states
+	}
+
+	var prefixes []string
+	if filter, err := database.GetPreference(appCtx, database.PrefHomeAssistantEntities); err == nil {
+		for _, prefix := range strings.Split(filter, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	return homeassistant.ToolPrompt(homeassistant.Filter(homeCache.states, prefixes))
+}
+
+// addActionProposals adds a confirm button for every action proposed in reply
+func addActionProposals(box *fyne.Container, reply string) {
+	for _, action := range homeassistant.ParseActions(reply) {
+		action := action
+		var btn *widget.Button
+		btn = widget.NewButtonWithIcon("Run: "+action.Describe(), theme.MediaPlayIcon(), func() {
+			confirmAction(action, btn)
+		})
+		box.Add(btn)
+	}
+}
+
+// confirmAction asks the user before calling a proposed Home Assistant service
+func confirmAction(action homeassistant.Action, btn *widget.Button) {
+	client, ok := homeAssistantClient()
+	if !ok {
+		dialog.ShowError(fmt.Errorf("Please set the Home Assistant URL and token in settings"), mainWindow)
+		return
+	}
+
+	dialog.ShowConfirm("Run action",
+		fmt.Sprintf("Call %s?", action.Describe()),
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := client.CallService(appCtx, action.Service, action.ServiceData()); err != nil {
+				dialog.ShowError(err, mainWindow)
+				return
+			}
+
+			// Reload on the next message so the model sees the new state
+			homeCache.Lock()
+			homeCache.loaded = time.Time{}
+			homeCache.Unlock()
+
+			btn.SetText("Done: " + action.Describe())
+			btn.SetIcon(theme.ConfirmIcon())
+			btn.Disable()
+		}, mainWindow)
+}
@@ -0,0 +1,97 @@
+package homeassistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// State is the current state of a Home Assistant entity
+type State struct {
+	EntityID   string                 `json:"entity_id"`
+	State      string                 `json:"state"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// Name returns the friendly name of the entity, or its ID
+func (s State) Name() string {
+	if name, ok := s.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return s.EntityID
+}
+
+// Unit returns the unit of measurement of a sensor, if any
+func (s State) Unit() string {
+	unit, _ := s.Attributes["unit_of_measurement"].(string)
+	return unit
+}
+
+// Client talks to the Home Assistant REST API with a long-lived access token
+type Client struct {
+	URL   string
+	Token string
+	HTTP  *http.Client
+}
+
+// States returns the state of every entity
+func (c *Client) States(ctx context.Context) ([]State, error) {
+	var states []State
+	if err := c.do(ctx, http.MethodGet, "/api/states", nil, &states); err != nil {
+		return nil, fmt.Errorf("failed to read Home Assistant states: %v", err)
+	}
+	return states, nil
+}
+
+// CallService calls service (e.g. "light.turn_on") with data
+func (c *Client) CallService(ctx context.Context, service string, data map[string]interface{}) error {
+	domain, name, ok := strings.Cut(service, ".")
+	if !ok {
+		return fmt.Errorf("invalid service %q, expected domain.service", service)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/services/"+domain+"/"+name, data, nil); err != nil {
+		return fmt.Errorf("failed to call %s: %v", service, err)
+	}
+	return nil
+}
+
+// do sends an API request with an optional JSON body and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.URL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,113 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxEntities bounds how many entities are listed in the system prompt
+const maxEntities = 100
+
+// DefaultDomains are the entity domains exposed when no filter is configured
+var DefaultDomains = []string{"sensor", "binary_sensor", "light", "switch", "climate", "cover", "lock", "fan", "media_player"}
+
+// toolPrompt exposes the home to the model and explains how to propose actions
+const toolPrompt = `You can see the user's Home Assistant. Current entity states:
+%s
+To control a device, add a fenced block like this to your reply, one block per action,
+and tell the user they can confirm it. Never claim an action was performed yourself.
+Messages can be published to the MQTT broker with the mqtt.publish service and "topic" and "payload" data.
+` + "```ha-action" + `
+{"service": "light.turn_on", "entity_id": "light.kitchen", "data": {"brightness_pct": 50}}
+` + "```"
+
+// actionPattern matches the fenced action blocks of a reply
+var actionPattern = regexp.MustCompile("(?s)```ha-action\\s*\\n(.*?)```")
+
+// Action is a service call proposed by the model
+type Action struct {
+	Service  string                 `json:"service"`
+	EntityID string                 `json:"entity_id"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// Describe formats the action on one line, e.g. "light.turn_on light.kitchen"
+func (a Action) Describe() string {
+	text := a.Service
+	if a.EntityID != "" {
+		text += " " + a.EntityID
+	}
+	if topic, ok := a.Data["topic"].(string); ok {
+		text += fmt.Sprintf(" %s=%v", topic, a.Data["payload"])
+	}
+	return text
+}
+
+// ServiceData returns the data sent with the service call, including the entity
+func (a Action) ServiceData() map[string]interface{} {
+	data := make(map[string]interface{}, len(a.Data)+1)
+	for key, value := range a.Data {
+		data[key] = value
+	}
+	if a.EntityID != "" {
+		data["entity_id"] = a.EntityID
+	}
+	return data
+}
+
+// Filter returns the states whose entity ID starts with one of prefixes, e.g.
+// "sensor." or "light.kitchen". Without prefixes the default domains are used.
+func Filter(states []State, prefixes []string) []State {
+	if len(prefixes) == 0 {
+		for _, domain := range DefaultDomains {
+			prefixes = append(prefixes, domain+".")
+		}
+	}
+	var result []State
+	for _, state := range states {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(state.EntityID, prefix) {
+				result = append(result, state)
+				break
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].EntityID < result[j].EntityID })
+	return result
+}
+
+// ToolPrompt describes states for the system prompt
+func ToolPrompt(states []State) string {
+	var list strings.Builder
+	for i, state := range states {
+		if i == maxEntities {
+			fmt.Fprintf(&list, "(%d more not shown)\n", len(states)-maxEntities)
+			break
+		}
+		fmt.Fprintf(&list, "- %s (%s): %s", state.EntityID, state.Name(), state.State)
+		if unit := state.Unit(); unit != "" {
+			list.WriteString(" " + unit)
+		}
+		list.WriteString("\n")
+	}
+	if len(states) == 0 {
+		list.WriteString("(no entities)\n")
+	}
+	return fmt.Sprintf(toolPrompt, list.String())
+}
+
+// ParseActions returns the actions the model proposed in reply
+func ParseActions(reply string) []Action {
+	var actions []Action
+	for _, match := range actionPattern.FindAllStringSubmatch(reply, -1) {
+		var action Action
+		if err := json.Unmarshal([]byte(match[1]), &action); err != nil || !strings.Contains(action.Service, ".") {
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
@@ -0,0 +1,61 @@
+package homeassistant
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseActions(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  []Action
+	}{
+		{
+			name:  "no blocks",
+			reply: "The kitchen light is on.",
+			want:  nil,
+		},
+		{
+			name:  "one action",
+			reply: "Turning it off.\n```ha-action\n{\"service\": \"light.turn_off\", \"entity_id\": \"light.kitchen\"}\n```\nDone.",
+			want:  []Action{{Service: "light.turn_off", EntityID: "light.kitchen"}},
+		},
+		{
+			name:  "action with data",
+			reply: "```ha-action\n{\"service\": \"climate.set_temperature\", \"entity_id\": \"climate.living\", \"data\": {\"temperature\": 21}}\n```",
+			want:  []Action{{Service: "climate.set_temperature", EntityID: "climate.living", Data: map[string]interface{}{"temperature": float64(21)}}},
+		},
+		{
+			name: "several actions in order",
+			reply: "```ha-action\n{\"service\": \"light.turn_on\", \"entity_id\": \"light.a\"}\n```\n" +
+				"and\n```ha-action\n{\"service\": \"switch.toggle\", \"entity_id\": \"switch.b\"}\n```",
+			want: []Action{
+				{Service: "light.turn_on", EntityID: "light.a"},
+				{Service: "switch.toggle", EntityID: "switch.b"},
+			},
+		},
+		{
+			name:  "invalid JSON is skipped",
+			reply: "```ha-action\n{service: light.turn_on}\n```\n```ha-action\n{\"service\": \"light.turn_on\", \"entity_id\": \"light.a\"}\n```",
+			want:  []Action{{Service: "light.turn_on", EntityID: "light.a"}},
+		},
+		{
+			name:  "service without a domain is skipped",
+			reply: "```ha-action\n{\"service\": \"turn_on\", \"entity_id\": \"light.a\"}\n```",
+			want:  nil,
+		},
+		{
+			name:  "other fences are ignored",
+			reply: "```json\n{\"service\": \"light.turn_on\", \"entity_id\": \"light.a\"}\n```",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseActions(tt.reply); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseActions() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// instructionLayers returns the system instruction layers that apply to chat
+func instructionLayers(chat *Chat) []llm.InstructionLayer {
+	var layers []llm.InstructionLayer
+	for level, key := range map[int]string{
+		llm.LayerGlobal:  database.PrefGlobalInstructions,
+		llm.LayerProject: database.PrefProjectInstructions,
+	} {
+		text, err := database.GetPreference(appCtx, key)
+		if err != nil {
+			log.Printf("Failed to get %s instructions: %v", key, err)
+			continue
+		}
+		layers = append(layers, llm.InstructionLayer{Level: level, Text: text})
+	}
+	if chat != nil {
+		layers = append(layers, llm.InstructionLayer{Level: llm.LayerChat, Text: chat.SystemPrompt})
+	}
+	return layers
+}
+
+// toolPrompts describe the configured tools to the model
+var toolPrompts = []func() string{calendarPrompt, homePrompt}
+
+// systemPrompt returns the merged system prompt for the next request in chat,
+// including the answer styles picked next to the input, followed by the
+// descriptions of the configured tools and of the logs attached to chat
+func systemPrompt(chat *Chat) string {
+	layers := instructionLayers(chat)
+	if styleGroup != nil {
+		layers = append(layers, llm.InstructionLayer{Level: llm.LayerStyle, Text: llm.StyleInstructions(styleGroup.Selected)})
+	}
+	prompt := llm.ComposeSystemPrompt(layers)
+	var tools []string
+	for _, toolPrompt := range toolPrompts {
+		tools = append(tools, toolPrompt())
+	}
+	if chat != nil {
+		tools = append(tools, logPrompt(chat.ID), threadPrompt(chat.ID))
+	}
+	for _, tool := range tools {
+		if tool != "" {
+			if prompt != "" {
+				prompt += "\n\n"
+			}
+			prompt += tool
+		}
+	}
+	return prompt
+}
+
+// showInstructionsInspector lets the user edit each instruction layer and shows
+// the merged system prompt that the next request in the current chat will use
+func showInstructionsInspector(w fyne.Window) {
+	newEditor := func(placeholder string) *widget.Entry {
+		entry := widget.NewMultiLineEntry()
+		entry.Wrapping = fyne.TextWrapWord
+		entry.SetPlaceHolder(placeholder)
+		entry.SetMinRowsVisible(3)
+		return entry
+	}
+
+	globalEntry := newEditor("Instructions for every chat")
+	projectEntry := newEditor("Instructions for the current workspace")
+	chatEntry := newEditor("Instructions for this chat only")
+	if text, err := database.GetPreference(appCtx, database.PrefGlobalInstructions); err == nil {
+		globalEntry.SetText(text)
+	}
+	if text, err := database.GetPreference(appCtx, database.PrefProjectInstructions); err == nil {
+		projectEntry.SetText(text)
+	}
+	chat := currentChat
+	if chat != nil {
+		chatEntry.SetText(chat.SystemPrompt)
+	} else {
+		chatEntry.Disable()
+	}
+
+	preview := widget.NewLabel("")
+	preview.Wrapping = fyne.TextWrapWord
+	updatePreview := func(string) {
+		merged := llm.ComposeSystemPrompt([]llm.InstructionLayer{
+			{Level: llm.LayerGlobal, Text: globalEntry.Text},
+			{Level: llm.LayerProject, Text: projectEntry.Text},
+			{Level: llm.LayerChat, Text: chatEntry.Text},
+		})
+		if merged == "" {
+			merged = "(no system prompt)"
+		}
+		preview.SetText(merged)
+	}
+	globalEntry.OnChanged = updatePreview
+	projectEntry.OnChanged = updatePreview
+	chatEntry.OnChanged = updatePreview
+	updatePreview("")
+
+	form := widget.NewForm(
+		&widget.FormItem{Text: "Global", Widget: globalEntry},
+		&widget.FormItem{Text: "Project", Widget: projectEntry},
+		&widget.FormItem{Text: "Chat", Widget: chatEntry},
+	)
+	content := container.NewVBox(
+		form,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Final system prompt", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		preview,
+	)
+
+	d := dialog.NewCustomConfirm("Instructions", "Save", "Cancel", container.NewVScroll(content), func(save bool) {
+		if !save {
+			return
+		}
+		if err := database.SetPreference(appCtx, database.PrefGlobalInstructions, globalEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save instructions: %v", err), w)
+			return
+		}
+		if err := database.SetPreference(appCtx, database.PrefProjectInstructions, projectEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save instructions: %v", err), w)
+			return
+		}
+		if chat != nil {
+			if target := findChat(chat.ID); target != nil {
+				target.SystemPrompt = chatEntry.Text
+				saveChatConfig(target)
+			}
+			chat.SystemPrompt = chatEntry.Text
+		}
+	}, w)
+	d.Resize(fyne.NewSize(600, 550))
+	d.Show()
+}
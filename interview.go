@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// chatModeInterview makes the AI ask the user questions about a goal
+const chatModeInterview = "interview"
+
+// interviewBar holds the controls shown while a chat is in interview mode
+var interviewBar *fyne.Container
+
+// interviewTranscript returns the messages exchanged since the interview started
+func interviewTranscript(chat *Chat) []llm.Turn {
+	start := chat.InterviewStart
+	if start > len(chat.Messages) {
+		start = len(chat.Messages)
+	}
+	var turns []llm.Turn
+	for _, msg := range chat.Messages[start:] {
+		if msg.Sender == "System" {
+			continue
+		}
+		turns = append(turns, llm.Turn{Speaker: msg.Sender, Text: msg.Text})
+	}
+	return turns
+}
+
+// startInterview switches the chat into interview mode for goal and asks the first question
+func startInterview(chatID int, goal string) {
+	chat := findChat(chatID)
+	if chat == nil {
+		return
+	}
+	chat.Mode = chatModeInterview
+	chat.InterviewGoal = goal
+	chat.InterviewStart = len(chat.Messages)
+	refreshInterviewBar()
+
+	AddMessage(chatID, "Interview: "+goal, "You", false)
+	go askInterviewQuestion(chatID)
+}
+
+// askInterviewQuestion adds the next interview question to the chat
+func askInterviewQuestion(chatID int) {
+	chat := findChat(chatID)
+	if chat == nil {
+		return
+	}
+	question, err := llm.InterviewQuestion(appCtx, currentModel, chat.InterviewGoal, interviewTranscript(chat))
+	if err != nil {
+		AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
+		return
+	}
+	AddMessage(chatID, question, "AI", true)
+}
+
+// finishInterview writes the structured document and leaves interview mode
+func finishInterview(chatID int) {
+	chat := findChat(chatID)
+	if chat == nil || chat.Mode != chatModeInterview {
+		return
+	}
+	document, err := llm.InterviewDocument(appCtx, currentModel, chat.InterviewGoal, interviewTranscript(chat))
+	if err != nil {
+		AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
+		// The interview goes on, so finishing can be tried again
+		refreshInterviewBar()
+		return
+	}
+
+	chat = findChat(chatID)
+	chat.Mode = ""
+	refreshInterviewBar()
+	AddMessage(chatID, document, "AI", true)
+}
+
+// showInterviewDialog asks for the goal of a new interview in the current chat
+func showInterviewDialog(w fyne.Window) {
+	chat := currentChatEntry()
+	if chat == nil {
+		return
+	}
+	chatID := chat.ID
+
+	goalEntry := widget.NewMultiLineEntry()
+	goalEntry.Wrapping = fyne.TextWrapWord
+	goalEntry.SetPlaceHolder("e.g. Spec out the export feature")
+
+	dialog.ShowForm("Interview me", "Start", "Cancel",
+		[]*widget.FormItem{{Text: "Goal", Widget: goalEntry}},
+		func(ok bool) {
+			goal := strings.TrimSpace(goalEntry.Text)
+			if !ok || goal == "" {
+				return
+			}
+			startInterview(chatID, goal)
+		}, w)
+}
+
+// refreshInterviewBar shows the finish control when the current chat is interviewing
+func refreshInterviewBar() {
+	if interviewBar == nil {
+		return
+	}
+	chat := currentChatEntry()
+	if chat == nil || chat.Mode != chatModeInterview {
+		interviewBar.Hide()
+		return
+	}
+
+	chatID := chat.ID
+	var finishBtn *widget.Button
+	finishBtn = widget.NewButtonWithIcon("Finish and write document", theme.ConfirmIcon(), func() {
+		finishBtn.Disable()
+		go finishInterview(chatID)
+	})
+	finishBtn.Importance = widget.HighImportance
+
+	interviewBar.Objects = []fyne.CanvasObject{
+		container.NewHBox(widget.NewLabel("Interview: "+chat.InterviewGoal), layout.NewSpacer(), finishBtn),
+	}
+	interviewBar.Refresh()
+	interviewBar.Show()
+}
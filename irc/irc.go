@@ -0,0 +1,163 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineLength keeps PRIVMSG lines well below the 512 byte protocol limit
+const maxLineLength = 400
+
+// dialTimeout bounds connecting to the server
+const dialTimeout = 30 * time.Second
+
+// Handler answers a mention of the bot by sender; an empty answer sends nothing
+type Handler func(sender, text string) string
+
+// Client is a minimal IRC client that joins one channel and answers mentions
+type Client struct {
+	Server   string // host:port
+	TLS      bool
+	Nick     string
+	Password string // Optional server password
+	Channel  string
+}
+
+// Run connects, joins the channel and calls handle for every message that
+// mentions the bot until ctx is cancelled or the connection drops
+func (c *Client) Run(ctx context.Context, handle Handler) error {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var err error
+	if c.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.Server, &tls.Config{})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.Server)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", c.Server, err)
+	}
+	defer conn.Close()
+
+	// Unblock the reader when the bridge is stopped
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	send := func(format string, args ...interface{}) error {
+		_, err := fmt.Fprintf(conn, format+"\r\n", args...)
+		return err
+	}
+
+	nick := c.Nick
+	if c.Password != "" {
+		send("PASS %s", c.Password)
+	}
+	send("NICK %s", nick)
+	send("USER %s 0 * :%s", nick, nick)
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("connection to %s lost: %v", c.Server, err)
+		}
+		prefix, command, params := parse(strings.TrimRight(line, "\r\n"))
+
+		switch command {
+		case "PING":
+			send("PONG :%s", strings.Join(params, " "))
+		case "001":
+			send("JOIN %s", c.Channel)
+		case "433":
+			// Nickname in use
+			nick += "_"
+			send("NICK %s", nick)
+		case "PRIVMSG":
+			if len(params) < 2 || !strings.EqualFold(params[0], c.Channel) {
+				continue
+			}
+			sender, _, _ := strings.Cut(prefix, "!")
+			text, ok := mention(params[1], nick)
+			if !ok {
+				continue
+			}
+			for _, reply := range split(handle(sender, text), maxLineLength) {
+				if err := send("PRIVMSG %s :%s: %s", c.Channel, sender, reply); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// parse splits a raw line into its prefix, command and parameters
+func parse(line string) (string, string, []string) {
+	var prefix string
+	if strings.HasPrefix(line, ":") {
+		prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	var trailing string
+	hasTrailing := false
+	if i := strings.Index(line, " :"); i >= 0 {
+		trailing = line[i+2:]
+		line = line[:i]
+		hasTrailing = true
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return prefix, "", nil
+	}
+	params := fields[1:]
+	if hasTrailing {
+		params = append(params, trailing)
+	}
+	return prefix, strings.ToUpper(fields[0]), params
+}
+
+// mention returns the text of a message addressed to nick, as in "nick: hi",
+// "nick, hi" or "@nick hi"
+func mention(text, nick string) (string, bool) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(text), "@")
+	if len(trimmed) < len(nick) || !strings.EqualFold(trimmed[:len(nick)], nick) {
+		return "", false
+	}
+	rest := trimmed[len(nick):]
+	if rest != "" && !strings.ContainsAny(rest[:1], ":, ") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimLeft(rest, ":,")), true
+}
+
+// split breaks text into lines of at most limit bytes; IRC has no multi-line messages
+func split(text string, limit int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		for len(line) > limit {
+			cut := strings.LastIndex(line[:limit], " ")
+			if cut <= 0 {
+				cut = limit
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+			}
+			lines = append(lines, line[:cut])
+			line = strings.TrimSpace(line[cut:])
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
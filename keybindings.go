@@ -0,0 +1,268 @@
+package main
+
+import (
+	"runtime"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// Keybinding modes of the message input
+const (
+	keybindingsDefault = "Default"
+	keybindingsVim     = "Vim"
+	keybindingsEmacs   = "Emacs"
+)
+
+// keybindingModes are the modes offered in the settings
+var keybindingModes = []string{keybindingsDefault, keybindingsVim, keybindingsEmacs}
+
+// refreshKeybindings applies the keybinding mode of the settings to the
+// message input
+func refreshKeybindings() {
+	mode, err := database.GetPreference(appCtx, database.PrefKeybindings)
+	if err != nil || mode == "" {
+		mode = keybindingsDefault
+	}
+	chatInput.setKeybindings(mode)
+}
+
+// setKeybindings switches the keybinding mode; vim starts in insert mode so
+// typing works as before
+func (e *CustomEntry) setKeybindings(mode string) {
+	e.keybindings = mode
+	e.pending = 0
+	e.setNormal(false)
+}
+
+// setNormal switches between vim's normal and insert mode
+func (e *CustomEntry) setNormal(normal bool) {
+	e.normal = normal
+	if e.onModeChange != nil {
+		e.onModeChange()
+	}
+}
+
+// modeName describes the vim mode for the status line, empty in other modes
+func (e *CustomEntry) modeName() string {
+	switch {
+	case e.keybindings != keybindingsVim:
+		return ""
+	case e.normal:
+		return "-- NORMAL --"
+	default:
+		return "-- INSERT --"
+	}
+}
+
+// TypedRune runs vim commands in normal mode and types otherwise
+func (e *CustomEntry) TypedRune(r rune) {
+	if e.keybindings == keybindingsVim && e.normal {
+		if r == 'u' && e.pending == 0 {
+			e.undoDraft()
+			return
+		}
+		e.edit(false, func() { e.vimCommand(r) })
+		return
+	}
+	e.edit(r != ' ' && r != '\n', func() { e.Entry.TypedRune(r) })
+}
+
+// key sends a key to the entry as if it was typed
+func (e *CustomEntry) key(name fyne.KeyName) {
+	e.Entry.TypedKey(&fyne.KeyEvent{Name: name})
+}
+
+// selecting moves the cursor with move while shift is held, selecting the
+// text passed over
+func (e *CustomEntry) selecting(move func()) {
+	e.Entry.KeyDown(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+	move()
+	e.Entry.KeyUp(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+}
+
+// word moves the cursor a word left or right, selecting with selectText
+func (e *CustomEntry) word(name fyne.KeyName, selectText bool) {
+	// The entry moves by word with the shortcut modifier, or Alt on macOS
+	modifier := fyne.KeyModifierShortcutDefault
+	if runtime.GOOS == "darwin" {
+		modifier = fyne.KeyModifierAlt
+	}
+	if selectText {
+		modifier |= fyne.KeyModifierShift
+	}
+	e.Entry.TypedShortcut(&desktop.CustomShortcut{KeyName: name, Modifier: modifier})
+}
+
+// cut moves the selection to the clipboard
+func (e *CustomEntry) cut() {
+	if e.SelectedText() != "" {
+		e.Entry.TypedShortcut(&fyne.ShortcutCut{Clipboard: mainWindow.Clipboard()})
+	}
+}
+
+// yank inserts the clipboard at the cursor without asking how to attach it
+func (e *CustomEntry) yank() {
+	e.Entry.TypedShortcut(&fyne.ShortcutPaste{Clipboard: mainWindow.Clipboard()})
+}
+
+// vimCommand runs a normal mode command. Lines are the rows shown, so a
+// wrapped paragraph counts as several lines.
+func (e *CustomEntry) vimCommand(r rune) {
+	// d, c and y wait for the motion they apply to
+	if operator := e.pending; operator != 0 {
+		e.pending = 0
+		switch {
+		case r == operator:
+			e.key(fyne.KeyHome)
+			e.selecting(func() { e.key(fyne.KeyEnd) })
+		case r == 'w':
+			e.word(fyne.KeyRight, true)
+		case r == 'b':
+			e.word(fyne.KeyLeft, true)
+		case r == '$':
+			e.selecting(func() { e.key(fyne.KeyEnd) })
+		case r == '0':
+			e.selecting(func() { e.key(fyne.KeyHome) })
+		default:
+			return
+		}
+		switch operator {
+		case 'y':
+			e.Entry.TypedShortcut(&fyne.ShortcutCopy{Clipboard: mainWindow.Clipboard()})
+			e.key(fyne.KeyHome)
+		case 'd':
+			e.cut()
+			if r == operator {
+				e.key(fyne.KeyDelete) // dd also removes the line break
+			}
+		case 'c':
+			e.cut()
+			e.setNormal(false)
+		}
+		return
+	}
+
+	switch r {
+	case 'h':
+		e.key(fyne.KeyLeft)
+	case 'l':
+		e.key(fyne.KeyRight)
+	case 'j':
+		e.key(fyne.KeyDown)
+	case 'k':
+		e.key(fyne.KeyUp)
+	case '0':
+		e.key(fyne.KeyHome)
+	case '$':
+		e.key(fyne.KeyEnd)
+	case 'w':
+		e.word(fyne.KeyRight, false)
+	case 'b':
+		e.word(fyne.KeyLeft, false)
+	case 'g':
+		e.key(fyne.KeyPageUp)
+	case 'G':
+		e.key(fyne.KeyPageDown)
+	case 'x':
+		e.key(fyne.KeyDelete)
+	case 'X':
+		e.key(fyne.KeyBackspace)
+	case 'D':
+		e.selecting(func() { e.key(fyne.KeyEnd) })
+		e.cut()
+	case 'C':
+		e.selecting(func() { e.key(fyne.KeyEnd) })
+		e.cut()
+		e.setNormal(false)
+	case 'd', 'c', 'y':
+		e.pending = r
+	case 'p':
+		e.yank()
+	case 'i':
+		e.setNormal(false)
+	case 'a':
+		e.key(fyne.KeyRight)
+		e.setNormal(false)
+	case 'I':
+		e.key(fyne.KeyHome)
+		e.setNormal(false)
+	case 'A':
+		e.key(fyne.KeyEnd)
+		e.setNormal(false)
+	case 'o':
+		e.key(fyne.KeyEnd)
+		e.Entry.TypedRune('\n')
+		e.setNormal(false)
+	case 'O':
+		e.key(fyne.KeyHome)
+		e.Entry.TypedRune('\n')
+		e.key(fyne.KeyUp)
+		e.setNormal(false)
+	}
+}
+
+// emacsShortcut runs the Emacs binding of a shortcut and reports whether there
+// is one. Ctrl+A and Ctrl+Y reach the entry as select all and redo.
+func (e *CustomEntry) emacsShortcut(shortcut fyne.Shortcut) bool {
+	switch s := shortcut.(type) {
+	case *fyne.ShortcutSelectAll:
+		e.key(fyne.KeyHome)
+		return true
+	case *fyne.ShortcutRedo:
+		e.yank()
+		return true
+	case *desktop.CustomShortcut:
+		switch s.Modifier {
+		case fyne.KeyModifierControl:
+			switch s.KeyName {
+			case fyne.KeyA:
+				e.key(fyne.KeyHome)
+			case fyne.KeyE:
+				e.key(fyne.KeyEnd)
+			case fyne.KeyF:
+				e.key(fyne.KeyRight)
+			case fyne.KeyB:
+				e.key(fyne.KeyLeft)
+			case fyne.KeyN:
+				e.key(fyne.KeyDown)
+			case fyne.KeyP:
+				e.key(fyne.KeyUp)
+			case fyne.KeyD:
+				e.key(fyne.KeyDelete)
+			case fyne.KeyH:
+				e.key(fyne.KeyBackspace)
+			case fyne.KeyK:
+				// Kill to the end of the line, or the line break at its end
+				e.selecting(func() { e.key(fyne.KeyEnd) })
+				if e.SelectedText() == "" {
+					e.key(fyne.KeyDelete)
+				}
+				e.cut()
+			case fyne.KeyY:
+				e.yank()
+			default:
+				return false
+			}
+			return true
+		case fyne.KeyModifierAlt:
+			switch s.KeyName {
+			case fyne.KeyF:
+				e.word(fyne.KeyRight, false)
+			case fyne.KeyB:
+				e.word(fyne.KeyLeft, false)
+			case fyne.KeyD:
+				e.word(fyne.KeyRight, true)
+				e.cut()
+			case fyne.KeyBackspace:
+				e.word(fyne.KeyLeft, true)
+				e.cut()
+			default:
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/liveview"
+)
+
+// liveView holds the running live view server, nil when it is off
+var liveView struct {
+	sync.Mutex
+	server *liveview.Server
+	cancel context.CancelFunc
+}
+
+// liveViewAddress returns the loopback address of the live view, or an empty
+// string when no port is set
+func liveViewAddress() string {
+	value, err := database.GetPreference(appCtx, database.PrefLiveViewPort)
+	if err != nil {
+		return ""
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port <= 0 || port > 65535 {
+		return ""
+	}
+	return net.JoinHostPort("127.0.0.1", value)
+}
+
+// restartLiveView stops the live view and starts it again on the port of the
+// settings, showing the active chat
+func restartLiveView() {
+	liveView.Lock()
+	if liveView.cancel != nil {
+		liveView.cancel()
+		liveView.server, liveView.cancel = nil, nil
+	}
+	addr := liveViewAddress()
+	if addr != "" {
+		ctx, cancel := context.WithCancel(appCtx)
+		server, err := liveview.Start(ctx, addr)
+		if err != nil {
+			cancel()
+			log.Printf("Failed to start the live view: %v", err)
+		} else {
+			log.Printf("Live view of the active chat at http://%s", addr)
+			liveView.server, liveView.cancel = server, cancel
+		}
+	}
+	liveView.Unlock()
+
+	if currentChat != nil {
+		publishLiveView(currentChat.ID, "")
+	}
+}
+
+// publishLiveView shows a chat on the live view when it is the active chat;
+// streaming is the answer received so far, empty when none is streaming
+func publishLiveView(chatID int, streaming string) {
+	liveView.Lock()
+	server := liveView.server
+	liveView.Unlock()
+	if server == nil || currentChat == nil || currentChat.ID != chatID {
+		return
+	}
+
+	messages := make([]liveview.Message, 0, len(currentChat.Messages)+1)
+	for _, msg := range currentChat.Messages {
+		messages = append(messages, liveview.Message{Sender: msg.Sender, Text: msg.Text, IsAI: msg.IsAI})
+	}
+	if streaming != "" {
+		messages = append(messages, liveview.Message{Sender: "AI", Text: streaming, IsAI: true})
+	}
+	server.Set(currentChat.Title, messages)
+}
@@ -0,0 +1,146 @@
+package liveview
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// page polls the transcript and shows the last messages over a transparent
+// background
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Live chat</title>
+<style>
+/* Transparent, so only the messages show over the stream. Add ?last=N to
+   the address to show fewer or more messages, ?title=0 to hide the title. */
+html, body { background: transparent; margin: 0; }
+body { font-family: system-ui, sans-serif; font-size: 22px; color: #f8f8f2; padding: 16px; }
+h1 { font-size: 18px; font-weight: 600; opacity: 0.8; margin: 0 0 12px; text-shadow: 0 1px 3px #000; }
+.message { max-width: 85%; margin: 0 0 12px; padding: 10px 14px; border-radius: 14px; background: rgba(40, 42, 54, 0.85); white-space: pre-wrap; overflow-wrap: anywhere; }
+.message.you { margin-left: auto; background: rgba(98, 114, 164, 0.9); }
+.sender { display: block; font-size: 14px; opacity: 0.7; margin-bottom: 4px; }
+</style>
+</head>
+<body>
+<h1 id="title"></h1>
+<div id="messages"></div>
+<script>
+const params = new URLSearchParams(location.search);
+const last = parseInt(params.get("last") || "6", 10);
+if (params.get("title") === "0") document.getElementById("title").style.display = "none";
+let version = -1;
+
+async function poll() {
+  try {
+    const response = await fetch("/transcript", {cache: "no-store"});
+    const t = await response.json();
+    if (t.version !== version) {
+      version = t.version;
+      render(t);
+    }
+  } catch (e) {
+    // The app is closed or restarting; keep the last view
+  }
+  setTimeout(poll, 500);
+}
+
+function render(t) {
+  document.getElementById("title").textContent = t.title || "";
+  const list = document.getElementById("messages");
+  list.replaceChildren();
+  for (const m of (t.messages || []).slice(-last)) {
+    const div = document.createElement("div");
+    div.className = m.ai ? "message" : "message you";
+    const sender = document.createElement("span");
+    sender.className = "sender";
+    sender.textContent = m.sender;
+    div.append(sender, m.text);
+    list.append(div);
+  }
+  window.scrollTo(0, document.body.scrollHeight);
+}
+
+poll();
+</script>
+</body>
+</html>
+`
+
+// Message is a message shown on the page
+type Message struct {
+	Sender string `json:"sender"`
+	Text   string `json:"text"` // Markdown, shown as plain text
+	IsAI   bool   `json:"ai"`
+}
+
+// transcript is what the page polls for
+type transcript struct {
+	Version  int       `json:"version"` // Changes whenever the chat does
+	Title    string    `json:"title"`
+	Messages []Message `json:"messages"`
+}
+
+// Server serves a read-only web page mirroring the chat last set with Set,
+// styled to be shown as a browser source in streaming software like OBS
+type Server struct {
+	mu      sync.Mutex
+	current transcript
+	server  *http.Server
+}
+
+// Start listens on addr, which should be a loopback address, and serves the
+// page until ctx is cancelled
+func Start(ctx context.Context, addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.servePage)
+	mux.HandleFunc("/transcript", s.serveTranscript)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go s.server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		s.server.Close()
+	}()
+	return s, nil
+}
+
+// Set replaces the chat shown on the page
+func (s *Server) Set(title string, messages []Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = transcript{Version: s.current.Version + 1, Title: title, Messages: messages}
+}
+
+func (s *Server) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, page)
+}
+
+func (s *Server) serveTranscript(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	data, err := json.Marshal(s.current)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Turn is one message in a multi-agent transcript
+type Turn struct {
+	Speaker string
+	Text    string
+}
+
+// agentSystemPrompt frames the conversation for a single participant
+const agentSystemPrompt = `You are %s, one of several participants in a group conversation with: %s.
+Reply only as %s, in your own voice, and do not write lines for anyone else.`
+
+// AgentReply generates the next message for speaker in a shared transcript.
+// The speaker's own earlier turns are sent as assistant messages and everyone
+// else's as user messages prefixed with their name. The call does not touch the
+// chat memory, so agents never see each other's private history.
+func AgentReply(ctx context.Context, modelName, speaker, instructions string, participants []string, transcript []Turn) (string, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+
+	others := make([]string, 0, len(participants))
+	for _, p := range participants {
+		if p != speaker {
+			others = append(others, p)
+		}
+	}
+	system := fmt.Sprintf(agentSystemPrompt, speaker, strings.Join(others, ", "), speaker)
+	if instructions != "" {
+		system += "\n\n" + instructions
+	}
+
+	messages := make([]llms.MessageContent, 0, len(transcript))
+	for _, turn := range transcript {
+		if turn.Speaker == speaker {
+			messages = append(messages, llms.TextParts(llms.ChatMessageTypeAI, turn.Text))
+			continue
+		}
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("%s: %s", turn.Speaker, turn.Text)))
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role == llms.ChatMessageTypeAI {
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, "Moderator: please continue."))
+	}
+
+	reply, err := generate(ctx, modelName, model, withSystemPrompt(system, messages))
+	if err != nil {
+		return "", fmt.Errorf("%s failed to reply: %v", speaker, err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(reply), speaker+":")), nil
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+)
+
+// auditContentLimit bounds the bytes of each body kept in the audit log
+const auditContentLimit = 64 * 1024
+
+// providerClient returns the shared HTTP client wrapped to record the requests
+// to model providers in the audit log when it is enabled, and to record or
+// replay them as fixtures when LLMSCHAT_VCR is set
+func providerClient(ctx context.Context) (*http.Client, error) {
+	client, err := HTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: auditTransport{vcrTransport(client.Transport)}, Timeout: client.Timeout}, nil
+}
+
+// auditTransport records the metadata of every request, and its bodies when
+// content auditing is enabled. API keys travel in headers, which are never
+// recorded.
+type auditTransport struct {
+	base http.RoundTripper
+}
+
+func (t auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if enabled, _ := database.GetPreference(ctx, database.PrefAuditLog); enabled != "true" {
+		return t.base.RoundTrip(req)
+	}
+	withContent, _ := database.GetPreference(ctx, database.PrefAuditContent)
+
+	u := *req.URL
+	u.RawQuery = ""
+	entry := database.AuditEntry{Method: req.Method, URL: u.String(), RequestBytes: req.ContentLength}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			var payload struct {
+				Model string `json:"model"`
+			}
+			if json.Unmarshal(data, &payload) == nil {
+				entry.Model = payload.Model
+			}
+			if withContent == "true" {
+				entry.RequestBody = string(data[:min(len(data), auditContentLimit)])
+			}
+		}
+	}
+
+	started := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Latency = time.Since(started)
+		saveAuditEntry(ctx, entry)
+		return nil, err
+	}
+	entry.Status = resp.StatusCode
+	resp.Body = &auditBody{ReadCloser: resp.Body, ctx: ctx, entry: entry, started: started, withContent: withContent == "true"}
+	return resp, nil
+}
+
+// auditBody counts, and when enabled keeps, the response body as it is read
+// and records the request once it is closed
+type auditBody struct {
+	io.ReadCloser
+	ctx         context.Context
+	entry       database.AuditEntry
+	started     time.Time
+	withContent bool
+	content     bytes.Buffer
+	once        sync.Once
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.entry.ResponseBytes += int64(n)
+	if b.withContent && b.content.Len() < auditContentLimit {
+		b.content.Write(p[:min(n, auditContentLimit-b.content.Len())])
+	}
+	if err != nil && err != io.EOF {
+		b.entry.Error = err.Error()
+	}
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.entry.Latency = time.Since(b.started)
+		b.entry.ResponseBody = b.content.String()
+		saveAuditEntry(b.ctx, b.entry)
+	})
+	return err
+}
+
+// saveAuditEntry records entry even when the request was cancelled
+func saveAuditEntry(ctx context.Context, entry database.AuditEntry) {
+	if err := database.SaveAuditEntry(context.WithoutCancel(ctx), entry); err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
+	}
+}
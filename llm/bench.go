@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// BenchResult is the measurement of one benchmark run
+type BenchResult struct {
+	TimeToFirstToken time.Duration
+	Duration         time.Duration
+	TokensPerSecond  float64
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // USD, 0 when the model has no known price
+	Priced           bool
+}
+
+// Bench streams prompt to modelName once, without any chat memory, and
+// measures the answer. The model may belong to any provider with a stored API
+// key; "Company/model" picks the provider when several offer the model.
+func Bench(ctx context.Context, modelName, prompt string) (BenchResult, error) {
+	var result BenchResult
+	company, apiKey, name, err := modelProvider(ctx, modelName)
+	if err != nil {
+		return result, err
+	}
+	model, err := newCompanyLLM(ctx, company, apiKey, name)
+	if err != nil {
+		return result, err
+	}
+
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	metrics := NewStreamMetrics()
+	started := time.Now()
+	resp, err := model.GenerateContent(ctx, messages,
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			metrics.Chunk()
+			return nil
+		}))
+	if err != nil {
+		return result, err
+	}
+	if len(resp.Choices) == 0 {
+		return result, fmt.Errorf("empty response from model")
+	}
+	answer := resp.Choices[0].Content
+	metrics.Finish(answer)
+	usage := recordUsage(ctx, name, messages, resp, answer, started)
+
+	// Reported token counts are more accurate than the estimate of Finish
+	metrics.Tokens = usage.CompletionTokens
+	result.TimeToFirstToken = metrics.TimeToFirstToken()
+	result.Duration = metrics.Duration()
+	result.TokensPerSecond = metrics.TokensPerSecond()
+	result.PromptTokens = usage.PromptTokens
+	result.CompletionTokens = usage.CompletionTokens
+	result.Cost, result.Priced = EstimateCost(name, usage.PromptTokens, usage.CompletionTokens)
+	return result, nil
+}
+
+// modelProvider finds the company serving modelName and its API key,
+// preferring the company of the settings, and returns the bare model name
+func modelProvider(ctx context.Context, modelName string) (database.Company, string, string, error) {
+	// Models of other providers work without settings
+	settings, err := database.GetSettings(ctx)
+	if err != nil {
+		return database.Company{}, "", "", fmt.Errorf("failed to get settings: %v", err)
+	}
+	if settings == nil {
+		settings = &database.Settings{}
+	}
+	companies, err := database.GetCompanies(ctx)
+	if err != nil {
+		return database.Company{}, "", "", fmt.Errorf("failed to get companies: %v", err)
+	}
+
+	// Model names may contain slashes, so only a known company is a prefix
+	name := modelName
+	if prefix, rest, ok := strings.Cut(modelName, "/"); ok {
+		for _, company := range companies {
+			if strings.EqualFold(company.Name, prefix) {
+				companies, name = []database.Company{company}, rest
+				break
+			}
+		}
+	}
+
+	// The company of the settings comes first
+	for i, company := range companies {
+		if company.ID == settings.CompanyID {
+			companies[0], companies[i] = companies[i], companies[0]
+			break
+		}
+	}
+	for _, company := range companies {
+		models, err := database.GetModelsByCompany(ctx, company.ID)
+		if err != nil {
+			return database.Company{}, "", "", fmt.Errorf("failed to get models: %v", err)
+		}
+		for _, model := range models {
+			if model.Name != name {
+				continue
+			}
+			if company.ID == settings.CompanyID {
+				return company, settings.APIKey, name, nil
+			}
+			apiKey, err := database.GetAPIKey(ctx, company.ID)
+			if err != nil {
+				return database.Company{}, "", "", fmt.Errorf("failed to get the %s API key: %v", company.Name, err)
+			}
+			return company, apiKey, name, nil
+		}
+	}
+	return database.Company{}, "", "", fmt.Errorf("no provider offers the model %q", modelName)
+}
@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// CacheEnabled reports whether the response cache is turned on in preferences
+func CacheEnabled(ctx context.Context) bool {
+	value, err := database.GetPreference(ctx, database.PrefResponseCache)
+	return err == nil && value == "true"
+}
+
+// cacheKey hashes the model and the full request context
+func cacheKey(model string, messages []llms.MessageContent) string {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(model+"\x00"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookupCache returns the cached answer for key if caching applies to req
+func lookupCache(ctx context.Context, key string, req Request) (string, bool) {
+	if key == "" || req.NoCache || !CacheEnabled(ctx) {
+		return "", false
+	}
+	response, ok, err := database.GetCachedResponse(ctx, key)
+	if err != nil {
+		log.Printf("Failed to read response cache: %v", err)
+		return "", false
+	}
+	return response, ok
+}
+
+// storeCache saves response under key if caching applies to req
+func storeCache(ctx context.Context, key string, req Request, response string) {
+	if key == "" || req.NoCache || response == "" || !CacheEnabled(ctx) {
+		return
+	}
+	if err := database.SaveCachedResponse(ctx, key, req.Model, response); err != nil {
+		log.Printf("Failed to write response cache: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// changelogPrompt turns project conversations into release notes
+const changelogPrompt = `You draft the changelog of a software project from the conversations its team had with
+an assistant. Keep only what was decided or changed: features added, behavior changed, bugs fixed,
+removals and notable decisions. Leave out questions that led nowhere, explanations and chit-chat.
+Write Markdown with a title naming the period, then the sections Added, Changed, Fixed, Removed and
+Decisions, leaving out empty sections. Each entry is one line; mention the code touched when known.`
+
+// changelogChatSize bounds how much of each conversation is sent, keeping its
+// latest messages
+const changelogChatSize = 24000
+
+// ChangelogChat is a conversation a changelog is drafted from
+type ChangelogChat struct {
+	Title      string
+	Transcript []Turn
+}
+
+// DraftChangelog asks the model for a Markdown changelog of the period named
+// period from the decisions and code changes discussed in chats
+func DraftChangelog(ctx context.Context, modelName, period string, chats []ChangelogChat) (string, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Draft the changelog for %s from these conversations.\n", period)
+	for _, chat := range chats {
+		var transcript strings.Builder
+		for _, turn := range chat.Transcript {
+			fmt.Fprintf(&transcript, "%s: %s\n", turn.Speaker, turn.Text)
+		}
+		text := transcript.String()
+		if len(text) > changelogChatSize {
+			text = "…" + text[len(text)-changelogChatSize:]
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n%s", chat.Title, text)
+	}
+
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, b.String())}
+	reply, err := generate(ctx, modelName, model, withSystemPrompt(changelogPrompt, messages))
+	if err != nil {
+		return "", fmt.Errorf("changelog error: %v", err)
+	}
+	return strings.TrimSpace(reply), nil
+}
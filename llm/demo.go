@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/memory/sqlite3"
+)
+
+// DemoCompany is the built-in provider answering with canned responses, for
+// demos, screenshots and UI tests without an API key or network
+const DemoCompany = "Demo"
+
+// Demo models; the instant one streams without pauses, for tests
+const (
+	DemoModel        = "demo"
+	DemoInstantModel = "demo-instant"
+)
+
+// demoEnv forces the demo provider for every request when set, whatever the
+// settings say
+const demoEnv = "LLMSCHAT_DEMO"
+
+// Pauses of the demo stream, to look like a real model
+const (
+	demoFirstToken = 400 * time.Millisecond
+	demoMinToken   = 15 * time.Millisecond
+	demoMaxToken   = 60 * time.Millisecond
+)
+
+// demoTokens splits an answer into the words it is streamed in
+var demoTokens = regexp.MustCompile(`\S+\s*|\s+`)
+
+// demoAnswers are the canned answers, picked by the first keyword found in the
+// prompt
+var demoAnswers = []struct {
+	keywords []string
+	answer   string
+}{
+	{[]string{"hello", "hi ", "hey"}, "Hello! I am the demo model. I answer with canned responses, so you can try the app without an API key. Ask me for some **code**, a **table** or a **list** of steps."},
+	{[]string{"code", "function", "golang", "program"}, "Here is a small Go function:\n\n```go\n// Reverse returns s with its runes in reverse order\nfunc Reverse(s string) string {\n\tr := []rune(s)\n\tfor i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {\n\t\tr[i], r[j] = r[j], r[i]\n\t}\n\treturn string(r)\n}\n```\n\nIt swaps runes from both ends, so it works with any Unicode text."},
+	{[]string{"table", "compare", "versus", " vs "}, "| Option | Speed | Cost |\n|---|---|---|\n| Small model | Fast | Low |\n| Large model | Slower | Higher |\n| Demo model | Instant | Free |\n\nThe demo model wins on price, but its answers are canned."},
+	{[]string{"list", "steps", "how do", "how to"}, "Here are the steps:\n\n1. Open the settings and pick a provider.\n2. Paste your API key.\n3. Choose a model and save.\n4. Start a new chat and ask away.\n\nUntil then, the demo provider keeps answering."},
+}
+
+// demoFallbacks answer prompts without a keyword, picked by the prompt's hash
+var demoFallbacks = []string{
+	"This is a demo answer to “%s”. No model was asked: the demo provider streams canned text so the app can be shown without an API key or network.",
+	"Good question about “%s”. In a real chat a model would answer here; the demo provider only pretends, with realistic streaming and token counts.",
+	"You asked: “%s”. Pick a real provider in the settings for a real answer. Meanwhile, try asking for code, a table or a list.",
+}
+
+// demoForced reports whether the demo provider answers every request
+func demoForced() bool {
+	return os.Getenv(demoEnv) != ""
+}
+
+// demoLLM is the model of the demo provider
+type demoLLM struct {
+	instant bool // Stream without pauses
+}
+
+// demoAnswer returns the canned answer to the last user message of messages
+func demoAnswer(messages []llms.MessageContent) string {
+	var prompt string
+	for _, msg := range messages {
+		if msg.Role != llms.ChatMessageTypeHuman {
+			continue
+		}
+		prompt = ""
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompt += text.Text
+			}
+		}
+	}
+
+	lower := " " + strings.ToLower(prompt) + " "
+	for _, a := range demoAnswers {
+		for _, keyword := range a.keywords {
+			if strings.Contains(lower, keyword) {
+				return a.answer
+			}
+		}
+	}
+
+	quoted := strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(quoted); len(runes) > 60 {
+		quoted = string(runes[:60]) + "…"
+	}
+	h := fnv.New32a()
+	h.Write([]byte(prompt))
+	return fmt.Sprintf(demoFallbacks[h.Sum32()%uint32(len(demoFallbacks))], quoted)
+}
+
+// GenerateContent answers with a canned response, streamed word by word with
+// pauses when a streaming function is given
+func (d *demoLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	answer := demoAnswer(messages)
+
+	if opts.StreamingFunc != nil {
+		// Seeded by the answer, so the same answer always streams alike
+		h := fnv.New64a()
+		h.Write([]byte(answer))
+		random := rand.New(rand.NewSource(int64(h.Sum64())))
+		pause := demoFirstToken
+		for _, token := range demoTokens.FindAllString(answer, -1) {
+			if !d.instant {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(pause):
+				}
+				pause = demoMinToken + time.Duration(random.Int63n(int64(demoMaxToken-demoMinToken)))
+			}
+			if err := opts.StreamingFunc(ctx, []byte(token)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	promptTokens := 0
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				promptTokens += EstimateTokens(text.Text)
+			}
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:    answer,
+		StopReason: "stop",
+		GenerationInfo: map[string]any{
+			"PromptTokens":     promptTokens,
+			"CompletionTokens": EstimateTokens(answer),
+		},
+	}}}, nil
+}
+
+// Call answers a single prompt
+func (d *demoLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, d, prompt, options...)
+}
+
+// demoClient chats with the demo model, keeping the memory like the real
+// providers so the app behaves the same
+type demoClient struct {
+	client *demoLLM
+	memory *sqlite3.SqliteChatMessageHistory
+}
+
+func (d *demoClient) Chat(ctx context.Context, req Request) (string, error) {
+	if err := addPrompt(ctx, d.memory, &req); err != nil {
+		return "", fmt.Errorf("failed to save user message: %v", err)
+	}
+	messages := withSystemPrompt(req.SystemPrompt, requestMessages(ctx, d.client, d.memory, req))
+	return complete(ctx, d.client, d.memory, req, messages)
+}
+
+func (d *demoClient) StreamChat(ctx context.Context, req Request) (<-chan string, error) {
+	stream := make(chan string)
+
+	go func() {
+		defer close(stream)
+
+		if err := addPrompt(ctx, d.memory, &req); err != nil {
+			req.finish(Result{Model: req.Model, Err: fmt.Errorf("failed to save user message: %v", err)})
+			return
+		}
+		messages := withSystemPrompt(req.SystemPrompt, requestMessages(ctx, d.client, d.memory, req))
+		if err := streamCompletion(ctx, d.client, d.memory, req, messages, stream); err != nil {
+			req.finish(Result{Model: req.Model, Err: fmt.Errorf("demo chat error: %v", err)})
+		}
+	}()
+
+	return stream, nil
+}
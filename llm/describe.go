@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// describePrompt asks for alt text that also carries the text shown in an image
+const describePrompt = `Describe this image as alt text for someone who cannot see it, in a few sentences. Transcribe any text, numbers, chart labels and values shown in it verbatim. Reply with the description only.`
+
+// visionModels are the models used to describe images, for the providers whose
+// chat models may not accept images
+var visionModels = map[string]string{
+	"OpenAI": "gpt-4o-mini",
+}
+
+// DescribeImage returns alt text for img including the text visible in it, so
+// image answers can be searched and exported as text
+func DescribeImage(ctx context.Context, img Image) (string, error) {
+	settings, company, err := currentCompany(ctx)
+	if err != nil {
+		return "", err
+	}
+	if company.Name == "Anthropic" {
+		return "", errAnthropicImages
+	}
+	modelName, ok := visionModels[company.Name]
+	if !ok {
+		models, err := database.GetModelsByCompany(ctx, settings.CompanyID)
+		if err != nil {
+			return "", err
+		}
+		for _, model := range models {
+			if model.ID == settings.ModelID {
+				modelName = model.Name
+			}
+		}
+	}
+	if modelName == "" {
+		return "", fmt.Errorf("no model to describe images with")
+	}
+
+	img, err = prepareImage(img, defaultImageLimit, true)
+	if err != nil {
+		return "", err
+	}
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+	message := llms.TextParts(llms.ChatMessageTypeHuman, describePrompt)
+	message.Parts = append(message.Parts, llms.ImageURLPart(img.DataURL()))
+	return generate(ctx, modelName, model, []llms.MessageContent{message})
+}
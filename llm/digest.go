@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Digest lengths
+const (
+	DigestShort  = "Short"
+	DigestMedium = "Medium"
+	DigestLong   = "Long"
+)
+
+// digestLengths tells the model how much to write for each length
+var digestLengths = map[string]string{
+	DigestShort:  "one line per story, at most 10 stories",
+	DigestMedium: "one or two sentences per story",
+	DigestLong:   "a short paragraph per story",
+}
+
+// digestPrompt asks the model to summarize news items
+const digestPrompt = `Write today's news digest in %s from the items below.
+Group related stories under Markdown headings by topic, with %s.
+Link every story to its source as a Markdown link, and skip items that are not news.
+
+%s`
+
+// NewsItem is a feed entry passed to Digest
+type NewsItem struct {
+	Source  string
+	Title   string
+	Link    string
+	Summary string
+}
+
+// Digest summarizes items into a Markdown news digest of the given length and language
+func Digest(ctx context.Context, modelName string, items []NewsItem, length, language string) (string, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+
+	instructions, ok := digestLengths[length]
+	if !ok {
+		instructions = digestLengths[DigestMedium]
+	}
+	if language == "" {
+		language = "English"
+	}
+
+	var list strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&list, "- [%s] %s (%s)\n", item.Source, item.Title, item.Link)
+		if item.Summary != "" {
+			fmt.Fprintf(&list, "  %s\n", item.Summary)
+		}
+	}
+
+	digest, err := generate(ctx, modelName, model, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(digestPrompt, language, instructions, list.String())),
+	})
+	if err != nil {
+		return "", fmt.Errorf("digest failed: %v", err)
+	}
+	return digest, nil
+}
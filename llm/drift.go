@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// driftPrompt asks the model to classify whether the latest message starts a new topic
+const driftPrompt = `You are classifying a chat conversation.
+
+Earlier messages:
+%s
+
+Latest message:
+%s
+
+Does the latest message start a topic unrelated to the earlier messages?
+Reply only with JSON: {"drift": true|false, "topic": "<short title of the new topic>"}`
+
+// driftContextMessages is how many earlier messages are shown to the classifier
+const driftContextMessages = 6
+
+// DetectTopicDrift asks the model whether latest moves the conversation away from
+// previous. It returns a short title for the new topic when it does.
+func DetectTopicDrift(ctx context.Context, modelName string, previous []string, latest string) (string, bool, error) {
+	if len(previous) == 0 {
+		return "", false, nil
+	}
+	if len(previous) > driftContextMessages {
+		previous = previous[len(previous)-driftContextMessages:]
+	}
+
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", false, err
+	}
+
+	prompt := fmt.Sprintf(driftPrompt, strings.Join(previous, "\n---\n"), latest)
+	answer, err := llms.GenerateFromSinglePrompt(ctx, model, prompt, llms.WithTemperature(0))
+	if err != nil {
+		return "", false, fmt.Errorf("drift detection failed: %v", err)
+	}
+
+	var result struct {
+		Drift bool   `json:"drift"`
+		Topic string `json:"topic"`
+	}
+	if err := json.Unmarshal([]byte(extractJSON(answer)), &result); err != nil {
+		return "", false, fmt.Errorf("failed to parse drift answer: %v", err)
+	}
+	return strings.TrimSpace(result.Topic), result.Drift && result.Topic != "", nil
+}
+
+// extractJSON returns the JSON object contained in a model answer, dropping any
+// surrounding prose or code fences
+func extractJSON(answer string) string {
+	start := strings.Index(answer, "{")
+	end := strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return answer
+	}
+	return answer[start : end+1]
+}
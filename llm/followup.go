@@ -0,0 +1,29 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// followUpPrompt keeps follow-up results to the transformed text
+const followUpPrompt = `You transform a text an assistant wrote, following the instruction you are given.
+Reply with the result only, in Markdown, without an introduction or a comment on what you changed.`
+
+// FollowUp applies instruction to answer, like translating or simplifying it,
+// without the chat memory, and returns the result
+func FollowUp(ctx context.Context, modelName, instruction, answer string) (string, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf("%s\n\nText:\n\n%s", instruction, answer)
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	reply, err := generate(ctx, modelName, model, withSystemPrompt(followUpPrompt, messages))
+	if err != nil {
+		return "", fmt.Errorf("follow-up error: %v", err)
+	}
+	return strings.TrimSpace(reply), nil
+}
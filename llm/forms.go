@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"gopkg.in/yaml.v3"
+)
+
+// FormField describes one field of a user-supplied form schema
+type FormField struct {
+	Name        string   `json:"name" yaml:"name"`
+	Type        string   `json:"type,omitempty" yaml:"type"`
+	Description string   `json:"description,omitempty" yaml:"description"`
+	Required    bool     `json:"required,omitempty" yaml:"required"`
+	Options     []string `json:"options,omitempty" yaml:"options"`
+}
+
+// formPrompt asks the model to extract field values from a conversation
+const formPrompt = `Extract values for the form below from the conversation.
+
+Form fields (JSON):
+%s
+
+Conversation:
+%s
+
+Reply only with a JSON object mapping each field name to its value, using the field type
+(string, number, boolean, date as YYYY-MM-DD, or one of the listed options).
+Use null for values the conversation does not mention. Do not invent values.`
+
+// ParseFormSchema parses a JSON or YAML form schema, given either as a list of
+// fields or as an object with a "fields" list
+func ParseFormSchema(data []byte) ([]FormField, error) {
+	var fields []FormField
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		var wrapped struct {
+			Fields []FormField `yaml:"fields"`
+		}
+		if err := yaml.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid form schema: %v", err)
+		}
+		fields = wrapped.Fields
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("form schema has no fields")
+	}
+	for i, f := range fields {
+		if strings.TrimSpace(f.Name) == "" {
+			return nil, fmt.Errorf("field %d has no name", i+1)
+		}
+	}
+	return fields, nil
+}
+
+// FillForm maps a conversation onto fields and returns the extracted values
+func FillForm(ctx context.Context, modelName string, fields []FormField, transcript []Turn) (map[string]interface{}, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode form schema: %v", err)
+	}
+	var conversation strings.Builder
+	for _, turn := range transcript {
+		fmt.Fprintf(&conversation, "%s: %s\n\n", turn.Speaker, turn.Text)
+	}
+
+	answer, err := generate(ctx, modelName, model, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(formPrompt, schema, conversation.String())),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("form filling failed: %v", err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(extractJSON(answer)), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse form values: %v", err)
+	}
+
+	// Keep only the requested fields, missing ones as null
+	result := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		result[f.Name] = values[f.Name]
+	}
+	return result, nil
+}
+
+// PostForm sends the form values as JSON to a webhook
+func PostForm(ctx context.Context, url string, values map[string]interface{}) error {
+	body, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to encode form values: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := HTTPClient(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"log"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/memory/sqlite3"
+)
+
+// contextWindows holds the context size in tokens of the default models
+var contextWindows = map[string]int{
+	"gpt-4":              8192,
+	"gpt-4-turbo":        128000,
+	"gpt-4-32k":          32768,
+	"gpt-4o":             128000,
+	"gpt-4o-mini":        128000,
+	"gpt-3.5-turbo":      16385,
+	"gpt-3.5-turbo-16k":  16385,
+	"claude-2.1":         200000,
+	"claude-2.0":         100000,
+	"claude-instant-1.2": 100000,
+	"gemini-pro":         32760,
+	"mistral-7b":         32768,
+	"mixtral-8x7b":       32768,
+	"deepseek-chat":      64000,
+	"deepseek-reasoner":  64000,
+}
+
+// defaultContextWindow is assumed for models not in contextWindows, small
+// enough for most local models
+const defaultContextWindow = 8192
+
+// answerReserve is the part of the context window kept for the answer when
+// the request does not cap its length
+const answerReserve = 1024
+
+// historyBudget returns how many tokens of past messages fit in the context
+// window of the model next to the system prompt, the prompt and the answer,
+// and within the chat's own limit
+func historyBudget(req Request) int {
+	window, ok := contextWindows[req.Model]
+	if !ok {
+		window = defaultContextWindow
+	}
+	reserve := answerReserve
+	if req.MaxTokens > 0 {
+		reserve = req.MaxTokens
+	}
+	budget := window - reserve - EstimateTokens(req.SystemPrompt) - EstimateTokens(req.Prompt)
+	if req.ContextTokens > 0 {
+		budget = min(budget, req.ContextTokens)
+	}
+	return budget
+}
+
+// TurnTokens is the estimated size of a past turn of a chat
+type TurnTokens struct {
+	Tokens int
+	Human  bool
+}
+
+// HistoryTokens returns the estimated tokens of the past turns of a chat,
+// oldest first, for EstimateRequestTokens. Callers keep them until the chat's
+// memory changes instead of reading it for every estimate.
+func HistoryTokens(ctx context.Context, chatID int) ([]TurnTokens, error) {
+	memory := sqlite3.NewSqliteChatMessageHistory(sqlite3.WithDB(database.DB()), sqlite3.WithSession(memorySession(chatID)))
+	history, err := memory.Messages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	turns := make([]TurnTokens, len(history))
+	for i, msg := range history {
+		turns[i] = TurnTokens{Tokens: EstimateTokens(msg.GetContent()), Human: msg.GetType() == llms.ChatMessageTypeHuman}
+	}
+	return turns, nil
+}
+
+// EstimateRequestTokens estimates the input tokens of sending req before its
+// prompt is added to the chat's memory, given the chat's past turns from
+// HistoryTokens: the system prompt, the prompt and the past turns that fit in
+// historyBudget, as requestMessages sends them. Picking relevant turns is
+// skipped, so with context compression the estimate is the most the request
+// sends.
+func EstimateRequestTokens(req Request, history []TurnTokens) int {
+	tokens := EstimateTokens(req.SystemPrompt) + EstimateTokens(req.Prompt)
+	budget := historyBudget(req)
+	if budget <= 0 {
+		return tokens
+	}
+	// The most recent turns that fit, as fitTokens picks them, starting with
+	// a human turn
+	start, used := len(history), 0
+	for start > 0 && used+history[start-1].Tokens <= budget {
+		start--
+		used += history[start].Tokens
+	}
+	for ; start < len(history) && !history[start].Human; start++ {
+		used -= history[start].Tokens
+	}
+	return tokens + used
+}
+
+// requestMessages builds the messages sent with the prompt of req: the past
+// turns of the chat, most recent first, as far as they fit in historyBudget,
+// then the prompt with its images. When context compression is enabled and
+// the model can embed, only the past turns most relevant to the prompt are
+// considered, unless the request skips embeddings.
+func requestMessages(ctx context.Context, model llms.Model, memory *sqlite3.SqliteChatMessageHistory, req Request) []llms.MessageContent {
+	current := llms.TextParts(llms.ChatMessageTypeHuman, req.Prompt)
+	for _, image := range req.Images {
+		current.Parts = append(current.Parts, llms.ImageURLPart(image.DataURL()))
+	}
+
+	budget := historyBudget(req)
+	if budget <= 0 {
+		return []llms.MessageContent{current}
+	}
+	history, err := memory.Messages(ctx)
+	if err != nil {
+		log.Printf("Failed to load history: %v", err)
+		return []llms.MessageContent{current}
+	}
+	// The prompt itself was just added to history, unless the latest answer is
+	// being continued
+	if len(history) > 0 && !req.Continue {
+		history = history[:len(history)-1]
+	}
+
+	// A continuation needs the latest turns as they are, so it skips picking
+	// relevant ones
+	selected := history
+	if e, ok := model.(embedder); ok && !req.NoEmbeddings && !req.Continue {
+		if limit := compressionLimit(ctx); limit > 0 {
+			if selected, err = relevantHistory(ctx, e, history, req.Prompt, limit); err != nil {
+				log.Printf("Failed to select relevant history: %v", err)
+				selected = history
+			}
+		}
+	}
+	selected = fitTokens(selected, budget)
+
+	// Conversations start with the user, whatever was cut off
+	for len(selected) > 0 && selected[0].GetType() != llms.ChatMessageTypeHuman {
+		selected = selected[1:]
+	}
+	return append(toMessageContent(selected), current)
+}
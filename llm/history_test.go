@@ -0,0 +1,27 @@
+package llm
+
+import "testing"
+
+func TestEstimateRequestTokens(t *testing.T) {
+	history := []TurnTokens{{100, true}, {200, false}, {30, true}, {40, false}}
+	req := Request{Prompt: "abcdefgh", Model: "gpt-4o"} // 2 tokens
+	tests := []struct {
+		name          string
+		contextTokens int
+		want          int
+	}{
+		{"everything fits", 0, 2 + 370},
+		{"oldest turns dropped", 250, 2 + 70},
+		{"does not start with an answer", 300, 2 + 70},
+		{"nothing fits", 10, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := req
+			req.ContextTokens = tt.contextTokens
+			if got := EstimateRequestTokens(req, history); got != tt.want {
+				t.Errorf("EstimateRequestTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
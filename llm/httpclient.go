@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+)
+
+// defaultRequestTimeout applies when no timeout is configured
+const defaultRequestTimeout = 5 * time.Minute
+
+// httpConfig holds the network preferences used to build the shared client
+type httpConfig struct {
+	proxy   string
+	timeout string
+	caFile  string
+}
+
+var sharedClient struct {
+	sync.Mutex
+	config httpConfig
+	client *http.Client
+}
+
+// HTTPClient returns the http.Client shared by all provider requests. It honours
+// the proxy (http, https or socks5), timeout and custom CA bundle preferences and
+// is rebuilt when they change.
+func HTTPClient(ctx context.Context) (*http.Client, error) {
+	config, err := loadHTTPConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedClient.Lock()
+	defer sharedClient.Unlock()
+	if sharedClient.client != nil && sharedClient.config == config {
+		return sharedClient.client, nil
+	}
+
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	sharedClient.config = config
+	sharedClient.client = client
+	return client, nil
+}
+
+func loadHTTPConfig(ctx context.Context) (httpConfig, error) {
+	var config httpConfig
+	var err error
+	if config.proxy, err = database.GetPreference(ctx, database.PrefProxyURL); err != nil {
+		return config, fmt.Errorf("failed to get proxy preference: %v", err)
+	}
+	if config.timeout, err = database.GetPreference(ctx, database.PrefRequestTimeout); err != nil {
+		return config, fmt.Errorf("failed to get timeout preference: %v", err)
+	}
+	if config.caFile, err = database.GetPreference(ctx, database.PrefCACertFile); err != nil {
+		return config, fmt.Errorf("failed to get CA preference: %v", err)
+	}
+	return config, nil
+}
+
+func newHTTPClient(config httpConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.proxy != "" {
+		proxyURL, err := url.Parse(config.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.caFile != "" {
+		pem, err := os.ReadFile(config.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	timeout := defaultRequestTimeout
+	if config.timeout != "" {
+		seconds, err := strconv.Atoi(config.timeout)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid request timeout %q", config.timeout)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/devalexandre/llmschat/database"
+)
+
+const (
+	dalleModel  = "dall-e-3"
+	imagenModel = "imagen-3.0-generate-002"
+)
+
+// GenerateImage creates an image from prompt using the configured company's image API
+// and returns the PNG data
+func GenerateImage(ctx context.Context, prompt string) ([]byte, error) {
+	settings, err := database.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %v", err)
+	}
+	if settings == nil {
+		return nil, fmt.Errorf("no settings found, please configure your settings first")
+	}
+
+	company, err := database.GetCompany(ctx, settings.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get company: %v", err)
+	}
+
+	switch company.Name {
+	case "OpenAI":
+		baseURL := company.BaseURL
+		if baseURL == "" {
+			baseURL = defaultOpenAIURL
+		}
+		return generateDalle(ctx, strings.TrimRight(baseURL, "/"), settings.APIKey, prompt)
+	case "Google":
+		return generateImagen(ctx, strings.TrimRight(company.BaseURL, "/"), settings.APIKey, prompt)
+	default:
+		return nil, fmt.Errorf("image generation is not supported for %s", company.Name)
+	}
+}
+
+// generateDalle calls the OpenAI images endpoint
+func generateDalle(ctx context.Context, baseURL, apiKey, prompt string) ([]byte, error) {
+	payload := map[string]interface{}{
+		"model":           dalleModel,
+		"prompt":          prompt,
+		"n":               1,
+		"size":            "1024x1024",
+		"response_format": "b64_json",
+	}
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+
+	var result struct {
+		Data []struct {
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+	}
+	if err := postJSON(ctx, baseURL+"/v1/images/generations", headers, payload, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("no image returned")
+	}
+	return base64.StdEncoding.DecodeString(result.Data[0].B64JSON)
+}
+
+// generateImagen calls the Gemini API Imagen predict endpoint
+func generateImagen(ctx context.Context, baseURL, apiKey, prompt string) ([]byte, error) {
+	payload := map[string]interface{}{
+		"instances":  []map[string]string{{"prompt": prompt}},
+		"parameters": map[string]int{"sampleCount": 1},
+	}
+	headers := map[string]string{"x-goog-api-key": apiKey}
+	url := fmt.Sprintf("%s/v1beta/models/%s:predict", baseURL, imagenModel)
+
+	var result struct {
+		Predictions []struct {
+			BytesBase64Encoded string `json:"bytesBase64Encoded"`
+		} `json:"predictions"`
+	}
+	if err := postJSON(ctx, url, headers, payload, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Predictions) == 0 {
+		return nil, fmt.Errorf("no image returned")
+	}
+	return base64.StdEncoding.DecodeString(result.Predictions[0].BytesBase64Encoded)
+}
+
+// postJSON sends payload as JSON to url and decodes the JSON response into out
+func postJSON(ctx context.Context, url string, headers map[string]string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client, err := providerClient(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
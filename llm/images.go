@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"github.com/devalexandre/llmschat/database"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Image is a picture sent along with a prompt to a vision model
+type Image struct {
+	Name     string
+	MimeType string
+	Data     []byte
+}
+
+// DataURL returns the image as a data: URL
+func (i Image) DataURL() string {
+	return "data:" + i.MimeType + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// imageLimits is the longest side in pixels each provider works with; bigger
+// images are downscaled by the provider anyway, so sending them only costs more
+var imageLimits = map[string]int{
+	"OpenAI":    2048,
+	"Anthropic": 1568,
+}
+
+// defaultImageLimit applies to the providers missing from imageLimits
+const defaultImageLimit = 2048
+
+// errAnthropicImages is returned for images sent to Anthropic models, which the
+// langchaingo client does not pass on
+var errAnthropicImages = errors.New("images can only be sent to OpenAI compatible models")
+
+// StripImageMetadata reports whether EXIF data like the GPS position is removed
+// from images before they are sent, which is the default
+func StripImageMetadata(ctx context.Context) bool {
+	value, err := database.GetPreference(ctx, database.PrefStripImageMetadata)
+	return err != nil || value != "false"
+}
+
+// PrepareImages downscales images to the limit of the selected provider and,
+// unless turned off, strips their metadata. Images that need neither are
+// returned as they are; the others are encoded again, which drops all metadata.
+func PrepareImages(ctx context.Context, images []Image) ([]Image, error) {
+	limit := defaultImageLimit
+	if _, company, err := currentCompany(ctx); err == nil && imageLimits[company.Name] > 0 {
+		limit = imageLimits[company.Name]
+	}
+	strip := StripImageMetadata(ctx)
+
+	prepared := make([]Image, len(images))
+	for i, img := range images {
+		var err error
+		if prepared[i], err = prepareImage(img, limit, strip); err != nil {
+			return nil, err
+		}
+	}
+	return prepared, nil
+}
+
+// prepareImage fits img within limit pixels and strips its metadata if asked
+func prepareImage(img Image, limit int, strip bool) (Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%s is not a supported image: %v", img.Name, err)
+	}
+	oversized := config.Width > limit || config.Height > limit
+	// GIF files carry no EXIF data
+	if !oversized && (!strip || format == "gif") {
+		img.MimeType = "image/" + format
+		return img, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to decode %s: %v", img.Name, err)
+	}
+	// The orientation is lost with the metadata, so apply it to the pixels
+	if format == "jpeg" {
+		decoded = orient(decoded, jpegOrientation(img.Data))
+	}
+	if bounds := decoded.Bounds(); bounds.Dx() > limit || bounds.Dy() > limit {
+		width, height := limit, bounds.Dy()*limit/bounds.Dx()
+		if bounds.Dy() > bounds.Dx() {
+			width, height = bounds.Dx()*limit/bounds.Dy(), limit
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), decoded, bounds, draw.Src, nil)
+		decoded = scaled
+	}
+
+	// Photos stay JPEG; PNG and GIF become PNG to keep their transparency
+	var buf bytes.Buffer
+	ext := ".png"
+	prepared := Image{Name: img.Name, MimeType: "image/png"}
+	if format == "jpeg" || format == "webp" {
+		ext = ".jpg"
+		prepared.MimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, decoded)
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to encode %s: %v", img.Name, err)
+	}
+	prepared.Name = strings.TrimSuffix(img.Name, filepath.Ext(img.Name)) + ext
+	prepared.Data = buf.Bytes()
+	return prepared, nil
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG file, 1 when it has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of TIFF data
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if string(tiff[:2]) == "MM" {
+		order = binary.BigEndian
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// orient turns and mirrors img so it shows upright without its EXIF orientation
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	size := image.Rect(0, 0, w, h)
+	if orientation >= 5 {
+		size = image.Rect(0, 0, h, w)
+	}
+	out := image.NewRGBA(size)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			out.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Instruction layers, composed from the broadest to the most specific
+const (
+	LayerGlobal = iota
+	LayerProject
+	LayerPersona
+	LayerChat
+	LayerStyle
+)
+
+// layerNames labels each layer in the inspector
+var layerNames = map[int]string{
+	LayerGlobal:  "Global",
+	LayerProject: "Project",
+	LayerPersona: "Persona",
+	LayerChat:    "Chat",
+	LayerStyle:   "Style",
+}
+
+// InstructionLayer is one level of system instructions
+type InstructionLayer struct {
+	Level int
+	Text  string
+}
+
+// Name returns the display name of the layer
+func (l InstructionLayer) Name() string {
+	return layerNames[l.Level]
+}
+
+// ComposeSystemPrompt merges the non-empty layers into a single system prompt,
+// ordered global, project, persona, chat, style so later layers can refine
+// earlier ones
+func ComposeSystemPrompt(layers []InstructionLayer) string {
+	ordered := append([]InstructionLayer(nil), layers...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Level < ordered[j].Level })
+
+	var parts []string
+	for _, layer := range ordered {
+		if text := strings.TrimSpace(layer.Text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// AnswerStyle is a one-tap modifier of how the model answers
+type AnswerStyle struct {
+	Name        string
+	Instruction string
+}
+
+// AnswerStyles are the styles offered next to the input
+var AnswerStyles = []AnswerStyle{
+	{"Bullets", "Answer only with bullet points."},
+	{"Code only", "Answer only with code, without explanations around it."},
+	{"ELI5", "Explain it like I'm five: simple words, short sentences and everyday examples."},
+	{"Short", "Keep the answer short, a few sentences at most."},
+}
+
+// StyleInstructions returns the instructions of the named answer styles
+func StyleInstructions(names []string) string {
+	var parts []string
+	for _, style := range AnswerStyles {
+		for _, name := range names {
+			if name == style.Name {
+				parts = append(parts, style.Instruction)
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// withSystemPrompt prepends system to messages when it is not empty
+func withSystemPrompt(system string, messages []llms.MessageContent) []llms.MessageContent {
+	if system == "" {
+		return messages
+	}
+	return append([]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeSystem, system)}, messages...)
+}
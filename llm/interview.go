@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// interviewPrompt makes the model ask questions instead of answering them
+const interviewPrompt = `You are interviewing the user to help them reach this goal: %s
+
+Ask exactly one short, specific clarifying question per message, building on the previous answers.
+Do not propose solutions or write the final document yet.
+When you have enough information, say so and suggest the user finishes the interview.`
+
+// interviewDocumentPrompt turns the interview into the final deliverable
+const interviewDocumentPrompt = `You interviewed the user to help them reach this goal: %s
+
+Using only the answers given in the interview, write a structured Markdown document with the sections
+Overview, Requirements, Constraints, Open Questions and Next Steps.`
+
+// InterviewQuestion returns the next clarifying question for goal given the interview so far
+func InterviewQuestion(ctx context.Context, modelName, goal string, transcript []Turn) (string, error) {
+	return interviewTurn(ctx, modelName, fmt.Sprintf(interviewPrompt, goal), transcript, "Please ask your first question.")
+}
+
+// InterviewDocument produces the structured document that concludes the interview
+func InterviewDocument(ctx context.Context, modelName, goal string, transcript []Turn) (string, error) {
+	transcript = append(transcript[:len(transcript):len(transcript)], Turn{Speaker: "You", Text: "Please write the document now."})
+	return interviewTurn(ctx, modelName, fmt.Sprintf(interviewDocumentPrompt, goal), transcript, "")
+}
+
+// interviewTurn sends the interview transcript with system and a final instruction
+func interviewTurn(ctx context.Context, modelName, system string, transcript []Turn, instruction string) (string, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+
+	messages := make([]llms.MessageContent, 0, len(transcript)+1)
+	for _, turn := range transcript {
+		role := llms.ChatMessageTypeHuman
+		if turn.Speaker == "AI" {
+			role = llms.ChatMessageTypeAI
+		}
+		messages = append(messages, llms.TextParts(role, turn.Text))
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role == llms.ChatMessageTypeAI {
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, instruction))
+	}
+
+	reply, err := generate(ctx, modelName, model, withSystemPrompt(system, messages))
+	if err != nil {
+		return "", fmt.Errorf("interview error: %v", err)
+	}
+	return reply, nil
+}
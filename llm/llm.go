@@ -2,10 +2,10 @@ package llm
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/devalexandre/llmschat/database"
 	"github.com/tmc/langchaingo/llms"
@@ -16,8 +16,58 @@ import (
 
 // Client represents an LLM client interface
 type Client interface {
-	Chat(ctx context.Context, prompt string) (string, error)
-	StreamChat(ctx context.Context, prompt string) (<-chan string, error)
+	Chat(ctx context.Context, req Request) (string, error)
+	StreamChat(ctx context.Context, req Request) (<-chan string, error)
+}
+
+// Request describes a single chat completion
+type Request struct {
+	Prompt       string
+	Model        string
+	SystemPrompt string
+	NoCache      bool     // Skip the response cache for this request
+	ChatID       int      // Conversation whose memory the request reads and extends
+	Images       []Image  // Pictures sent with the prompt, prepared by PrepareImages
+	Temperature  *float64 // Sampling temperature, nil for the model's default
+	MaxTokens    int      // Longest answer in tokens, 0 for the model's default
+	NoEmbeddings bool     // Skip embedding the history to pick relevant turns, to save power and data
+	Continue     bool     // Extend the latest answer, which was cut off, instead of answering a new prompt
+
+	// ContextTokens caps the estimated tokens of the past messages sent with
+	// the prompt, dropping the oldest first; 0 to send what fits in the
+	// model's context window
+	ContextTokens int
+
+	// OnFinish is called with the token counts and finish reason once the
+	// answer is complete, before a stream is closed
+	OnFinish func(Result)
+
+	promptMemoryID int64 // Row of the prompt in the chat's memory once it is saved
+}
+
+// ContinuePrompt asks the model to pick up an answer cut off at the token
+// limit; it is sent with Continue requests and left out of history
+const ContinuePrompt = "Your last answer was cut off. Continue exactly where it stopped, mid-sentence if needed, without repeating anything or adding an introduction."
+
+// finish hands result to the OnFinish callback of r, if any, with the memory
+// row of the prompt
+func (r Request) finish(result Result) {
+	result.PromptMemoryID = r.promptMemoryID
+	if r.OnFinish != nil {
+		r.OnFinish(result)
+	}
+}
+
+// callOptions returns the generation parameters of req as call options
+func (r Request) callOptions() []llms.CallOption {
+	var options []llms.CallOption
+	if r.Temperature != nil {
+		options = append(options, llms.WithTemperature(*r.Temperature))
+	}
+	if r.MaxTokens > 0 {
+		options = append(options, llms.WithMaxTokens(r.MaxTokens))
+	}
+	return options
 }
 
 // provider implementations
@@ -31,195 +81,400 @@ type anthropicClient struct {
 	memory *sqlite3.SqliteChatMessageHistory
 }
 
-func (o *openAIClient) Chat(ctx context.Context, prompt string) (string, error) {
+func (o *openAIClient) Chat(ctx context.Context, req Request) (string, error) {
 	// Add user message to history
-	err := o.memory.AddUserMessage(ctx, prompt)
+	err := addPrompt(ctx, o.memory, &req)
 	if err != nil {
 		return "", fmt.Errorf("failed to save user message: %v", err)
 	}
 
 	// Get completion with context from history
-	completion, err := o.client.Call(ctx, prompt)
+	messages := withSystemPrompt(req.SystemPrompt, requestMessages(ctx, o.client, o.memory, req))
+	completion, err := complete(ctx, o.client, o.memory, req, messages)
 	if err != nil {
 		return "", fmt.Errorf("openai chat error: %v", err)
 	}
-
-	// Save AI response to history
-	err = o.memory.AddAIMessage(ctx, completion)
-	if err != nil {
-		return "", fmt.Errorf("failed to save AI response: %v", err)
-	}
-
 	return completion, nil
 }
 
-func (o *openAIClient) StreamChat(ctx context.Context, prompt string) (<-chan string, error) {
+func (o *openAIClient) StreamChat(ctx context.Context, req Request) (<-chan string, error) {
 	stream := make(chan string)
 
 	go func() {
 		defer close(stream)
 
 		// Add user message to history
-		err := o.memory.AddUserMessage(ctx, prompt)
+		err := addPrompt(ctx, o.memory, &req)
 		if err != nil {
-			stream <- fmt.Sprintf("failed to save user message: %v", err)
+			req.finish(Result{Model: req.Model, Err: fmt.Errorf("failed to save user message: %v", err)})
 			return
 		}
 
 		// Stream completion with context from history
-		_, err = o.client.GenerateContent(ctx, []llms.MessageContent{
-			llms.TextParts(llms.ChatMessageTypeHuman, prompt),
-		}, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-			stream <- string(chunk)
-			return nil
-		}))
-		if err != nil {
-			stream <- fmt.Sprintf("openai chat error: %v", err)
-			return
+		messages := withSystemPrompt(req.SystemPrompt, requestMessages(ctx, o.client, o.memory, req))
+		if err := streamCompletion(ctx, o.client, o.memory, req, messages, stream); err != nil {
+			req.finish(Result{Model: req.Model, Err: fmt.Errorf("openai chat error: %v", err)})
 		}
 	}()
 
 	return stream, nil
 }
 
-func (a *anthropicClient) Chat(ctx context.Context, prompt string) (string, error) {
+func (a *anthropicClient) Chat(ctx context.Context, req Request) (string, error) {
+	if len(req.Images) > 0 {
+		return "", errAnthropicImages
+	}
+
 	// Add user message to history
-	err := a.memory.AddUserMessage(ctx, prompt)
+	err := addPrompt(ctx, a.memory, &req)
 	if err != nil {
 		return "", fmt.Errorf("failed to save user message: %v", err)
 	}
 
 	// Get completion with context from history
-	completion, err := a.client.Call(ctx, prompt)
+	messages := withSystemPrompt(req.SystemPrompt, requestMessages(ctx, a.client, a.memory, req))
+	completion, err := complete(ctx, a.client, a.memory, req, messages)
 	if err != nil {
 		return "", fmt.Errorf("anthropic chat error: %v", err)
 	}
-
-	// Save AI response to history
-	err = a.memory.AddAIMessage(ctx, completion)
-	if err != nil {
-		return "", fmt.Errorf("failed to save AI response: %v", err)
-	}
-
 	return completion, nil
 }
 
-func (a *anthropicClient) StreamChat(ctx context.Context, prompt string) (<-chan string, error) {
+func (a *anthropicClient) StreamChat(ctx context.Context, req Request) (<-chan string, error) {
+	if len(req.Images) > 0 {
+		stream := make(chan string)
+		close(stream)
+		return stream, errAnthropicImages
+	}
 	stream := make(chan string)
 
 	go func() {
 		defer close(stream)
 
 		// Add user message to history
-		err := a.memory.AddUserMessage(ctx, prompt)
+		err := addPrompt(ctx, a.memory, &req)
 		if err != nil {
-			stream <- fmt.Sprintf("failed to save user message: %v", err)
+			req.finish(Result{Model: req.Model, Err: fmt.Errorf("failed to save user message: %v", err)})
 			return
 		}
 
 		// Stream completion with context from history
-		_, err = a.client.GenerateContent(ctx, []llms.MessageContent{
-			llms.TextParts(llms.ChatMessageTypeHuman, prompt),
-		}, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-			stream <- string(chunk)
-			return nil
-		}))
-		if err != nil {
-			stream <- fmt.Sprintf("anthropic chat error: %v", err)
-			return
+		messages := withSystemPrompt(req.SystemPrompt, requestMessages(ctx, a.client, a.memory, req))
+		if err := streamCompletion(ctx, a.client, a.memory, req, messages, stream); err != nil {
+			req.finish(Result{Model: req.Model, Err: fmt.Errorf("anthropic chat error: %v", err)})
 		}
 	}()
 
 	return stream, nil
 }
 
-// NewClient creates a new LLM client based on the selected model in settings
-func NewClient(modelName string) (Client, error) {
-	settings, err := database.GetSettings()
+// addPrompt saves the prompt of req to history and notes its row; a
+// continuation has none, as the model is asked to extend its latest answer
+func addPrompt(ctx context.Context, memory *sqlite3.SqliteChatMessageHistory, req *Request) error {
+	if req.Continue {
+		return nil
+	}
+	if err := memory.AddUserMessage(ctx, req.Prompt); err != nil {
+		return err
+	}
+	req.promptMemoryID = memoryRow(ctx, req.ChatID, false)
+	return nil
+}
+
+// saveAnswer saves completion to history, appending a continuation to the
+// answer it continues, and returns the row of a new answer
+func saveAnswer(ctx context.Context, memory *sqlite3.SqliteChatMessageHistory, req Request, completion string) (int64, error) {
+	if req.Continue {
+		return 0, database.ExtendAnswer(ctx, req.ChatID, completion)
+	}
+	if err := memory.AddAIMessage(ctx, completion); err != nil {
+		return 0, err
+	}
+	return memoryRow(ctx, req.ChatID, true), nil
+}
+
+// memoryRow returns the row of the prompt, or answer with isAI, just saved to
+// the memory of a chat, 0 when unknown
+func memoryRow(ctx context.Context, chatID int, isAI bool) int64 {
+	if chatID == 0 {
+		return 0
+	}
+	id, err := database.LatestMemoryID(ctx, chatID, isAI)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get settings: %v", err)
+		log.Printf("Failed to find the memory row: %v", err)
 	}
-	if settings == nil {
-		return nil, fmt.Errorf("no settings found, please configure your settings first")
+	return id
+}
+
+// complete runs a non-streaming completion, answering from the response cache when
+// possible, and saves the answer to history
+func complete(ctx context.Context, model llms.Model, memory *sqlite3.SqliteChatMessageHistory, req Request, messages []llms.MessageContent) (string, error) {
+	key := cacheKey(req.Model, messages)
+	completion, cached := lookupCache(ctx, key, req)
+	result := Result{Model: req.Model, FinishReason: FinishCached}
+	if !cached {
+		var err error
+		completion, result, err = generateResult(ctx, req.Model, model, messages, req.callOptions()...)
+		if err != nil {
+			return "", err
+		}
+		storeCache(ctx, key, req, completion)
 	}
 
-	// Get company information
-	companies, err := database.GetCompanies()
+	// Save AI response to history
+	memoryID, err := saveAnswer(ctx, memory, req, completion)
+	result.AnswerMemoryID = memoryID
+	req.finish(result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get companies: %v", err)
+		return "", fmt.Errorf("failed to save AI response: %v", err)
 	}
+	return completion, nil
+}
 
-	var companyInfo database.Company
-	for _, company := range companies {
-		if company.ID == settings.CompanyID {
-			companyInfo = company
-			break
+// streamCompletion streams a completion into stream, answering from the response
+// cache when possible, and saves the answer to history. When ctx is cancelled
+// while streaming, the part streamed so far is saved as the answer.
+func streamCompletion(ctx context.Context, model llms.Model, memory *sqlite3.SqliteChatMessageHistory, req Request, messages []llms.MessageContent, stream chan<- string) error {
+	key := cacheKey(req.Model, messages)
+	completion, cached := lookupCache(ctx, key, req)
+	result := Result{Model: req.Model, FinishReason: FinishCached}
+	if cached {
+		stream <- completion
+	} else {
+		var builder strings.Builder
+		started := time.Now()
+		options := append(req.callOptions(), llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			builder.Write(chunk)
+			stream <- string(chunk)
+			return nil
+		}))
+		resp, err := model.GenerateContent(ctx, messages, options...)
+		completion = builder.String()
+		switch {
+		case err != nil && ctx.Err() == nil:
+			return err
+		case err != nil:
+			// Stopped by the user; the streamed part is kept as the answer
+			ctx = context.WithoutCancel(ctx)
+			result = recordUsage(ctx, req.Model, messages, nil, completion, started)
+			result.FinishReason = FinishStopped
+		default:
+			result = recordUsage(ctx, req.Model, messages, resp, completion, started)
+			storeCache(ctx, key, req, completion)
 		}
 	}
+	// Save AI response to history
+	memoryID, err := saveAnswer(ctx, memory, req, completion)
+	if err != nil {
+		log.Printf("Failed to save AI response: %v", err)
+	}
+	result.AnswerMemoryID = memoryID
+	req.finish(result)
+	return nil
+}
+
+// generate runs a non-streaming completion, records its usage and returns the
+// first choice
+func generate(ctx context.Context, modelName string, model llms.Model, messages []llms.MessageContent, options ...llms.CallOption) (string, error) {
+	completion, _, err := generateResult(ctx, modelName, model, messages, options...)
+	return completion, err
+}
+
+// generateResult is generate also returning the token counts and finish reason
+func generateResult(ctx context.Context, modelName string, model llms.Model, messages []llms.MessageContent, options ...llms.CallOption) (string, Result, error) {
+	started := time.Now()
+	resp, err := model.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return "", Result{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return "", Result{}, fmt.Errorf("empty response from model")
+	}
+	result := recordUsage(ctx, modelName, messages, resp, resp.Choices[0].Content, started)
+	return resp.Choices[0].Content, result, nil
+}
+
+// memorySession returns the memory session holding the history of a chat
+func memorySession(chatID int) string {
+	if chatID == 0 {
+		return "default"
+	}
+	return fmt.Sprintf("chat-%d", chatID)
+}
+
+// SeedMemory fills the model's memory of a chat with its messages, for chats
+// whose messages were copied from another one. Notices of the app itself,
+// sent by System, are not part of the conversation and left out.
+func SeedMemory(ctx context.Context, chatID int, messages []database.MessageRecord) error {
+	mem := sqlite3.NewSqliteChatMessageHistory(sqlite3.WithDB(database.DB()), sqlite3.WithSession(memorySession(chatID)))
+	for _, m := range messages {
+		var err error
+		switch {
+		case m.Sender == "System":
+			continue
+		case m.IsAI:
+			err = mem.AddAIMessage(ctx, m.Text)
+		default:
+			err = mem.AddUserMessage(ctx, m.Text)
+		}
+		if err != nil {
+			return err
+		}
+		// Stored messages are linked to their row, so deleting one forgets it
+		if m.ID != 0 {
+			if err := database.SetMessageMemory(ctx, m.ID, memoryRow(ctx, chatID, m.IsAI)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// NewClient creates a new LLM client based on the selected model in settings,
+// with the conversation memory of chat chatID
+func NewClient(ctx context.Context, modelName string, chatID int) (Client, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the memory in the app's database, on its shared connection
+	mem := sqlite3.NewSqliteChatMessageHistory(sqlite3.WithDB(database.DB()), sqlite3.WithSession(memorySession(chatID)))
 
-	// Initialize SQLite memory using the database path from InitDB
-	dbPath := filepath.Join("data", "chat.db")
-	db, err := sql.Open("sqlite3", dbPath)
+	switch client := model.(type) {
+	case *openai.LLM:
+		return &openAIClient{client: client, memory: mem}, nil
+	case *anthropic.LLM:
+		return &anthropicClient{client: client, memory: mem}, nil
+	case *demoLLM:
+		return &demoClient{client: client, memory: mem}, nil
+	default:
+		return nil, fmt.Errorf("unsupported model type %T", model)
+	}
+}
+
+// newLLM creates the provider model for modelName from the current settings,
+// without any conversation memory attached
+func newLLM(ctx context.Context, modelName string) (llms.Model, error) {
+	if demoForced() {
+		return &demoLLM{instant: modelName == DemoInstantModel}, nil
+	}
+	settings, companyInfo, err := currentCompany(ctx)
 	if err != nil {
-		log.Printf("Failed to open database: %v", err)
+		return nil, err
+	}
+	return newCompanyLLM(ctx, companyInfo, settings.APIKey, modelName)
+}
 
+// newCompanyLLM returns a langchaingo model for modelName served by company,
+// whether or not it is the company of the settings
+func newCompanyLLM(ctx context.Context, companyInfo database.Company, apiKey, modelName string) (llms.Model, error) {
+	if companyInfo.Name == DemoCompany {
+		return &demoLLM{instant: modelName == DemoInstantModel}, nil
 	}
 
-	mem := sqlite3.NewSqliteChatMessageHistory(sqlite3.WithDB(db))
+	httpClient, err := providerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create appropriate client based on company
 	switch companyInfo.Name {
 	case "OpenAI":
 		client, err := openai.New(
-			openai.WithToken(settings.APIKey),
+			openai.WithToken(apiKey),
 			openai.WithModel(modelName),
+			openai.WithHTTPClient(httpClient),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create OpenAI client: %v", err)
 		}
-		return &openAIClient{client: client, memory: mem}, nil
+		return client, nil
 
 	case "Anthropic":
 		client, err := anthropic.New(
-			anthropic.WithToken(settings.APIKey),
+			anthropic.WithToken(apiKey),
 			anthropic.WithModel(modelName),
+			anthropic.WithHTTPClient(httpClient),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Anthropic client: %v", err)
 		}
-		return &anthropicClient{client: client, memory: mem}, nil
+		return client, nil
 
 	case "Deepseek":
 		client, err := openai.New(
-			openai.WithToken(settings.APIKey),
+			openai.WithToken(apiKey),
 			openai.WithModel(modelName),
 			openai.WithBaseURL(companyInfo.BaseURL),
+			openai.WithHTTPClient(httpClient),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Deepseek client: %v", err)
 		}
-		return &openAIClient{client: client, memory: mem}, nil
+		return client, nil
+
+	case "Local":
+		baseURL, err := LocalServerURL(ctx)
+		if err != nil {
+			return nil, err
+		}
+		client, err := openai.New(
+			openai.WithToken("local"),
+			openai.WithModel(modelName),
+			openai.WithBaseURL(baseURL+"/v1"),
+			openai.WithHTTPClient(localClient),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create local client: %v", err)
+		}
+		return client, nil
 
 	default:
 		return nil, fmt.Errorf("unsupported company: %s", companyInfo.Name)
 	}
 }
 
+// currentCompany returns the settings and the company of the selected model
+func currentCompany(ctx context.Context) (*database.Settings, database.Company, error) {
+	settings, err := database.GetSettings(ctx)
+	if err != nil {
+		return nil, database.Company{}, fmt.Errorf("failed to get settings: %v", err)
+	}
+	if settings == nil {
+		return nil, database.Company{}, fmt.Errorf("no settings found, please configure your settings first")
+	}
+
+	// Get company information
+	companies, err := database.GetCompanies(ctx)
+	if err != nil {
+		return nil, database.Company{}, fmt.Errorf("failed to get companies: %v", err)
+	}
+
+	var companyInfo database.Company
+	for _, company := range companies {
+		if company.ID == settings.CompanyID {
+			companyInfo = company
+			break
+		}
+	}
+	return settings, companyInfo, nil
+}
+
 // GetResponse gets a response from the LLM
-func GetResponse(prompt string, modelName string) (string, error) {
-	client, err := NewClient(modelName)
+func GetResponse(ctx context.Context, req Request) (string, error) {
+	client, err := NewClient(ctx, req.Model, req.ChatID)
 	if err != nil {
 		fmt.Printf("Failed to create client: %v\n", err)
 		return "", err
 	}
 
-	ctx := context.Background()
-	return client.Chat(ctx, prompt)
+	return client.Chat(ctx, req)
 }
 
 // GetResponseStream gets a streaming response from the LLM
-func GetResponseStream(prompt string, modelName string) (<-chan string, error) {
-	client, err := NewClient(modelName)
+func GetResponseStream(ctx context.Context, req Request) (<-chan string, error) {
+	client, err := NewClient(ctx, req.Model, req.ChatID)
 	if err != nil {
 		fmt.Printf("Failed to create client: %v\n", err)
 		stream := make(chan string)
@@ -227,6 +482,5 @@ func GetResponseStream(prompt string, modelName string) (<-chan string, error) {
 		return stream, err
 	}
 
-	ctx := context.Background()
-	return client.StreamChat(ctx, prompt)
+	return client.StreamChat(ctx, req)
 }
@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+)
+
+// defaultLlamaServer is the llama.cpp server binary looked up on PATH
+const defaultLlamaServer = "llama-server"
+
+// localStartTimeout bounds how long loading a GGUF model may take
+const localStartTimeout = 2 * time.Minute
+
+// localServer is the llama.cpp process serving the Local company. It exposes an
+// OpenAI compatible API on a loopback port and is restarted when the model changes.
+var localServer struct {
+	sync.Mutex
+	cmd       *exec.Cmd
+	exited    chan struct{}
+	modelPath string
+	baseURL   string
+}
+
+// localClient talks to the loopback server directly, bypassing any proxy
+var localClient = &http.Client{}
+
+// LocalServerURL starts llama.cpp for the configured GGUF model if needed and
+// returns the base URL of its OpenAI compatible API
+func LocalServerURL(ctx context.Context) (string, error) {
+	modelPath, err := database.GetPreference(ctx, database.PrefLocalModelPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get local model path: %v", err)
+	}
+	if modelPath == "" {
+		return "", fmt.Errorf("no local model configured, please pick a GGUF file in settings")
+	}
+	if _, err := os.Stat(modelPath); err != nil {
+		return "", fmt.Errorf("local model not found: %v", err)
+	}
+
+	localServer.Lock()
+	defer localServer.Unlock()
+
+	if localServer.cmd != nil && localServer.modelPath == modelPath && running(localServer.exited) {
+		return localServer.baseURL, nil
+	}
+	stopLocalServer()
+
+	binary, err := database.GetPreference(ctx, database.PrefLlamaServer)
+	if err != nil || binary == "" {
+		binary = defaultLlamaServer
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		return "", fmt.Errorf("llama.cpp server not found (%s), install llama.cpp or set its path in settings", binary)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(binary, "--model", modelPath, "--host", "127.0.0.1", "--port", fmt.Sprint(port))
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start llama.cpp: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	if err := waitForLocalServer(ctx, exited, baseURL); err != nil {
+		cmd.Process.Kill()
+		return "", err
+	}
+
+	localServer.cmd = cmd
+	localServer.exited = exited
+	localServer.modelPath = modelPath
+	localServer.baseURL = baseURL
+	return baseURL, nil
+}
+
+// WarmUp loads the model of the settings ahead of the first prompt when it is
+// served locally, and reports whether there was one to load
+func WarmUp(ctx context.Context) (bool, error) {
+	_, company, err := currentCompany(ctx)
+	if err != nil {
+		return false, err
+	}
+	if company.Name != "Local" {
+		return false, nil
+	}
+	_, err = LocalServerURL(ctx)
+	return true, err
+}
+
+// StopLocalServer terminates the llama.cpp process, if one is running
+func StopLocalServer() {
+	localServer.Lock()
+	defer localServer.Unlock()
+	stopLocalServer()
+}
+
+// stopLocalServer kills the running process; the caller holds the lock
+func stopLocalServer() {
+	if localServer.cmd != nil && localServer.cmd.Process != nil {
+		localServer.cmd.Process.Kill()
+	}
+	localServer.cmd = nil
+	localServer.exited = nil
+	localServer.modelPath = ""
+	localServer.baseURL = ""
+}
+
+// waitForLocalServer polls the health endpoint until the model is loaded
+func waitForLocalServer(ctx context.Context, exited <-chan struct{}, baseURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, localStartTimeout)
+	defer cancel()
+
+	for {
+		if !running(exited) {
+			return fmt.Errorf("llama.cpp exited while loading the model")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/health", nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := localClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for llama.cpp to load the model")
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// running reports whether the process behind exited is still alive
+func running(exited <-chan struct{}) bool {
+	select {
+	case <-exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// freePort asks the OS for an unused loopback port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,54 @@
+package llm
+
+import "time"
+
+// StreamMetrics measures the latency and throughput of a streamed response
+type StreamMetrics struct {
+	start  time.Time
+	first  time.Time
+	end    time.Time
+	Tokens int
+}
+
+// NewStreamMetrics starts measuring a response that is requested now
+func NewStreamMetrics() *StreamMetrics {
+	return &StreamMetrics{start: time.Now()}
+}
+
+// Chunk records the arrival of a streamed chunk
+func (m *StreamMetrics) Chunk() {
+	if m.first.IsZero() {
+		m.first = time.Now()
+	}
+}
+
+// Finish records the end of the stream and the full response text
+func (m *StreamMetrics) Finish(text string) {
+	m.end = time.Now()
+	m.Tokens = EstimateTokens(text)
+}
+
+// TimeToFirstToken returns how long the first chunk took to arrive
+func (m *StreamMetrics) TimeToFirstToken() time.Duration {
+	if m.first.IsZero() {
+		return 0
+	}
+	return m.first.Sub(m.start)
+}
+
+// Duration returns the total time from request to the end of the stream
+func (m *StreamMetrics) Duration() time.Duration {
+	return m.end.Sub(m.start)
+}
+
+// TokensPerSecond returns the generation rate after the first token
+func (m *StreamMetrics) TokensPerSecond() float64 {
+	if m.first.IsZero() {
+		return 0
+	}
+	elapsed := m.end.Sub(m.first).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(m.Tokens) / elapsed
+}
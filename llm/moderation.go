@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Moderation modes stored in preferences
+const (
+	ModerationOff   = "off"
+	ModerationWarn  = "warn"
+	ModerationBlock = "block"
+)
+
+// ModerationResult is the outcome of a moderation check
+type ModerationResult struct {
+	Flagged    bool
+	Categories []string
+}
+
+// Moderate runs text through the OpenAI moderation endpoint
+func Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	baseURL, apiKey, err := openAICredentials(ctx, "moderation")
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]string{"input": text}
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+
+	var result struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := postJSON(ctx, baseURL+"/v1/moderations", headers, payload, &result); err != nil {
+		return nil, fmt.Errorf("moderation check failed: %v", err)
+	}
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("moderation check returned no results")
+	}
+
+	r := result.Results[0]
+	moderation := &ModerationResult{Flagged: r.Flagged}
+	for category, flagged := range r.Categories {
+		if flagged {
+			moderation.Categories = append(moderation.Categories, category)
+		}
+	}
+	sort.Strings(moderation.Categories)
+	return moderation, nil
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Message is a role-tagged message of a conversation given by another program
+type Message struct {
+	Role    string `json:"role"` // system, user or assistant
+	Content string `json:"content"`
+}
+
+// messageRoles maps the accepted roles to the provider message types
+var messageRoles = map[string]llms.ChatMessageType{
+	"system":    llms.ChatMessageTypeSystem,
+	"user":      llms.ChatMessageTypeHuman,
+	"assistant": llms.ChatMessageTypeAI,
+}
+
+// StreamConversation sends messages to modelName as they are, without any chat
+// memory, passing each chunk of the answer to onChunk, and returns the answer
+func StreamConversation(ctx context.Context, modelName string, messages []Message, onChunk func(string) error) (string, error) {
+	content := make([]llms.MessageContent, 0, len(messages))
+	for i, msg := range messages {
+		role, ok := messageRoles[msg.Role]
+		if !ok {
+			return "", fmt.Errorf("message %d has unknown role %q", i+1, msg.Role)
+		}
+		content = append(content, llms.TextParts(role, msg.Content))
+	}
+	if len(content) == 0 {
+		return "", fmt.Errorf("no messages to send")
+	}
+
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+	started := time.Now()
+	resp, err := model.GenerateContent(ctx, content,
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			return onChunk(string(chunk))
+		}))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("empty response from model")
+	}
+	recordUsage(ctx, modelName, content, resp, resp.Choices[0].Content, started)
+	return resp.Choices[0].Content, nil
+}
@@ -0,0 +1,52 @@
+package llm
+
+import "math"
+
+// Price is the cost in USD per million tokens for a model
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// tokenApproximation is the average number of characters per token
+const tokenApproximation = 4
+
+// pricing holds the list prices of the default models
+var pricing = map[string]Price{
+	"gpt-4":              {Input: 30, Output: 60},
+	"gpt-4-turbo":        {Input: 10, Output: 30},
+	"gpt-4-32k":          {Input: 60, Output: 120},
+	"gpt-4o":             {Input: 2.5, Output: 10},
+	"gpt-4o-mini":        {Input: 0.15, Output: 0.6},
+	"gpt-3.5-turbo":      {Input: 0.5, Output: 1.5},
+	"gpt-3.5-turbo-16k":  {Input: 3, Output: 4},
+	"claude-2.1":         {Input: 8, Output: 24},
+	"claude-2.0":         {Input: 8, Output: 24},
+	"claude-instant-1.2": {Input: 0.8, Output: 2.4},
+	"gemini-pro":         {Input: 0.5, Output: 1.5},
+	"mistral-7b":         {Input: 0.25, Output: 0.25},
+	"mixtral-8x7b":       {Input: 0.7, Output: 0.7},
+	"deepseek-chat":      {Input: 0.27, Output: 1.1},
+	"deepseek-reasoner":  {Input: 0.55, Output: 2.19},
+}
+
+// GetPrice returns the price of model and whether it is known
+func GetPrice(model string) (Price, bool) {
+	p, ok := pricing[model]
+	return p, ok
+}
+
+// EstimateTokens approximates the number of tokens in text without
+// downloading a tokenizer, which keeps it cheap enough to run on every keystroke
+func EstimateTokens(text string) int {
+	return int(math.Ceil(float64(len([]rune(text))) / tokenApproximation))
+}
+
+// EstimateCost returns the USD cost of a request and whether the model has a known price
+func EstimateCost(model string, promptTokens, completionTokens int) (float64, bool) {
+	p, ok := pricing[model]
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6, true
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// defaultRelevantTurns is how many past messages are kept when compressing context
+const defaultRelevantTurns = 6
+
+// embedder is implemented by providers that can create embeddings
+type embedder interface {
+	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// embeddingCache avoids re-embedding the same history messages on every request
+var embeddingCache = struct {
+	sync.Mutex
+	vectors map[string][]float32
+}{vectors: make(map[string][]float32)}
+
+// compressionLimit returns how many past messages to keep, or 0 when compression is disabled
+func compressionLimit(ctx context.Context) int {
+	enabled, err := database.GetPreference(ctx, database.PrefContextCompression)
+	if err != nil || enabled != "true" {
+		return 0
+	}
+	value, err := database.GetPreference(ctx, database.PrefRelevantTurns)
+	if err != nil || value == "" {
+		return defaultRelevantTurns
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return defaultRelevantTurns
+	}
+	return limit
+}
+
+// relevantHistory returns up to limit messages from history that are most similar
+// to prompt, in their original order
+func relevantHistory(ctx context.Context, e embedder, history []llms.ChatMessage, prompt string, limit int) ([]llms.ChatMessage, error) {
+	if len(history) <= limit {
+		return history, nil
+	}
+
+	texts := make([]string, 0, len(history)+1)
+	for _, msg := range history {
+		texts = append(texts, msg.GetContent())
+	}
+	texts = append(texts, prompt)
+
+	vectors, err := embedTexts(ctx, e, texts)
+	if err != nil {
+		return nil, err
+	}
+	query := vectors[len(vectors)-1]
+
+	type scored struct {
+		index int
+		score float64
+	}
+	scores := make([]scored, len(history))
+	for i := range history {
+		scores[i] = scored{index: i, score: cosineSimilarity(vectors[i], query)}
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
+
+	keep := make([]int, 0, limit)
+	for _, s := range scores[:limit] {
+		keep = append(keep, s.index)
+	}
+	sort.Ints(keep)
+
+	selected := make([]llms.ChatMessage, 0, limit)
+	for _, i := range keep {
+		selected = append(selected, history[i])
+	}
+	return selected, nil
+}
+
+// embedTexts embeds texts, reusing cached vectors where possible
+func embedTexts(ctx context.Context, e embedder, texts []string) ([][]float32, error) {
+	embeddingCache.Lock()
+	var missing []string
+	for _, t := range texts {
+		if _, ok := embeddingCache.vectors[t]; !ok {
+			missing = append(missing, t)
+		}
+	}
+	embeddingCache.Unlock()
+
+	if len(missing) > 0 {
+		vectors, err := e.CreateEmbedding(ctx, missing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embeddings: %v", err)
+		}
+		if len(vectors) != len(missing) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(missing), len(vectors))
+		}
+		embeddingCache.Lock()
+		for i, t := range missing {
+			embeddingCache.vectors[t] = vectors[i]
+		}
+		embeddingCache.Unlock()
+	}
+
+	embeddingCache.Lock()
+	defer embeddingCache.Unlock()
+	result := make([][]float32, len(texts))
+	for i, t := range texts {
+		result[i] = embeddingCache.vectors[t]
+	}
+	return result, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// fitTokens returns the most recent messages of history whose estimated tokens
+// add up to at most budget, or all of them when budget is 0
+func fitTokens(history []llms.ChatMessage, budget int) []llms.ChatMessage {
+	if budget <= 0 {
+		return history
+	}
+	used := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		used += EstimateTokens(history[i].GetContent())
+		if used > budget {
+			return history[i+1:]
+		}
+	}
+	return history
+}
+
+// toMessageContent converts stored chat messages into request messages
+func toMessageContent(history []llms.ChatMessage) []llms.MessageContent {
+	messages := make([]llms.MessageContent, 0, len(history))
+	for _, msg := range history {
+		messages = append(messages, llms.TextParts(msg.GetType(), msg.GetContent()))
+	}
+	return messages
+}
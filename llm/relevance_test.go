@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"same direction", []float32{1, 2, 3}, []float32{2, 4, 6}, 1},
+		{"opposite", []float32{1, 0}, []float32{-1, 0}, -1},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"zero vector", []float32{0, 0}, []float32{1, 1}, 0},
+		{"shorter b", []float32{1, 1, 5}, []float32{1, 1}, 1},
+		{"empty", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cosineSimilarity(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeEmbedder embeds each text with a fixed vector
+type fakeEmbedder map[string][]float32
+
+func (f fakeEmbedder) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		v, ok := f[text]
+		if !ok {
+			return nil, fmt.Errorf("no vector for %q", text)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+func TestRelevantHistory(t *testing.T) {
+	history := []llms.ChatMessage{
+		llms.HumanChatMessage{Content: "relevance: how do I bake bread?"},
+		llms.AIChatMessage{Content: "relevance: flour, water, yeast"},
+		llms.HumanChatMessage{Content: "relevance: what is a goroutine?"},
+		llms.AIChatMessage{Content: "relevance: a lightweight thread"},
+	}
+	e := fakeEmbedder{
+		"relevance: how do I bake bread?": {1, 0},
+		"relevance: flour, water, yeast":  {0.9, 0.1},
+		"relevance: what is a goroutine?": {0, 1},
+		"relevance: a lightweight thread": {0.1, 0.9},
+		"relevance: and the channels?":    {0.2, 1},
+		"relevance: and the oven?":        {1, 0.2},
+	}
+	tests := []struct {
+		name   string
+		prompt string
+		limit  int
+		want   []string
+	}{
+		{"keeps the closest in order", "relevance: and the channels?", 2, []string{"relevance: what is a goroutine?", "relevance: a lightweight thread"}},
+		{"other topic", "relevance: and the oven?", 2, []string{"relevance: how do I bake bread?", "relevance: flour, water, yeast"}},
+		{"limit above history keeps all", "relevance: and the oven?", 10, []string{"relevance: how do I bake bread?", "relevance: flour, water, yeast", "relevance: what is a goroutine?", "relevance: a lightweight thread"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := relevantHistory(context.Background(), e, history, tt.prompt, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("kept %d messages, want %d", len(got), len(tt.want))
+			}
+			for i, msg := range got {
+				if msg.GetContent() != tt.want[i] {
+					t.Errorf("message %d = %q, want %q", i, msg.GetContent(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRelevantHistoryEmbeddingError(t *testing.T) {
+	history := []llms.ChatMessage{
+		llms.HumanChatMessage{Content: "relevance: unknown one"},
+		llms.AIChatMessage{Content: "relevance: unknown two"},
+	}
+	if _, err := relevantHistory(context.Background(), fakeEmbedder{}, history, "relevance: unknown prompt", 1); err == nil {
+		t.Error("relevantHistory() succeeded without embeddings")
+	}
+}
+
+func TestFitTokens(t *testing.T) {
+	// Each turn is 8 characters, 2 estimated tokens
+	history := []llms.ChatMessage{
+		llms.HumanChatMessage{Content: "first q?"},
+		llms.AIChatMessage{Content: "first a."},
+		llms.HumanChatMessage{Content: "second ?"},
+		llms.AIChatMessage{Content: "second a"},
+	}
+	tests := []struct {
+		name    string
+		history []llms.ChatMessage
+		budget  int
+		want    int // Number of latest turns kept
+	}{
+		{"no budget keeps all", history, 0, 4},
+		{"negative budget keeps all", history, -5, 4},
+		{"everything fits", history, 8, 4},
+		{"large budget", history, 100, 4},
+		{"drops the oldest", history, 6, 3},
+		{"partial turn is dropped", history, 5, 2},
+		{"one turn", history, 2, 1},
+		{"too small for any turn", history, 1, 0},
+		{"empty history", nil, 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fitTokens(tt.history, tt.budget)
+			if len(got) != tt.want {
+				t.Fatalf("kept %d turns, want %d", len(got), tt.want)
+			}
+			// The kept turns are the latest ones, in order
+			for i, msg := range got {
+				if want := tt.history[len(tt.history)-tt.want+i]; msg.GetContent() != want.GetContent() {
+					t.Errorf("turn %d = %q, want %q", i, msg.GetContent(), want.GetContent())
+				}
+			}
+		})
+	}
+}
+
+func TestFitTokensCountsRunes(t *testing.T) {
+	// 8 runes in 24 bytes are still 2 tokens
+	history := []llms.ChatMessage{llms.HumanChatMessage{Content: strings.Repeat("é", 4) + strings.Repeat("日", 4)}}
+	if got := fitTokens(history, 2); len(got) != 1 {
+		t.Errorf("kept %d turns, want 1", len(got))
+	}
+}
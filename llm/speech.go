@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const ttsModel = "tts-1"
+
+// Voices lists the voices accepted by the OpenAI speech endpoint
+var Voices = []string{"alloy", "echo", "fable", "onyx", "nova", "shimmer"}
+
+// Synthesize converts text to speech with OpenAI TTS and returns WAV audio
+func Synthesize(ctx context.Context, text, voice string) ([]byte, error) {
+	baseURL, apiKey, err := openAICredentials(ctx, "read aloud")
+	if err != nil {
+		return nil, err
+	}
+	if voice == "" {
+		voice = Voices[0]
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"model":           ttsModel,
+		"input":           text,
+		"voice":           voice,
+		"response_format": "wav",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode speech request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v1/audio/speech", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create speech request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client, err := providerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("speech request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("speech synthesis failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read speech audio: %v", err)
+	}
+	return audio, nil
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// testGenPrompt asks for a test file of a Go source file
+const testGenPrompt = `You write unit tests for Go code. Write table-driven tests with the standard testing
+package only, covering the exported functions and their edge cases. The tests are compiled and run
+with go test in the package's directory, so use the package's real names and import paths.
+Reply with the complete _test.go file in a single fenced go block and nothing else.`
+
+// testFixPrompt sends back the go test output of a test file
+const testFixPrompt = "go test failed with this output:\n```\n%s\n```\nFix the test file so it compiles and reply with the complete file again."
+
+// testBlockPattern matches the fenced Go block of a reply
+var testBlockPattern = regexp.MustCompile("(?s)```(?:go|golang)?[ \t]*\n(.*?)```")
+
+// TestAttempt is a test file the model wrote and the go test output it got
+type TestAttempt struct {
+	Code   string
+	Output string
+}
+
+// GenerateTests asks the model for a _test.go file for the Go source file
+// named name. Earlier attempts are sent back with their go test output so the
+// model can fix what did not compile.
+func GenerateTests(ctx context.Context, modelName, name, source string, attempts []TestAttempt) (string, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("Write the tests of %s:\n```go\n%s\n```", name, source)),
+	}
+	for _, attempt := range attempts {
+		messages = append(messages,
+			llms.TextParts(llms.ChatMessageTypeAI, "```go\n"+attempt.Code+"\n```"),
+			llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(testFixPrompt, attempt.Output)),
+		)
+	}
+
+	reply, err := generate(ctx, modelName, model, withSystemPrompt(testGenPrompt, messages))
+	if err != nil {
+		return "", fmt.Errorf("test generation error: %v", err)
+	}
+	if match := testBlockPattern.FindStringSubmatch(reply); match != nil {
+		reply = match[1]
+	}
+	return strings.TrimSpace(reply) + "\n", nil
+}
@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// threadPrompt keeps a thread on the message it was opened on
+const threadPrompt = `The user opened a side discussion on this message of a conversation to ask clarifying
+questions about it. Answer briefly and only about this message.
+
+Message:
+
+%s`
+
+// ThreadReply answers the last question of a thread on the message parent,
+// without the chat memory
+func ThreadReply(ctx context.Context, modelName, parent string, transcript []Turn) (string, error) {
+	model, err := newLLM(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+
+	messages := make([]llms.MessageContent, 0, len(transcript))
+	for _, turn := range transcript {
+		role := llms.ChatMessageTypeHuman
+		if turn.Speaker == "AI" {
+			role = llms.ChatMessageTypeAI
+		}
+		messages = append(messages, llms.TextParts(role, turn.Text))
+	}
+
+	reply, err := generate(ctx, modelName, model, withSystemPrompt(fmt.Sprintf(threadPrompt, parent), messages))
+	if err != nil {
+		return "", fmt.Errorf("thread error: %v", err)
+	}
+	return reply, nil
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/devalexandre/llmschat/database"
+)
+
+const (
+	defaultOpenAIURL = "https://api.openai.com"
+	whisperModel     = "whisper-1"
+)
+
+// Transcribe sends recorded audio to a Whisper endpoint and returns the transcript.
+// A local whisper.cpp server configured in preferences takes precedence over OpenAI.
+func Transcribe(ctx context.Context, audio io.Reader) (string, error) {
+	endpoint, apiKey, err := whisperEndpoint(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("model", whisperModel); err != nil {
+		return "", fmt.Errorf("failed to write model field: %v", err)
+	}
+	if err := writer.WriteField("response_format", "json"); err != nil {
+		return "", fmt.Errorf("failed to write format field: %v", err)
+	}
+	part, err := writer.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to create audio field: %v", err)
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return "", fmt.Errorf("failed to read audio: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to finish request body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcription request: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client, err := providerClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("transcription failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode transcription: %v", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// whisperEndpoint resolves the transcription URL and the key used to call it
+func whisperEndpoint(ctx context.Context) (string, string, error) {
+	localURL, err := database.GetPreference(ctx, database.PrefWhisperURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get whisper preference: %v", err)
+	}
+	if localURL != "" {
+		return strings.TrimRight(localURL, "/") + "/inference", "", nil
+	}
+
+	baseURL, apiKey, err := openAICredentials(ctx, "voice input")
+	if err != nil {
+		return "", "", err
+	}
+	return baseURL + "/v1/audio/transcriptions", apiKey, nil
+}
+
+// openAICredentials returns the OpenAI base URL and key from settings.
+// feature names the caller in the error shown when another provider is configured.
+func openAICredentials(ctx context.Context, feature string) (string, string, error) {
+	settings, err := database.GetSettings(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get settings: %v", err)
+	}
+	if settings == nil {
+		return "", "", fmt.Errorf("no settings found, please configure your settings first")
+	}
+
+	company, err := database.GetCompany(ctx, settings.CompanyID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get company: %v", err)
+	}
+	if company.Name != "OpenAI" {
+		return "", "", fmt.Errorf("%s needs an OpenAI key in settings", feature)
+	}
+
+	baseURL := company.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIURL
+	}
+	return strings.TrimRight(baseURL, "/"), settings.APIKey, nil
+}
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// FinishCached is the finish reason of answers taken from the response cache
+const FinishCached = "cache"
+
+// FinishStopped is the finish reason of answers the user stopped while they
+// streamed
+const FinishStopped = "stopped"
+
+// FinishLength is the finish reason of answers cut off at the token limit
+const FinishLength = "length"
+
+// Truncated reports whether reason says the answer was cut off at the token
+// limit; providers name it differently
+func Truncated(reason string) bool {
+	switch strings.ToLower(reason) {
+	case FinishLength, "max_tokens":
+		return true
+	}
+	return false
+}
+
+// Result describes a finished completion
+type Result struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	FinishReason     string // Why the model stopped, like "stop" or "length"
+	Latency          time.Duration
+	Err              error // The request failed; the stream holds what arrived before
+
+	// Rows of the prompt and the answer in the chat's memory, 0 when they
+	// were not saved
+	PromptMemoryID int64
+	AnswerMemoryID int64
+}
+
+// recordUsage stores the tokens, cost and latency of a completion and returns
+// them with the finish reason. Token counts reported by the provider are used
+// when present, otherwise they are estimated from the text.
+func recordUsage(ctx context.Context, modelName string, messages []llms.MessageContent, resp *llms.ContentResponse, completion string, started time.Time) Result {
+	usage := database.Usage{Model: modelName, Latency: time.Since(started)}
+	var finishReason string
+	if resp != nil && len(resp.Choices) > 0 {
+		info := resp.Choices[0].GenerationInfo
+		usage.PromptTokens = firstInt(info, "PromptTokens", "InputTokens")
+		usage.CompletionTokens = firstInt(info, "CompletionTokens", "OutputTokens")
+		finishReason = resp.Choices[0].StopReason
+	}
+	if usage.PromptTokens == 0 {
+		for _, msg := range messages {
+			for _, part := range msg.Parts {
+				if text, ok := part.(llms.TextContent); ok {
+					usage.PromptTokens += EstimateTokens(text.Text)
+				}
+			}
+		}
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = EstimateTokens(completion)
+	}
+	usage.Cost, _ = EstimateCost(modelName, usage.PromptTokens, usage.CompletionTokens)
+
+	if err := database.SaveUsage(ctx, usage); err != nil {
+		log.Printf("Failed to record usage: %v", err)
+	}
+	return Result{
+		Model:            modelName,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		FinishReason:     finishReason,
+		Latency:          usage.Latency,
+	}
+}
+
+// firstInt returns the first of keys holding a positive integer in info
+func firstInt(info map[string]any, keys ...string) int {
+	for _, key := range keys {
+		if n, ok := info[key].(int); ok && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// vcrEnv records the provider traffic to fixtures when set to "record" and
+// answers from them, without network, when set to "replay"
+const vcrEnv = "LLMSCHAT_VCR"
+
+// vcrDirEnv is the folder of the fixtures, testdata/vcr when unset
+const vcrDirEnv = "LLMSCHAT_VCR_DIR"
+
+// VCR modes
+const (
+	vcrRecord = "record"
+	vcrReplay = "replay"
+)
+
+// vcrFixture is a recorded request and what the provider answered: the body
+// as it was read, chunk by chunk, so streams replay the same way, or the error
+type vcrFixture struct {
+	Method    string   `json:"method"`
+	URL       string   `json:"url"`
+	Request   string   `json:"request,omitempty"`
+	Status    int      `json:"status,omitempty"`
+	Type      string   `json:"content_type,omitempty"`
+	Chunks    []string `json:"chunks,omitempty"`
+	Error     string   `json:"error,omitempty"`      // The request failed
+	BodyError string   `json:"body_error,omitempty"` // The body broke off
+}
+
+// vcr counts the identical requests of the session so retries get their own
+// fixture
+var vcr struct {
+	sync.Mutex
+	seen map[string]int
+}
+
+// vcrTransport wraps base to record or replay the provider traffic when
+// LLMSCHAT_VCR asks for it
+func vcrTransport(base http.RoundTripper) http.RoundTripper {
+	switch mode := os.Getenv(vcrEnv); mode {
+	case vcrRecord, vcrReplay:
+		dir := os.Getenv(vcrDirEnv)
+		if dir == "" {
+			dir = filepath.Join("testdata", "vcr")
+		}
+		return recorderTransport{base: base, dir: dir, replay: mode == vcrReplay}
+	case "":
+	default:
+		log.Printf("Ignoring %s=%q, use %s or %s", vcrEnv, mode, vcrRecord, vcrReplay)
+	}
+	return base
+}
+
+// recorderTransport names each fixture after a hash of the method, URL and
+// body of the request and how often it was sent before. The query, where some
+// providers take the API key, and the headers are left out of fixtures.
+type recorderTransport struct {
+	base   http.RoundTripper
+	dir    string
+	replay bool
+}
+
+func (t recorderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := *req.URL
+	u.RawQuery = ""
+	fixture := vcrFixture{Method: req.Method, URL: u.String()}
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		fixture.Request = string(data)
+	}
+	path := t.fixturePath(fixture)
+
+	if t.replay {
+		return replayFixture(req, path)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		fixture.Error = err.Error()
+		saveFixture(path, fixture)
+		return nil, err
+	}
+	fixture.Status = resp.StatusCode
+	fixture.Type = resp.Header.Get("Content-Type")
+	resp.Body = &recordingBody{ReadCloser: resp.Body, path: path, fixture: fixture}
+	return resp, nil
+}
+
+// fixturePath returns the file of the nth identical request of the session
+func (t recorderTransport) fixturePath(fixture vcrFixture) string {
+	sum := sha256.Sum256([]byte(fixture.Method + " " + fixture.URL + "\n" + fixture.Request))
+	key := hex.EncodeToString(sum[:8])
+
+	vcr.Lock()
+	defer vcr.Unlock()
+	if vcr.seen == nil {
+		vcr.seen = make(map[string]int)
+	}
+	vcr.seen[key]++
+	return filepath.Join(t.dir, fmt.Sprintf("%s-%d.json", key, vcr.seen[key]))
+}
+
+// replayFixture answers req with the fixture at path
+func replayFixture(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no recorded response for %s %s in %s, record it with %s=%s", req.Method, req.URL.Path, path, vcrEnv, vcrRecord)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fixture vcrFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+	}
+	if fixture.Error != "" {
+		return nil, errors.New(fixture.Error)
+	}
+
+	header := make(http.Header)
+	if fixture.Type != "" {
+		header.Set("Content-Type", fixture.Type)
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode: fixture.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       &replayBody{ctx: req.Context(), chunks: fixture.Chunks, err: fixture.BodyError},
+		Request:    req,
+	}, nil
+}
+
+// recordingBody keeps the response body as it is read and saves the fixture
+// once the body ends or is closed
+type recordingBody struct {
+	io.ReadCloser
+	path    string
+	fixture vcrFixture
+	once    sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.fixture.Chunks = append(b.fixture.Chunks, string(p[:n]))
+	}
+	if err != nil {
+		if err != io.EOF {
+			b.fixture.BodyError = err.Error()
+		}
+		b.save()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.save()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) save() {
+	b.once.Do(func() { saveFixture(b.path, b.fixture) })
+}
+
+// saveFixture writes fixture to path
+func saveFixture(path string, fixture vcrFixture) {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		log.Printf("Failed to record provider response: %v", err)
+	}
+}
+
+// replayBody returns the recorded chunks one read at a time, then the
+// recorded error or io.EOF
+type replayBody struct {
+	ctx    context.Context
+	chunks []string
+	rest   bytes.Reader
+	err    string
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if b.rest.Len() == 0 {
+		if len(b.chunks) == 0 {
+			if b.err != "" {
+				return 0, errors.New(b.err)
+			}
+			return 0, io.EOF
+		}
+		b.rest.Reset([]byte(b.chunks[0]))
+		b.chunks = b.chunks[1:]
+	}
+	return b.rest.Read(p)
+}
+
+func (b *replayBody) Close() error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// replayModel returns an OpenAI model answering from the fixtures in
+// testdata/vcr, without network
+func replayModel(t *testing.T) *openai.LLM {
+	t.Helper()
+	t.Setenv(vcrEnv, vcrReplay)
+	vcr.Lock()
+	vcr.seen = nil
+	vcr.Unlock()
+
+	client := &http.Client{Transport: vcrTransport(http.DefaultTransport)}
+	model, err := openai.New(
+		openai.WithToken("test-key"),
+		openai.WithBaseURL("https://api.openai.com/v1"),
+		openai.WithModel("gpt-4o-mini"),
+		openai.WithHTTPClient(client),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
+
+func TestVCRReplaysStream(t *testing.T) {
+	model := replayModel(t)
+
+	var chunks []string
+	resp, err := model.GenerateContent(context.Background(),
+		[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Say something short.")},
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			if len(chunk) > 0 {
+				chunks = append(chunks, string(chunk))
+			}
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+
+	want := []string{"Replayed", " answers", " stream too."}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+	if got := resp.Choices[0].Content; got != "Replayed answers stream too." {
+		t.Errorf("content = %q", got)
+	}
+	if got := resp.Choices[0].StopReason; got != "stop" {
+		t.Errorf("stop reason = %q, want stop", got)
+	}
+}
+
+func TestVCRReplayWithoutFixture(t *testing.T) {
+	model := replayModel(t)
+
+	_, err := model.GenerateContent(context.Background(),
+		[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "A prompt that was never recorded.")},
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error { return nil }))
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Fatalf("err = %v, want a missing fixture error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+	"github.com/devalexandre/llmschat/logsearch"
+)
+
+// logSearchRounds bounds how many times in a row the model can search the
+// attached logs before it has to answer
+const logSearchRounds = 3
+
+// logFiles returns the text attachments of a chat too large to be sent whole,
+// which the model searches instead
+func logFiles(chatID int) []logsearch.File {
+	attachments, err := database.GetChatAttachments(appCtx, chatID)
+	if err != nil {
+		log.Printf("Failed to load attachments: %v", err)
+		return nil
+	}
+	var files []logsearch.File
+	for _, a := range attachments {
+		if !strings.HasPrefix(a.MimeType, "text/") || a.Size <= pasteExcerptSize {
+			continue
+		}
+		data, err := a.Data()
+		if err != nil {
+			log.Printf("Failed to read attachment: %v", err)
+			continue
+		}
+		files = append(files, logsearch.NewFile(a.Name, string(data)))
+	}
+	return files
+}
+
+// logPrompt tells the model how to search the large text attachments of a chat
+func logPrompt(chatID int) string {
+	files := logFiles(chatID)
+	if len(files) == 0 {
+		return ""
+	}
+	return logsearch.ToolPrompt(files)
+}
+
+// runLogSearches runs the log searches the model asked for in reply, posts the
+// matching lines and sends them back to the model, until it answers without
+// searching or logSearchRounds is reached
+func runLogSearches(chatID int, reply string) {
+	for round := 0; round < logSearchRounds; round++ {
+		queries := logsearch.ParseQueries(reply)
+		if len(queries) == 0 {
+			return
+		}
+		files := logFiles(chatID)
+		var results []string
+		for _, q := range queries {
+			result := fmt.Sprintf("There is no attached log named %q.", q.File)
+			for _, file := range files {
+				if file.Name != q.File {
+					continue
+				}
+				text, err := file.Grep(q)
+				if err != nil {
+					text = fmt.Sprintf("The search in %s failed: %v", q.File, err)
+				}
+				result = text
+				break
+			}
+			results = append(results, result)
+		}
+		found := strings.Join(results, "\n\n")
+		AddMessage(chatID, found, "Log search", false)
+
+		chat := findChat(chatID)
+		if chat == nil {
+			return
+		}
+		var err error
+		reply, err = llm.GetResponse(appCtx, llm.Request{
+			Prompt:        found,
+			Model:         currentModel,
+			SystemPrompt:  systemPrompt(chat),
+			ChatID:        chatID,
+			Temperature:   chat.Temperature,
+			MaxTokens:     chat.MaxTokens,
+			ContextTokens: chat.ContextTokens,
+		})
+		if err != nil {
+			AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
+			return
+		}
+		AddMessage(chatID, reply, "AI", true)
+	}
+}
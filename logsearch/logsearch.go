@@ -0,0 +1,202 @@
+package logsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Limits on what one search sends back to the model
+const (
+	DefaultLimit  = 200
+	MaxLimit      = 500
+	maxLineLength = 500
+)
+
+// toolPrompt explains how the model searches the attached logs
+const toolPrompt = `The user attached large log files; you only saw excerpts of them:
+%s
+To search a file, add a fenced block like this to your reply, one block per search, and stop there.
+The matching lines are sent back to you in the next message. "pattern" is a regular expression;
+"from", "to" and "limit" are optional and limit the lines to a time range and a count.
+` + "```log-grep" + `
+{"file": "app.log", "pattern": "ERROR|timeout", "from": "2024-05-02T15:00", "to": "2024-05-02T15:30", "limit": 100}
+` + "```"
+
+// queryPattern matches the fenced search blocks of a reply
+var queryPattern = regexp.MustCompile("(?s)```log-grep\\s*\\n(.*?)```")
+
+// timestampPatterns match the timestamp at the start of a log line, with the
+// layouts to parse them
+var timestampPatterns = []struct {
+	pattern *regexp.Regexp
+	layouts []string
+}{
+	{regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)`),
+		[]string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02T15:04:05Z0700", "2006-01-02 15:04:05Z0700"}},
+	{regexp.MustCompile(`^\[?(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2})`), []string{"2006/01/02 15:04:05"}},
+	{regexp.MustCompile(`^([A-Z][a-z]{2} +\d{1,2} \d{2}:\d{2}:\d{2})`), []string{"Jan _2 15:04:05"}},
+}
+
+// queryLayouts are the accepted formats of the from and to times of a query
+var queryLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// File is a log the model can search
+type File struct {
+	Name  string
+	Lines []string
+}
+
+// NewFile splits the text of a log into lines
+func NewFile(name, text string) File {
+	return File{Name: name, Lines: strings.Split(strings.TrimRight(text, "\n"), "\n")}
+}
+
+// Query is a search the model asked for
+type Query struct {
+	File    string `json:"file"`
+	Pattern string `json:"pattern"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Limit   int    `json:"limit"`
+}
+
+// ToolPrompt describes the searchable files for the system prompt
+func ToolPrompt(files []File) string {
+	var list strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&list, "- %s: %d lines", file.Name, len(file.Lines))
+		if first, last, ok := file.TimeRange(); ok {
+			fmt.Fprintf(&list, " from %s to %s", first.Format("2006-01-02 15:04:05"), last.Format("2006-01-02 15:04:05"))
+		}
+		list.WriteString("\n")
+	}
+	return fmt.Sprintf(toolPrompt, list.String())
+}
+
+// ParseQueries returns the searches the model asked for in reply
+func ParseQueries(reply string) []Query {
+	var queries []Query
+	for _, match := range queryPattern.FindAllStringSubmatch(reply, -1) {
+		var q Query
+		if err := json.Unmarshal([]byte(match[1]), &q); err != nil || q.File == "" {
+			continue
+		}
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// lineTime parses the timestamp at the start of a log line
+func lineTime(line string, year int) (time.Time, bool) {
+	for _, p := range timestampPatterns {
+		match := p.pattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value := strings.Replace(match[1], ",", ".", 1)
+		for _, layout := range p.layouts {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				if t.Year() == 0 {
+					t = t.AddDate(year, 0, 0)
+				}
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// TimeRange returns the first and last timestamps of the file
+func (f File) TimeRange() (first, last time.Time, ok bool) {
+	year := time.Now().Year()
+	for _, line := range f.Lines {
+		if t, found := lineTime(line, year); found {
+			first, ok = t, true
+			break
+		}
+	}
+	for i := len(f.Lines) - 1; i >= 0 && ok; i-- {
+		if t, found := lineTime(f.Lines[i], year); found {
+			last = t
+			break
+		}
+	}
+	return first, last, ok
+}
+
+// parseQueryTime parses the from or to time of a query; empty means no bound
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range queryLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// Grep runs a query on the file and returns the matching lines, numbered, as a
+// message for the model. Lines without a timestamp, like the rest of a stack
+// trace, take the time of the line before them.
+func (f File) Grep(q Query) (string, error) {
+	pattern, err := regexp.Compile("(?i)" + q.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v", err)
+	}
+	from, err := parseQueryTime(q.From)
+	if err != nil {
+		return "", err
+	}
+	to, err := parseQueryTime(q.To)
+	if err != nil {
+		return "", err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	var b strings.Builder
+	year := time.Now().Year()
+	var current time.Time
+	matches := 0
+	for i, line := range f.Lines {
+		if t, ok := lineTime(line, year); ok {
+			current = t
+		}
+		if (q.From != "" || q.To != "") && current.IsZero() {
+			continue
+		}
+		if (!from.IsZero() && current.Before(from)) || (!to.IsZero() && current.After(to)) {
+			continue
+		}
+		if !pattern.MatchString(line) {
+			continue
+		}
+		matches++
+		if matches <= limit {
+			if runes := []rune(line); len(runes) > maxLineLength {
+				line = string(runes[:maxLineLength]) + "…"
+			}
+			fmt.Fprintf(&b, "%d: %s\n", i+1, line)
+		}
+	}
+
+	summary := fmt.Sprintf("%d lines of %s match %q", matches, f.Name, q.Pattern)
+	if q.From != "" || q.To != "" {
+		summary += fmt.Sprintf(" between %q and %q", q.From, q.To)
+	}
+	if matches == 0 {
+		return summary + ".", nil
+	}
+	if matches > limit {
+		summary += fmt.Sprintf(", the first %d are", limit)
+	}
+	return summary + ":\n```log\n" + b.String() + "```", nil
+}
@@ -0,0 +1,144 @@
+package logsearch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = `2024-05-02 14:59:58 INFO starting
+2024-05-02T15:00:01Z ERROR connection refused
+panic: nil map
+	at handler.go:12
+2024-05-02 15:10:00,123 WARN slow request
+[2024-05-02 15:31:00] ERROR timeout
+2024/05/02 15:45:00 error again`
+
+func TestGrep(t *testing.T) {
+	// Times are read in local time, as the lines carry no zone
+	old := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = old })
+
+	f := NewFile("app.log", sample+"\n")
+	tests := []struct {
+		name    string
+		query   Query
+		lines   []int // Numbers of the matching lines shown
+		summary string
+	}{
+		{"pattern is case insensitive", Query{Pattern: "error"}, []int{2, 6, 7}, `3 lines of app.log match "error":`},
+		{"regular expression", Query{Pattern: "ERROR|WARN"}, []int{2, 5, 6, 7}, ""},
+		{"from bound", Query{Pattern: "error", From: "2024-05-02T15:30"}, []int{6, 7}, ""},
+		{"to bound", Query{Pattern: "error", To: "2024-05-02 15:30"}, []int{2}, ""},
+		{"untimed lines take the time before them", Query{Pattern: "handler", From: "2024-05-02T15:00", To: "2024-05-02T15:05"}, []int{4}, ""},
+		{"day bound", Query{Pattern: ".", From: "2024-05-03"}, nil, `0 lines of app.log match "." between "2024-05-03" and "".`},
+		{"limit", Query{Pattern: "error", Limit: 2}, []int{2, 6}, `3 lines of app.log match "error", the first 2 are:`},
+		{"no match", Query{Pattern: "fatal"}, nil, `0 lines of app.log match "fatal".`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.Grep(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.summary != "" && strings.SplitN(got, "\n", 2)[0] != tt.summary {
+				t.Errorf("summary = %q, want %q", strings.SplitN(got, "\n", 2)[0], tt.summary)
+			}
+			var numbers []int
+			for _, line := range strings.Split(got, "\n") {
+				var n int
+				if _, err := fmt.Sscanf(line, "%d:", &n); err == nil {
+					numbers = append(numbers, n)
+				}
+			}
+			if fmt.Sprint(numbers) != fmt.Sprint(tt.lines) {
+				t.Errorf("matching lines = %v, want %v\n%s", numbers, tt.lines, got)
+			}
+		})
+	}
+}
+
+func TestGrepErrors(t *testing.T) {
+	f := NewFile("app.log", sample)
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{"invalid pattern", Query{Pattern: "("}},
+		{"invalid from", Query{Pattern: "x", From: "yesterday"}},
+		{"invalid to", Query{Pattern: "x", To: "05/02/2024"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.Grep(tt.query); err == nil {
+				t.Error("Grep() succeeded")
+			}
+		})
+	}
+}
+
+func TestGrepTruncatesLongLines(t *testing.T) {
+	f := NewFile("long.log", "ERROR "+strings.Repeat("é", 2*maxLineLength))
+	got, err := f.Grep(Query{Pattern: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "…") || strings.Count(got, "é") != maxLineLength-len("ERROR ") {
+		t.Errorf("long line was not cut at %d runes:\n%s", maxLineLength, got)
+	}
+}
+
+func TestParseQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  []Query
+	}{
+		{"no block", "Nothing to search.", nil},
+		{"one block", "Let me look.\n```log-grep\n{\"file\": \"app.log\", \"pattern\": \"ERROR\", \"limit\": 10}\n```", []Query{{File: "app.log", Pattern: "ERROR", Limit: 10}}},
+		{"two blocks", "```log-grep\n{\"file\": \"a.log\", \"pattern\": \"x\"}\n```\n```log-grep\n{\"file\": \"b.log\", \"pattern\": \"y\", \"from\": \"2024-05-02\"}\n```", []Query{{File: "a.log", Pattern: "x"}, {File: "b.log", Pattern: "y", From: "2024-05-02"}}},
+		{"invalid JSON is skipped", "```log-grep\n{file: a.log}\n```", nil},
+		{"block without file is skipped", "```log-grep\n{\"pattern\": \"x\"}\n```", nil},
+		{"other fences are ignored", "```json\n{\"file\": \"a.log\", \"pattern\": \"x\"}\n```", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQueries(tt.reply)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ParseQueries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		first, last string
+		ok          bool
+	}{
+		{"timestamps", sample, "2024-05-02 14:59:58", "2024-05-02 15:45:00", true},
+		{"untimed lines around", "header\n2024-05-02 10:00:00 a\n2024-05-02 11:00:00 b\ntrailer", "2024-05-02 10:00:00", "2024-05-02 11:00:00", true},
+		{"no timestamps", "just\ntext", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, ok := NewFile("x.log", tt.text).TimeRange()
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if got := first.Format("2006-01-02 15:04:05"); got != tt.first {
+				t.Errorf("first = %s, want %s", got, tt.first)
+			}
+			if got := last.Format("2006-01-02 15:04:05"); got != tt.last {
+				t.Errorf("last = %s, want %s", got, tt.last)
+			}
+		})
+	}
+}
@@ -1,38 +1,76 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/highlight"
 	"github.com/devalexandre/llmschat/llm"
-	"github.com/devalexandre/llmschat/themes/dracula"
+	"github.com/devalexandre/llmschat/templates"
 )
 
 type ChatMessage struct {
-	Text   string
-	Sender string
-	IsAI   bool
+	ID        int // Stored ID, 0 when the message could not be saved
+	Text      string
+	Sender    string
+	IsAI      bool
+	CreatedAt time.Time
+	MemoryID  int64 // Row in the model's memory of the chat, 0 when unknown
+
+	database.MessageMeta // How an AI message was generated
 }
 
 type Chat struct {
-	ID       int
-	Title    string
-	Messages []ChatMessage
+	ID        int
+	Title     string
+	Messages  []ChatMessage
+	Agents    []Agent
+	AgentMode string
+	NextAgent int // Index of the agent that speaks next in round robin mode
+	Tags      []string
+	FolderID  int // 0 when the chat is not in a folder
+
+	database.ChatConfig // Model, system prompt and generation parameters
+
+	Mode           string
+	InterviewGoal  string
+	InterviewStart int // Index of the first interview message
+
+	BypassCache bool // Always ask the model even when a cached answer exists
+
+	Loaded bool // Messages have been read from the database
 }
 
 // Custom entry widget that implements Focusable
 type CustomEntry struct {
 	widget.Entry
-	onEnter func()
+	onEnter  func()
+	onEscape func()
+	onPaste  func(text string) bool // Returns true when it takes over the paste
+
+	keybindings  string // keybindingsDefault, keybindingsVim or keybindingsEmacs
+	normal       bool   // Vim's normal mode, where keys are commands
+	pending      rune   // Vim operator waiting for its motion
+	onModeChange func()
+
+	history draftHistory
 }
 
 func NewCustomEntry() *CustomEntry {
@@ -46,44 +84,121 @@ func NewCustomEntry() *CustomEntry {
 // TypedKey handles keyboard events for the CustomEntry
 // - Enter: Triggers the onEnter callback (sends message)
 // - Shift+Enter: Adds a new line to the text input
+// - Escape: Triggers the onEscape callback (leaves the input), or enters
+// normal mode with vim keybindings
 func (e *CustomEntry) TypedKey(key *fyne.KeyEvent) {
 	fmt.Printf("Key pressed: %v\n", key.Name)
+	if key.Name == fyne.KeyEscape && e.keybindings == keybindingsVim && !e.normal {
+		e.setNormal(true)
+		return
+	}
+	if key.Name == fyne.KeyEscape && e.onEscape != nil {
+		e.onEscape()
+		return
+	}
 	if key.Name == fyne.KeyReturn {
 		if fyne.KeyModifierShift != 0 {
-			e.Entry.TypedKey(key) // Shift+Enter: new line
+			e.edit(false, func() { e.Entry.TypedKey(key) }) // Shift+Enter: new line
 		} else if e.onEnter != nil {
 			e.onEnter() // Enter: custom action
 		}
 		return
 	}
-	e.Entry.TypedKey(key)
+	e.edit(key.Name == fyne.KeyBackspace || key.Name == fyne.KeyDelete, func() { e.Entry.TypedKey(key) })
+}
 
+// TypedShortcut lets onPaste handle pasted text before the entry inserts it
+// and keeps undo and redo in the draft history
+func (e *CustomEntry) TypedShortcut(shortcut fyne.Shortcut) {
+	if s, ok := shortcut.(*desktop.CustomShortcut); ok && e.keybindings == keybindingsEmacs &&
+		s.KeyName == fyne.KeySlash && s.Modifier == fyne.KeyModifierControl {
+		e.undoDraft()
+		return
+	}
+	if e.keybindings == keybindingsEmacs {
+		handled := false
+		e.edit(false, func() { handled = e.emacsShortcut(shortcut) })
+		if handled {
+			return
+		}
+	}
+	if s, ok := shortcut.(*desktop.CustomShortcut); ok && e.keybindings == keybindingsVim &&
+		s.KeyName == fyne.KeyR && s.Modifier == fyne.KeyModifierControl {
+		e.redoDraft()
+		return
+	}
+	if e.historyShortcut(shortcut) {
+		return
+	}
+	if paste, ok := shortcut.(*fyne.ShortcutPaste); ok && e.onPaste != nil && paste.Clipboard != nil {
+		if e.onPaste(paste.Clipboard.Content()) {
+			return
+		}
+	}
+	e.edit(false, func() { e.Entry.TypedShortcut(shortcut) })
+}
+
+// insert types text at the cursor, replacing the selection
+func (e *CustomEntry) insert(text string) {
+	e.edit(false, func() { e.Entry.TypedShortcut(&fyne.ShortcutPaste{Clipboard: textClipboard(text)}) })
 }
 
 // Global variables
 var (
 	currentModel   string
 	mainScroll     *container.Scroll
-	chats          []Chat
+	chats          []*Chat
 	currentChat    *Chat
-	chatList       *widget.List
+	chatList       *widget.Tree // Folders and chats in the sidebar
+	folders        []database.Folder
 	chatContainers map[int]*fyne.Container // Map to store message containers for each chat
 	mainContainer  *fyne.Container         // Container to hold current chat messages
+	mainWindow     fyne.Window
+	cacheCheck     *widget.Check
+	modelSelect    *widget.Select
+	restoringModel bool // The model select is following the chat, not the user
+	styleGroup     *widget.CheckGroup
+	tagFilter      *widget.Select // Lists only the chats with the chosen tag
+	sendBtn        *sendButton    // Turns into a stop button while the chat's answer streams
+	chatInput      *CustomEntry
+
+	// chatsMu guards chats and the messages of each chat, which the news and
+	// report schedulers and the streams change from their own goroutines
+	chatsMu sync.Mutex
+
+	// streams holds the answer streaming in each chat
+	streams   = make(map[int]*activeStream)
+	streamsMu sync.Mutex
+
+	// appCtx is cancelled when the app shuts down, stopping the database
+	// queries and model requests still running
+	appCtx, stopApp = context.WithCancel(context.Background())
 )
 
 func main() {
+	// Run a command line subcommand instead of the app when given one
+	if len(os.Args) > 1 && !isOpenArg(os.Args[1]) {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	// Initialize database
 	fmt.Println("Initializing database...")
-	if err := database.InitDB(); err != nil {
+	if err := database.InitDB(appCtx); err != nil {
 		fmt.Printf("Failed to initialize database: %v\n", err)
 	}
 	fmt.Println("Database initialized.")
 
 	defer database.Close()
+	defer stopApp()
+	defer llm.StopLocalServer()
 
 	a := app.New()
-	a.Settings().SetTheme(&dracula.DraculaTheme{})
+	watchFocus(a)
+	if err := applyAppearance(); err != nil {
+		log.Printf("Failed to apply the appearance settings: %v", err)
+	}
 	w := a.NewWindow("AI Chat")
+	mainWindow = w
 	w.Resize(fyne.NewSize(900, 700))
 
 	// Initialize chat containers map
@@ -96,125 +211,253 @@ func main() {
 	mainScroll.SetMinSize(fyne.NewSize(600, 600))
 
 	// Create model selection
-	modelSelect := widget.NewSelect([]string{}, func(value string) {
+	modelSelect = widget.NewSelect([]string{}, func(value string) {
 		currentModel = value
+		// A model picked by the user sticks to the open chat
+		if chat := currentChatEntry(); chat != nil && !restoringModel && chat.Model != value {
+			chat.Model = value
+			saveChatConfig(chat)
+		}
 	})
 	modelSelect.Hide() // Hide initially
-
-	// Check if we have API key configured and load available models
-	settings, err := database.GetSettings()
-	if err == nil && settings != nil && settings.APIKey != "" {
-		// Get models for the current company
-		models, err := database.GetModelsByCompany(settings.CompanyID)
-		if err == nil && len(models) > 0 {
-			modelNames := make([]string, len(models))
-			for i, model := range models {
-				modelNames[i] = model.Name
-			}
-			modelSelect.Options = modelNames
-
-			// Set current model from settings
-			for _, model := range models {
-				if model.ID == settings.ModelID {
-					modelSelect.SetSelected(model.Name)
-					currentModel = model.Name
-					break
-				}
-			}
-			modelSelect.Show()
+	refreshModelSelect()
+
+	// Images and pasted text attached to the next message
+	var pendingImages []llm.Image
+	var pendingFiles []pastedFile
+	imagesLabel := widget.NewLabel("")
+	imagesLabel.Importance = widget.LowImportance
+	fileChips := container.NewHBox()
+	var imagesBar *fyne.Container
+	refreshImagesBar := func() {
+		if len(pendingImages) == 0 && len(pendingFiles) == 0 {
+			imagesBar.Hide()
+			return
+		}
+		names := make([]string, len(pendingImages))
+		for i, image := range pendingImages {
+			names[i] = image.Name
+		}
+		imagesLabel.SetText("Attached: " + strings.Join(names, ", "))
+		fileChips.Objects = nil
+		for _, file := range pendingFiles {
+			fileChips.Add(newFileChip(w, file))
 		}
+		imagesBar.Show()
 	}
+	imagesBar = container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		pendingImages = nil
+		pendingFiles = nil
+		refreshImagesBar()
+	}), container.NewHBox(imagesLabel, fileChips))
+	imagesBar.Hide()
+
+	// Answer styles added to the system prompt while they are checked
+	styleNames := make([]string, len(llm.AnswerStyles))
+	for i, style := range llm.AnswerStyles {
+		styleNames[i] = style.Name
+	}
+	styleGroup = widget.NewCheckGroup(styleNames, nil)
+	styleGroup.Horizontal = true
 
 	// Custom input field with Enter key handling
 	input := NewCustomEntry()
 	input.SetPlaceHolder("Type your message... (Press Enter to send, Shift+Enter for new line)")
+	chatInput = input
+
+	// The vim mode is shown above the input
+	keybindingsLabel := widget.NewLabel("")
+	keybindingsLabel.Importance = widget.LowImportance
+	keybindingsLabel.Hide()
+	input.onModeChange = func() {
+		keybindingsLabel.SetText(input.modeName())
+		if keybindingsLabel.Text == "" {
+			keybindingsLabel.Hide()
+		} else {
+			keybindingsLabel.Show()
+		}
+	}
+	refreshKeybindings()
 
 	input.Resize(fyne.NewSize(500, 60))
 
-	// Styled send button
-	sendFunc := func() {
+	// Large pastes of code, logs or JSON are wrapped in a fenced block or
+	// attached as a file, as the user picks
+	input.onPaste = func(text string) bool {
+		return askPaste(w, text, input.insert, func(file pastedFile) {
+			pendingFiles = append(pendingFiles, file)
+			refreshImagesBar()
+		})
+	}
+
+	// sendMessage posts the input text and streams the AI reply
+	sendMessage := func() {
 		if currentChat == nil {
 			return
 		}
 
-		userMessage := input.Text
+		userMessage := templates.Resolve(input.Text, templateContext(w, input))
 		if userMessage != "" {
 			// Add user message
-			AddMessage(currentChat.ID, userMessage, "You", false)
+			messageID := AddMessage(currentChat.ID, userMessage, "You", false)
 			input.SetText("")
+			images := pendingImages
+			pendingImages = nil
+			files := pendingFiles
+			pendingFiles = nil
+			refreshImagesBar()
+
+			// Workspace files referenced with @file:path are sent along
+			refFiles, problems := referencedFiles(userMessage)
+			files = append(files, refFiles...)
+			if len(problems) > 0 {
+				dialog.ShowInformation("Files not sent", strings.Join(problems, "\n"), w)
+			}
+			saveFiles(currentChat.ID, messageID, files)
 
-			// Get AI response with current model in stream mode
-			go func() {
-				// Get chat container
-				msgContainer := chatContainers[currentChat.ID]
-				if msgContainer == nil {
-					return
-				}
+			// /image prompts are routed to the image generation API
+			if prompt, ok := strings.CutPrefix(userMessage, imageCommand); ok {
+				go generateImageReply(currentChat.ID, strings.TrimSpace(prompt))
+				return
+			}
 
-				// Create initial AI message container
-				aiMessage := container.NewVBox()
-				senderLabel := widget.NewLabel("AI")
-				senderLabel.TextStyle = fyne.TextStyle{Italic: true}
+			// Multi-agent chats are answered by their participants
+			chatID := currentChat.ID
+			if chat := findChat(chatID); chat != nil && len(chat.Agents) > 0 {
+				go runAgentTurns(chatID, false)
+				return
+			}
 
-				loadingLabel := widget.NewLabel("Loading...")
-				aiMessage.Add(loadingLabel)
-				msgContainer.Refresh()
+			// Interview chats reply with the next question
+			if chat := findChat(chatID); chat != nil && chat.Mode == chatModeInterview {
+				go askInterviewQuestion(chatID)
+				return
+			}
 
-				stream, err := llm.GetResponseStream(userMessage, currentModel)
-				aiMessage.Remove(loadingLabel)
+			// Get AI response with current model in stream mode
+			go func() {
+				// Attached images are resized and stripped before they are
+				// stored and sent
+				images, err := llm.PrepareImages(appCtx, images)
 				if err != nil {
-					errMsg := fmt.Sprintf("Error: %v", err)
-					AddMessage(currentChat.ID, errMsg, "System", true)
+					AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
 					return
 				}
-
-				messageLabel := widget.NewRichText()
-				messageLabel.Wrapping = fyne.TextWrapWord
-				messageBox := container.NewVBox(messageLabel)
-				messageContainer := container.NewBorder(
-					nil, nil, layout.NewSpacer(), layout.NewSpacer(),
-					messageBox,
-				)
-
-				aiMessage.Add(senderLabel)
-				aiMessage.Add(messageContainer)
-				aiMessage.Add(widget.NewSeparator())
-				msgContainer.Add(aiMessage)
-
-				fullText := ""
-				for chunk := range stream {
-					fullText += chunk
-					messageLabel.ParseMarkdown(fullText)
-					messageLabel.Refresh()
-					if currentChat.ID == currentChat.ID {
-						mainScroll.ScrollToBottom()
+				for _, image := range images {
+					if _, err := database.SaveAttachment(appCtx, chatID, messageID, image.Name, image.MimeType, image.Data); err != nil {
+						log.Printf("Failed to save image: %v", err)
 					}
 				}
-
-				// After streaming is complete, store the AI response in chat history
-				msg := ChatMessage{
-					Text:   fullText,
-					Sender: "AI",
-					IsAI:   true,
-				}
-				currentChat.Messages = append(currentChat.Messages, msg)
+				streamAnswer(chatID, messageID, userMessage, files, images, currentModel)
 			}()
 		}
 	}
 
-	send := widget.NewButtonWithIcon("Send", theme.MailSendIcon(), sendFunc)
-	send.Resize(fyne.NewSize(100, 60))
+	// Workspace paths offered while an @file: reference is typed
+	fileBar := container.NewVBox()
+	fileBar.Hide()
+	input.OnChanged = func(text string) {
+		refreshFileSuggestions(fileBar, input)
+	}
+
+	// Styled send button
+	sendFunc := func() {
+		// Enter does not send while the chat's answer streams, the stop
+		// button has to end it first
+		if currentChat != nil && isStreaming(currentChat.ID) {
+			return
+		}
+		moderatePrompt(input.Text, w, func() {
+			cost, ok := llm.EstimateCost(currentModel, requestTokens(input.Text, pendingFiles), 0)
+			if ok && cost >= costWarningThreshold() {
+				dialog.ShowConfirm("Expensive message",
+					fmt.Sprintf("This message will cost about $%.3f with %s. Send anyway?", cost, currentModel),
+					func(confirmed bool) {
+						if confirmed {
+							sendMessage()
+						}
+					}, w)
+				return
+			}
+			sendMessage()
+		})
+	}
+
+	// Send button stops the answer of the chat while it streams
+	sendBtn = newSendButton(func() {
+		if currentChat != nil && stopStream(currentChat.ID) {
+			return
+		}
+		sendFunc()
+	})
+	sendBtn.costText = func() (string, bool) {
+		return costPreview(input.Text, pendingFiles)
+	}
+	sendBtn.Resize(fyne.NewSize(100, 60))
 
 	// Set up Enter key handling
 	input.onEnter = sendFunc
 
+	// Microphone button records audio and inserts the Whisper transcript
+	recorder := &audioRecorder{}
+	var micBtn *widget.Button
+	micBtn = widget.NewButtonWithIcon("", theme.MediaRecordIcon(), func() {
+		if !recorder.Recording() {
+			if err := recorder.Start(); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			micBtn.SetIcon(theme.MediaStopIcon())
+			return
+		}
+
+		micBtn.SetIcon(theme.MediaRecordIcon())
+		micBtn.Disable()
+		go func() {
+			defer micBtn.Enable()
+			text, err := transcribeRecording(recorder)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Voice input failed: %v", err), w)
+				return
+			}
+			if input.Text != "" && !strings.HasSuffix(input.Text, " ") {
+				text = " " + text
+			}
+			input.SetText(input.Text + text)
+		}()
+	})
+
+	// Image button attaches a picture for vision models to the next message
+	imageBtn := widget.NewButtonWithIcon("", theme.FileImageIcon(), func() {
+		picker := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to read image: %v", err), w)
+				return
+			}
+			pendingImages = append(pendingImages, llm.Image{Name: reader.URI().Name(), MimeType: reader.URI().MimeType(), Data: data})
+			refreshImagesBar()
+		}, w)
+		picker.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".jpg", ".jpeg", ".gif", ".webp"}))
+		picker.Show()
+	})
+
+	// Prompt button inserts a stored prompt template
+	promptsBtn := widget.NewButtonWithIcon("", theme.ListIcon(), func() {
+		showPromptPicker(w, input)
+	})
+
 	// Create a container with layout that respects sizes
 	inputWrapper := container.NewHBox(layout.NewSpacer())
 	inputWrapper.Add(input)
 
 	// Create the input container with proper layout
 	inputContainer := container.NewBorder(
-		nil, nil, nil, send,
+		container.NewVBox(imagesBar, styleGroup, keybindingsLabel, fileBar), nil, nil, container.NewHBox(imageBtn, promptsBtn, micBtn, sendBtn),
 		container.NewStack(
 			input,
 		),
@@ -223,10 +466,69 @@ func main() {
 	// Create sidebar with chat history
 	sidebar := createSidebar(w)
 
+	// Instructions inspector shows the merged system prompt for the next request
+	instructionsBtn := widget.NewButtonWithIcon("Instructions", theme.DocumentIcon(), func() {
+		showInstructionsInspector(w)
+	})
+
+	// Parameters button sets the temperature and answer length of the chat
+	parametersBtn := widget.NewButtonWithIcon("Parameters", theme.SettingsIcon(), func() {
+		showChatParameters(w)
+	})
+
+	// Agents button manages the AI participants of the current chat
+	agentsBtn := widget.NewButtonWithIcon("Agents", theme.AccountIcon(), func() {
+		showAgentsDialog(w)
+	})
+
+	// Interview button starts a session where the AI asks the questions
+	interviewBtn := widget.NewButtonWithIcon("Interview", theme.QuestionIcon(), func() {
+		showInterviewDialog(w)
+	})
+
+	// Form button maps the conversation onto a form schema
+	formBtn := widget.NewButtonWithIcon("Fill form", theme.ListIcon(), func() {
+		showFormFillDialog(w)
+	})
+
+	// Export button saves the chat as a file that opens in the app
+	exportBtn := widget.NewButtonWithIcon("Export", theme.DownloadIcon(), func() {
+		exportChatFile(w)
+	})
+
+	// Select button picks messages to copy or export without the rest of the chat
+	selectBtn := widget.NewButtonWithIcon("Select", theme.CheckButtonCheckedIcon(), func() {
+		if selecting {
+			stopSelecting()
+			return
+		}
+		startSelecting()
+	})
+
+	// Attachments button lists the files of the chat
+	attachmentsBtn := widget.NewButtonWithIcon("Attachments", theme.FileIcon(), func() {
+		showAttachmentsDialog(w)
+	})
+
+	// Per-chat switch to skip the response cache
+	cacheCheck = widget.NewCheck("Cache", func(checked bool) {
+		if chat := currentChatEntry(); chat != nil {
+			chat.BypassCache = !checked
+		}
+	})
+
+	// Moderator controls for multi-agent chats and interview controls
+	agentBar = container.NewVBox()
+	interviewBar = container.NewVBox()
+	powerBar = container.NewVBox()
+	selectionBar = container.NewVBox()
+	refreshChatBars()
+	refreshPowerBar()
+
 	// Main content with model selector above messages
 	mainContent := container.NewBorder(
-		modelSelect, // Place model selector at top
-		container.NewPadded(inputContainer),
+		container.NewBorder(nil, nil, nil, container.NewHBox(cacheCheck, attachmentsBtn, selectBtn, exportBtn, formBtn, interviewBtn, agentsBtn, parametersBtn, instructionsBtn), modelSelect), // Place model selector at top
+		container.NewVBox(agentBar, interviewBar, powerBar, selectionBar, container.NewPadded(inputContainer)),
 		nil,
 		nil,
 		mainScroll,
@@ -239,30 +541,324 @@ func main() {
 	content.SetOffset(0.2)
 
 	w.SetContent(content)
+
+	// Messages are navigated with the keyboard while the input is not
+	// focused; Escape switches between the input and the messages
+	resend := func(prompt string) {
+		input.SetText(prompt)
+		sendFunc()
+	}
+	input.onEscape = func() {
+		w.Canvas().Unfocus()
+		moveMessageCursor(0)
+	}
 	w.Canvas().SetOnTypedKey(func(key *fyne.KeyEvent) {
-		fmt.Printf("Key pressed: %v\n", key.Name)
-		// Add additional key handling logic here
+		switch key.Name {
+		case fyne.KeyDown:
+			handleMessageKey(w, input, resend, 'j')
+		case fyne.KeyUp:
+			handleMessageKey(w, input, resend, 'k')
+		case fyne.KeyEscape:
+			clearMessageCursor()
+			w.Canvas().Focus(input)
+		}
+	})
+	w.Canvas().SetOnTypedRune(func(r rune) {
+		handleMessageKey(w, input, resend, r)
+	})
+	go runNewsScheduler()
+	go runTrashPurge()
+	go runReportScheduler()
+	go runPowerMonitor()
+	go runWarmUp()
+	go checkDatabase(w)
+	go offerLegacyMigration(w)
+
+	// Open the chat exports and links the app was launched with or that are dropped on it
+	for _, arg := range os.Args[1:] {
+		handleOpenArg(w, arg)
+	}
+	w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		importDroppedFiles(w, uris)
 	})
+	restartBridges()
+	restartLiveView()
 	w.ShowAndRun()
 }
 
-func AddMessage(chatID int, text, sender string, isAI bool) {
-	// Find chat by ID
-	var targetChat *Chat
+// findChat returns the chat with the given ID, or nil if there is none
+func findChat(chatID int) *Chat {
+	chatsMu.Lock()
+	defer chatsMu.Unlock()
 	for i := range chats {
 		if chats[i].ID == chatID {
-			targetChat = &chats[i]
-			break
+			return chats[i]
 		}
 	}
+	return nil
+}
+
+// addChat adds a chat to the chat list and returns it
+func addChat(chat *Chat) *Chat {
+	chatsMu.Lock()
+	chats = append(chats, chat)
+	chatsMu.Unlock()
+	return chat
+}
+
+// currentChatEntry returns the stored entry of the current chat
+func currentChatEntry() *Chat {
+	if currentChat == nil {
+		return nil
+	}
+	return findChat(currentChat.ID)
+}
+
+// storeMessage appends msg to chat, saves it to the database and returns its
+// stored ID, or 0 when it could not be saved
+func storeMessage(chat *Chat, msg ChatMessage) int {
+	loadMessages(chat)
+	id, err := database.SaveMessage(appCtx, chat.ID, msg.Sender, msg.Text, msg.IsAI)
+	if err != nil {
+		log.Printf("Failed to save message: %v", err)
+	} else {
+		if msg.MessageMeta != (database.MessageMeta{}) {
+			if err := database.SetMessageMeta(appCtx, id, msg.MessageMeta); err != nil {
+				log.Printf("Failed to save message details: %v", err)
+			}
+		}
+		if msg.MemoryID != 0 {
+			if err := database.SetMessageMemory(appCtx, id, msg.MemoryID); err != nil {
+				log.Printf("Failed to link message to memory: %v", err)
+			}
+		}
+	}
+	msg.ID = id
+	msg.CreatedAt = time.Now()
+	chatsMu.Lock()
+	chat.Messages = append(chat.Messages, msg)
+	chatsMu.Unlock()
+	publishLiveView(chat.ID, "")
+	return id
+}
+
+// linkMemory links a stored message to its row in the model's memory of the
+// chat, so deleting or editing the message forgets that row
+func linkMemory(chatID, messageID int, memoryID int64) {
+	if messageID == 0 || memoryID == 0 {
+		return
+	}
+	if err := database.SetMessageMemory(appCtx, messageID, memoryID); err != nil {
+		log.Printf("Failed to link message to memory: %v", err)
+		return
+	}
+	if chat := findChat(chatID); chat != nil {
+		for i := range chat.Messages {
+			if chat.Messages[i].ID == messageID {
+				chat.Messages[i].MemoryID = memoryID
+			}
+		}
+	}
+}
+
+// streamAnswer streams the answer of model to a user message into a chat and
+// stores it; the pasted files and the prepared images are sent along. The
+// stored prompt promptID is linked to its row in the model's memory.
+func streamAnswer(chatID, promptID int, userMessage string, files []pastedFile, images []llm.Image, model string) {
+	msgContainer := chatContainers[chatID]
+	if msgContainer == nil {
+		return
+	}
+
+	metrics := llm.NewStreamMetrics()
+	chat := findChat(chatID)
+	model = answerModel(model)
+	req := llm.Request{
+		Prompt:       withStackSources(withPastedFiles(userMessage, files), userMessage, files),
+		Model:        model,
+		SystemPrompt: systemPrompt(chat),
+		ChatID:       chatID,
+		Images:       images,
+		NoEmbeddings: economyActive(),
+	}
+	var result llm.Result
+	req.OnFinish = func(r llm.Result) {
+		result = r
+	}
+	if chat != nil {
+		req.NoCache = chat.BypassCache
+		req.Temperature = chat.Temperature
+		req.MaxTokens = chat.MaxTokens
+		req.ContextTokens = chat.ContextTokens
+	}
+	ctx, cancel := context.WithCancel(appCtx)
+	active := startStream(chatID, cancel)
+	stream, err := llm.GetResponseStream(ctx, req)
+	if err != nil {
+		endStream(chatID, active)
+		addFailedAnswer(chatID, err)
+		return
+	}
+
+	aiMessage := container.NewVBox()
+	senderIcon, senderLabel := newSenderLabel("AI", "")
+	messageLabel := widget.NewRichText()
+	messageLabel.Wrapping = fyne.TextWrapWord
+	messageBox := container.NewVBox(messageLabel)
+	messageContainer := container.NewBorder(
+		nil, nil, layout.NewSpacer(), layout.NewSpacer(),
+		messageBox,
+	)
+
+	fullText := ""
+	item := newMessageItem(aiMessage, "AI", func() string { return fullText })
+	item.createdAt = time.Now()
+	readBtn := newReadAloudButton(func() string { return fullText })
+	item.icon = senderIcon
+	item.picked = newPickCheck()
+	aiMessage.Add(container.NewHBox(item.picked, senderIcon, senderLabel, newTimeLabel(item.createdAt), layout.NewSpacer(), newRegenerateButton(chatID, item), readBtn, newFollowUpButton(item), newStarButton(item), newThreadButton(item), newCopyButton(item), newDeleteButton(chatID, item)))
+	aiMessage.Add(messageContainer)
+	continueBar := container.NewHBox()
+	aiMessage.Add(continueBar)
+	proposalBox := container.NewVBox()
+	aiMessage.Add(proposalBox)
+	item.footer = newStatsFooter("")
+	aiMessage.Add(item.footer)
+	item.followups = container.NewVBox()
+	aiMessage.Add(item.followups)
+	aiMessage.Add(widget.NewSeparator())
+	addMessageItem(msgContainer, item)
+
+	// With reduced motion the answer grows by whole paragraphs and the view
+	// does not follow it
+	calm := reducedMotion()
+	if calm {
+		messageLabel.ParseMarkdown("_Writing the answer…_")
+	}
+	shown := 0
+	for chunk := range stream {
+		metrics.Chunk()
+		fullText += chunk
+		publishLiveView(chatID, fullText)
+		if calm {
+			end := strings.LastIndex(fullText, "\n\n")
+			if end <= shown {
+				continue
+			}
+			shown = end
+			messageLabel.ParseMarkdown(fullText[:end])
+			messageLabel.Refresh()
+			continue
+		}
+		messageLabel.ParseMarkdown(fullText)
+		messageLabel.Refresh()
+		if currentChat != nil && currentChat.ID == chatID {
+			mainScroll.ScrollToBottom()
+		}
+	}
+	linkMemory(chatID, promptID, result.PromptMemoryID)
+
+	// A failed request is shown as an error card that can send it again
+	if result.Err != nil && ctx.Err() == nil {
+		endStream(chatID, active)
+		removeMessageItem(msgContainer, item)
+		addFailedAnswer(chatID, result.Err)
+		return
+	}
+
+	// A stopped answer keeps the part streamed so far
+	if ctx.Err() != nil {
+		fullText += "\n\n_(stopped)_"
+		messageLabel.ParseMarkdown(fullText)
+		messageLabel.Refresh()
+	}
+	endStream(chatID, active)
+
+	// Name the language of the code blocks the model left unlabeled, then
+	// format the Go code of the answer and flag the snippets that do not
+	// even parse
+	formatted, warning := checkGoCode(highlight.Label(fullText))
+	fullText = formatted
+
+	// Now the answer is complete, show its code blocks highlighted
+	messageBox.Objects = []fyne.CanvasObject{newMarkdownView(fullText)}
+	messageBox.Refresh()
+	if warning != "" {
+		warningLabel := widget.NewLabel(warning)
+		warningLabel.Importance = widget.WarningImportance
+		warningLabel.Wrapping = fyne.TextWrapWord
+		proposalBox.Add(warningLabel)
+	}
+
+	// Offer to run any events or actions the model proposed
+	addEventProposals(proposalBox, fullText)
+	addActionProposals(proposalBox, fullText)
+
+	// Show and record streaming throughput
+	metrics.Finish(fullText)
+	if err := database.SaveStreamMetric(appCtx, database.StreamMetric{
+		Model:        model,
+		TTFT:         metrics.TimeToFirstToken(),
+		Duration:     metrics.Duration(),
+		Tokens:       metrics.Tokens,
+		TokensPerSec: metrics.TokensPerSecond(),
+	}); err != nil {
+		log.Printf("Failed to save stream metrics: %v", err)
+	}
 
+	// After streaming is complete, store the AI response in chat history
+	msg := ChatMessage{
+		Text:   fullText,
+		Sender: "AI",
+		IsAI:   true,
+		MessageMeta: database.MessageMeta{
+			Model:            result.Model,
+			PromptTokens:     result.PromptTokens,
+			CompletionTokens: result.CompletionTokens,
+			FinishReason:     result.FinishReason,
+			Latency:          metrics.Duration(),
+		},
+		MemoryID: result.AnswerMemoryID,
+	}
+	if msg.Model == "" {
+		msg.Model = model
+	}
+	item.footer.SetText(formatMessageStats(msg.MessageMeta) + " · " + formatStreamMetrics(metrics))
+	if messageStatsEnabled() {
+		item.footer.Show()
+	}
+	chat = findChat(chatID)
+	if chat == nil {
+		return
+	}
+	item.messageID = storeMessage(chat, msg)
+	if ctx.Err() == nil {
+		notify(chatID, database.EventAnswer, fullText)
+	}
+	if llm.Truncated(result.FinishReason) {
+		addContinueButton(chatID, item, messageBox, continueBar, &fullText, msg.MessageMeta)
+	}
+
+	if driftDetectionEnabled() {
+		checkTopicDrift(chatID, len(chat.Messages)-2)
+	}
+
+	// Answer the searches the model asked for in the attached logs
+	runLogSearches(chatID, fullText)
+}
+
+// AddMessage stores and shows a message and returns its stored ID
+func AddMessage(chatID int, text, sender string, isAI bool) int {
+	// Find chat by ID
+	targetChat := findChat(chatID)
+
+	var messageID int
 	if targetChat != nil {
-		msg := ChatMessage{
+		messageID = storeMessage(targetChat, ChatMessage{
 			Text:   text,
 			Sender: sender,
 			IsAI:   isAI,
-		}
-		targetChat.Messages = append(targetChat.Messages, msg)
+		})
 
 		// Update chat title with first part of user message (if not already set)
 		if !isAI && targetChat.Title == fmt.Sprintf("Chat %d", targetChat.ID) {
@@ -279,49 +875,128 @@ func AddMessage(chatID int, text, sender string, isAI bool) {
 			if len(title) > 30 {
 				title = title[:27] + "..."
 			}
+			chatsMu.Lock()
 			targetChat.Title = title
+			chatsMu.Unlock()
+			if err := database.UpdateChatTitle(appCtx, chatID, title); err != nil {
+				log.Printf("Failed to save chat title: %v", err)
+			}
 			chatList.Refresh()
 		}
 	}
 
-	// Get or create chat container
+	renderMessage(chatID, ChatMessage{ID: messageID, Text: text, Sender: sender, IsAI: isAI, CreatedAt: time.Now()})
+	if sender == "System" && strings.HasPrefix(text, "Error") {
+		notify(chatID, database.EventError, text)
+	}
+	return messageID
+}
+
+// rerenderChat renders the messages of an opened chat again, after messages
+// were changed or removed
+func rerenderChat(chat *Chat) {
+	msgContainer, exists := chatContainers[chat.ID]
+	if !exists {
+		return
+	}
+	msgContainer.Objects = nil
+	for _, msg := range chat.Messages {
+		renderMessage(chat.ID, msg)
+	}
+	publishLiveView(chat.ID, "")
+}
+
+// renderMessage shows a message in the chat's message container. Chats that
+// were never opened have no container yet and render all messages when opened.
+func renderMessage(chatID int, msg ChatMessage) {
 	msgContainer, exists := chatContainers[chatID]
 	if !exists {
-		msgContainer = container.NewVBox()
-		chatContainers[chatID] = msgContainer
+		return
 	}
 
-	// Create standard text label
-	messageLabel := widget.NewRichTextFromMarkdown(text)
+	text, sender, isAI := msg.Text, msg.Sender, msg.IsAI
+	failed := msg.Failed
+	retryBar := container.NewHBox()
 
 	// Create message container with proper alignment and styling
-	messageBox := container.NewPadded(messageLabel)
-	var messageContainer *fyne.Container
-
-	if isAI {
-		// AI message styling (left-aligned)
-		messageContainer = container.NewHBox(
-			messageBox,
-			layout.NewSpacer(),
+	var messageContainer, body *fyne.Container
+
+	if failed {
+		messageContainer = newErrorCard(text, retryBar)
+	} else if isAI {
+		// AI message styling (left-aligned), as wide as a streamed answer so
+		// long lines wrap and code blocks fit
+		body = container.NewPadded(newMarkdownView(text))
+		messageContainer = container.NewBorder(
+			nil, nil, nil, layout.NewSpacer(),
+			body,
 		)
 	} else {
 		// User message styling (right-aligned)
 		messageContainer = container.NewHBox(
 			layout.NewSpacer(),
-			messageBox,
+			container.NewPadded(widget.NewRichTextFromMarkdown(text)),
 		)
 	}
 
 	// Add sender label
-	senderLabel := widget.NewLabel(fmt.Sprintf("%s", sender))
-	senderLabel.TextStyle = fyne.TextStyle{Italic: true}
+	senderIcon, senderLabel := newSenderLabel(sender, text)
+	if msg.Edited {
+		senderLabel.SetText(sender + " (edited)")
+	}
+	createdAt := msg.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	picked := newPickCheck()
+	header := container.NewHBox(picked, senderIcon, senderLabel, newTimeLabel(createdAt), layout.NewSpacer())
+	rows := []fyne.CanvasObject{header, messageContainer}
+	continueBar := container.NewHBox()
+	if isAI && sender == "AI" {
+		rows = append(rows, continueBar)
+	}
+	var footer *widget.Label
+	if isAI {
+		footer = newStatsFooter(formatMessageStats(msg.MessageMeta))
+		rows = append(rows, footer)
+	}
+	followups := container.NewVBox()
+	if isAI && sender == "AI" {
+		rows = append(rows, followups)
+	}
+	content := container.NewVBox(append(rows, widget.NewSeparator())...)
+	item := newMessageItem(content, sender, func() string { return text })
+	item.followups = followups
+	item.picked = picked
+	item.messageID = msg.ID
+	item.footer = footer
+	item.icon = senderIcon
+	item.createdAt = createdAt
+	switch {
+	case isAI && sender == "AI":
+		header.Add(newRegenerateButton(chatID, item))
+		header.Add(newReadAloudButton(func() string { return text }))
+		header.Add(newFollowUpButton(item))
+		if msg.ID != 0 {
+			loadFollowups(item)
+		}
+		if llm.Truncated(msg.FinishReason) {
+			addContinueButton(chatID, item, body, continueBar, &text, msg.MessageMeta)
+		}
+	case sender == "You":
+		header.Add(newEditButton(chatID, item))
+	case failed:
+		addRetryButtons(chatID, item, retryBar)
+	}
+	if !failed {
+		header.Add(newStarButton(item))
+		header.Add(newThreadButton(item))
+	}
+	header.Add(newCopyButton(item))
+	header.Add(newDeleteButton(chatID, item))
 
 	// Add message with padding
-	msgContainer.Add(container.NewVBox(
-		senderLabel,
-		messageContainer,
-		widget.NewSeparator(),
-	))
+	addMessageItem(msgContainer, item)
 
 	msgContainer.Refresh()
 
@@ -331,29 +1006,310 @@ func AddMessage(chatID int, text, sender string, isAI bool) {
 	}
 }
 
-func createNewChat() *Chat {
-	newID := len(chats) + 1
-	chat := &Chat{
-		ID:       newID,
-		Title:    fmt.Sprintf("Chat %d", newID),
-		Messages: make([]ChatMessage, 0),
+// defaultCostWarning is the estimated cost in USD above which sending asks for confirmation
+const defaultCostWarning = 0.10
+
+// costWarningThreshold returns the configured cost warning threshold
+func costWarningThreshold() float64 {
+	value, err := database.GetPreference(appCtx, database.PrefCostWarning)
+	if err != nil || value == "" {
+		return defaultCostWarning
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultCostWarning
+	}
+	return threshold
+}
+
+// requestTokens estimates the input tokens of sending text with files in the
+// current chat: the system prompt and the history sent along count too
+func requestTokens(text string, files []pastedFile) int {
+	req := llm.Request{Prompt: withPastedFiles(text, files), Model: currentModel}
+	if chat := currentChatEntry(); chat != nil {
+		req.ChatID = chat.ID
+		req.SystemPrompt = systemPrompt(chat)
+		req.MaxTokens = chat.MaxTokens
+		req.ContextTokens = chat.ContextTokens
+	}
+	var history []llm.TurnTokens
+	if req.ChatID != 0 {
+		history = chatHistoryTokens(req.ChatID)
+	}
+	return llm.EstimateRequestTokens(req, history)
+}
+
+// moderatePrompt runs text through the moderation check when enabled and calls
+// next if it may be sent
+func moderatePrompt(text string, w fyne.Window, next func()) {
+	mode, err := database.GetPreference(appCtx, database.PrefModeration)
+	if err != nil || mode == "" || mode == llm.ModerationOff || text == "" {
+		next()
+		return
+	}
+
+	go func() {
+		result, err := llm.Moderate(appCtx, text)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if !result.Flagged {
+			next()
+			return
+		}
+
+		reason := strings.Join(result.Categories, ", ")
+		if mode == llm.ModerationBlock {
+			dialog.ShowError(fmt.Errorf("This message was blocked by moderation (%s)", reason), w)
+			return
+		}
+		dialog.ShowConfirm("Message flagged",
+			fmt.Sprintf("This message was flagged by moderation (%s). Send anyway?", reason),
+			func(confirmed bool) {
+				if confirmed {
+					next()
+				}
+			}, w)
+	}()
+}
+
+// imageCommand prefixes prompts that should generate an image instead of text
+const imageCommand = "/image "
+
+// generateImageReply generates an image for prompt, saves it as an attachment
+// and adds it to the chat as an AI message
+func generateImageReply(chatID int, prompt string) {
+	data, err := llm.GenerateImage(appCtx, prompt)
+	if err != nil {
+		AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
+		return
+	}
+
+	attachment, err := database.SaveAttachment(appCtx, chatID, 0, "image.png", "image/png", data)
+	if err != nil {
+		AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
+		return
+	}
+	path, err := attachment.File()
+	if err != nil {
+		AddMessage(chatID, fmt.Sprintf("Error: %v", err), "System", true)
+		return
+	}
+
+	messageID := AddMessage(chatID, fmt.Sprintf("![%s](%s)", prompt, storage.NewFileURI(path)), "AI", true)
+	if messageID != 0 {
+		if err := database.LinkAttachment(appCtx, attachment.ID, messageID); err != nil {
+			log.Printf("Failed to link image to its message: %v", err)
+		}
+	}
+
+	// Describe the image so exports and search have its content as text
+	description, err := llm.DescribeImage(appCtx, llm.Image{Name: attachment.Name, MimeType: attachment.MimeType, Data: data})
+	if err != nil {
+		log.Printf("Failed to describe image: %v", err)
+		return
+	}
+	if err := database.SetAttachmentDescription(appCtx, attachment.ID, description); err != nil {
+		log.Printf("Failed to save image description: %v", err)
+	}
+}
+
+// newReadAloudButton creates a button that reads the text returned by text aloud,
+// turning into a stop control while playing
+func newReadAloudButton(text func() string) *widget.Button {
+	var btn *widget.Button
+	playing := false
+	btn = widget.NewButtonWithIcon("", theme.VolumeUpIcon(), func() {
+		if playing {
+			player.Stop()
+			return
+		}
+		playing = true
+		btn.SetIcon(theme.MediaStopIcon())
+		go func() {
+			defer func() {
+				playing = false
+				btn.SetIcon(theme.VolumeUpIcon())
+			}()
+			if err := player.Speak(text()); err != nil {
+				dialog.ShowError(fmt.Errorf("Read aloud failed: %v", err), mainWindow)
+			}
+		}()
+	})
+	btn.Importance = widget.LowImportance
+	return btn
+}
+
+// newChat stores a new chat titled title and adds it to the chat list. An empty
+// title is replaced by the first user message.
+func newChat(title string) *Chat {
+	id, err := database.CreateChat(appCtx, title)
+	if err != nil {
+		log.Printf("Failed to save chat: %v", err)
+		// Keep working in memory with an ID after the known chats
+		chatsMu.Lock()
+		for _, chat := range chats {
+			id = max(id, chat.ID)
+		}
+		chatsMu.Unlock()
+		id++
+	}
+	if title == "" {
+		title = fmt.Sprintf("Chat %d", id)
+	}
+	// New chats start with the model in the settings and keep it
+	config := database.ChatConfig{}
+	if name, err := settingsModel(appCtx); err == nil {
+		config.Model = name
+		if err := database.SetChatConfig(appCtx, id, config); err != nil {
+			log.Printf("Failed to save chat model: %v", err)
+		}
+	}
+	return addChat(&Chat{
+		ID:         id,
+		Title:      title,
+		Messages:   make([]ChatMessage, 0),
+		Loaded:     true,
+		ChatConfig: config,
+	})
+}
+
+// loadChats reads the stored chats; their messages are loaded when first opened
+func loadChats() {
+	records, err := database.GetChats(appCtx)
+	if err != nil {
+		log.Printf("Failed to load chats: %v", err)
+		return
+	}
+	tags, err := database.GetChatTags(appCtx)
+	if err != nil {
+		log.Printf("Failed to load chat tags: %v", err)
+	}
+	for _, record := range records {
+		title := record.Title
+		if title == "" {
+			title = fmt.Sprintf("Chat %d", record.ID)
+		}
+		addChat(&Chat{ID: record.ID, Title: title, Tags: tags[record.ID], FolderID: record.FolderID, ChatConfig: record.ChatConfig})
+	}
+	refreshTagFilter()
+	loadFolders()
+}
+
+// loadMessages reads the stored messages of chat the first time it is opened
+func loadMessages(chat *Chat) {
+	chatsMu.Lock()
+	defer chatsMu.Unlock()
+	if chat.Loaded {
+		return
+	}
+	chat.Loaded = true
+	records, err := database.GetMessages(appCtx, chat.ID)
+	if err != nil {
+		log.Printf("Failed to load messages: %v", err)
+		return
+	}
+	for _, record := range records {
+		chat.Messages = append(chat.Messages, ChatMessage{
+			ID:          record.ID,
+			Text:        record.Text,
+			Sender:      record.Sender,
+			IsAI:        record.IsAI,
+			CreatedAt:   record.CreatedAt,
+			MemoryID:    record.MemoryID,
+			MessageMeta: record.MessageMeta,
+		})
+	}
+}
+
+// refreshModelSelect lists the models of the active profile's company and
+// selects its model, hiding the selector until an API key is configured
+func refreshModelSelect() {
+	settings, err := database.GetSettings(appCtx)
+	if err != nil || settings == nil || settings.APIKey == "" {
+		modelSelect.Hide()
+		return
 	}
-	chats = append(chats, *chat)
+	// Get models for the current company
+	models, err := database.GetModelsByCompany(appCtx, settings.CompanyID)
+	if err != nil || len(models) == 0 {
+		modelSelect.Hide()
+		return
+	}
+	modelNames := make([]string, len(models))
+	for i, model := range models {
+		modelNames[i] = model.Name
+	}
+	modelSelect.Options = modelNames
+
+	// Set current model from settings
+	for _, model := range models {
+		if model.ID == settings.ModelID {
+			restoringModel = true
+			modelSelect.SetSelected(model.Name)
+			restoringModel = false
+			currentModel = model.Name
+			break
+		}
+	}
+	modelSelect.Show()
+	refreshChatModel()
+}
+
+// refreshChatModel selects the model the current chat was using, or the one
+// in the settings for chats from before models were kept, when the active
+// profile offers it
+func refreshChatModel() {
+	chat := currentChatEntry()
+	if modelSelect == nil || chat == nil {
+		return
+	}
+	name := chat.Model
+	if name == "" {
+		name, _ = settingsModel(appCtx)
+	}
+	for _, option := range modelSelect.Options {
+		if option == name {
+			restoringModel = true
+			modelSelect.SetSelected(option)
+			restoringModel = false
+			currentModel = option
+			return
+		}
+	}
+}
+
+// saveChatConfig stores the model and generation parameters of chat
+func saveChatConfig(chat *Chat) {
+	if err := database.SetChatConfig(appCtx, chat.ID, chat.ChatConfig); err != nil {
+		log.Printf("Failed to save chat configuration: %v", err)
+	}
+}
+
+func createNewChat() *Chat {
+	chat := newChat("")
+	openNewChat(chat, "How can I help you today?")
+	return chat
+}
+
+// openNewChat shows a chat just created with newChat, starting with welcome
+func openNewChat(chat *Chat, welcome string) {
+	stopSelecting()
 	currentChat = chat
 
 	// Create new message container for this chat
 	chatContainers[chat.ID] = container.NewVBox()
 
 	// Add welcome message
-	welcomeMessage := "How can I help you today?"
-	AddMessage(chat.ID, welcomeMessage, "AI", true)
+	AddMessage(chat.ID, welcome, "AI", true)
 
 	// Switch to the new chat container
 	mainContainer.Objects = []fyne.CanvasObject{chatContainers[chat.ID]}
 	mainContainer.Refresh()
 
 	chatList.Refresh()
-	return chat
+	refreshChatBars()
 }
 
 func switchToChat(chat *Chat) {
@@ -361,7 +1317,10 @@ func switchToChat(chat *Chat) {
 		return
 	}
 
+	clearMessageCursor()
+	stopSelecting()
 	currentChat = chat
+	loadMessages(chat)
 
 	// Get or create message container for this chat
 	msgContainer, exists := chatContainers[chat.ID]
@@ -371,7 +1330,7 @@ func switchToChat(chat *Chat) {
 
 		// If this is the first time viewing this chat, display its messages
 		for _, msg := range chat.Messages {
-			AddMessage(chat.ID, msg.Text, msg.Sender, msg.IsAI)
+			renderMessage(chat.ID, msg)
 		}
 	}
 
@@ -379,6 +1338,114 @@ func switchToChat(chat *Chat) {
 	mainContainer.Objects = []fyne.CanvasObject{msgContainer}
 	mainContainer.Refresh()
 	mainScroll.ScrollToBottom()
+	refreshChatBars()
+	publishLiveView(chat.ID, "")
+}
+
+// refreshChatBars updates the per-chat controls around the input
+func refreshChatBars() {
+	forgetHistoryTokens(0)
+	refreshChatModel()
+	refreshAgentBar()
+	refreshInterviewBar()
+	refreshCacheCheck()
+	refreshSendButton()
+	refreshSelectionBar()
+}
+
+// activeStream is an answer streaming in a chat; each request has its own, so
+// a request that ends late cannot forget the stream of a newer one
+type activeStream struct {
+	cancel context.CancelFunc
+}
+
+// startStream records how to cancel the answer streaming in a chat and
+// returns the stream to pass to endStream
+func startStream(chatID int, cancel context.CancelFunc) *activeStream {
+	s := &activeStream{cancel: cancel}
+	streamsMu.Lock()
+	if old, ok := streams[chatID]; ok {
+		old.cancel()
+	}
+	streams[chatID] = s
+	streamsMu.Unlock()
+	refreshSendButton()
+	return s
+}
+
+// endStream cancels a stream once it stopped and forgets it, unless a newer
+// stream of the chat took its place
+func endStream(chatID int, s *activeStream) {
+	s.cancel()
+	streamsMu.Lock()
+	if streams[chatID] == s {
+		delete(streams, chatID)
+	}
+	streamsMu.Unlock()
+	forgetHistoryTokens(chatID)
+	refreshSendButton()
+}
+
+// stopStream cancels the answer streaming in a chat and reports whether there
+// was one
+func stopStream(chatID int) bool {
+	streamsMu.Lock()
+	s, ok := streams[chatID]
+	streamsMu.Unlock()
+	if ok {
+		s.cancel()
+	}
+	return ok
+}
+
+// isStreaming reports whether an answer is streaming in a chat
+func isStreaming(chatID int) bool {
+	streamsMu.Lock()
+	_, ok := streams[chatID]
+	streamsMu.Unlock()
+	return ok
+}
+
+// waitForStream tells the user to wait when an answer streams in a chat and
+// reports whether one does, so nothing else is sent to it meanwhile
+func waitForStream(chatID int, title string) bool {
+	if !isStreaming(chatID) {
+		return false
+	}
+	dialog.ShowInformation(title, "Wait until the answer is complete, or stop it.", mainWindow)
+	return true
+}
+
+// refreshSendButton shows the stop button while the current chat's answer streams
+func refreshSendButton() {
+	if sendBtn == nil {
+		return
+	}
+	chatID := 0
+	if currentChat != nil {
+		chatID = currentChat.ID
+	}
+	if isStreaming(chatID) {
+		sendBtn.SetText("Stop")
+		sendBtn.SetIcon(theme.MediaStopIcon())
+	} else {
+		sendBtn.SetText("Send")
+		sendBtn.SetIcon(theme.MailSendIcon())
+	}
+}
+
+// refreshCacheCheck shows the cache switch for the current chat when caching is enabled
+func refreshCacheCheck() {
+	if cacheCheck == nil {
+		return
+	}
+	chat := currentChatEntry()
+	if chat == nil || !llm.CacheEnabled(appCtx) {
+		cacheCheck.Hide()
+		return
+	}
+	cacheCheck.SetChecked(!chat.BypassCache)
+	cacheCheck.Show()
 }
 
 func createSidebar(w fyne.Window) fyne.CanvasObject {
@@ -390,32 +1457,150 @@ func createSidebar(w fyne.Window) fyne.CanvasObject {
 	newChatBtn := widget.NewButtonWithIcon("New Chat", theme.ContentAddIcon(), func() {
 		createNewChat()
 	})
+	personaBtn := widget.NewButtonWithIcon("Persona", theme.AccountIcon(), func() {
+		showPersonaPicker(w)
+	})
 
-	// Create chat list
-	chatList = widget.NewList(
-		func() int { return len(chats) },
-		func() fyne.CanvasObject {
-			return widget.NewLabel("Template Chat")
+	// Create tag filter
+	tagFilter = widget.NewSelect([]string{allTags}, func(string) {
+		chatList.UnselectAll()
+		chatList.Refresh()
+		if currentChat != nil {
+			selectChat(currentChat.ID)
+		}
+	})
+
+	// Create new folder button
+	newFolderBtn := widget.NewButtonWithIcon("New Folder", theme.FolderNewIcon(), func() {
+		showNewFolder(w, 0)
+	})
+
+	// Create the tree of folders and chats, with the tags of each chat as
+	// chips; chats are dragged onto a folder to move them into it
+	chatList = widget.NewTree(
+		sidebarChildren,
+		func(uid widget.TreeNodeID) bool { return uid == "" || strings.HasPrefix(uid, folderPrefix) },
+		func(branch bool) fyne.CanvasObject {
+			label := widget.NewLabel("Template Chat")
+			label.Truncation = fyne.TextTruncateEllipsis
+			if branch {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				var buttons []fyne.CanvasObject
+				for _, icon := range []fyne.Resource{theme.FolderNewIcon(), theme.DocumentCreateIcon(), theme.HistoryIcon(), theme.DeleteIcon()} {
+					btn := widget.NewButtonWithIcon("", icon, nil)
+					btn.Importance = widget.LowImportance
+					buttons = append(buttons, btn)
+				}
+				return newSidebarRow(container.NewBorder(nil, nil, nil, container.NewHBox(buttons...), label))
+			}
+			tagsBtn := widget.NewButtonWithIcon("", theme.ListIcon(), nil)
+			tagsBtn.Importance = widget.LowImportance
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			deleteBtn.Importance = widget.LowImportance
+			return newSidebarRow(container.NewBorder(nil, nil, nil, container.NewHBox(container.NewHBox(), tagsBtn, deleteBtn), label))
 		},
-		func(id widget.ListItemID, item fyne.CanvasObject) {
-			label := item.(*widget.Label)
-			label.SetText(chats[id].Title)
+		func(uid widget.TreeNodeID, branch bool, item fyne.CanvasObject) {
+			row := item.(*sidebarRow)
+			row.node = uid
+			content := row.content.(*fyne.Container)
+			actions := content.Objects[1].(*fyne.Container).Objects
+			if branch {
+				folder := findFolder(nodeID(uid))
+				if folder == nil {
+					return
+				}
+				folderID := folder.ID
+				content.Objects[0].(*widget.Label).SetText(folder.Name)
+				actions[0].(*widget.Button).OnTapped = func() {
+					showNewFolder(w, folderID)
+				}
+				actions[1].(*widget.Button).OnTapped = func() {
+					showRenameFolder(w, folderID)
+				}
+				actions[2].(*widget.Button).OnTapped = func() {
+					showChangelog(w, folderID)
+				}
+				actions[3].(*widget.Button).OnTapped = func() {
+					deleteFolder(w, folderID)
+				}
+				return
+			}
+			chat := findChat(nodeID(uid))
+			if chat == nil {
+				return
+			}
+			chatID := chat.ID
+			content.Objects[0].(*widget.Label).SetText(chat.Title)
+			actions[0].(*fyne.Container).Objects = tagChips(chat.Tags)
+			actions[0].Refresh()
+			actions[1].(*widget.Button).OnTapped = func() {
+				showChatTags(w, chatID)
+			}
+			actions[2].(*widget.Button).OnTapped = func() {
+				trashChat(chatID)
+			}
 		},
 	)
-	chatList.OnSelected = func(id widget.ListItemID) {
-		switchToChat(&chats[id])
+	chatList.OnSelected = func(uid widget.TreeNodeID) {
+		if strings.HasPrefix(uid, folderPrefix) {
+			chatList.Unselect(uid)
+			chatList.ToggleBranch(uid)
+			return
+		}
+		switchToChat(findChat(nodeID(uid)))
+	}
+	chatList.OnBranchOpened = func(uid widget.TreeNodeID) {
+		setFolderCollapsed(nodeID(uid), false)
 	}
+	chatList.OnBranchClosed = func(uid widget.TreeNodeID) {
+		setFolderCollapsed(nodeID(uid), true)
+	}
+	tagFilter.SetSelected(allTags)
 
 	// Create settings button
 	settingsBtn := widget.NewButtonWithIcon("Settings", theme.SettingsIcon(), func() {
 		showSettingsModal(w)
 	})
 
+	// Create news feeds button
+	newsBtn := widget.NewButtonWithIcon("News", theme.MailComposeIcon(), func() {
+		showNewsDialog(w)
+	})
+
+	// Create saved button listing the starred messages of all chats
+	savedBtn := widget.NewButtonWithIcon("Saved", starIcon, func() {
+		showSavedMessages(w)
+	})
+
+	// Create trash button listing the deleted chats
+	trashBtn := widget.NewButtonWithIcon("Trash", theme.DeleteIcon(), func() {
+		showTrashDialog(w)
+	})
+
+	// Create performance history button
+	performanceBtn := widget.NewButtonWithIcon("Performance", theme.InfoIcon(), func() {
+		showPerformanceHistory(w)
+	})
+
+	// Create usage button with the cost per model and the activity calendar
+	usageBtn := widget.NewButtonWithIcon("Usage", theme.HistoryIcon(), func() {
+		showUsage(w)
+	})
+
+	// Create global message search
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search messages")
+	searchEntry.OnSubmitted = func(query string) {
+		showSearchResults(w, query)
+	}
+
 	// Sidebar content with settings at bottom
 	topContent := container.NewVBox(
 		title,
 		separator,
-		newChatBtn,
+		container.NewGridWithColumns(3, newChatBtn, personaBtn, newFolderBtn),
+		searchEntry,
+		tagFilter,
 		widget.NewSeparator(),
 	)
 
@@ -424,159 +1609,72 @@ func createSidebar(w fyne.Window) fyne.CanvasObject {
 		topContent,
 		container.NewVBox(
 			widget.NewSeparator(),
+			newProfileSwitcher(w),
+			newsBtn,
+			savedBtn,
+			trashBtn,
+			performanceBtn,
+			usageBtn,
 			settingsBtn,
 		),
 		nil, nil,
 		chatList, // Add chat list in the middle
 	)
 
-	// Create initial chat if none exists
+	// Restore stored chats, opening the most recent one, or create the first chat
+	loadChats()
 	if len(chats) == 0 {
 		createNewChat()
+	} else {
+		selectChat(chats[len(chats)-1].ID)
 	}
 
 	return container.NewPadded(content)
 }
 
-func showSettingsModal(w fyne.Window) {
-	// Create form fields with increased width
-	nameEntry := widget.NewEntry()
-	nameEntry.SetPlaceHolder("Enter your name")
-	nameEntry.Resize(fyne.NewSize(300, 36))
-
-	apiKeyEntry := widget.NewPasswordEntry()
-	apiKeyEntry.SetPlaceHolder("Enter your API key")
-	apiKeyEntry.Resize(fyne.NewSize(300, 36))
-
-	// Get companies from database
-	companies, err := database.GetCompanies()
+// templateContext gathers the system values used to resolve template variables
+func templateContext(w fyne.Window, input *CustomEntry) templates.Context {
+	workspace, err := database.GetPreference(appCtx, database.PrefWorkspaceDir)
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("Failed to load companies: %v", err), w)
-		return
+		log.Printf("Failed to get workspace preference: %v", err)
 	}
-
-	// Create company names slice for select widget
-	companyNames := make([]string, len(companies))
-	companyMap := make(map[string]int) // Map company names to IDs
-	for i, company := range companies {
-		companyNames[i] = company.Name
-		companyMap[company.Name] = company.ID
+	return templates.Context{
+		Clipboard: w.Clipboard().Content(),
+		Selection: input.SelectedText(),
+		Workspace: workspace,
 	}
+}
 
-	// Create model selection (will be updated based on company selection)
-	var selectedCompanyID int
-	var selectedModelID int
-	modelSelect := widget.NewSelect([]string{}, func(value string) {
-		// Find model ID from selected value
-		models, err := database.GetModelsByCompany(selectedCompanyID)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("Failed to load models: %v", err), w)
-			return
-		}
-		for _, model := range models {
-			if model.Name == value {
-				selectedModelID = model.ID
-				break
-			}
-		}
-	})
-	modelSelect.Resize(fyne.NewSize(300, 36))
-	modelSelect.Hide() // Hide initially until company is selected
-
-	// Create company selection
-	companySelect := widget.NewSelect(companyNames, func(value string) {
-		selectedCompanyID = companyMap[value]
-		// Load models for selected company
-		models, err := database.GetModelsByCompany(selectedCompanyID)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("Failed to load models: %v", err), w)
-			return
-		}
-		modelNames := make([]string, len(models))
-		for i, model := range models {
-			modelNames[i] = model.Name
-		}
-		modelSelect.Options = modelNames
-		if len(modelNames) > 0 {
-			modelSelect.SetSelected(modelNames[0])
-			modelSelect.Show()
-			modelSelect.Refresh()
-		}
-	})
-	companySelect.Resize(fyne.NewSize(300, 36))
-
-	// Load current settings if they exist
-	if settings, err := database.GetSettings(); err == nil && settings != nil {
-		nameEntry.SetText(settings.Name)
-		apiKeyEntry.SetText(settings.APIKey)
-		// Set company
-		for name, id := range companyMap {
-			if id == settings.CompanyID {
-				companySelect.SetSelected(name)
-				break
-			}
-		}
+// transcribeRecording stops the recorder and transcribes the captured audio
+func transcribeRecording(recorder *audioRecorder) (string, error) {
+	path, err := recorder.Stop()
+	if err != nil {
+		return "", err
 	}
+	defer os.Remove(path)
 
-	// Create form with wider layout
-	formContainer := container.NewVBox(
-		widget.NewForm(
-			&widget.FormItem{Text: "Name", Widget: nameEntry},
-			&widget.FormItem{Text: "Company", Widget: companySelect},
-			&widget.FormItem{Text: "Model", Widget: modelSelect},
-			&widget.FormItem{Text: "API Key", Widget: apiKeyEntry},
-		),
-	)
-
-	// Create buttons
-	saveBtn := widget.NewButton("Save", func() {
-		if modelSelect.Selected == "" {
-			dialog.ShowError(fmt.Errorf("Please select a model"), w)
-			return
-		}
-
-		// Save settings to database
-		err := database.SaveSettings(
-			nameEntry.Text,
-			selectedCompanyID,
-			selectedModelID,
-			apiKeyEntry.Text,
-		)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), w)
-			return
-		}
-		dialog.ShowInformation("Success", "Settings saved", w)
-	})
-	cancelBtn := widget.NewButton("Cancel", func() {})
-
-	// Create button container
-	buttons := container.NewHBox(
-		layout.NewSpacer(),
-		cancelBtn,
-		saveBtn,
-	)
-
-	// Create main container with padding
-	content := container.NewVBox(
-		formContainer,
-		widget.NewSeparator(),
-		buttons,
-	)
-
-	// Show custom dialog with increased size
-	d := dialog.NewCustom("Settings", "", content, w)
-	d.Resize(fyne.NewSize(400, 350))
-	d.Show()
-
-	// Trigger initial model list population if company is selected
-	if companySelect.Selected != "" {
-		companySelect.OnChanged(companySelect.Selected)
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open recording: %v", err)
 	}
+	defer f.Close()
+
+	return llm.Transcribe(appCtx, f)
 }
 
 func GetAIResponse(prompt string) string {
-	response, err := llm.GetResponse(prompt, currentModel)
+	req := llm.Request{
+		Prompt:       prompt,
+		Model:        currentModel,
+		SystemPrompt: systemPrompt(currentChat),
+	}
+	if currentChat != nil {
+		req.ChatID = currentChat.ID
+		req.Temperature = currentChat.Temperature
+		req.MaxTokens = currentChat.MaxTokens
+		req.ContextTokens = currentChat.ContextTokens
+	}
+	response, err := llm.GetResponse(appCtx, req)
 	if err != nil {
 		fmt.Printf("Failed to get response: %v\n", err)
 		return fmt.Sprintf("Error: %v", err)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// checkDatabase runs the integrity check and offers to repair a damaged database
+func checkDatabase(w fyne.Window) {
+	problems, err := database.CheckIntegrity(appCtx)
+	if err != nil {
+		log.Printf("Failed to check database integrity: %v", err)
+		return
+	}
+	if problems == "" {
+		return
+	}
+	log.Printf("Database integrity check failed:\n%s", problems)
+
+	dialog.ShowConfirm("Database damaged",
+		"The chat database is damaged, so some chats or settings may be missing or fail to save. Try to repair it now? A copy of the damaged file is kept.",
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			damaged, err := database.Repair(appCtx)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			reloadChats()
+			dialog.ShowInformation("Database repaired", fmt.Sprintf("The database was repaired. The damaged copy was saved to %s", damaged), w)
+		}, w)
+}
+
+// offerLegacyMigration offers to move the chats of an older version, kept in
+// the data folder of the working directory, into the data directory and shows
+// the row counts of every table afterwards
+func offerLegacyMigration(w fyne.Window) {
+	path := database.LegacyDatabase(appCtx)
+	if path == "" {
+		return
+	}
+
+	dialog.ShowConfirm("Chats from an older version",
+		fmt.Sprintf("Found the chats of an older version in %s. Move them into %s? The current data is backed up first and the old files are kept.", path, database.DataDir()),
+		func(confirmed bool) {
+			if !confirmed {
+				if err := database.SkipLegacyDatabase(appCtx, path); err != nil {
+					log.Printf("Failed to save migration choice: %v", err)
+				}
+				return
+			}
+			safety, counts, err := database.MigrateLegacy(appCtx, path)
+			if safety != "" {
+				reloadChats()
+				restartBridges()
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%v. The previous data was saved to %s", err, safety), w)
+				return
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "The chats were migrated and every row was found. The previous data was saved to %s.\n", safety)
+			for _, count := range counts {
+				fmt.Fprintf(&b, "\n%s: %d of %d rows", count.Table, count.Now, count.Legacy)
+			}
+			label := widget.NewLabel(b.String())
+			label.Wrapping = fyne.TextWrapWord
+			d := dialog.NewCustom("Migration complete", "Close", container.NewVScroll(label), w)
+			d.Resize(fyne.NewSize(500, 400))
+			d.Show()
+		}, w)
+}
+
+// refreshDatabaseSize shows the size of the database in label
+func refreshDatabaseSize(label *widget.Label) {
+	size, err := database.Size(appCtx)
+	if err != nil {
+		label.SetText(fmt.Sprintf("Unknown size: %v", err))
+		return
+	}
+	label.SetText(fmt.Sprintf("%s, %s reclaimable", formatBytes(size.File), formatBytes(size.Reclaimable)))
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,171 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// syncTimeout is how long, in milliseconds, a sync call waits for events
+const syncTimeout = 30000
+
+// Message is a text message received in the room
+type Message struct {
+	Sender string
+	Body   string
+}
+
+// Client talks to a Matrix homeserver with an access token and follows one room
+type Client struct {
+	Homeserver string
+	Token      string
+	RoomID     string
+	HTTP       *http.Client
+
+	since string
+	txnID int64
+}
+
+// WhoAmI returns the user ID of the token's account
+func (c *Client) WhoAmI(ctx context.Context) (string, error) {
+	var result struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &result); err != nil {
+		return "", fmt.Errorf("failed to identify Matrix account: %v", err)
+	}
+	return result.UserID, nil
+}
+
+// Join joins the room, resolving an alias like #team:example.org to its ID
+func (c *Client) Join(ctx context.Context) error {
+	var result struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(c.RoomID), map[string]interface{}{}, &result); err != nil {
+		return fmt.Errorf("failed to join %s: %v", c.RoomID, err)
+	}
+	if result.RoomID != "" {
+		c.RoomID = result.RoomID
+	}
+	return nil
+}
+
+// Messages waits for new text messages in the room. The first call only
+// records the current position so the room history is not answered.
+func (c *Client) Messages(ctx context.Context) ([]Message, error) {
+	first := c.since == ""
+	query := url.Values{"timeout": {fmt.Sprint(syncTimeout)}}
+	if first {
+		query.Set("timeout", "0")
+	} else {
+		query.Set("since", c.since)
+	}
+
+	var result struct {
+		NextBatch string `json:"next_batch"`
+		Rooms     struct {
+			Join map[string]struct {
+				Timeline struct {
+					Events []struct {
+						Type    string `json:"type"`
+						Sender  string `json:"sender"`
+						Content struct {
+							MsgType string `json:"msgtype"`
+							Body    string `json:"body"`
+						} `json:"content"`
+					} `json:"events"`
+				} `json:"timeline"`
+			} `json:"join"`
+		} `json:"rooms"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &result); err != nil {
+		return nil, fmt.Errorf("Matrix sync failed: %v", err)
+	}
+	c.since = result.NextBatch
+	if first {
+		return nil, nil
+	}
+
+	var messages []Message
+	for _, event := range result.Rooms.Join[c.RoomID].Timeline.Events {
+		if event.Type == "m.room.message" && event.Content.MsgType == "m.text" {
+			messages = append(messages, Message{Sender: event.Sender, Body: event.Content.Body})
+		}
+	}
+	return messages, nil
+}
+
+// Send posts a text message to the room
+func (c *Client) Send(ctx context.Context, text string) error {
+	c.txnID++
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/llmschat-%d-%d",
+		url.PathEscape(c.RoomID), time.Now().UnixNano(), c.txnID)
+	err := c.do(ctx, http.MethodPut, path, map[string]interface{}{
+		"msgtype": "m.text",
+		"body":    text,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send Matrix message: %v", err)
+	}
+	return nil
+}
+
+// do sends an API request with an optional JSON body and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.Homeserver, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Mention returns the text of body when it mentions userID, either by its full
+// ID or by its localpart (e.g. "bot" for "@bot:example.org")
+func Mention(body, userID string) (string, bool) {
+	localpart, _, _ := strings.Cut(strings.TrimPrefix(userID, "@"), ":")
+	names := []string{userID}
+	if localpart != "" {
+		names = append(names, "@"+localpart, localpart)
+	}
+	lower := strings.ToLower(body)
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if i := strings.Index(lower, name); i >= 0 {
+			text := body[:i] + body[i+len(name):]
+			return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text), ":,")), true
+		}
+	}
+	return "", false
+}
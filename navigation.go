@@ -0,0 +1,192 @@
+package main
+
+import (
+	"image/color"
+	"regexp"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// codeBlockPattern matches the fenced code blocks of a message, capturing the code
+var codeBlockPattern = regexp.MustCompile("(?s)```[^\n]*\n(.*?)\n?```")
+
+// messageItem is a message in a chat's message container, highlighted while
+// it is selected with the keyboard
+type messageItem struct {
+	widget.BaseWidget
+	highlight *canvas.Rectangle
+	content   fyne.CanvasObject
+	sender    string
+	text      func() string // The text of the message, which grows while it streams
+	yanked    int           // Code blocks already copied with y, to cycle through them
+	messageID int           // Stored ID, 0 while an answer streams
+	createdAt time.Time
+	footer    *widget.Label   // Stats of an AI message, nil for other messages
+	icon      *widget.Icon    // Role of the sender
+	followups *fyne.Container // Results of follow-up actions on an AI answer
+	picked    *widget.Check   // Picks the message to copy or export with others, shown while selecting
+}
+
+func newMessageItem(content fyne.CanvasObject, sender string, text func() string) *messageItem {
+	highlight := canvas.NewRectangle(color.Transparent)
+	highlight.CornerRadius = theme.InputRadiusSize()
+	item := &messageItem{highlight: highlight, content: content, sender: sender, text: text}
+	item.ExtendBaseWidget(item)
+	return item
+}
+
+func (m *messageItem) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(m.highlight, m.content))
+}
+
+// setSelected shows or hides the selection highlight
+func (m *messageItem) setSelected(selected bool) {
+	m.highlight.FillColor = color.Transparent
+	if selected {
+		m.highlight.FillColor = theme.Color(theme.ColorNameSelection)
+	}
+	m.highlight.Refresh()
+}
+
+// nextCodeBlock returns the code block after the one copied last, starting
+// again from the first one at the end
+func (m *messageItem) nextCodeBlock() (string, bool) {
+	blocks := codeBlockPattern.FindAllStringSubmatch(m.text(), -1)
+	if len(blocks) == 0 {
+		return "", false
+	}
+	code := blocks[m.yanked%len(blocks)][1]
+	m.yanked++
+	return code, true
+}
+
+// messageCursor is the index of the message selected with the keyboard among
+// the messages of the current chat, -1 when none is
+var messageCursor = -1
+
+// messageItems returns the messages shown for the current chat, without the
+// suggestions and other rows between them
+func messageItems() []*messageItem {
+	if currentChat == nil {
+		return nil
+	}
+	msgContainer := chatContainers[currentChat.ID]
+	if msgContainer == nil {
+		return nil
+	}
+	var items []*messageItem
+	for _, object := range msgContainer.Objects {
+		if item, ok := object.(*messageItem); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// selectedMessage returns the message selected with the keyboard, or nil
+func selectedMessage() *messageItem {
+	items := messageItems()
+	if messageCursor < 0 || messageCursor >= len(items) {
+		return nil
+	}
+	return items[messageCursor]
+}
+
+// moveMessageCursor selects the message delta rows away from the selected
+// one, starting from the last message when none is selected
+func moveMessageCursor(delta int) {
+	items := messageItems()
+	if len(items) == 0 {
+		return
+	}
+	if item := selectedMessage(); item != nil {
+		item.setSelected(false)
+	}
+	if messageCursor < 0 || messageCursor >= len(items) {
+		messageCursor = len(items)
+	}
+	messageCursor = max(0, min(len(items)-1, messageCursor+delta))
+	item := items[messageCursor]
+	item.setSelected(true)
+	scrollToMessage(chatContainers[currentChat.ID], item)
+}
+
+// clearMessageCursor removes the keyboard selection
+func clearMessageCursor() {
+	if item := selectedMessage(); item != nil {
+		item.setSelected(false)
+	}
+	messageCursor = -1
+}
+
+// scrollToMessage scrolls the chat so a row of its message container is at
+// the top, or as far as it goes
+func scrollToMessage(msgContainer *fyne.Container, row fyne.CanvasObject) {
+	offset := row.Position().Y + msgContainer.Position().Y + mainContainer.Position().Y
+	if max := mainScroll.Content.MinSize().Height - mainScroll.Size().Height; offset > max {
+		offset = max
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	mainScroll.Offset = fyne.NewPos(0, offset)
+	mainScroll.Refresh()
+}
+
+// handleMessageKey runs the keyboard actions on the selected message while the
+// input is not focused: j/k or the arrows move the selection, c copies the
+// message, y copies its next code block, e puts it in the input to edit, r
+// sends the prompt again and d deletes it. Escape goes back to the input.
+func handleMessageKey(w fyne.Window, input *CustomEntry, resend func(string), key rune) {
+	switch key {
+	case 'j':
+		moveMessageCursor(1)
+		return
+	case 'k':
+		moveMessageCursor(-1)
+		return
+	}
+
+	item := selectedMessage()
+	if item == nil {
+		return
+	}
+	switch key {
+	case 'c':
+		copyMessage(item)
+	case 'y':
+		if code, ok := item.nextCodeBlock(); ok {
+			w.Clipboard().SetContent(code)
+			showToast("Copied code block")
+		}
+	case 'e':
+		input.SetText(item.text())
+		clearMessageCursor()
+		w.Canvas().Focus(input)
+	case 'r':
+		// An answer is regenerated in place; a prompt is sent again
+		items := messageItems()
+		if item.sender == "AI" && currentChat != nil {
+			clearMessageCursor()
+			regenerateAnswer(currentChat.ID, item, currentModel, true)
+			return
+		}
+		for i := messageCursor; i >= 0; i-- {
+			if items[i].sender == "You" {
+				prompt := items[i].text()
+				clearMessageCursor()
+				resend(prompt)
+				return
+			}
+		}
+	case 'd':
+		if currentChat != nil {
+			confirmDeleteMessage(currentChat.ID, item)
+		}
+	}
+}
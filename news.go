@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/feeds"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// newsChatTitle is the title of the chat that receives the daily digest
+const newsChatTitle = "News"
+
+// digestCheckInterval is how often the scheduler checks whether a digest is due
+const digestCheckInterval = time.Hour
+
+// maxDigestItems bounds how many new items a single digest covers
+const maxDigestItems = 60
+
+// digestMutex prevents the scheduler and a manual run from overlapping
+var digestMutex sync.Mutex
+
+// runNewsScheduler writes a digest once a day while the app is running
+func runNewsScheduler() {
+	for {
+		// The digest waits while saving power
+		if economyActive() {
+			time.Sleep(digestCheckInterval)
+			continue
+		}
+		if last, err := database.GetPreference(appCtx, database.PrefDigestLastRun); err == nil && last != time.Now().Format("2006-01-02") {
+			if _, err := runDigest(); err != nil {
+				log.Printf("Failed to write news digest: %v", err)
+			}
+		}
+		time.Sleep(digestCheckInterval)
+	}
+}
+
+// runDigest summarizes the unseen items of every feed into the News chat and
+// returns how many items it covered
+func runDigest() (int, error) {
+	digestMutex.Lock()
+	defer digestMutex.Unlock()
+
+	registered, err := database.GetFeeds(appCtx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load feeds: %v", err)
+	}
+	if len(registered) == 0 {
+		return 0, nil
+	}
+	if currentModel == "" {
+		return 0, fmt.Errorf("no model selected")
+	}
+	client, err := llm.HTTPClient(appCtx)
+	if err != nil {
+		return 0, err
+	}
+
+	type seenItem struct {
+		feedID int
+		guid   string
+	}
+	var items []llm.NewsItem
+	var seen []seenItem
+	for _, f := range registered {
+		feed, err := feeds.Fetch(appCtx, client, f.URL)
+		if err != nil {
+			log.Printf("Failed to fetch %s: %v", f.URL, err)
+			continue
+		}
+		for _, item := range feed.Items {
+			if len(items) == maxDigestItems {
+				break
+			}
+			if done, err := database.FeedItemSeen(appCtx, f.ID, item.GUID); err != nil || done {
+				continue
+			}
+			items = append(items, llm.NewsItem{Source: f.Title, Title: item.Title, Link: item.Link, Summary: item.Summary})
+			seen = append(seen, seenItem{f.ID, item.GUID})
+		}
+	}
+
+	if len(items) > 0 {
+		length, _ := database.GetPreference(appCtx, database.PrefDigestLength)
+		language, _ := database.GetPreference(appCtx, database.PrefDigestLanguage)
+		digest, err := llm.Digest(appCtx, currentModel, items, length, language)
+		if err != nil {
+			return 0, err
+		}
+		AddMessage(newsChat().ID, digest, "AI", true)
+
+		for _, item := range seen {
+			if err := database.MarkFeedItemSeen(appCtx, item.feedID, item.guid); err != nil {
+				log.Printf("Failed to mark feed item: %v", err)
+			}
+		}
+	}
+
+	if err := database.SetPreference(appCtx, database.PrefDigestLastRun, time.Now().Format("2006-01-02")); err != nil {
+		log.Printf("Failed to save digest run: %v", err)
+	}
+	return len(items), nil
+}
+
+// newsChat returns the chat that receives digests, creating it when needed
+func newsChat() *Chat {
+	if value, err := database.GetPreference(appCtx, database.PrefNewsChatID); err == nil {
+		if id, err := strconv.Atoi(value); err == nil {
+			if chat := findChat(id); chat != nil {
+				return chat
+			}
+		}
+	}
+
+	chat := newChat(newsChatTitle)
+	if err := database.SetPreference(appCtx, database.PrefNewsChatID, strconv.Itoa(chat.ID)); err != nil {
+		log.Printf("Failed to save news chat: %v", err)
+	}
+	chatList.Refresh()
+	return chat
+}
+
+// showNewsDialog manages the registered feeds and the digest options
+func showNewsDialog(w fyne.Window) {
+	list := container.NewVBox()
+	var refreshList func()
+	refreshList = func() {
+		list.Objects = nil
+		registered, err := database.GetFeeds(appCtx)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load feeds: %v", err), w)
+			return
+		}
+		for _, f := range registered {
+			feedID := f.ID
+			removeBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				if err := database.DeleteFeed(appCtx, feedID); err != nil {
+					dialog.ShowError(fmt.Errorf("Failed to remove feed: %v", err), w)
+					return
+				}
+				refreshList()
+			})
+			label := widget.NewLabel(f.Title)
+			label.Truncation = fyne.TextTruncateEllipsis
+			list.Add(container.NewBorder(nil, nil, nil, removeBtn, label))
+		}
+		if len(registered) == 0 {
+			list.Add(widget.NewLabel("No feeds yet"))
+		}
+		list.Refresh()
+	}
+	refreshList()
+
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://example.com/feed.xml")
+	var addBtn *widget.Button
+	addBtn = widget.NewButtonWithIcon("Add", theme.ContentAddIcon(), func() {
+		url := strings.TrimSpace(urlEntry.Text)
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			dialog.ShowError(fmt.Errorf("Please enter a feed URL"), w)
+			return
+		}
+		addBtn.Disable()
+		go func() {
+			defer addBtn.Enable()
+			client, err := llm.HTTPClient(appCtx)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			feed, err := feeds.Fetch(appCtx, client, url)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			title := feed.Title
+			if title == "" {
+				title = url
+			}
+			if err := database.AddFeed(appCtx, url, title); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to add feed: %v", err), w)
+				return
+			}
+			urlEntry.SetText("")
+			refreshList()
+		}()
+	})
+
+	lengthSelect := widget.NewSelect([]string{llm.DigestShort, llm.DigestMedium, llm.DigestLong}, func(length string) {
+		if err := database.SetPreference(appCtx, database.PrefDigestLength, length); err != nil {
+			log.Printf("Failed to save digest length: %v", err)
+		}
+	})
+	lengthSelect.SetSelected(llm.DigestMedium)
+	if value, err := database.GetPreference(appCtx, database.PrefDigestLength); err == nil && value != "" {
+		lengthSelect.SetSelected(value)
+	}
+
+	languageEntry := widget.NewEntry()
+	languageEntry.SetPlaceHolder("English")
+	if value, err := database.GetPreference(appCtx, database.PrefDigestLanguage); err == nil {
+		languageEntry.SetText(value)
+	}
+	languageEntry.OnChanged = func(language string) {
+		if err := database.SetPreference(appCtx, database.PrefDigestLanguage, strings.TrimSpace(language)); err != nil {
+			log.Printf("Failed to save digest language: %v", err)
+		}
+	}
+
+	var runBtn *widget.Button
+	runBtn = widget.NewButtonWithIcon("Write digest now", theme.MediaPlayIcon(), func() {
+		runBtn.Disable()
+		go func() {
+			defer runBtn.Enable()
+			count, err := runDigest()
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if count == 0 {
+				dialog.ShowInformation("News", "No new items", w)
+				return
+			}
+			switchToChat(newsChat())
+		}()
+	})
+
+	content := container.NewVBox(
+		list,
+		container.NewBorder(nil, nil, nil, addBtn, urlEntry),
+		widget.NewSeparator(),
+		widget.NewForm(
+			&widget.FormItem{Text: "Length", Widget: lengthSelect},
+			&widget.FormItem{Text: "Language", Widget: languageEntry},
+		),
+		container.NewHBox(layout.NewSpacer(), runBtn),
+	)
+
+	d := dialog.NewCustom("News feeds", "Close", container.NewVScroll(content), w)
+	d.Resize(fyne.NewSize(500, 450))
+	d.Show()
+}
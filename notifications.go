@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// notificationEvents lists the events that can be notified, in order
+var notificationEvents = []string{database.EventAnswer, database.EventError, database.EventBridge}
+
+// eventNames are the names of the events in the settings
+var eventNames = map[string]string{
+	database.EventAnswer: "Answer finished",
+	database.EventError:  "Error",
+	database.EventBridge: "Bridge message",
+}
+
+// defaultEventRules apply to the events without a rule of their own
+var defaultEventRules = map[string]database.NotificationRule{
+	database.EventAnswer: {Mode: database.NotifyUnfocused},
+	database.EventError:  {Mode: database.NotifyUnfocused},
+	database.EventBridge: {Mode: database.NotifyNever},
+}
+
+// notifyModes lists the notification modes in order
+var notifyModes = []string{database.NotifyAlways, database.NotifyUnfocused, database.NotifyNever}
+
+// notifyModeNames are the names of the notification modes in the settings
+var notifyModeNames = map[string]string{
+	database.NotifyAlways:    "Always",
+	database.NotifyUnfocused: "Only when unfocused",
+	database.NotifyNever:     "Never",
+}
+
+// windowFocused tells whether the app is in the foreground
+var windowFocused atomic.Bool
+
+// watchFocus keeps windowFocused up to date
+func watchFocus(a fyne.App) {
+	windowFocused.Store(true)
+	a.Lifecycle().SetOnEnteredForeground(func() { windowFocused.Store(true) })
+	a.Lifecycle().SetOnExitedForeground(func() { windowFocused.Store(false) })
+}
+
+// notificationRule returns the rule of an event in a chat: the chat's own rule
+// when it has one, otherwise the rule of the event
+func notificationRule(chatID int, event string) database.NotificationRule {
+	if rules, err := database.GetChatNotifications(appCtx); err != nil {
+		log.Printf("Failed to load chat notification rules: %v", err)
+	} else if rule, ok := rules[chatID]; ok {
+		return rule
+	}
+	rules, err := database.GetEventNotifications(appCtx)
+	if err != nil {
+		log.Printf("Failed to load notification rules: %v", err)
+	}
+	if rule, ok := rules[event]; ok {
+		return rule
+	}
+	return defaultEventRules[event]
+}
+
+// quietHours reports whether now falls in the quiet hours of the settings,
+// which may span midnight
+func quietHours(now time.Time) bool {
+	startValue, _ := database.GetPreference(appCtx, database.PrefQuietHoursStart)
+	endValue, _ := database.GetPreference(appCtx, database.PrefQuietHoursEnd)
+	start, err := time.Parse("15:04", startValue)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", endValue)
+	if err != nil {
+		return false
+	}
+	minutes := now.Hour()*60 + now.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from <= to {
+		return minutes >= from && minutes < to
+	}
+	return minutes >= from || minutes < to
+}
+
+// notify shows a system notification of an event in a chat, with a chime
+// when the rule asks for one. Nothing is shown during the quiet hours.
+func notify(chatID int, event, text string) {
+	rule := notificationRule(chatID, event)
+	switch {
+	case rule.Mode == database.NotifyNever,
+		rule.Mode == database.NotifyUnfocused && windowFocused.Load(),
+		quietHours(time.Now()):
+		return
+	}
+
+	title := eventNames[event]
+	if chat := findChat(chatID); chat != nil {
+		title = chat.Title
+	}
+	content, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(content); len(runes) > 120 {
+		content = string(runes[:117]) + "..."
+	}
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(title, content))
+	if rule.Sound {
+		go func() {
+			if err := playChime(); err != nil {
+				log.Printf("Failed to play the notification sound: %v", err)
+			}
+		}()
+	}
+}
+
+// playChime plays two short rising tones
+func playChime() error {
+	f, err := os.CreateTemp("", "llmschat-chime-*.wav")
+	if err != nil {
+		return fmt.Errorf("failed to create audio file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(chimeWAV())
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write audio file: %v", err)
+	}
+	cmd, err := playbackCommand(appCtx, f.Name())
+	if err != nil {
+		return err
+	}
+	return cmd.Run()
+}
+
+// chimeWAV returns the chime as a 16-bit mono WAV file
+func chimeWAV() []byte {
+	const rate = 22050
+	var samples []int16
+	for _, frequency := range []float64{880, 1320} {
+		n := rate * 12 / 100
+		for i := range n {
+			// Fade out each tone so it does not click
+			volume := 0.3 * (1 - float64(i)/float64(n))
+			samples = append(samples, int16(volume*math.MaxInt16*math.Sin(2*math.Pi*frequency*float64(i)/rate)))
+		}
+	}
+
+	var buf bytes.Buffer
+	size := uint32(len(samples) * 2)
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+size)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size                      uint32
+		Format, Channels          uint16
+		Rate, ByteRate            uint32
+		BlockAlign, BitsPerSample uint16
+	}{16, 1, 1, rate, rate * 2, 2, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, size)
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// newRuleControls returns a select of the notification modes and a sound
+// check showing rule
+func newRuleControls(rule database.NotificationRule) (*widget.Select, *widget.Check) {
+	names := make([]string, len(notifyModes))
+	for i, mode := range notifyModes {
+		names[i] = notifyModeNames[mode]
+	}
+	modeSelect := widget.NewSelect(names, nil)
+	modeSelect.SetSelected(notifyModeNames[rule.Mode])
+	soundCheck := widget.NewCheck("Sound", nil)
+	soundCheck.SetChecked(rule.Sound)
+	return modeSelect, soundCheck
+}
+
+// selectedRule reads the rule shown by the controls of newRuleControls
+func selectedRule(modeSelect *widget.Select, soundCheck *widget.Check) database.NotificationRule {
+	rule := database.NotificationRule{Mode: database.NotifyNever, Sound: soundCheck.Checked}
+	for mode, name := range notifyModeNames {
+		if name == modeSelect.Selected {
+			rule.Mode = mode
+		}
+	}
+	return rule
+}
+
+// newNotificationSettings returns the Notifications tab of the settings and
+// the function that validates and saves it
+func newNotificationSettings() (fyne.CanvasObject, func() error) {
+	eventRules, err := database.GetEventNotifications(appCtx)
+	if err != nil {
+		log.Printf("Failed to load notification rules: %v", err)
+	}
+	form := widget.NewForm()
+	type ruleControls struct {
+		mode  *widget.Select
+		sound *widget.Check
+	}
+	eventControls := make(map[string]ruleControls)
+	for _, event := range notificationEvents {
+		rule, ok := eventRules[event]
+		if !ok {
+			rule = defaultEventRules[event]
+		}
+		modeSelect, soundCheck := newRuleControls(rule)
+		eventControls[event] = ruleControls{modeSelect, soundCheck}
+		form.Append(eventNames[event], container.NewBorder(nil, nil, nil, soundCheck, modeSelect))
+	}
+
+	quietStartEntry := widget.NewEntry()
+	quietStartEntry.SetPlaceHolder("22:00")
+	quietEndEntry := widget.NewEntry()
+	quietEndEntry.SetPlaceHolder("07:00")
+	if value, err := database.GetPreference(appCtx, database.PrefQuietHoursStart); err == nil {
+		quietStartEntry.SetText(value)
+	}
+	if value, err := database.GetPreference(appCtx, database.PrefQuietHoursEnd); err == nil {
+		quietEndEntry.SetText(value)
+	}
+	form.Append("Quiet hours", container.NewGridWithColumns(3, quietStartEntry, widget.NewLabel("to"), quietEndEntry))
+
+	// Chats with a rule of their own, which applies to all their events
+	chatRules, err := database.GetChatNotifications(appCtx)
+	if err != nil {
+		log.Printf("Failed to load chat notification rules: %v", err)
+	}
+	chatControls := make(map[int]ruleControls)
+	chatRows := container.NewVBox()
+	var addSelect *widget.Select
+	var addChatRow func(chat *Chat, rule database.NotificationRule)
+	refreshAddSelect := func() {
+		var titles []string
+		for _, chat := range chats {
+			if _, ok := chatControls[chat.ID]; !ok {
+				titles = append(titles, chat.Title)
+			}
+		}
+		addSelect.Options = titles
+		addSelect.ClearSelected()
+	}
+	addChatRow = func(chat *Chat, rule database.NotificationRule) {
+		modeSelect, soundCheck := newRuleControls(rule)
+		chatControls[chat.ID] = ruleControls{modeSelect, soundCheck}
+		var row *fyne.Container
+		removeBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+			delete(chatControls, chat.ID)
+			chatRows.Remove(row)
+			refreshAddSelect()
+		})
+		row = container.NewBorder(nil, nil, widget.NewLabel(chat.Title), container.NewHBox(soundCheck, removeBtn), modeSelect)
+		chatRows.Add(row)
+	}
+	addSelect = widget.NewSelect(nil, func(title string) {
+		if title == "" {
+			return
+		}
+		for _, chat := range chats {
+			if chat.Title == title {
+				if _, ok := chatControls[chat.ID]; !ok {
+					addChatRow(chat, database.NotificationRule{Mode: database.NotifyAlways})
+					break
+				}
+			}
+		}
+		refreshAddSelect()
+	})
+	addSelect.PlaceHolder = "Add a chat with its own rule"
+	for _, chat := range chats {
+		if rule, ok := chatRules[chat.ID]; ok {
+			addChatRow(chat, rule)
+		}
+	}
+	refreshAddSelect()
+
+	hint := widget.NewLabel("A chat's own rule applies to all its events. Nothing is notified during the quiet hours.")
+	hint.Importance = widget.LowImportance
+	hint.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(form, widget.NewSeparator(), widget.NewLabel("Chats"), chatRows, addSelect, hint)
+
+	save := func() error {
+		quietStart := strings.TrimSpace(quietStartEntry.Text)
+		quietEnd := strings.TrimSpace(quietEndEntry.Text)
+		for _, value := range []string{quietStart, quietEnd} {
+			if _, err := time.Parse("15:04", value); value != "" && err != nil {
+				return fmt.Errorf("Quiet hours must be times like 22:00")
+			}
+		}
+		if (quietStart == "") != (quietEnd == "") {
+			return fmt.Errorf("Quiet hours need both a start and an end")
+		}
+
+		for event, controls := range eventControls {
+			if err := database.SetEventNotification(appCtx, event, selectedRule(controls.mode, controls.sound)); err != nil {
+				return fmt.Errorf("Failed to save notification rules: %v", err)
+			}
+		}
+		for chatID := range chatRules {
+			if _, ok := chatControls[chatID]; ok {
+				continue
+			}
+			if err := database.DeleteChatNotification(appCtx, chatID); err != nil {
+				return fmt.Errorf("Failed to save notification rules: %v", err)
+			}
+		}
+		for chatID, controls := range chatControls {
+			if err := database.SetChatNotification(appCtx, chatID, selectedRule(controls.mode, controls.sound)); err != nil {
+				return fmt.Errorf("Failed to save notification rules: %v", err)
+			}
+		}
+		for key, value := range map[string]string{
+			database.PrefQuietHoursStart: quietStart,
+			database.PrefQuietHoursEnd:   quietEnd,
+		} {
+			if err := database.SetPreference(appCtx, key, value); err != nil {
+				return fmt.Errorf("Failed to save settings: %v", err)
+			}
+		}
+		return nil
+	}
+	return content, save
+}
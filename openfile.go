@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/chatfile"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// urlScheme is the scheme of links that open the app, like llmschat://chat/12
+const urlScheme = "llmschat"
+
+// isOpenArg reports whether a command line argument is a chat export or a
+// llmschat:// link handed over by the desktop, rather than a CLI command
+func isOpenArg(arg string) bool {
+	return strings.HasSuffix(strings.ToLower(arg), chatfile.Extension) || strings.HasPrefix(arg, urlScheme+"://")
+}
+
+// handleOpenArg imports a chat export or follows a llmschat:// link
+func handleOpenArg(w fyne.Window, arg string) {
+	if !strings.HasPrefix(arg, urlScheme+"://") {
+		previewImport(w, []string{arg})
+		return
+	}
+	if err := openURL(arg); err != nil {
+		dialog.ShowError(err, w)
+	}
+}
+
+// openURL follows a llmschat:// link: llmschat://chat/ID opens a chat and
+// llmschat://new starts a new one
+func openURL(link string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return fmt.Errorf("invalid link %s: %v", link, err)
+	}
+	switch u.Host {
+	case "new":
+		createNewChat()
+		return nil
+	case "chat":
+		id, err := strconv.Atoi(strings.Trim(u.Path, "/"))
+		if err != nil || findChat(id) == nil {
+			return fmt.Errorf("the chat of %s does not exist", link)
+		}
+		selectChat(id)
+		return nil
+	default:
+		return fmt.Errorf("unsupported link %s", link)
+	}
+}
+
+// readChatFile reads the chats of a .llmchat or JSON export
+func readChatFile(path string) ([]chatfile.Chat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	exported, err := chatfile.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return exported, nil
+}
+
+// importChats stores exported chats, adds them to the sidebar and returns the
+// ID of the last one
+func importChats(exported []chatfile.Chat) (int, error) {
+	var id int
+	for _, chat := range exported {
+		if chat.CreatedAt.IsZero() {
+			chat.CreatedAt = time.Now()
+		}
+		records := make([]database.MessageRecord, len(chat.Messages))
+		for i, msg := range chat.Messages {
+			if msg.CreatedAt.IsZero() {
+				msg.CreatedAt = chat.CreatedAt
+			}
+			records[i] = database.MessageRecord{Sender: msg.Sender, Text: msg.Text, IsAI: msg.IsAI, CreatedAt: msg.CreatedAt}
+		}
+
+		var err error
+		id, err = database.ImportChat(appCtx, chat.Title, chat.CreatedAt, records)
+		if err != nil {
+			return id, fmt.Errorf("failed to import chat: %v", err)
+		}
+		title := chat.Title
+		if title == "" {
+			title = fmt.Sprintf("Chat %d", id)
+		}
+		addChat(&Chat{ID: id, Title: title})
+	}
+	chatList.Refresh()
+	return id, nil
+}
+
+// storedChat returns a stored chat in the export format
+func storedChat(record database.ChatRecord) (chatfile.Chat, error) {
+	exported := chatfile.Chat{Title: record.Title, CreatedAt: record.CreatedAt}
+	messages, err := database.GetMessages(appCtx, record.ID)
+	if err != nil {
+		return exported, err
+	}
+	attachments, err := database.GetChatAttachments(appCtx, record.ID)
+	if err != nil {
+		return exported, err
+	}
+	for _, msg := range messages {
+		m := chatfile.Message{Sender: msg.Sender, Text: msg.Text, IsAI: msg.IsAI, CreatedAt: msg.CreatedAt}
+		for _, a := range attachments {
+			if a.MessageID == msg.ID {
+				m.Attachments = append(m.Attachments, chatfile.Attachment{Name: a.Name, MimeType: a.MimeType, Description: a.Description})
+			}
+		}
+		exported.Messages = append(exported.Messages, m)
+	}
+	return exported, nil
+}
+
+// storedChats indexes the chats in the sidebar by fingerprint and by creation
+// second, the two ways an exported chat is matched with the one it came from
+func storedChats() (map[string]int, map[int64]int, error) {
+	records, err := database.GetChats(appCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	byFingerprint := make(map[string]int, len(records))
+	byCreated := make(map[int64]int, len(records))
+	for _, record := range records {
+		exported, err := storedChat(record)
+		if err != nil {
+			return nil, nil, err
+		}
+		byFingerprint[exported.Fingerprint()] = record.ID
+		byCreated[record.CreatedAt.Unix()] = record.ID
+	}
+	return byFingerprint, byCreated, nil
+}
+
+// mergeChat adds the messages of an exported chat missing from a stored one
+// and reloads the stored chat
+func mergeChat(chatID int, exported chatfile.Chat) error {
+	records := make([]database.MessageRecord, len(exported.Messages))
+	for i, msg := range exported.Messages {
+		if msg.CreatedAt.IsZero() {
+			msg.CreatedAt = exported.CreatedAt
+		}
+		records[i] = database.MessageRecord{Sender: msg.Sender, Text: msg.Text, IsAI: msg.IsAI, CreatedAt: msg.CreatedAt}
+	}
+	added, err := database.MergeMessages(appCtx, chatID, records)
+	if err != nil {
+		return fmt.Errorf("failed to merge chat: %v", err)
+	}
+	if added == 0 {
+		return nil
+	}
+	if chat := findChat(chatID); chat != nil {
+		chat.Messages = nil
+		chat.Loaded = false
+	}
+	delete(chatContainers, chatID)
+	return nil
+}
+
+// exportChatFile saves the current chat as a .llmchat file
+func exportChatFile(w fyne.Window) {
+	chat := currentChatEntry()
+	if chat == nil {
+		return
+	}
+	records, err := database.GetChats(appCtx)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	exported := chatfile.Chat{Title: chat.Title, CreatedAt: time.Now()}
+	for _, record := range records {
+		if record.ID == chat.ID {
+			if exported, err = storedChat(record); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			exported.Title = chat.Title
+		}
+	}
+
+	picker := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		if err := chatfile.Write(writer, exported); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to export chat: %v", err), w)
+		}
+	}, w)
+	picker.SetFileName(strings.NewReplacer("/", "-", `\`, "-", ":", "-").Replace(chat.Title) + chatfile.Extension)
+	picker.SetFilter(storage.NewExtensionFileFilter([]string{chatfile.Extension}))
+	picker.Show()
+}
+
+// How chats that are already stored are handled on import
+const (
+	importSkip      = "Skip them"
+	importDuplicate = "Import them as copies"
+	importMerge     = "Merge their messages by time"
+)
+
+// importDroppedFiles previews the chat exports dropped on the window
+func importDroppedFiles(w fyne.Window, uris []fyne.URI) {
+	var paths []string
+	for _, uri := range uris {
+		ext := strings.ToLower(uri.Extension())
+		if ext == chatfile.Extension || ext == ".json" {
+			paths = append(paths, uri.Path())
+		}
+	}
+	previewImport(w, paths)
+}
+
+// previewImport shows how many chats and messages the exports hold and which
+// of them are already stored, and imports them once confirmed. Stored chats
+// are skipped, imported as copies or merged, as chosen in the preview.
+func previewImport(w fyne.Window, paths []string) {
+	var exported []chatfile.Chat
+	for _, path := range paths {
+		read, err := readChatFile(path)
+		if err != nil {
+			dialog.ShowError(err, w)
+			continue
+		}
+		exported = append(exported, read...)
+	}
+	if len(exported) == 0 {
+		return
+	}
+
+	byFingerprint, byCreated, err := storedChats()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to check for duplicates: %v", err), w)
+		return
+	}
+	// A chat matches a stored one with the same messages, or the one it was
+	// exported from and that has gone on since
+	matches := make([]int, len(exported))
+	messages, identical, changed := 0, 0, 0
+	for i, chat := range exported {
+		messages += len(chat.Messages)
+		if id, ok := byFingerprint[chat.Fingerprint()]; ok {
+			matches[i] = id
+			identical++
+		} else if id, ok := byCreated[chat.CreatedAt.Unix()]; ok && !chat.CreatedAt.IsZero() {
+			matches[i] = id
+			changed++
+		}
+	}
+
+	summary := fmt.Sprintf("%d chats with %d messages found.", len(exported), messages)
+	if identical > 0 {
+		summary += fmt.Sprintf("\n%d of them are already stored.", identical)
+	}
+	if changed > 0 {
+		summary += fmt.Sprintf("\n%d of them are stored with different messages.", changed)
+	}
+	strategy := widget.NewRadioGroup([]string{importSkip, importDuplicate, importMerge}, nil)
+	strategy.SetSelected(importSkip)
+	content := container.NewVBox(widget.NewLabel(summary))
+	if identical+changed > 0 {
+		content.Add(widget.NewLabel("Chats already stored:"))
+		content.Add(strategy)
+	}
+
+	dialog.ShowCustomConfirm("Import chats", "Import", "Cancel", content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		var fresh []chatfile.Chat
+		last := 0
+		for i, chat := range exported {
+			switch {
+			case matches[i] == 0 || strategy.Selected == importDuplicate:
+				fresh = append(fresh, chat)
+			case strategy.Selected == importMerge:
+				if err := mergeChat(matches[i], chat); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				last = matches[i]
+			}
+		}
+		if len(fresh) > 0 {
+			id, err := importChats(fresh)
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+			if id != 0 {
+				last = id
+			}
+		}
+		if last != 0 {
+			chatList.UnselectAll()
+			selectChat(last)
+		}
+	}, w)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// maxTemperature is the highest sampling temperature offered
+const maxTemperature = 2.0
+
+// showChatParameters lets the user set the sampling temperature, the longest
+// answer and how much of the past conversation is sent in the current chat,
+// kept with the chat for when it is reopened
+func showChatParameters(w fyne.Window) {
+	chat := currentChatEntry()
+	if chat == nil {
+		return
+	}
+
+	temperature := widget.NewSlider(0, maxTemperature)
+	temperature.Step = 0.1
+	temperatureLabel := widget.NewLabel("")
+	temperature.OnChanged = func(value float64) {
+		temperatureLabel.SetText(fmt.Sprintf("%.1f", value))
+	}
+	defaultTemperature := widget.NewCheck("Model default", func(checked bool) {
+		if checked {
+			temperature.Disable()
+			temperatureLabel.SetText("")
+		} else {
+			temperature.Enable()
+			temperatureLabel.SetText(fmt.Sprintf("%.1f", temperature.Value))
+		}
+	})
+	if chat.Temperature != nil {
+		temperature.SetValue(*chat.Temperature)
+		defaultTemperature.SetChecked(false)
+		temperatureLabel.SetText(fmt.Sprintf("%.1f", temperature.Value))
+	} else {
+		temperature.SetValue(1)
+		defaultTemperature.SetChecked(true)
+	}
+
+	maxTokens := widget.NewEntry()
+	maxTokens.SetPlaceHolder("Model default")
+	if chat.MaxTokens > 0 {
+		maxTokens.SetText(strconv.Itoa(chat.MaxTokens))
+	}
+	contextTokens := widget.NewEntry()
+	contextTokens.SetPlaceHolder("No limit")
+	if chat.ContextTokens > 0 {
+		contextTokens.SetText(strconv.Itoa(chat.ContextTokens))
+	}
+
+	items := []*widget.FormItem{
+		{Text: "Temperature", Widget: temperature},
+		{Text: "", Widget: container.NewHBox(defaultTemperature, temperatureLabel)},
+		{Text: "Max tokens", Widget: maxTokens, HintText: "Longest answer, empty for the model's default"},
+		{Text: "Context tokens", Widget: contextTokens, HintText: "Most recent messages sent along, up to this size"},
+	}
+	dialog.ShowForm(fmt.Sprintf("Parameters of %s", chat.Title), "Save", "Cancel", items, func(save bool) {
+		if !save {
+			return
+		}
+		tokens, err := tokenLimit(maxTokens.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Max tokens must be a positive number"), w)
+			return
+		}
+		contextSize, err := tokenLimit(contextTokens.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Context tokens must be a positive number"), w)
+			return
+		}
+		target := findChat(chat.ID)
+		if target == nil {
+			return
+		}
+		target.Temperature = nil
+		if !defaultTemperature.Checked {
+			value := temperature.Value
+			target.Temperature = &value
+		}
+		target.MaxTokens = tokens
+		target.ContextTokens = contextSize
+		saveChatConfig(target)
+	}, w)
+}
+
+// tokenLimit parses a token count entered by the user; empty means no limit
+func tokenLimit(text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid token count %q", text)
+	}
+	return n, nil
+}
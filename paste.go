@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/highlight"
+)
+
+// pasteMinLines is how many lines a paste needs before it is offered to be
+// wrapped in a fenced block
+const pasteMinLines = 5
+
+// pasteAttachSize is the size in bytes above which a paste is offered as an
+// attachment first
+const pasteAttachSize = 8 * 1024
+
+// Pastes above these sizes would freeze the input, so they are attached
+// without asking
+const (
+	pasteHugeSize  = 256 * 1024
+	pasteHugeLines = 2000
+)
+
+// pasteExcerptSize is the size in bytes above which only excerpts of a pasted
+// file are sent, as the whole file would not fit the model's context
+const pasteExcerptSize = 32 * 1024
+
+// excerptEdgeLines and excerptContextLines are how many lines of the start and
+// end of a file, and around each matching line, an excerpt keeps
+const (
+	excerptEdgeLines    = 20
+	excerptContextLines = 2
+	excerptMaxLines     = 400
+)
+
+// Kinds of pasted text
+const (
+	pasteText = "text"
+	pasteCode = "code"
+	pasteLogs = "logs"
+	pasteJSON = "JSON"
+)
+
+// pastedFile is pasted text attached to the next message
+type pastedFile struct {
+	Name string
+	Lang string // Language of the fenced block the model receives it in
+	Text string
+}
+
+// newPastedFile names pasted text after the language of its fenced block
+func newPastedFile(text, lang string) pastedFile {
+	name := "pasted." + lang
+	if lang == "" {
+		name = "pasted.txt"
+	}
+	return pastedFile{Name: name, Lang: lang, Text: text}
+}
+
+// lines returns the lines of the file
+func (f pastedFile) lines() []string {
+	return strings.Split(strings.TrimRight(f.Text, "\n"), "\n")
+}
+
+// textClipboard is a clipboard holding text, used to insert text at the cursor
+type textClipboard string
+
+func (c textClipboard) Content() string   { return string(c) }
+func (c textClipboard) SetContent(string) {}
+
+// logLine matches lines starting with a timestamp or a log level
+var logLine = regexp.MustCompile(`^(\[?\d{4}[-/]\d{2}[-/]\d{2}[ T]\d{2}:\d{2}|\[?\d{2}:\d{2}:\d{2}|\[?(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL)\b)`)
+
+// codeHints are the markers that make a paste look like code of a language,
+// checked in order
+var codeHints = []struct {
+	lang    string
+	pattern *regexp.Regexp
+}{
+	{"go", regexp.MustCompile(`(?m)^(package \w+|func .*\{$)`)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|import \w+$|from \w+ import )`)},
+	{"c", regexp.MustCompile(`(?m)^#include [<"]`)},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let) \w+ = |=> \{|^\s*function \w+\(`)},
+	{"sql", regexp.MustCompile(`(?im)^\s*(SELECT .+ FROM|CREATE TABLE|INSERT INTO)\b`)},
+	{"sh", regexp.MustCompile(`(?m)^#!/bin/(ba)?sh`)},
+}
+
+// codeLineEnd matches the lines ending like code in most languages
+var codeLineEnd = regexp.MustCompile(`(?m)[;{}]\s*$`)
+
+// detectPaste returns the kind of a pasted text and the language of its
+// fenced block
+func detectPaste(text string) (kind, lang string) {
+	trimmed := strings.TrimSpace(text)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return pasteJSON, "json"
+	}
+
+	lines := strings.Split(trimmed, "\n")
+	logs := 0
+	for _, line := range lines {
+		if logLine.MatchString(strings.TrimSpace(line)) {
+			logs++
+		}
+	}
+	if logs*2 >= len(lines) {
+		return pasteLogs, "log"
+	}
+
+	for _, hint := range codeHints {
+		if hint.pattern.MatchString(trimmed) {
+			return pasteCode, hint.lang
+		}
+	}
+	if len(codeLineEnd.FindAllStringIndex(trimmed, -1))*5 >= len(lines) {
+		return pasteCode, highlight.Detect(trimmed)
+	}
+	return pasteText, ""
+}
+
+// fence wraps text in a fenced block long enough not to be closed by the
+// backticks inside it
+func fence(text, lang string) string {
+	ticks := "```"
+	for strings.Contains(text, ticks) {
+		ticks += "`"
+	}
+	return ticks + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + ticks
+}
+
+// askPaste asks how to paste a large text: wrapped in a fenced block through
+// insert, attached as a file through attach, or as is. Huge pastes are attached
+// without asking. It returns false for pastes small enough to be inserted
+// right away.
+func askPaste(w fyne.Window, text string, insert func(string), attach func(pastedFile)) bool {
+	kind, lang := detectPaste(text)
+	lines := strings.Count(strings.TrimSpace(text), "\n") + 1
+	if len(text) > pasteHugeSize || lines > pasteHugeLines {
+		attach(newPastedFile(text, lang))
+		return true
+	}
+	large := len(text) > pasteAttachSize
+	if !large && (kind == pasteText || lines < pasteMinLines) {
+		return false
+	}
+
+	var d dialog.Dialog
+	wrapBtn := widget.NewButton("Code block", func() {
+		d.Hide()
+		insert(fence(text, lang) + "\n")
+	})
+	attachBtn := widget.NewButton("Attachment", func() {
+		d.Hide()
+		attach(newPastedFile(text, lang))
+	})
+	plainBtn := widget.NewButton("As is", func() {
+		d.Hide()
+		insert(text)
+	})
+	if large {
+		attachBtn.Importance = widget.HighImportance
+	} else {
+		wrapBtn.Importance = widget.HighImportance
+	}
+	if kind == pasteText {
+		wrapBtn.Hide()
+	}
+
+	message := widget.NewLabel(fmt.Sprintf("This looks like %d lines of %s (%d KB). How should it be pasted?", lines, kind, (len(text)+1023)/1024))
+	message.Wrapping = fyne.TextWrapWord
+	d = dialog.NewCustomWithoutButtons("Paste", container.NewVBox(message, container.NewHBox(wrapBtn, attachBtn, plainBtn)), w)
+	d.Resize(fyne.NewSize(400, 150))
+	d.Show()
+	return true
+}
+
+// withPastedFiles appends the attached pasted files to a prompt as fenced
+// blocks; of large files only the excerpts relevant to the prompt are sent
+func withPastedFiles(prompt string, files []pastedFile) string {
+	message := prompt
+	for _, file := range files {
+		if len(file.Text) <= pasteExcerptSize {
+			prompt += fmt.Sprintf("\n\n%s:\n%s", file.Name, fence(file.Text, file.Lang))
+			continue
+		}
+		lines := file.lines()
+		prompt += fmt.Sprintf("\n\n%s has %d lines; these excerpts are its first and last lines and the lines matching the message, numbered:\n%s",
+			file.Name, len(lines), fence(excerpt(lines, message), file.Lang))
+	}
+	return prompt
+}
+
+// excerptHints are searched for in every excerpt besides the words of the prompt
+var excerptHints = []string{"error", "fail", "panic", "exception", "fatal"}
+
+// excerpt returns the numbered lines at the start and end of lines and around
+// the lines containing a word of the prompt or an error, with "..." for gaps
+func excerpt(lines []string, prompt string) string {
+	words := append([]string{}, excerptHints...)
+	for _, word := range strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.'
+	}) {
+		if len(word) >= 4 {
+			words = append(words, word)
+		}
+	}
+
+	keep := make([]bool, len(lines))
+	for i := 0; i < len(lines) && i < excerptEdgeLines; i++ {
+		keep[i] = true
+		keep[len(lines)-1-i] = true
+	}
+	kept := 0
+	for i, line := range lines {
+		if kept >= excerptMaxLines {
+			break
+		}
+		if !containsAny(strings.ToLower(line), words) {
+			continue
+		}
+		for j := max(0, i-excerptContextLines); j <= min(len(lines)-1, i+excerptContextLines); j++ {
+			if !keep[j] {
+				keep[j] = true
+				kept++
+			}
+		}
+	}
+
+	var b strings.Builder
+	gap := false
+	for i, line := range lines {
+		if !keep[i] {
+			gap = true
+			continue
+		}
+		if gap {
+			b.WriteString("...\n")
+			gap = false
+		}
+		fmt.Fprintf(&b, "%d: %s\n", i+1, line)
+	}
+	if gap {
+		b.WriteString("...\n")
+	}
+	return b.String()
+}
+
+// containsAny reports whether s contains one of words
+func containsAny(s string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// newFileChip shows an attached pasted file with its size; tapping it opens
+// the file
+func newFileChip(w fyne.Window, file pastedFile) fyne.CanvasObject {
+	label := fmt.Sprintf("%s · %d lines", file.Name, len(file.lines()))
+	chip := widget.NewButtonWithIcon(label, theme.FileTextIcon(), func() {
+		showPastedFile(w, file)
+	})
+	chip.Importance = widget.LowImportance
+	return chip
+}
+
+// showPastedFile lists the lines of a pasted file, filtered by the lines
+// containing the text typed in the grep entry
+func showPastedFile(w fyne.Window, file pastedFile) {
+	lines := file.lines()
+	matches := make([]int, len(lines))
+	for i := range matches {
+		matches[i] = i
+	}
+
+	list := widget.NewList(
+		func() int { return len(matches) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.TextStyle = fyne.TextStyle{Monospace: true}
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			i := matches[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%d: %s", i+1, lines[i]))
+		},
+	)
+	count := widget.NewLabel("")
+	grep := widget.NewEntry()
+	grep.SetPlaceHolder("Grep")
+	grep.OnChanged = func(query string) {
+		query = strings.ToLower(query)
+		matches = matches[:0]
+		for i, line := range lines {
+			if strings.Contains(strings.ToLower(line), query) {
+				matches = append(matches, i)
+			}
+		}
+		count.SetText(fmt.Sprintf("%d of %d lines", len(matches), len(lines)))
+		list.Refresh()
+		list.ScrollToTop()
+	}
+	grep.OnChanged("")
+
+	d := dialog.NewCustom(file.Name, "Close", container.NewBorder(container.NewBorder(nil, nil, nil, count, grep), nil, nil, nil, list), w)
+	d.Resize(fyne.NewSize(800, 550))
+	d.Show()
+}
+
+// saveFiles stores the pasted files sent with a message as its attachments
+func saveFiles(chatID, messageID int, files []pastedFile) {
+	for _, file := range files {
+		if _, err := database.SaveAttachment(appCtx, chatID, messageID, file.Name, "text/plain", []byte(file.Text)); err != nil {
+			log.Printf("Failed to save pasted file: %v", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// performanceHistoryLimit is how many recent responses the history view lists
+const performanceHistoryLimit = 100
+
+// formatStreamMetrics renders the throughput added to the footer of a streamed
+// AI message
+func formatStreamMetrics(m *llm.StreamMetrics) string {
+	return fmt.Sprintf("%s to first token · %.1f tok/s",
+		m.TimeToFirstToken().Round(10*time.Millisecond), m.TokensPerSecond())
+}
+
+// showPerformanceHistory shows average and recent streaming metrics per model
+func showPerformanceHistory(w fyne.Window) {
+	perf, err := database.GetModelPerformance(appCtx)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load performance history: %v", err), w)
+		return
+	}
+	recent, err := database.GetStreamMetrics(appCtx, performanceHistoryLimit)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load performance history: %v", err), w)
+		return
+	}
+
+	summary := widget.NewTable(
+		func() (int, int) { return len(perf) + 1, 4 },
+		func() fyne.CanvasObject { return widget.NewLabel("gpt-3.5-turbo-16k") },
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText([]string{"Model", "Responses", "Avg TTFT", "Avg tok/s"}[id.Col])
+				return
+			}
+			p := perf[id.Row-1]
+			label.TextStyle = fyne.TextStyle{}
+			label.SetText([]string{
+				p.Model,
+				fmt.Sprintf("%d", p.Responses),
+				p.AvgTTFT.Round(10 * time.Millisecond).String(),
+				fmt.Sprintf("%.1f", p.AvgTokensPerSec),
+			}[id.Col])
+		},
+	)
+
+	history := widget.NewList(
+		func() int { return len(recent) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			m := recent[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s  %s  TTFT %s · %.1f tok/s · %d tokens",
+				m.CreatedAt.Local().Format("2006-01-02 15:04"), m.Model,
+				m.TTFT.Round(10*time.Millisecond), m.TokensPerSec, m.Tokens))
+		},
+	)
+
+	content := container.NewVSplit(summary, history)
+	content.SetOffset(0.35)
+
+	d := dialog.NewCustom("Performance", "Close", content, w)
+	d.Resize(fyne.NewSize(650, 500))
+	d.Show()
+}
+
+// messageStatsEnabled reports whether AI messages show their stats footer,
+// which is on unless turned off in the settings
+func messageStatsEnabled() bool {
+	value, _ := database.GetPreference(appCtx, database.PrefMessageStats)
+	return value != "false"
+}
+
+// formatMessageStats renders the model, tokens, cost and response time of an
+// AI message, or an empty string when they were not recorded
+func formatMessageStats(meta database.MessageMeta) string {
+	if meta.Model == "" {
+		return ""
+	}
+	text := fmt.Sprintf("%s · %d → %d tokens", meta.Model, meta.PromptTokens, meta.CompletionTokens)
+	if cost, ok := llm.EstimateCost(meta.Model, meta.PromptTokens, meta.CompletionTokens); ok {
+		text += fmt.Sprintf(" · $%.4f", cost)
+	}
+	if meta.Latency > 0 {
+		text += " · " + meta.Latency.Round(100*time.Millisecond).String()
+	}
+	return text
+}
+
+// newStatsFooter returns the muted footer under an AI message, hidden when it
+// is empty or turned off
+func newStatsFooter(text string) *widget.Label {
+	footer := widget.NewLabel(text)
+	footer.Importance = widget.LowImportance
+	footer.Wrapping = fyne.TextWrapWord
+	if text == "" || !messageStatsEnabled() {
+		footer.Hide()
+	}
+	return footer
+}
+
+// refreshMessageStats shows or hides the stats footers of the opened chats
+// after the setting changed
+func refreshMessageStats() {
+	enabled := messageStatsEnabled()
+	forEachMessageItem(func(item *messageItem) {
+		if item.footer == nil {
+			return
+		}
+		if enabled && item.footer.Text != "" {
+			item.footer.Show()
+		} else {
+			item.footer.Hide()
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// settingsModelOption stands for the model in the settings in the persona editor
+const settingsModelOption = "Model in the settings"
+
+// showPersonaPicker lists the personas; picking one starts a new chat with its
+// system prompt, model and temperature
+func showPersonaPicker(w fyne.Window) {
+	var d dialog.Dialog
+	list := container.NewVBox()
+	var refreshList func()
+	refreshList = func() {
+		list.Objects = nil
+		personas, err := database.GetPersonas(appCtx)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load personas: %v", err), w)
+			return
+		}
+		for _, p := range personas {
+			persona := p
+			useBtn := widget.NewButton(strings.TrimSpace(persona.Avatar+" "+persona.Name), func() {
+				d.Hide()
+				startPersonaChat(persona)
+			})
+			useBtn.Alignment = widget.ButtonAlignLeading
+			editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+				showPersonaEditor(w, &persona, refreshList)
+			})
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Delete persona", fmt.Sprintf("Delete the persona %q?", persona.Name), func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					if err := database.DeletePersona(appCtx, persona.ID); err != nil {
+						dialog.ShowError(fmt.Errorf("Failed to delete persona: %v", err), w)
+						return
+					}
+					refreshList()
+				}, w)
+			})
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(editBtn, deleteBtn), useBtn))
+		}
+		if len(personas) == 0 {
+			list.Add(widget.NewLabel("No personas yet. A persona starts chats with its own instructions, model and temperature."))
+		}
+		list.Refresh()
+	}
+	refreshList()
+
+	newBtn := widget.NewButtonWithIcon("New persona", theme.ContentAddIcon(), func() {
+		showPersonaEditor(w, nil, refreshList)
+	})
+	d = dialog.NewCustom("Start a chat with a persona", "Close", container.NewBorder(nil, newBtn, nil, nil, container.NewVScroll(list)), w)
+	d.Resize(fyne.NewSize(500, 400))
+	d.Show()
+}
+
+// showPersonaEditor creates a persona, or edits persona when it is not nil
+func showPersonaEditor(w fyne.Window, persona *database.Persona, onSaved func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Code Reviewer")
+	avatarEntry := widget.NewEntry()
+	avatarEntry.SetPlaceHolder("🧐")
+	modelChoice := widget.NewSelect(append([]string{settingsModelOption}, modelSelect.Options...), nil)
+	modelChoice.SetSelected(settingsModelOption)
+	temperatureEntry := widget.NewEntry()
+	temperatureEntry.SetPlaceHolder("Model default")
+	promptEntry := widget.NewMultiLineEntry()
+	promptEntry.Wrapping = fyne.TextWrapWord
+	promptEntry.SetPlaceHolder("You review code for bugs, unclear names and missing tests. Quote the lines you comment on.")
+	promptEntry.SetMinRowsVisible(8)
+
+	title := "New persona"
+	if persona != nil {
+		title = "Edit persona"
+		nameEntry.SetText(persona.Name)
+		avatarEntry.SetText(persona.Avatar)
+		if persona.Model != "" {
+			// Keep a model the current provider does not offer
+			if !slices.Contains(modelChoice.Options, persona.Model) {
+				modelChoice.Options = append(modelChoice.Options, persona.Model)
+			}
+			modelChoice.SetSelected(persona.Model)
+		}
+		if persona.Temperature != nil {
+			temperatureEntry.SetText(strconv.FormatFloat(*persona.Temperature, 'f', -1, 64))
+		}
+		promptEntry.SetText(persona.SystemPrompt)
+	}
+
+	form := widget.NewForm(
+		&widget.FormItem{Text: "Name", Widget: nameEntry},
+		&widget.FormItem{Text: "Avatar", Widget: avatarEntry, HintText: "An emoji or a few letters"},
+		&widget.FormItem{Text: "Model", Widget: modelChoice},
+		&widget.FormItem{Text: "Temperature", Widget: temperatureEntry, HintText: fmt.Sprintf("From 0 to %.0f, empty for the model's default", maxTemperature)},
+		&widget.FormItem{Text: "Instructions", Widget: promptEntry},
+	)
+	d := dialog.NewCustomConfirm(title, "Save", "Cancel", form, func(save bool) {
+		if !save {
+			return
+		}
+		saved := database.Persona{
+			Name:         nameEntry.Text,
+			Avatar:       strings.TrimSpace(avatarEntry.Text),
+			SystemPrompt: promptEntry.Text,
+		}
+		if persona != nil {
+			saved.ID = persona.ID
+		}
+		if modelChoice.Selected != settingsModelOption {
+			saved.Model = modelChoice.Selected
+		}
+		if text := strings.TrimSpace(temperatureEntry.Text); text != "" {
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil || value < 0 || value > maxTemperature {
+				dialog.ShowError(fmt.Errorf("Temperature must be a number from 0 to %.0f", maxTemperature), w)
+				return
+			}
+			saved.Temperature = &value
+		}
+		if _, err := database.SavePersona(appCtx, saved); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save persona: %v", err), w)
+			return
+		}
+		onSaved()
+	}, w)
+	d.Resize(fyne.NewSize(550, 500))
+	d.Show()
+}
+
+// startPersonaChat creates a chat with the instructions, model and temperature
+// of persona, greeting with its avatar and name
+func startPersonaChat(persona database.Persona) {
+	chat := newChat("")
+	chat.SystemPrompt = persona.SystemPrompt
+	chat.Temperature = persona.Temperature
+	if persona.Model != "" {
+		chat.Model = persona.Model
+	}
+	saveChatConfig(chat)
+	openNewChat(chat, strings.TrimSpace(fmt.Sprintf("%s **%s** here. How can I help you today?", persona.Avatar, persona.Name)))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// systemVoice selects the operating system speech engine instead of OpenAI TTS
+const systemVoice = "system"
+
+// speechPlayer reads AI replies aloud, playing at most one reply at a time
+type speechPlayer struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+var player = &speechPlayer{}
+
+// Speak synthesizes text with the configured voice and plays it.
+// It blocks until playback finishes or Stop is called.
+func (p *speechPlayer) Speak(text string) error {
+	p.Stop()
+
+	ctx, cancel := context.WithCancel(appCtx)
+	p.mu.Lock()
+	p.cancel = cancel
+	p.mu.Unlock()
+	defer cancel()
+
+	voice, err := database.GetPreference(appCtx, database.PrefTTSVoice)
+	if err != nil {
+		return fmt.Errorf("failed to get voice preference: %v", err)
+	}
+
+	var cmd *exec.Cmd
+	if voice == systemVoice {
+		cmd, err = systemSpeechCommand(ctx, text)
+		if err != nil {
+			return err
+		}
+	} else {
+		audio, err := llm.Synthesize(ctx, text, voice)
+		if err != nil {
+			return err
+		}
+		f, err := os.CreateTemp("", "llmschat-*.wav")
+		if err != nil {
+			return fmt.Errorf("failed to create audio file: %v", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(audio)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write audio file: %v", err)
+		}
+		cmd, err = playbackCommand(ctx, f.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("playback failed: %v", err)
+	}
+	return nil
+}
+
+// Stop interrupts the reply currently being synthesized or played
+func (p *speechPlayer) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+}
+
+// playbackCommand returns the command that plays a WAV file on this platform
+func playbackCommand(ctx context.Context, path string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.CommandContext(ctx, "afplay", path), nil
+	case "windows":
+		script := fmt.Sprintf("(New-Object Media.SoundPlayer '%s').PlaySync()", path)
+		return exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script), nil
+	}
+	for _, name := range []string{"paplay", "aplay", "play"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return exec.CommandContext(ctx, name, path), nil
+		}
+	}
+	return nil, fmt.Errorf("no audio player found, install pulseaudio-utils, alsa-utils or sox")
+}
+
+// systemSpeechCommand returns a command that speaks text with the local speech engine
+func systemSpeechCommand(ctx context.Context, text string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.CommandContext(ctx, "say", text), nil
+	case "windows":
+		cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command",
+			"Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())")
+		cmd.Stdin = strings.NewReader(text)
+		return cmd, nil
+	}
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return exec.CommandContext(ctx, name, text), nil
+		}
+	}
+	return nil, fmt.Errorf("no speech engine found, install espeak-ng")
+}
@@ -0,0 +1,136 @@
+package power
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds each command run to read the power or network state
+const commandTimeout = 5 * time.Second
+
+// State is what is known of the power supply and the network connection.
+// Whatever cannot be detected on this platform is false.
+type State struct {
+	OnBattery  bool
+	PowerSaver bool // Battery or power saver mode is on
+	Metered    bool // The connection is metered, like a phone hotspot
+}
+
+// Constrained reports whether the machine should save power or data
+func (s State) Constrained() bool {
+	return s.OnBattery || s.PowerSaver || s.Metered
+}
+
+// String describes the constraints, like "on battery, metered connection"
+func (s State) String() string {
+	var parts []string
+	if s.OnBattery {
+		parts = append(parts, "on battery")
+	}
+	if s.PowerSaver {
+		parts = append(parts, "power saver")
+	}
+	if s.Metered {
+		parts = append(parts, "metered connection")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Detect reads the power and network state of this machine
+func Detect(ctx context.Context) State {
+	switch runtime.GOOS {
+	case "linux":
+		return State{
+			OnBattery:  linuxOnBattery(),
+			PowerSaver: linuxPowerSaver(ctx),
+			Metered:    linuxMetered(ctx),
+		}
+	case "darwin":
+		batt := output(ctx, "pmset", "-g", "batt")
+		settings := output(ctx, "pmset", "-g")
+		return State{
+			OnBattery:  strings.Contains(batt, "'Battery Power'"),
+			PowerSaver: strings.Contains(strings.Join(strings.Fields(settings), " "), "lowpowermode 1"),
+		}
+	case "windows":
+		// A BatteryStatus of 1 is discharging; a Variable or Fixed cost
+		// connection is metered
+		status := output(ctx, "powershell", "-NoProfile", "-Command", "(Get-CimInstance Win32_Battery).BatteryStatus")
+		saver := output(ctx, "powershell", "-NoProfile", "-Command",
+			"[Windows.System.Power.PowerManager,Windows.System.Power,ContentType=WindowsRuntime]::EnergySaverStatus")
+		cost := output(ctx, "powershell", "-NoProfile", "-Command",
+			"[Windows.Networking.Connectivity.NetworkInformation,Windows.Networking.Connectivity,ContentType=WindowsRuntime]::GetInternetConnectionProfile().GetConnectionCost().NetworkCostType")
+		return State{
+			OnBattery:  strings.TrimSpace(status) == "1",
+			PowerSaver: strings.TrimSpace(saver) == "On",
+			Metered:    strings.TrimSpace(cost) == "Variable" || strings.TrimSpace(cost) == "Fixed",
+		}
+	}
+	return State{}
+}
+
+// linuxOnBattery reports whether the machine has a battery and no mains
+// power supply is online
+func linuxOnBattery() bool {
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+	battery := false
+	for _, supply := range supplies {
+		switch readFile(filepath.Join(supply, "type")) {
+		case "Mains", "USB":
+			if readFile(filepath.Join(supply, "online")) == "1" {
+				return false
+			}
+		case "Battery":
+			if readFile(filepath.Join(supply, "scope")) != "Device" {
+				battery = true
+			}
+		}
+	}
+	return battery
+}
+
+// linuxPowerSaver reports whether the power profile saves power
+func linuxPowerSaver(ctx context.Context) bool {
+	if readFile("/sys/firmware/acpi/platform_profile") == "low-power" {
+		return true
+	}
+	return strings.TrimSpace(output(ctx, "powerprofilesctl", "get")) == "power-saver"
+}
+
+// linuxMetered asks NetworkManager whether the connection is metered, which is
+// 1 for yes and 3 when it guesses so
+func linuxMetered(ctx context.Context) bool {
+	metered := output(ctx, "busctl", "get-property", "org.freedesktop.NetworkManager",
+		"/org/freedesktop/NetworkManager", "org.freedesktop.NetworkManager", "Metered")
+	value := strings.TrimSpace(strings.TrimPrefix(metered, "u"))
+	return value == "1" || value == "3"
+}
+
+// readFile returns the trimmed content of a file, or "" when it cannot be read
+func readFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// output runs a command and returns its output, or "" when it is not
+// installed or fails
+func output(ctx context.Context, name string, args ...string) string {
+	if _, err := exec.LookPath(name); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// profileSelect switches between the settings profiles in the sidebar
+var profileSelect *widget.Select
+
+// newProfileSwitcher returns the sidebar row to switch and manage profiles
+func newProfileSwitcher(w fyne.Window) fyne.CanvasObject {
+	profileSelect = widget.NewSelect(nil, nil)
+	profileSelect.PlaceHolder = database.DefaultProfile
+	refreshProfileSelect()
+
+	manageBtn := widget.NewButtonWithIcon("", theme.AccountIcon(), func() {
+		showProfilesDialog(w)
+	})
+	return container.NewBorder(nil, nil, nil, manageBtn, profileSelect)
+}
+
+// refreshProfileSelect lists the profiles and selects the active one
+func refreshProfileSelect() {
+	profiles, err := database.GetProfiles(appCtx)
+	if err != nil {
+		log.Printf("Failed to load profiles: %v", err)
+		return
+	}
+	ids := make(map[string]int, len(profiles))
+	options := make([]string, len(profiles))
+	active := ""
+	for i, p := range profiles {
+		options[i] = p.Profile
+		ids[p.Profile] = p.ID
+		if p.Active {
+			active = p.Profile
+		}
+	}
+
+	// Select without switching, then react to the user's choices
+	profileSelect.OnChanged = nil
+	profileSelect.Options = options
+	profileSelect.SetSelected(active)
+	profileSelect.OnChanged = func(name string) {
+		if err := switchProfile(ids[name]); err != nil {
+			dialog.ShowError(err, mainWindow)
+		}
+	}
+	profileSelect.Refresh()
+}
+
+// switchProfile activates a profile and reloads everything that depends on it
+func switchProfile(id int) error {
+	if err := database.SwitchProfile(appCtx, id); err != nil {
+		return fmt.Errorf("Failed to switch profile: %v", err)
+	}
+	refreshModelSelect()
+	refreshChatBars()
+	restartBridges()
+	return nil
+}
+
+// showProfilesDialog lists the profiles with buttons to rename and delete them
+// and to add new ones based on the active profile
+func showProfilesDialog(w fyne.Window) {
+	list := container.NewVBox()
+	var refreshList func()
+	refreshList = func() {
+		list.Objects = nil
+		profiles, err := database.GetProfiles(appCtx)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load profiles: %v", err), w)
+			return
+		}
+		for _, p := range profiles {
+			profile := p
+			label := widget.NewLabel(profile.Profile)
+			if profile.Active {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+			}
+			renameBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+				askProfileName(w, "Rename profile", profile.Profile, func(name string) error {
+					return database.RenameProfile(appCtx, profile.ID, name)
+				}, refreshList)
+			})
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Delete profile", fmt.Sprintf("Delete the profile %q and its API key?", profile.Profile), func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					if err := database.DeleteProfile(appCtx, profile.ID); err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					refreshList()
+				}, w)
+			})
+			if profile.Active {
+				deleteBtn.Disable()
+			}
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(renameBtn, deleteBtn), label))
+		}
+		if len(profiles) == 0 {
+			list.Add(widget.NewLabel("Save the settings to create the first profile"))
+		}
+		list.Refresh()
+		refreshProfileSelect()
+	}
+	refreshList()
+
+	newBtn := widget.NewButtonWithIcon("New profile", theme.ContentAddIcon(), func() {
+		askProfileName(w, "New profile", "", func(name string) error {
+			_, err := database.CreateProfile(appCtx, name)
+			return err
+		}, refreshList)
+	})
+	hint := widget.NewLabel("New profiles start with the provider and model of the active one. Switch to a profile and open the settings to change them.")
+	hint.Wrapping = fyne.TextWrapWord
+	hint.Importance = widget.LowImportance
+
+	d := dialog.NewCustom("Profiles", "Close", container.NewBorder(nil, container.NewVBox(hint, newBtn), nil, nil, container.NewVScroll(list)), w)
+	d.Resize(fyne.NewSize(420, 380))
+	d.Show()
+}
+
+// askProfileName asks for a profile name and passes it to save
+func askProfileName(w fyne.Window, title, name string, save func(string) error, onSaved func()) {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("Work")
+	entry.SetText(name)
+	dialog.ShowForm(title, "Save", "Cancel", []*widget.FormItem{
+		{Text: "Name", Widget: entry},
+	}, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if err := save(entry.Text); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		onSaved()
+	}, w)
+}
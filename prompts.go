@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/templates"
+)
+
+// showPromptPicker lists the prompt templates; picking one asks for its
+// variables and puts the filled text in the input
+func showPromptPicker(w fyne.Window, input *CustomEntry) {
+	var d dialog.Dialog
+	list := container.NewVBox()
+	var refreshList func()
+	refreshList = func() {
+		list.Objects = nil
+		prompts, err := database.GetPrompts(appCtx)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load prompts: %v", err), w)
+			return
+		}
+		for _, p := range prompts {
+			prompt := p
+			useBtn := widget.NewButton(prompt.Name, func() {
+				d.Hide()
+				usePrompt(w, input, prompt)
+			})
+			useBtn.Alignment = widget.ButtonAlignLeading
+			editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+				showPromptEditor(w, &prompt, refreshList)
+			})
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Delete prompt", fmt.Sprintf("Delete the prompt %q?", prompt.Name), func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					if err := database.DeletePrompt(appCtx, prompt.ID); err != nil {
+						dialog.ShowError(fmt.Errorf("Failed to delete prompt: %v", err), w)
+						return
+					}
+					refreshList()
+				}, w)
+			})
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(editBtn, deleteBtn), useBtn))
+		}
+		if len(prompts) == 0 {
+			list.Add(widget.NewLabel("No prompts yet. Variables like {{topic}} are asked for when a prompt is used."))
+		}
+		list.Refresh()
+	}
+	refreshList()
+
+	newBtn := widget.NewButtonWithIcon("New prompt", theme.ContentAddIcon(), func() {
+		showPromptEditor(w, nil, refreshList)
+	})
+	d = dialog.NewCustom("Prompts", "Close", container.NewBorder(nil, newBtn, nil, nil, container.NewVScroll(list)), w)
+	d.Resize(fyne.NewSize(500, 400))
+	d.Show()
+}
+
+// showPromptEditor creates a prompt template, or edits prompt when it is not nil
+func showPromptEditor(w fyne.Window, prompt *database.Prompt, onSaved func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Code review")
+	textEntry := widget.NewMultiLineEntry()
+	textEntry.Wrapping = fyne.TextWrapWord
+	textEntry.SetPlaceHolder("Review this {{language}} code for bugs:\n{{clipboard}}")
+	textEntry.SetMinRowsVisible(8)
+	title := "New prompt"
+	if prompt != nil {
+		title = "Edit prompt"
+		nameEntry.SetText(prompt.Name)
+		textEntry.SetText(prompt.Text)
+	}
+
+	form := widget.NewForm(
+		&widget.FormItem{Text: "Name", Widget: nameEntry},
+		&widget.FormItem{Text: "Text", Widget: textEntry, HintText: "{{name}} asks for a value; built-ins like {{date}} and {{clipboard}} are filled in on send"},
+	)
+	d := dialog.NewCustomConfirm(title, "Save", "Cancel", form, func(save bool) {
+		if !save {
+			return
+		}
+		var err error
+		if prompt == nil {
+			_, err = database.CreatePrompt(appCtx, nameEntry.Text, textEntry.Text)
+		} else {
+			err = database.UpdatePrompt(appCtx, prompt.ID, nameEntry.Text, textEntry.Text)
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save prompt: %v", err), w)
+			return
+		}
+		onSaved()
+	}, w)
+	d.Resize(fyne.NewSize(550, 400))
+	d.Show()
+}
+
+// usePrompt asks for the variables of a prompt template and inserts the
+// filled text at the cursor of the input, so it can be undone like typing
+func usePrompt(w fyne.Window, input *CustomEntry, prompt database.Prompt) {
+	names := templates.Variables(prompt.Text)
+	if len(names) == 0 {
+		input.insert(prompt.Text)
+		w.Canvas().Focus(input)
+		return
+	}
+
+	// Values are often code or text, so every variable takes several lines
+	entries := make(map[string]*widget.Entry, len(names))
+	form := widget.NewForm()
+	for _, name := range names {
+		entry := widget.NewMultiLineEntry()
+		entry.Wrapping = fyne.TextWrapWord
+		entry.SetMinRowsVisible(3)
+		entries[name] = entry
+		form.Append(name, entry)
+	}
+	d := dialog.NewCustomConfirm(prompt.Name, "Use", "Cancel", form, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		values := make(map[string]string, len(entries))
+		for name, entry := range entries {
+			values[name] = entry.Text
+		}
+		input.insert(templates.Fill(prompt.Text, values))
+		w.Canvas().Focus(input)
+	}, w)
+	d.Resize(fyne.NewSize(500, 0))
+	d.Show()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// audioRecorder captures microphone input to a WAV file using whichever
+// command-line recorder is available on the system
+type audioRecorder struct {
+	cmd  *exec.Cmd
+	path string
+}
+
+// recorderCommand returns the recording command for the current platform
+func recorderCommand(path string) (*exec.Cmd, error) {
+	if _, err := exec.LookPath("rec"); err == nil {
+		return exec.Command("rec", "-q", "-c", "1", "-r", "16000", path), nil
+	}
+	if _, err := exec.LookPath("arecord"); err == nil {
+		return exec.Command("arecord", "-q", "-f", "S16_LE", "-c", "1", "-r", "16000", path), nil
+	}
+	if _, err := exec.LookPath("ffmpeg"); err == nil {
+		var input []string
+		switch runtime.GOOS {
+		case "darwin":
+			input = []string{"-f", "avfoundation", "-i", ":0"}
+		case "windows":
+			input = []string{"-f", "dshow", "-i", "audio=default"}
+		default:
+			input = []string{"-f", "pulse", "-i", "default"}
+		}
+		args := append([]string{"-y", "-loglevel", "quiet"}, input...)
+		args = append(args, "-ac", "1", "-ar", "16000", path)
+		return exec.Command("ffmpeg", args...), nil
+	}
+	return nil, fmt.Errorf("no audio recorder found, install sox, alsa-utils or ffmpeg")
+}
+
+// Start begins recording into a temporary WAV file
+func (r *audioRecorder) Start() error {
+	f, err := os.CreateTemp("", "llmschat-*.wav")
+	if err != nil {
+		return fmt.Errorf("failed to create audio file: %v", err)
+	}
+	f.Close()
+
+	cmd, err := recorderCommand(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to start recording: %v", err)
+	}
+
+	r.cmd = cmd
+	r.path = f.Name()
+	return nil
+}
+
+// Recording reports whether a recording is in progress
+func (r *audioRecorder) Recording() bool {
+	return r.cmd != nil
+}
+
+// Stop ends the recording and returns the path of the captured WAV file.
+// The caller is responsible for removing the file.
+func (r *audioRecorder) Stop() (string, error) {
+	if r.cmd == nil {
+		return "", fmt.Errorf("not recording")
+	}
+	cmd, path := r.cmd, r.path
+	r.cmd, r.path = nil, ""
+
+	// Interrupt lets the recorder finalize the WAV header before exiting
+	if runtime.GOOS == "windows" {
+		cmd.Process.Kill()
+	} else {
+		cmd.Process.Signal(syscall.SIGINT)
+	}
+	cmd.Wait()
+
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		os.Remove(path)
+		return "", fmt.Errorf("no audio was recorded")
+	}
+	return path, nil
+}
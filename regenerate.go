@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// Whether a regenerated answer takes the place of the old one
+const (
+	regenerateReplace = "Replace this answer"
+	regenerateAppend  = "Add a new answer"
+)
+
+// newRegenerateButton returns a button asking which model answers the prompt
+// of an AI message again and whether the new answer replaces the old one
+func newRegenerateButton(chatID int, item *messageItem) *widget.Button {
+	return widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+		models := widget.NewSelect(modelSelect.Options, nil)
+		models.SetSelected(currentModel)
+		mode := widget.NewRadioGroup([]string{regenerateReplace, regenerateAppend}, nil)
+		mode.SetSelected(regenerateReplace)
+		mode.Required = true
+
+		form := container.NewVBox(widget.NewLabel("Model"), models, mode)
+		dialog.ShowCustomConfirm("Regenerate", "Regenerate", "Cancel", form, func(ok bool) {
+			if !ok || models.Selected == "" {
+				return
+			}
+			regenerateAnswer(chatID, item, models.Selected, mode.Selected == regenerateReplace)
+		}, mainWindow)
+	})
+}
+
+// regenerateAnswer sends the user message before an AI message again, with the
+// files and images it was sent with, and streams the answer of model. With
+// replace the old answer is deleted.
+func regenerateAnswer(chatID int, item *messageItem, model string, replace bool) {
+	chat := findChat(chatID)
+	msgContainer := chatContainers[chatID]
+	if chat == nil || msgContainer == nil {
+		return
+	}
+	if waitForStream(chatID, "Regenerate") {
+		return
+	}
+
+	// The prompt is the last user message stored before the answer
+	var prompt ChatMessage
+	for _, msg := range chat.Messages {
+		if msg.ID == item.messageID {
+			break
+		}
+		if msg.Sender == "You" {
+			prompt = msg
+		}
+	}
+	if prompt.ID == 0 {
+		dialog.ShowInformation("Regenerate", "No prompt found for this answer.", mainWindow)
+		return
+	}
+	userMessage := prompt.Text
+
+	// Pasted files and images are sent along again
+	files, images, err := messageAttachments(prompt.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load attachments: %v", err), mainWindow)
+		return
+	}
+
+	if replace {
+		if err := deleteMessage(chatID, item); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to delete answer: %v", err), mainWindow)
+			return
+		}
+		// The prompt is added to the memory again when it is sent
+		forgetMessage(chatID, prompt)
+	}
+	go streamAnswer(chatID, prompt.ID, userMessage, files, images, model)
+}
+
+// messageAttachments returns the pasted files and images a user message was
+// sent with
+func messageAttachments(messageID int) ([]pastedFile, []llm.Image, error) {
+	attachments, err := database.GetMessageAttachments(appCtx, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []pastedFile
+	var images []llm.Image
+	for _, a := range attachments {
+		data, err := a.Data()
+		if err != nil {
+			log.Printf("Failed to read attachment %s: %v", a.Name, err)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(a.MimeType, "image/"):
+			images = append(images, llm.Image{Name: a.Name, MimeType: a.MimeType, Data: data})
+		case a.MimeType == "text/plain":
+			lang := strings.TrimPrefix(filepath.Ext(a.Name), ".")
+			if lang == "txt" {
+				lang = ""
+			}
+			files = append(files, pastedFile{Name: a.Name, Lang: lang, Text: string(data)})
+		}
+	}
+	return files, images, nil
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// Total is the usage of a day or a model
+type Total struct {
+	Name     string
+	Requests int
+	Tokens   int
+	Cost     float64 // USD
+}
+
+// Chat is a chat with the messages sent in the period
+type Chat struct {
+	Title    string
+	Messages int
+}
+
+// Prompt is a saved prompt template created or changed in the period
+type Prompt struct {
+	Name string
+	Text string
+}
+
+// Report summarizes the usage of a period, usually a week
+type Report struct {
+	Start  time.Time
+	End    time.Time
+	Days   []Total // Oldest first
+	Models []Total // Most expensive first
+	Chats  []Chat  // Busiest first
+	Saved  []Prompt
+}
+
+// Requests returns the requests sent in the period
+func (r Report) Requests() int {
+	n := 0
+	for _, day := range r.Days {
+		n += day.Requests
+	}
+	return n
+}
+
+// Tokens returns the tokens used in the period
+func (r Report) Tokens() int {
+	n := 0
+	for _, day := range r.Days {
+		n += day.Tokens
+	}
+	return n
+}
+
+// Cost returns the cost of the period in USD
+func (r Report) Cost() float64 {
+	cost := 0.0
+	for _, day := range r.Days {
+		cost += day.Cost
+	}
+	return cost
+}
+
+// Title names the report after its period
+func (r Report) Title() string {
+	return fmt.Sprintf("Usage from %s to %s", r.Start.Format("2006-01-02"), r.End.Format("2006-01-02"))
+}
+
+// Markdown renders the report as Markdown
+func (r Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title())
+	fmt.Fprintf(&b, "%d requests, %d tokens, $%.4f\n", r.Requests(), r.Tokens(), r.Cost())
+
+	writeTable := func(title, first string, totals []Total) {
+		if len(totals) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| %s | Requests | Tokens | Cost |\n|---|---:|---:|---:|\n", title, first)
+		for _, t := range totals {
+			fmt.Fprintf(&b, "| %s | %d | %d | $%.4f |\n", markdownCell(t.Name), t.Requests, t.Tokens, t.Cost)
+		}
+	}
+	writeTable("Per day", "Day", r.Days)
+	writeTable("Per model", "Model", r.Models)
+
+	if len(r.Chats) > 0 {
+		b.WriteString("\n## Top chats\n\n")
+		for _, chat := range r.Chats {
+			fmt.Fprintf(&b, "- %s: %d messages\n", chat.Title, chat.Messages)
+		}
+	}
+	if len(r.Saved) > 0 {
+		b.WriteString("\n## Saved prompts\n")
+		for _, prompt := range r.Saved {
+			fmt.Fprintf(&b, "\n### %s\n\n%s\n", prompt.Name, quote(prompt.Text))
+		}
+	}
+	return b.String()
+}
+
+// markdownCell escapes the pipes that would end a table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// quote renders text as a Markdown block quote
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> ")
+}
+
+// htmlTemplate lays out the report as a standalone page
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"cost": func(cost float64) string { return fmt.Sprintf("$%.4f", cost) },
+	"totals": func(first string, totals []Total) any {
+		return struct {
+			First  string
+			Totals []Total
+		}{first, totals}
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.8em; border-bottom: 1px solid #ddd; }
+td.n { text-align: right; }
+blockquote { white-space: pre-wrap; color: #555; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Requests}} requests, {{.Tokens}} tokens, {{cost .Cost}}</p>
+{{define "totals"}}<table>
+<tr><th>{{.First}}</th><th>Requests</th><th>Tokens</th><th>Cost</th></tr>
+{{range .Totals}}<tr><td>{{.Name}}</td><td class="n">{{.Requests}}</td><td class="n">{{.Tokens}}</td><td class="n">{{cost .Cost}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Days}}<h2>Per day</h2>
+{{template "totals" (totals "Day" .)}}{{end}}
+{{with .Models}}<h2>Per model</h2>
+{{template "totals" (totals "Model" .)}}{{end}}
+{{with .Chats}}<h2>Top chats</h2>
+<ul>
+{{range .}}<li>{{.Title}}: {{.Messages}} messages</li>
+{{end}}</ul>{{end}}
+{{with .Saved}}<h2>Saved prompts</h2>
+{{range .}}<h3>{{.Name}}</h3>
+<blockquote>{{.Text}}</blockquote>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// HTML renders the report as a standalone HTML page
+func (r Report) HTML() (string, error) {
+	var b strings.Builder
+	if err := htmlTemplate.Execute(&b, r); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
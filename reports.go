@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/report"
+)
+
+// reportPeriod is how far back a usage report looks and how often it is
+// written to the report folder
+const reportPeriod = 7 * 24 * time.Hour
+
+// reportCheckInterval is how often the scheduler checks whether a report is due
+const reportCheckInterval = time.Hour
+
+// reportTopChats is how many of the busiest chats a report lists
+const reportTopChats = 5
+
+// buildReport summarizes the usage, busiest chats and saved prompts of the
+// period ending at end
+func buildReport(end time.Time) (report.Report, error) {
+	start := end.Add(-reportPeriod)
+	r := report.Report{Start: start, End: end}
+
+	days, err := database.GetUsageByDay(appCtx, start)
+	if err != nil {
+		return r, err
+	}
+	for _, t := range days {
+		r.Days = append(r.Days, report.Total{Name: t.Key, Requests: t.Requests, Tokens: t.PromptTokens + t.CompletionTokens, Cost: t.Cost})
+	}
+	models, err := database.GetUsageByModel(appCtx, start)
+	if err != nil {
+		return r, err
+	}
+	for _, t := range models {
+		r.Models = append(r.Models, report.Total{Name: t.Key, Requests: t.Requests, Tokens: t.PromptTokens + t.CompletionTokens, Cost: t.Cost})
+	}
+
+	top, err := database.GetTopChats(appCtx, start, reportTopChats)
+	if err != nil {
+		return r, err
+	}
+	for _, chat := range top {
+		title := chat.Title
+		if title == "" {
+			title = fmt.Sprintf("Chat %d", chat.ID)
+		}
+		r.Chats = append(r.Chats, report.Chat{Title: title, Messages: chat.Messages})
+	}
+
+	prompts, err := database.GetPrompts(appCtx)
+	if err != nil {
+		return r, err
+	}
+	for _, prompt := range prompts {
+		if !prompt.UpdatedAt.Before(start) {
+			r.Saved = append(r.Saved, report.Prompt{Name: prompt.Name, Text: prompt.Text})
+		}
+	}
+	return r, nil
+}
+
+// renderReport renders r as HTML for .html and .htm paths and as Markdown otherwise
+func renderReport(r report.Report, path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return r.HTML()
+	default:
+		return r.Markdown(), nil
+	}
+}
+
+// writeReportFolder writes the report of the week as Markdown and HTML into
+// dir and returns the path of the Markdown file
+func writeReportFolder(dir string, r report.Report) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report folder: %v", err)
+	}
+	name := filepath.Join(dir, "usage-"+r.End.Format("2006-01-02"))
+	for _, ext := range []string{".html", ".md"} {
+		text, err := renderReport(r, name+ext)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(name+ext, []byte(text), 0644); err != nil {
+			return "", fmt.Errorf("failed to write report: %v", err)
+		}
+	}
+	return name + ".md", nil
+}
+
+// runReportScheduler writes a usage report into the report folder once a week
+// while the app is running
+func runReportScheduler() {
+	for {
+		// Reports wait while saving power
+		if economyActive() {
+			time.Sleep(reportCheckInterval)
+			continue
+		}
+		if dir, err := database.GetPreference(appCtx, database.PrefReportDir); err == nil && dir != "" {
+			last, _ := database.GetPreference(appCtx, database.PrefReportLastRun)
+			if t, err := time.Parse(time.RFC3339, last); err != nil || time.Since(t) >= reportPeriod {
+				if err := writeWeeklyReport(dir); err != nil {
+					log.Printf("Failed to write usage report: %v", err)
+				}
+			}
+		}
+		time.Sleep(reportCheckInterval)
+	}
+}
+
+// writeWeeklyReport writes the report of the last week into dir and records when
+func writeWeeklyReport(dir string) error {
+	r, err := buildReport(time.Now())
+	if err != nil {
+		return err
+	}
+	path, err := writeReportFolder(dir, r)
+	if err != nil {
+		return err
+	}
+	log.Printf("Usage report written to %s", path)
+	return database.SetPreference(appCtx, database.PrefReportLastRun, time.Now().Format(time.RFC3339))
+}
+
+// exportReport saves the report of the last week as Markdown or, when the file
+// name ends in .html, as a web page
+func exportReport(w fyne.Window) {
+	r, err := buildReport(time.Now())
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to build report: %v", err), w)
+		return
+	}
+	picker := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		text, err := renderReport(r, writer.URI().Path())
+		if err == nil {
+			_, err = writer.Write([]byte(text))
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to export report: %v", err), w)
+		}
+	}, w)
+	picker.SetFileName("usage-" + r.End.Format("2006-01-02") + ".md")
+	picker.SetFilter(storage.NewExtensionFileFilter([]string{".md", ".html"}))
+	picker.Show()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// newErrorCard shows a failed request as a card holding the error and the
+// retry buttons of actions
+func newErrorCard(text string, actions *fyne.Container) *fyne.Container {
+	label := widget.NewLabel(strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(text, "Error"), ":")))
+	label.Importance = widget.DangerImportance
+	label.Wrapping = fyne.TextWrapWord
+	card := widget.NewCard("", "", container.NewVBox(label, actions))
+	return container.NewPadded(card)
+}
+
+// addFailedAnswer stores and shows the error of an answer that failed, marked
+// so its card offers to send the prompt again
+func addFailedAnswer(chatID int, err error) {
+	msg := ChatMessage{
+		Text:        fmt.Sprintf("Error: %v", err),
+		Sender:      "System",
+		IsAI:        true,
+		MessageMeta: database.MessageMeta{Failed: true},
+	}
+	if chat := findChat(chatID); chat != nil {
+		msg.ID = storeMessage(chat, msg)
+	}
+	msg.CreatedAt = time.Now()
+	renderMessage(chatID, msg)
+	notify(chatID, database.EventError, msg.Text)
+}
+
+// addRetryButtons fills actions with buttons sending the prompt of a failed
+// request again, with the same model or another one; the error card is
+// replaced by the new answer
+func addRetryButtons(chatID int, item *messageItem, actions *fyne.Container) {
+	retryBtn := widget.NewButtonWithIcon("Retry", theme.ViewRefreshIcon(), func() {
+		regenerateAnswer(chatID, item, currentModel, true)
+	})
+	retryWithBtn := widget.NewButtonWithIcon("Retry with…", theme.MenuDropDownIcon(), func() {
+		models := widget.NewSelect(modelSelect.Options, nil)
+		models.SetSelected(currentModel)
+		dialog.ShowCustomConfirm("Retry with another model", "Retry", "Cancel", models, func(ok bool) {
+			if !ok || models.Selected == "" {
+				return
+			}
+			regenerateAnswer(chatID, item, models.Selected, true)
+		}, mainWindow)
+	})
+	actions.Objects = []fyne.CanvasObject{retryBtn, retryWithBtn}
+	actions.Refresh()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// searchSnippetLength is how many characters of a matching message are listed
+const searchSnippetLength = 90
+
+// showSearchResults lists the messages matching query; picking one opens its
+// chat scrolled to the message
+func showSearchResults(w fyne.Window, query string) {
+	if strings.TrimSpace(query) == "" {
+		return
+	}
+	results, err := database.SearchMessages(appCtx, query)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Search failed: %v", err), w)
+		return
+	}
+	if len(results) == 0 {
+		dialog.ShowInformation("Search", fmt.Sprintf("No messages match %q", query), w)
+		return
+	}
+
+	var d dialog.Dialog
+	list := widget.NewList(
+		func() int { return len(results) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabel("Chat")
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			snippet := widget.NewLabel("Message")
+			snippet.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(title, snippet)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			result := results[id]
+			labels := item.(*fyne.Container).Objects
+			title := fmt.Sprintf("Chat %d", result.ChatID)
+			if chat := findChat(result.ChatID); chat != nil {
+				title = chat.Title
+			}
+			labels[0].(*widget.Label).SetText(title)
+			labels[1].(*widget.Label).SetText(result.Sender + ": " + searchSnippet(result.Text, query))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		d.Hide()
+		jumpToMessage(results[id])
+	}
+
+	d = dialog.NewCustom(fmt.Sprintf("%d messages match %q", len(results), query), "Close", list, w)
+	d.Resize(fyne.NewSize(600, 450))
+	d.Show()
+}
+
+// searchSnippet returns the part of text around the first word of query
+func searchSnippet(text, query string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	start := 0
+	if words := strings.Fields(query); len(words) > 0 {
+		if i := strings.Index(strings.ToLower(text), strings.ToLower(words[0])); i > 0 {
+			start = len([]rune(text[:i])) - searchSnippetLength/3
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + searchSnippetLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// jumpToMessage opens the chat of msg and scrolls to it
+func jumpToMessage(msg database.MessageRecord) {
+	for i := range chats {
+		if chats[i].ID != msg.ChatID {
+			continue
+		}
+		chat := chats[i]
+		selectChat(chat.ID)
+
+		// Render the chat again so every stored message has a row
+		rerenderChat(chat)
+		msgContainer := chatContainers[chat.ID]
+		for _, object := range msgContainer.Objects {
+			if item, ok := object.(*messageItem); ok && item.messageID == msg.ID {
+				mainContainer.Refresh()
+				scrollToMessage(msgContainer, item)
+				return
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// selecting is set while messages of the current chat are picked to be
+// copied or exported together
+var selecting bool
+
+// selectionBar shows how many messages are picked and what to do with them
+var selectionBar *fyne.Container
+
+// newPickCheck returns the check picking a message for the selection, shown
+// while selecting
+func newPickCheck() *widget.Check {
+	check := widget.NewCheck("", func(bool) { refreshSelectionBar() })
+	if !selecting {
+		check.Hide()
+	}
+	return check
+}
+
+// startSelecting shows the checks picking the messages of the current chat
+func startSelecting() {
+	if currentChat == nil {
+		return
+	}
+	selecting = true
+	for _, item := range messageItems() {
+		if item.picked != nil {
+			item.picked.Show()
+		}
+	}
+	refreshSelectionBar()
+}
+
+// stopSelecting hides the checks of the current chat and forgets the picked
+// messages
+func stopSelecting() {
+	if !selecting {
+		return
+	}
+	selecting = false
+	for _, item := range messageItems() {
+		if item.picked != nil {
+			item.picked.SetChecked(false)
+			item.picked.Hide()
+		}
+	}
+	refreshSelectionBar()
+}
+
+// pickedMessages returns the stored messages picked in the current chat, in
+// the order they are shown
+func pickedMessages() []database.MessageRecord {
+	if currentChat == nil {
+		return nil
+	}
+	messages, err := database.GetMessages(appCtx, currentChat.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load messages: %v", err), mainWindow)
+		return nil
+	}
+	byID := make(map[int]database.MessageRecord, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	var picked []database.MessageRecord
+	for _, item := range messageItems() {
+		if item.picked == nil || !item.picked.Checked {
+			continue
+		}
+		if msg, ok := byID[item.messageID]; ok {
+			picked = append(picked, msg)
+		}
+	}
+	return picked
+}
+
+// selectionMarkdown returns the picked messages as Markdown, like a chat
+// exported from the command line, and whether any were picked
+func selectionMarkdown() (string, bool) {
+	chat := currentChatEntry()
+	messages := pickedMessages()
+	if chat == nil || len(messages) == 0 {
+		return "", false
+	}
+	attachments, err := database.GetChatAttachments(appCtx, chat.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load attachments: %v", err), mainWindow)
+		return "", false
+	}
+	var b bytes.Buffer
+	writeMessagesMarkdown(&b, chat.Title, messages[0].CreatedAt, messages, attachments)
+	return b.String(), true
+}
+
+// copySelection puts the picked messages on the clipboard as Markdown
+func copySelection() {
+	text, ok := selectionMarkdown()
+	if !ok {
+		return
+	}
+	mainWindow.Clipboard().SetContent(text)
+	showToast("Copied selection")
+	stopSelecting()
+}
+
+// exportSelection saves the picked messages as a Markdown file
+func exportSelection(w fyne.Window) {
+	chat := currentChatEntry()
+	text, ok := selectionMarkdown()
+	if !ok {
+		return
+	}
+	picker := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write([]byte(text)); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to export selection: %v", err), w)
+			return
+		}
+		stopSelecting()
+	}, w)
+	picker.SetFileName(strings.NewReplacer("/", "-", `\`, "-", ":", "-").Replace(chat.Title) + ".md")
+	picker.SetFilter(storage.NewExtensionFileFilter([]string{".md"}))
+	picker.Show()
+}
+
+// refreshSelectionBar shows the number of picked messages with the actions
+// on them while selecting
+func refreshSelectionBar() {
+	if selectionBar == nil {
+		return
+	}
+	if !selecting {
+		selectionBar.Hide()
+		return
+	}
+
+	count := 0
+	for _, item := range messageItems() {
+		if item.picked != nil && item.picked.Checked {
+			count++
+		}
+	}
+	copyBtn := widget.NewButtonWithIcon("Copy selection as Markdown", theme.ContentCopyIcon(), copySelection)
+	exportBtn := widget.NewButtonWithIcon("Export selection", theme.DownloadIcon(), func() {
+		exportSelection(mainWindow)
+	})
+	if count == 0 {
+		copyBtn.Disable()
+		exportBtn.Disable()
+	}
+	doneBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), stopSelecting)
+	doneBtn.Importance = widget.LowImportance
+
+	selectionBar.Objects = []fyne.CanvasObject{
+		container.NewHBox(widget.NewLabel(fmt.Sprintf("%d selected", count)), layout.NewSpacer(), copyBtn, exportBtn, doneBtn),
+	}
+	selectionBar.Refresh()
+	selectionBar.Show()
+}
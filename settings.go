@@ -0,0 +1,704 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+func showSettingsModal(w fyne.Window) {
+	// Create form fields with increased width
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Enter your name")
+	nameEntry.Resize(fyne.NewSize(300, 36))
+
+	apiKeyEntry := widget.NewPasswordEntry()
+	apiKeyEntry.SetPlaceHolder("Optional, replaces the provider key for this profile")
+	apiKeyEntry.Resize(fyne.NewSize(300, 36))
+
+	companyKeyEntry := widget.NewPasswordEntry()
+	companyKeyEntry.SetPlaceHolder("Enter your API key")
+
+	whisperEntry := widget.NewEntry()
+	whisperEntry.SetPlaceHolder("Optional local whisper.cpp URL")
+	if url, err := database.GetPreference(appCtx, database.PrefWhisperURL); err == nil {
+		whisperEntry.SetText(url)
+	}
+
+	workspaceEntry := widget.NewEntry()
+	workspaceEntry.SetPlaceHolder("Optional project folder")
+	if dir, err := database.GetPreference(appCtx, database.PrefWorkspaceDir); err == nil {
+		workspaceEntry.SetText(dir)
+	}
+
+	dataDirEntry := widget.NewEntry()
+	dataDirEntry.SetPlaceHolder(database.DefaultDataDir())
+	dataDirEntry.SetText(database.ConfiguredDataDir())
+	dataDirBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			dataDirEntry.SetText(uri.Path())
+		}, w)
+	})
+
+	backupBtn := widget.NewButtonWithIcon("Back up", theme.DocumentSaveIcon(), func() {
+		backupDatabase(w)
+	})
+	restoreBtn := widget.NewButtonWithIcon("Restore", theme.HistoryIcon(), func() {
+		restoreDatabase(w)
+	})
+	exportBtn := widget.NewButtonWithIcon("Export JSON", theme.UploadIcon(), func() {
+		exportDatabaseJSON(w)
+	})
+	importBtn := widget.NewButtonWithIcon("Import JSON", theme.DownloadIcon(), func() {
+		importDatabaseJSON(w)
+	})
+
+	dbSizeLabel := widget.NewLabel("")
+	refreshDatabaseSize(dbSizeLabel)
+	var compactBtn *widget.Button
+	compactBtn = widget.NewButtonWithIcon("Compact", theme.ViewRefreshIcon(), func() {
+		compactBtn.Disable()
+		go func() {
+			defer compactBtn.Enable()
+			if err := database.Maintain(appCtx); err != nil {
+				dialog.ShowError(err, w)
+			}
+			refreshDatabaseSize(dbSizeLabel)
+		}()
+	})
+
+	voiceSelect := widget.NewSelect(append([]string{systemVoice}, llm.Voices...), nil)
+	if voice, err := database.GetPreference(appCtx, database.PrefTTSVoice); err == nil && voice != "" {
+		voiceSelect.SetSelected(voice)
+	} else {
+		voiceSelect.SetSelected(llm.Voices[0])
+	}
+
+	reportDirEntry := widget.NewEntry()
+	reportDirEntry.SetPlaceHolder("Optional folder for a weekly usage report")
+	if dir, err := database.GetPreference(appCtx, database.PrefReportDir); err == nil {
+		reportDirEntry.SetText(dir)
+	}
+	reportDirBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			reportDirEntry.SetText(uri.Path())
+		}, w)
+	})
+
+	costWarningEntry := widget.NewEntry()
+	costWarningEntry.SetPlaceHolder(fmt.Sprintf("%.2f", defaultCostWarning))
+	if value, err := database.GetPreference(appCtx, database.PrefCostWarning); err == nil {
+		costWarningEntry.SetText(value)
+	}
+
+	compressionCheck := widget.NewCheck("Include only the most relevant past messages (OpenAI)", nil)
+	if value, err := database.GetPreference(appCtx, database.PrefContextCompression); err == nil {
+		compressionCheck.SetChecked(value == "true")
+	}
+
+	moderationSelect := widget.NewSelect([]string{llm.ModerationOff, llm.ModerationWarn, llm.ModerationBlock}, nil)
+	moderationSelect.SetSelected(llm.ModerationOff)
+	if value, err := database.GetPreference(appCtx, database.PrefModeration); err == nil && value != "" {
+		moderationSelect.SetSelected(value)
+	}
+
+	stripImagesCheck := widget.NewCheck("Remove location and camera data from attached images", nil)
+	stripImagesCheck.SetChecked(llm.StripImageMetadata(appCtx))
+
+	driftCheck := widget.NewCheck("Suggest a new chat when the topic changes", nil)
+	driftCheck.SetChecked(driftDetectionEnabled())
+
+	lineSpacingSelect := widget.NewSelect(lineSpacingNames, nil)
+	lineSpacingSelect.SetSelected(lineSpacingNames[0])
+	if value, err := database.GetPreference(appCtx, database.PrefLineSpacing); err == nil && value != "" {
+		lineSpacingSelect.SetSelected(value)
+	}
+	fontSelect := widget.NewSelect(readingFonts, nil)
+	fontSelect.SetSelected(fontDefault)
+	if value, err := database.GetPreference(appCtx, database.PrefReadingFont); err == nil && value != "" {
+		fontSelect.SetSelected(value)
+	}
+	schemeSelect := widget.NewSelect(colorSchemeNames, nil)
+	schemeSelect.SetSelected(schemeDracula)
+	if value, err := database.GetPreference(appCtx, database.PrefColorScheme); err == nil && value != "" {
+		schemeSelect.SetSelected(value)
+	}
+	roleIconsCheck := widget.NewCheck("Show an icon of the sender's role on messages", nil)
+	roleIconsCheck.SetChecked(roleIconsEnabled())
+	reducedMotionCheck := widget.NewCheck("Reduce motion: show answers a paragraph at a time, without following them or animated indicators", nil)
+	reducedMotionCheck.SetChecked(reducedMotion())
+	fontHint := widget.NewLabel(fmt.Sprintf("Fonts are read from %s or the system's font folders", filepath.Join(database.DataDir(), "fonts")))
+	fontHint.Importance = widget.LowImportance
+	fontHint.Wrapping = fyne.TextWrapWord
+
+	powerAwareCheck := widget.NewCheck("Save power on battery, in power saver mode and on metered connections", nil)
+	powerAwareCheck.SetChecked(powerAwareEnabled())
+	economyModelEntry := widget.NewSelectEntry(modelSelect.Options)
+	economyModelEntry.SetPlaceHolder("Keep the chat's model")
+	if value, err := database.GetPreference(appCtx, database.PrefEconomyModel); err == nil {
+		economyModelEntry.SetText(value)
+	}
+
+	statsCheck := widget.NewCheck("Show the model, tokens, cost and time under answers", nil)
+	statsCheck.SetChecked(messageStatsEnabled())
+
+	goFormatCheck := widget.NewCheck("Format Go code in answers with gofmt", nil)
+	goVetCheck := widget.NewCheck("Also run go vet", nil)
+	if value, err := database.GetPreference(appCtx, database.PrefGoFormat); err == nil {
+		goFormatCheck.SetChecked(value == "true")
+	}
+	if value, err := database.GetPreference(appCtx, database.PrefGoVet); err == nil {
+		goVetCheck.SetChecked(value == "true")
+	}
+
+	keybindingsSelect := widget.NewSelect(keybindingModes, nil)
+	keybindingsSelect.SetSelected(keybindingsDefault)
+	if value, err := database.GetPreference(appCtx, database.PrefKeybindings); err == nil && value != "" {
+		keybindingsSelect.SetSelected(value)
+	}
+
+	cacheCheck := widget.NewCheck("Answer repeated prompts from the cache", nil)
+	cacheCheck.SetChecked(llm.CacheEnabled(appCtx))
+	clearCacheBtn := widget.NewButtonWithIcon("Clear", theme.DeleteIcon(), func() {
+		if err := database.ClearResponseCache(appCtx); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to clear cache: %v", err), w)
+			return
+		}
+		dialog.ShowInformation("Cache", "Response cache cleared", w)
+	})
+
+	auditCheck := widget.NewCheck("Record the requests sent to model providers", nil)
+	auditContentCheck := widget.NewCheck("Include prompts and answers", nil)
+	if value, err := database.GetPreference(appCtx, database.PrefAuditLog); err == nil {
+		auditCheck.SetChecked(value == "true")
+	}
+	if value, err := database.GetPreference(appCtx, database.PrefAuditContent); err == nil {
+		auditContentCheck.SetChecked(value == "true")
+	}
+	auditBtn := widget.NewButtonWithIcon("View", theme.InfoIcon(), func() {
+		showDiagnostics(w)
+	})
+
+	proxyEntry := widget.NewEntry()
+	proxyEntry.SetPlaceHolder("http://proxy:8080 or socks5://proxy:1080")
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetPlaceHolder("300")
+	caFileEntry := widget.NewEntry()
+	caFileEntry.SetPlaceHolder("Optional PEM bundle path")
+	caFileBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			caFileEntry.SetText(reader.URI().Path())
+		}, w)
+	})
+	for entry, key := range map[*widget.Entry]string{
+		proxyEntry:   database.PrefProxyURL,
+		timeoutEntry: database.PrefRequestTimeout,
+		caFileEntry:  database.PrefCACertFile,
+	} {
+		if value, err := database.GetPreference(appCtx, key); err == nil {
+			entry.SetText(value)
+		}
+	}
+
+	localModelEntry := widget.NewEntry()
+	localModelEntry.SetPlaceHolder("GGUF file for the Local company")
+	localModelBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		picker := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			localModelEntry.SetText(reader.URI().Path())
+		}, w)
+		picker.SetFilter(storage.NewExtensionFileFilter([]string{".gguf", ".llamafile"}))
+		picker.Show()
+	})
+	llamaServerEntry := widget.NewEntry()
+	llamaServerEntry.SetPlaceHolder("llama-server")
+	warmUpCheck := widget.NewCheck("Load the model when the app starts", nil)
+	warmUpCheck.SetChecked(warmUpAtStart())
+	warmUpTimeEntry := widget.NewEntry()
+	warmUpTimeEntry.SetPlaceHolder("Daily at, like 08:30")
+	for entry, key := range map[*widget.Entry]string{
+		localModelEntry:  database.PrefLocalModelPath,
+		llamaServerEntry: database.PrefLlamaServer,
+		warmUpTimeEntry:  database.PrefWarmUpTime,
+	} {
+		if value, err := database.GetPreference(appCtx, key); err == nil {
+			entry.SetText(value)
+		}
+	}
+
+	calendarSourceEntry := widget.NewEntry()
+	calendarSourceEntry.SetPlaceHolder("ICS file or feed URL to read")
+	calendarSourceBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		picker := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			calendarSourceEntry.SetText(reader.URI().Path())
+		}, w)
+		picker.SetFilter(storage.NewExtensionFileFilter([]string{".ics"}))
+		picker.Show()
+	})
+	calendarTargetEntry := widget.NewEntry()
+	calendarTargetEntry.SetPlaceHolder("ICS file or CalDAV collection URL")
+	caldavUserEntry := widget.NewEntry()
+	caldavPasswordEntry := widget.NewPasswordEntry()
+	for entry, key := range map[*widget.Entry]string{
+		calendarSourceEntry: database.PrefCalendarSource,
+		calendarTargetEntry: database.PrefCalendarTarget,
+		caldavUserEntry:     database.PrefCalDAVUser,
+		caldavPasswordEntry: database.PrefCalDAVPassword,
+	} {
+		if value, err := database.GetPreference(appCtx, key); err == nil {
+			entry.SetText(value)
+		}
+	}
+
+	homeURLEntry := widget.NewEntry()
+	homeURLEntry.SetPlaceHolder("http://homeassistant.local:8123")
+	homeTokenEntry := widget.NewPasswordEntry()
+	homeTokenEntry.SetPlaceHolder("Long-lived access token")
+	homeEntitiesEntry := widget.NewEntry()
+	homeEntitiesEntry.SetPlaceHolder("Optional, e.g. sensor., light.kitchen")
+	for entry, key := range map[*widget.Entry]string{
+		homeURLEntry:      database.PrefHomeAssistantURL,
+		homeTokenEntry:    database.PrefHomeAssistantToken,
+		homeEntitiesEntry: database.PrefHomeAssistantEntities,
+	} {
+		if value, err := database.GetPreference(appCtx, key); err == nil {
+			entry.SetText(value)
+		}
+	}
+
+	telegramTokenEntry := widget.NewPasswordEntry()
+	telegramTokenEntry.SetPlaceHolder("Bot token from @BotFather")
+	telegramAllowedEntry := widget.NewEntry()
+	telegramAllowedEntry.SetPlaceHolder("User IDs or @usernames, comma separated")
+	for entry, key := range map[*widget.Entry]string{
+		telegramTokenEntry:   database.PrefTelegramToken,
+		telegramAllowedEntry: database.PrefTelegramAllowed,
+	} {
+		if value, err := database.GetPreference(appCtx, key); err == nil {
+			entry.SetText(value)
+		}
+	}
+
+	personaEntry := widget.NewMultiLineEntry()
+	personaEntry.Wrapping = fyne.TextWrapWord
+	personaEntry.SetPlaceHolder("Persona used to answer in IRC and Matrix")
+	liveViewEntry := widget.NewEntry()
+	liveViewEntry.SetPlaceHolder("Off, or a port like 4848 for http://127.0.0.1:4848")
+	if value, err := database.GetPreference(appCtx, database.PrefLiveViewPort); err == nil {
+		liveViewEntry.SetText(value)
+	}
+	ircServerEntry := widget.NewEntry()
+	ircServerEntry.SetPlaceHolder("irc.libera.chat:6697")
+	ircTLSCheck := widget.NewCheck("TLS", nil)
+	ircNickEntry := widget.NewEntry()
+	ircPasswordEntry := widget.NewPasswordEntry()
+	ircPasswordEntry.SetPlaceHolder("Optional server password")
+	ircChannelEntry := widget.NewEntry()
+	ircChannelEntry.SetPlaceHolder("#team")
+	matrixServerEntry := widget.NewEntry()
+	matrixServerEntry.SetPlaceHolder("https://matrix.org")
+	matrixTokenEntry := widget.NewPasswordEntry()
+	matrixTokenEntry.SetPlaceHolder("Access token of the bot account")
+	matrixRoomEntry := widget.NewEntry()
+	matrixRoomEntry.SetPlaceHolder("!room:matrix.org or #team:matrix.org")
+	for entry, key := range map[*widget.Entry]string{
+		personaEntry:      database.PrefBridgePersona,
+		ircServerEntry:    database.PrefIRCServer,
+		ircNickEntry:      database.PrefIRCNick,
+		ircPasswordEntry:  database.PrefIRCPassword,
+		ircChannelEntry:   database.PrefIRCChannel,
+		matrixServerEntry: database.PrefMatrixHomeserver,
+		matrixTokenEntry:  database.PrefMatrixToken,
+		matrixRoomEntry:   database.PrefMatrixRoom,
+	} {
+		if value, err := database.GetPreference(appCtx, key); err == nil {
+			entry.SetText(value)
+		}
+	}
+	if value, err := database.GetPreference(appCtx, database.PrefIRCTLS); err == nil {
+		ircTLSCheck.SetChecked(value == "true")
+	}
+
+	keyringCheck := widget.NewCheck("Store API keys in the system keyring", nil)
+	keyringCheck.SetChecked(database.KeyringEnabled(appCtx))
+
+	// Get companies from database
+	companies, err := database.GetCompanies(appCtx)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load companies: %v", err), w)
+		return
+	}
+
+	// Create company names slice for select widget
+	companyNames := make([]string, len(companies))
+	companyMap := make(map[string]int) // Map company names to IDs
+	for i, company := range companies {
+		companyNames[i] = company.Name
+		companyMap[company.Name] = company.ID
+	}
+
+	// Create model selection (will be updated based on company selection)
+	var selectedCompanyID int
+	var selectedModelID int
+	modelSelect := widget.NewSelect([]string{}, func(value string) {
+		// Find model ID from selected value
+		models, err := database.GetModelsByCompany(appCtx, selectedCompanyID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load models: %v", err), w)
+			return
+		}
+		for _, model := range models {
+			if model.Name == value {
+				selectedModelID = model.ID
+				break
+			}
+		}
+	})
+	modelSelect.Resize(fyne.NewSize(300, 36))
+	modelSelect.Hide() // Hide initially until company is selected
+
+	// Provider keys edited in this dialog, by company; others are read from the database
+	companyKeys := make(map[int]string)
+
+	// Create company selection
+	companySelect := widget.NewSelect(companyNames, func(value string) {
+		// Show the key of the newly selected company, keeping unsaved edits
+		if selectedCompanyID != 0 {
+			companyKeys[selectedCompanyID] = companyKeyEntry.Text
+		}
+		selectedCompanyID = companyMap[value]
+		key, ok := companyKeys[selectedCompanyID]
+		if !ok {
+			var err error
+			if key, err = database.GetAPIKey(appCtx, selectedCompanyID); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to load API key: %v", err), w)
+			}
+		}
+		companyKeyEntry.SetText(key)
+		// Load models for selected company
+		models, err := database.GetModelsByCompany(appCtx, selectedCompanyID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load models: %v", err), w)
+			return
+		}
+		modelNames := make([]string, len(models))
+		for i, model := range models {
+			modelNames[i] = model.Name
+		}
+		modelSelect.Options = modelNames
+		if len(modelNames) > 0 {
+			modelSelect.SetSelected(modelNames[0])
+			modelSelect.Show()
+			modelSelect.Refresh()
+		}
+	})
+	companySelect.Resize(fyne.NewSize(300, 36))
+
+	// Load current settings if they exist
+	if settings, err := database.GetSettings(appCtx); err == nil && settings != nil {
+		nameEntry.SetText(settings.Name)
+		// The profile key field edits the profile's own key; without one the
+		// profile uses the provider key shared by the profiles of its company
+		apiKeyEntry.SetText(settings.OwnKey)
+		// Set company
+		for name, id := range companyMap {
+			if id == settings.CompanyID {
+				companySelect.SetSelected(name)
+				break
+			}
+		}
+	}
+
+	notificationsTab, saveNotifications := newNotificationSettings()
+
+	// Create tabbed form with wider layout
+	formContainer := container.NewAppTabs(
+		container.NewTabItem("General", widget.NewForm(
+			&widget.FormItem{Text: "Name", Widget: nameEntry},
+			&widget.FormItem{Text: "Company", Widget: companySelect},
+			&widget.FormItem{Text: "Model", Widget: modelSelect},
+			&widget.FormItem{Text: "API Key", Widget: companyKeyEntry},
+			&widget.FormItem{Text: "Profile key", Widget: apiKeyEntry},
+			&widget.FormItem{Text: "Keyring", Widget: keyringCheck},
+			&widget.FormItem{Text: "Local model", Widget: container.NewBorder(nil, nil, nil, localModelBtn, localModelEntry)},
+			&widget.FormItem{Text: "llama.cpp server", Widget: llamaServerEntry},
+			&widget.FormItem{Text: "Warm-up", Widget: container.NewGridWithColumns(2, warmUpCheck, warmUpTimeEntry)},
+			&widget.FormItem{Text: "Workspace", Widget: workspaceEntry},
+			&widget.FormItem{Text: "Data folder", Widget: container.NewBorder(nil, nil, nil, dataDirBtn, dataDirEntry)},
+			&widget.FormItem{Text: "Backup", Widget: container.NewHBox(backupBtn, restoreBtn, exportBtn, importBtn)},
+			&widget.FormItem{Text: "Database", Widget: container.NewBorder(nil, nil, nil, compactBtn, dbSizeLabel)},
+		)),
+		container.NewTabItem("Features", widget.NewForm(
+			&widget.FormItem{Text: "Whisper URL", Widget: whisperEntry},
+			&widget.FormItem{Text: "Voice", Widget: voiceSelect},
+			&widget.FormItem{Text: "Cost warning ($)", Widget: costWarningEntry},
+			&widget.FormItem{Text: "Usage reports", Widget: container.NewBorder(nil, nil, nil, reportDirBtn, reportDirEntry)},
+			&widget.FormItem{Text: "Context", Widget: compressionCheck},
+			&widget.FormItem{Text: "Moderation", Widget: moderationSelect},
+			&widget.FormItem{Text: "Topic drift", Widget: driftCheck},
+			&widget.FormItem{Text: "Message stats", Widget: statsCheck},
+			&widget.FormItem{Text: "Power", Widget: powerAwareCheck},
+			&widget.FormItem{Text: "Economy model", Widget: economyModelEntry, HintText: "A cheaper or local model answers while saving power; background jobs wait"},
+			&widget.FormItem{Text: "Images", Widget: stripImagesCheck},
+			&widget.FormItem{Text: "Go code", Widget: container.NewHBox(goFormatCheck, goVetCheck)},
+			&widget.FormItem{Text: "Input keys", Widget: keybindingsSelect},
+			&widget.FormItem{Text: "Response cache", Widget: container.NewBorder(nil, nil, nil, clearCacheBtn, cacheCheck)},
+			&widget.FormItem{Text: "Audit log", Widget: container.NewBorder(nil, nil, nil, auditBtn, container.NewHBox(auditCheck, auditContentCheck))},
+		)),
+		container.NewTabItem("Appearance", widget.NewForm(
+			&widget.FormItem{Text: "Colors", Widget: schemeSelect},
+			&widget.FormItem{Text: "Roles", Widget: roleIconsCheck},
+			&widget.FormItem{Text: "Motion", Widget: reducedMotionCheck},
+			&widget.FormItem{Text: "Line spacing", Widget: lineSpacingSelect},
+			&widget.FormItem{Text: "Font", Widget: fontSelect},
+			&widget.FormItem{Text: "", Widget: fontHint},
+		)),
+		container.NewTabItem("Notifications", notificationsTab),
+		container.NewTabItem("Network", widget.NewForm(
+			&widget.FormItem{Text: "Proxy", Widget: proxyEntry},
+			&widget.FormItem{Text: "Timeout (s)", Widget: timeoutEntry},
+			&widget.FormItem{Text: "CA bundle", Widget: container.NewBorder(nil, nil, nil, caFileBtn, caFileEntry)},
+		)),
+		container.NewTabItem("Calendar", widget.NewForm(
+			&widget.FormItem{Text: "Read from", Widget: container.NewBorder(nil, nil, nil, calendarSourceBtn, calendarSourceEntry)},
+			&widget.FormItem{Text: "Save events to", Widget: calendarTargetEntry},
+			&widget.FormItem{Text: "CalDAV user", Widget: caldavUserEntry},
+			&widget.FormItem{Text: "CalDAV password", Widget: caldavPasswordEntry},
+		)),
+		container.NewTabItem("Home", widget.NewForm(
+			&widget.FormItem{Text: "Home Assistant", Widget: homeURLEntry},
+			&widget.FormItem{Text: "Token", Widget: homeTokenEntry},
+			&widget.FormItem{Text: "Entities", Widget: homeEntitiesEntry},
+		)),
+		container.NewTabItem("Bridges", widget.NewForm(
+			&widget.FormItem{Text: "Telegram bot", Widget: telegramTokenEntry},
+			&widget.FormItem{Text: "Allowed users", Widget: telegramAllowedEntry},
+			&widget.FormItem{Text: "Team persona", Widget: personaEntry},
+			&widget.FormItem{Text: "IRC server", Widget: container.NewBorder(nil, nil, nil, ircTLSCheck, ircServerEntry)},
+			&widget.FormItem{Text: "IRC nick", Widget: ircNickEntry},
+			&widget.FormItem{Text: "IRC password", Widget: ircPasswordEntry},
+			&widget.FormItem{Text: "IRC channel", Widget: ircChannelEntry},
+			&widget.FormItem{Text: "Matrix server", Widget: matrixServerEntry},
+			&widget.FormItem{Text: "Matrix token", Widget: matrixTokenEntry},
+			&widget.FormItem{Text: "Matrix room", Widget: matrixRoomEntry},
+			&widget.FormItem{Text: "Live view port", Widget: liveViewEntry},
+		)),
+	)
+
+	// Create buttons
+	saveBtn := widget.NewButton("Save", func() {
+		if modelSelect.Selected == "" {
+			dialog.ShowError(fmt.Errorf("Please select a model"), w)
+			return
+		}
+		if companySelect.Selected == "Local" && localModelEntry.Text == "" {
+			dialog.ShowError(fmt.Errorf("Please pick a GGUF model file for the Local company"), w)
+			return
+		}
+		if costWarningEntry.Text != "" {
+			if _, err := strconv.ParseFloat(costWarningEntry.Text, 64); err != nil {
+				dialog.ShowError(fmt.Errorf("Cost warning must be a number"), w)
+				return
+			}
+		}
+		if proxyEntry.Text != "" {
+			if u, err := url.Parse(proxyEntry.Text); err != nil || u.Host == "" {
+				dialog.ShowError(fmt.Errorf("Proxy must be a URL like http://host:port"), w)
+				return
+			}
+		}
+		if homeURLEntry.Text != "" {
+			if u, err := url.Parse(homeURLEntry.Text); err != nil || u.Host == "" {
+				dialog.ShowError(fmt.Errorf("Home Assistant must be a URL like http://host:8123"), w)
+				return
+			}
+		}
+		if ircServerEntry.Text != "" {
+			if _, _, err := net.SplitHostPort(ircServerEntry.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("IRC server must be host:port"), w)
+				return
+			}
+		}
+		if warmUpTimeEntry.Text != "" {
+			if _, err := time.Parse("15:04", warmUpTimeEntry.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("Warm-up time must be like 08:30"), w)
+				return
+			}
+		}
+		if timeoutEntry.Text != "" {
+			if seconds, err := strconv.Atoi(timeoutEntry.Text); err != nil || seconds <= 0 {
+				dialog.ShowError(fmt.Errorf("Timeout must be a positive number of seconds"), w)
+				return
+			}
+		}
+
+		if err := saveNotifications(); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		// Choose where keys are stored before saving them
+		if err := database.SetPreference(appCtx, database.PrefUseKeyring, strconv.FormatBool(keyringCheck.Checked)); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), w)
+			return
+		}
+
+		// Save settings to database
+		err := database.SaveSettings(appCtx,
+			nameEntry.Text,
+			selectedCompanyID,
+			selectedModelID,
+			apiKeyEntry.Text,
+		)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), w)
+			return
+		}
+		companyKeys[selectedCompanyID] = companyKeyEntry.Text
+		for companyID, key := range companyKeys {
+			if err := database.SetAPIKey(appCtx, companyID, key); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to save API key: %v", err), w)
+				return
+			}
+		}
+		prefs := map[string]string{
+			database.PrefWhisperURL:            whisperEntry.Text,
+			database.PrefWorkspaceDir:          workspaceEntry.Text,
+			database.PrefTTSVoice:              voiceSelect.Selected,
+			database.PrefCostWarning:           costWarningEntry.Text,
+			database.PrefReportDir:             reportDirEntry.Text,
+			database.PrefContextCompression:    strconv.FormatBool(compressionCheck.Checked),
+			database.PrefModeration:            moderationSelect.Selected,
+			database.PrefDriftDetection:        strconv.FormatBool(driftCheck.Checked),
+			database.PrefMessageStats:          strconv.FormatBool(statsCheck.Checked),
+			database.PrefPowerAware:            strconv.FormatBool(powerAwareCheck.Checked),
+			database.PrefEconomyModel:          strings.TrimSpace(economyModelEntry.Text),
+			database.PrefColorScheme:           schemeSelect.Selected,
+			database.PrefRoleIcons:             strconv.FormatBool(roleIconsCheck.Checked),
+			database.PrefReducedMotion:         strconv.FormatBool(reducedMotionCheck.Checked),
+			database.PrefLineSpacing:           lineSpacingSelect.Selected,
+			database.PrefReadingFont:           fontSelect.Selected,
+			database.PrefStripImageMetadata:    strconv.FormatBool(stripImagesCheck.Checked),
+			database.PrefGoFormat:              strconv.FormatBool(goFormatCheck.Checked),
+			database.PrefGoVet:                 strconv.FormatBool(goVetCheck.Checked),
+			database.PrefKeybindings:           keybindingsSelect.Selected,
+			database.PrefProxyURL:              proxyEntry.Text,
+			database.PrefRequestTimeout:        timeoutEntry.Text,
+			database.PrefCACertFile:            caFileEntry.Text,
+			database.PrefResponseCache:         strconv.FormatBool(cacheCheck.Checked),
+			database.PrefAuditLog:              strconv.FormatBool(auditCheck.Checked),
+			database.PrefAuditContent:          strconv.FormatBool(auditContentCheck.Checked),
+			database.PrefLocalModelPath:        localModelEntry.Text,
+			database.PrefLlamaServer:           llamaServerEntry.Text,
+			database.PrefWarmUpAtStart:         strconv.FormatBool(warmUpCheck.Checked),
+			database.PrefWarmUpTime:            warmUpTimeEntry.Text,
+			database.PrefCalendarSource:        calendarSourceEntry.Text,
+			database.PrefCalendarTarget:        calendarTargetEntry.Text,
+			database.PrefCalDAVUser:            caldavUserEntry.Text,
+			database.PrefCalDAVPassword:        caldavPasswordEntry.Text,
+			database.PrefHomeAssistantURL:      homeURLEntry.Text,
+			database.PrefHomeAssistantToken:    homeTokenEntry.Text,
+			database.PrefHomeAssistantEntities: homeEntitiesEntry.Text,
+			database.PrefTelegramToken:         telegramTokenEntry.Text,
+			database.PrefTelegramAllowed:       telegramAllowedEntry.Text,
+			database.PrefBridgePersona:         personaEntry.Text,
+			database.PrefIRCServer:             ircServerEntry.Text,
+			database.PrefIRCTLS:                strconv.FormatBool(ircTLSCheck.Checked),
+			database.PrefIRCNick:               ircNickEntry.Text,
+			database.PrefIRCPassword:           ircPasswordEntry.Text,
+			database.PrefIRCChannel:            ircChannelEntry.Text,
+			database.PrefMatrixHomeserver:      matrixServerEntry.Text,
+			database.PrefMatrixToken:           matrixTokenEntry.Text,
+			database.PrefMatrixRoom:            matrixRoomEntry.Text,
+			database.PrefLiveViewPort:          liveViewEntry.Text,
+		}
+		for key, value := range prefs {
+			if err := database.SetPreference(appCtx, key, value); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), w)
+				return
+			}
+		}
+		refreshModelSelect()
+		refreshProfileSelect()
+		refreshKeybindings()
+		refreshChatBars()
+		refreshMessageStats()
+		refreshRoleIcons()
+		refreshPowerBar()
+		if err := applyAppearance(); err != nil {
+			dialog.ShowError(err, w)
+		}
+		restartBridges()
+		restartLiveView()
+
+		if dataDirEntry.Text != database.ConfiguredDataDir() {
+			if err := database.SetDataDir(dataDirEntry.Text); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			dialog.ShowInformation("Success", "Settings saved. Restart the app to move your data to the new folder.", w)
+			return
+		}
+		dialog.ShowInformation("Success", "Settings saved", w)
+	})
+	cancelBtn := widget.NewButton("Cancel", func() {})
+
+	// Create button container
+	buttons := container.NewHBox(
+		layout.NewSpacer(),
+		cancelBtn,
+		saveBtn,
+	)
+
+	// Create main container with padding
+	content := container.NewVBox(
+		formContainer,
+		widget.NewSeparator(),
+		buttons,
+	)
+
+	// Show custom dialog with increased size
+	title := "Settings"
+	if settings, err := database.GetSettings(appCtx); err == nil && settings != nil {
+		title += " · " + settings.Profile
+	}
+	d := dialog.NewCustom(title, "", content, w)
+	d.Resize(fyne.NewSize(500, 450))
+	d.Show()
+
+	// Trigger initial model list population if company is selected
+	if companySelect.Selected != "" {
+		companySelect.OnChanged(companySelect.Selected)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/stacktrace"
+)
+
+// withStackSources appends the workspace source of the frames of the Go stack
+// traces in the message and its pasted files, so the model sees the code that
+// panicked
+func withStackSources(prompt, message string, files []pastedFile) string {
+	text := message
+	for _, file := range files {
+		text += "\n" + file.Text
+	}
+	if !stacktrace.Contains(text) {
+		return prompt
+	}
+	workspace, err := database.GetPreference(appCtx, database.PrefWorkspaceDir)
+	if err != nil {
+		log.Printf("Failed to get workspace preference: %v", err)
+		return prompt
+	}
+	if workspace == "" {
+		return prompt
+	}
+	if sources := stacktrace.Enrich(text, workspace); sources != "" {
+		prompt += "\n\n" + sources
+	}
+	return prompt
+}
@@ -0,0 +1,135 @@
+package stacktrace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Limits on the source pulled into a prompt
+const (
+	maxFrames    = 10
+	contextLines = 5
+)
+
+// Frame is a call in a Go stack trace
+type Frame struct {
+	Function string
+	File     string // As printed in the trace, usually the path on the machine that crashed
+	Line     int
+}
+
+// framePattern matches the file line of a frame, like "\t/src/app/main.go:42 +0x1d"
+var framePattern = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?\s*$`)
+
+// tracePattern matches the lines that start a Go panic or goroutine dump
+var tracePattern = regexp.MustCompile(`(?m)^(panic: |fatal error: |goroutine \d+ \[)`)
+
+// Contains reports whether text holds a Go stack trace
+func Contains(text string) bool {
+	return tracePattern.MatchString(text) && len(Frames(text)) > 0
+}
+
+// Frames returns the frames of the Go stack traces in text, in order and
+// without repeats
+func Frames(text string) []Frame {
+	var frames []Frame
+	seen := make(map[string]bool)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		match := framePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(match[2])
+		frame := Frame{File: match[1], Line: n}
+		if i > 0 {
+			frame.Function = strings.TrimSpace(lines[i-1])
+		}
+		key := fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		if !seen[key] {
+			seen[key] = true
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// Locate finds the file of a frame in the workspace: the trace holds the path
+// on the machine that crashed, so the longest trailing part of it that exists
+// under the workspace is used. Files outside the workspace are not returned.
+func Locate(workspace, file string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	for i := range parts {
+		path := filepath.Join(workspace, filepath.FromSlash(strings.Join(parts[i:], "/")))
+		rel, err := filepath.Rel(workspace, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// sourceLines returns the numbered lines around line in path, marking line
+func sourceLines(path string, line int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan() && n <= line+contextLines; n++ {
+		if n < line-contextLines {
+			continue
+		}
+		marker := "  "
+		if n == line {
+			marker = "> "
+		}
+		fmt.Fprintf(&b, "%s%d\t%s\n", marker, n, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("%s has no line %d", path, line)
+	}
+	return b.String(), nil
+}
+
+// Enrich returns the source around the frames of the Go stack traces in text
+// that are found in the workspace, as Markdown to append to a prompt, or an
+// empty string when none is found
+func Enrich(text, workspace string) string {
+	var b strings.Builder
+	found := 0
+	for _, frame := range Frames(text) {
+		if found == maxFrames {
+			break
+		}
+		path, ok := Locate(workspace, frame.File)
+		if !ok {
+			continue
+		}
+		source, err := sourceLines(path, frame.Line)
+		if err != nil {
+			continue
+		}
+		rel, _ := filepath.Rel(workspace, path)
+		fmt.Fprintf(&b, "\n%s at %s:%d\n```go\n%s```\n", frame.Function, filepath.ToSlash(rel), frame.Line, source)
+		found++
+	}
+	if found == 0 {
+		return ""
+	}
+	return "Source of the stack trace frames, from the user's workspace:\n" + b.String()
+}
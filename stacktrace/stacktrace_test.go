@@ -0,0 +1,138 @@
+package stacktrace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const trace = `panic: runtime error: index out of range [3] with length 3
+
+goroutine 1 [running]:
+main.parse(...)
+	/home/ci/src/app/parser/parse.go:12
+main.main()
+	/home/ci/src/app/main.go:7 +0x1d
+exit status 2`
+
+func TestFrames(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Frame
+	}{
+		{"panic", trace, []Frame{
+			{Function: "main.parse(...)", File: "/home/ci/src/app/parser/parse.go", Line: 12},
+			{Function: "main.main()", File: "/home/ci/src/app/main.go", Line: 7},
+		}},
+		{"repeated frames once", "goroutine 1 [running]:\nmain.f()\n\t/a/f.go:3 +0x1\ngoroutine 2 [chan receive]:\nmain.f()\n\t/a/f.go:3 +0x1", []Frame{
+			{Function: "main.f()", File: "/a/f.go", Line: 3},
+		}},
+		{"windows path", "panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\tC:/work/app/main.go:9 +0x25", []Frame{
+			{Function: "main.main()", File: "C:/work/app/main.go", Line: 9},
+		}},
+		{"frame on the first line", "\t/a/b.go:1", []Frame{{File: "/a/b.go", Line: 1}}},
+		{"file mentioned in prose", "see main.go:12 for details", nil},
+		{"not go", "\t/a/b.py:3", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Frames(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Frames() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"panic", trace, true},
+		{"fatal error", "fatal error: all goroutines are asleep - deadlock!\n\ngoroutine 1 [chan receive]:\nmain.main()\n\t/a/main.go:5 +0x1", true},
+		{"frames without a header", "main.main()\n\t/a/main.go:5 +0x1", false},
+		{"header without frames", "panic: boom", false},
+		{"prose", "My program panics, what is wrong?", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Contains(tt.text); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	workspace := t.TempDir()
+	for _, file := range []string{"main.go", "parser/parse.go"} {
+		path := filepath.Join(workspace, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outside := filepath.Join(filepath.Dir(workspace), "secret.go")
+	if err := os.WriteFile(outside, []byte("package secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		want string // Relative to the workspace, empty when not found
+	}{
+		{"path of another machine", "/home/ci/src/app/parser/parse.go", "parser/parse.go"},
+		{"top level file", "/home/ci/src/app/main.go", "main.go"},
+		{"relative path", "parser/parse.go", "parser/parse.go"},
+		{"missing file", "/home/ci/src/app/other.go", ""},
+		{"directory", "/home/ci/src/app/parser", ""},
+		{"outside the workspace", "../" + filepath.Base(outside), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, ok := Locate(workspace, tt.file)
+			if ok != (tt.want != "") {
+				t.Fatalf("Locate(%q) = %q, %v", tt.file, path, ok)
+			}
+			if ok && path != filepath.Join(workspace, filepath.FromSlash(tt.want)) {
+				t.Errorf("Locate(%q) = %q, want %s", tt.file, path, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	workspace := t.TempDir()
+	var source strings.Builder
+	for i := 1; i <= 20; i++ {
+		source.WriteString("line\n")
+	}
+	if err := os.MkdirAll(filepath.Join(workspace, "parser"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "parser", "parse.go"), []byte(source.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := Enrich(trace, workspace)
+	for _, want := range []string{"main.parse(...) at parser/parse.go:12", "> 12\tline", "  7\tline", "  17\tline"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Enrich() is missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"  6\t", "  18\t", "main.go"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Enrich() has %q:\n%s", unwanted, got)
+		}
+	}
+	if got := Enrich(trace, t.TempDir()); got != "" {
+		t.Errorf("Enrich() without the sources = %q, want empty", got)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// starIcon and starredIcon are the outlined and filled stars of the star
+// button, which the theme has no icons for
+var (
+	starIcon = theme.NewThemedResource(fyne.NewStaticResource("star.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><path fill="#000000" d="M22 9.24l-7.19-.62L12 2 9.19 8.63 2 9.24l5.46 4.73L5.82 21 12 17.27 18.18 21l-1.63-7.03L22 9.24zM12 15.4l-3.76 2.27 1-4.28-3.32-2.88 4.38-.38L12 6.1l1.71 4.04 4.38.38-3.32 2.88 1 4.28L12 15.4z"/></svg>`)))
+
+	starredIcon = theme.NewPrimaryThemedResource(fyne.NewStaticResource("starred.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><path fill="#000000" d="M12 17.27L18.18 21l-1.64-7.03L22 9.24l-7.19-.61L12 2 9.19 8.63 2 9.24l5.46 4.73L5.82 21z"/></svg>`)))
+)
+
+// newStarButton returns a button starring a message, to find it again in the
+// Saved view
+func newStarButton(item *messageItem) *widget.Button {
+	starred := false
+	if item.messageID != 0 {
+		var err error
+		if starred, err = database.IsStarred(appCtx, item.messageID); err != nil {
+			log.Printf("Failed to load star: %v", err)
+		}
+	}
+	btn := widget.NewButtonWithIcon("", starIcon, nil)
+	setStarIcon := func() {
+		if starred {
+			btn.SetIcon(starredIcon)
+			return
+		}
+		btn.SetIcon(starIcon)
+	}
+	btn.OnTapped = func() {
+		if item.messageID == 0 {
+			dialog.ShowInformation("Star", "Wait until the answer is complete.", mainWindow)
+			return
+		}
+		if err := database.SetStarred(appCtx, item.messageID, !starred); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save star: %v", err), mainWindow)
+			return
+		}
+		starred = !starred
+		setStarIcon()
+	}
+	btn.Importance = widget.LowImportance
+	setStarIcon()
+	return btn
+}
+
+// showSavedMessages lists the starred messages of all chats; picking one
+// opens its chat scrolled to the message
+func showSavedMessages(w fyne.Window) {
+	messages, err := database.GetStarredMessages(appCtx)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load saved messages: %v", err), w)
+		return
+	}
+
+	var d dialog.Dialog
+	list := container.NewVBox()
+	for _, m := range messages {
+		msg := m
+		var row fyne.CanvasObject
+		title := widget.NewLabel(msg.ChatTitle)
+		title.TextStyle = fyne.TextStyle{Bold: true}
+		title.Truncation = fyne.TextTruncateEllipsis
+		snippet := widget.NewLabel(msg.Sender + ": " + searchSnippet(msg.Text, ""))
+		snippet.Truncation = fyne.TextTruncateEllipsis
+		info := widget.NewLabel("Starred " + msg.StarredAt.Local().Format("2006-01-02 15:04"))
+		info.Importance = widget.LowImportance
+
+		openBtn := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() {
+			d.Hide()
+			jumpToMessage(msg.MessageRecord)
+		})
+		unstarBtn := widget.NewButtonWithIcon("", starredIcon, func() {
+			if err := database.SetStarred(appCtx, msg.ID, false); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to save star: %v", err), w)
+				return
+			}
+			list.Remove(row)
+			// Shown messages of the chat get their star button updated
+			if chat := findChat(msg.ChatID); chat != nil {
+				rerenderChat(chat)
+			}
+		})
+		unstarBtn.Importance = widget.LowImportance
+		row = container.NewBorder(nil, nil, nil, container.NewHBox(unstarBtn, openBtn), container.NewVBox(title, snippet, info))
+		list.Add(row)
+	}
+	if len(messages) == 0 {
+		list.Add(widget.NewLabel("No saved messages yet. Star a message to keep it here."))
+	}
+
+	d = dialog.NewCustom("Saved", "Close", container.NewVScroll(list), w)
+	d.Resize(fyne.NewSize(600, 450))
+	d.Show()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// allTags is the tag filter option that lists every chat
+const allTags = "All tags"
+
+// chatListed reports whether the tag filter lists chat in the sidebar
+func chatListed(chat *Chat) bool {
+	return tagFilter == nil || tagFilter.Selected == allTags || hasTag(chat.Tags, tagFilter.Selected)
+}
+
+// hasTag reports whether tags holds tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTags splits a comma separated list into lower case tags, sorted and
+// without duplicates
+func parseTags(text string) []string {
+	var tags []string
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// tagChips returns a small button per tag that filters the sidebar by it
+func tagChips(tags []string) []fyne.CanvasObject {
+	chips := make([]fyne.CanvasObject, len(tags))
+	for i, tag := range tags {
+		tag := tag
+		chip := widget.NewButton(tag, func() {
+			tagFilter.SetSelected(tag)
+		})
+		chip.Importance = widget.LowImportance
+		chips[i] = chip
+	}
+	return chips
+}
+
+// refreshTagFilter offers the tags of the chats in the filter, going back to
+// every chat when the chosen tag is no longer used
+func refreshTagFilter() {
+	if tagFilter == nil {
+		return
+	}
+	options := []string{allTags}
+	for _, chat := range chats {
+		for _, tag := range chat.Tags {
+			if !hasTag(options[1:], tag) {
+				options = append(options, tag)
+			}
+		}
+	}
+	sort.Strings(options[1:])
+	tagFilter.Options = options
+	if !hasTag(options, tagFilter.Selected) {
+		tagFilter.SetSelected(allTags)
+	}
+	tagFilter.Refresh()
+}
+
+// showChatTags edits the tags of a chat as a comma separated list
+func showChatTags(w fyne.Window, chatID int) {
+	chat := findChat(chatID)
+	if chat == nil {
+		return
+	}
+	entry := widget.NewEntry()
+	entry.SetText(strings.Join(chat.Tags, ", "))
+	entry.SetPlaceHolder("work, coding, writing")
+
+	dialog.ShowForm(fmt.Sprintf("Tags of %s", chat.Title), "Save", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Tags", entry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		tags := parseTags(entry.Text)
+		if err := database.SetChatTags(appCtx, chatID, tags); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save tags: %v", err), w)
+			return
+		}
+		if chat := findChat(chatID); chat != nil {
+			chat.Tags = tags
+		}
+		refreshTagFilter()
+		chatList.Refresh()
+	}, w)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/irc"
+	"github.com/devalexandre/llmschat/llm"
+	"github.com/devalexandre/llmschat/matrix"
+)
+
+// teamRetryDelay is how long a team bridge waits before reconnecting
+const teamRetryDelay = 30 * time.Second
+
+// teamBridges holds the cancel function of the running IRC and Matrix bridges
+var teamBridges struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// restartTeamBridges stops the running IRC and Matrix bridges and starts the
+// configured ones again
+func restartTeamBridges() {
+	teamBridges.Lock()
+	defer teamBridges.Unlock()
+
+	if teamBridges.cancel != nil {
+		teamBridges.cancel()
+		teamBridges.cancel = nil
+	}
+
+	prefs := make(map[string]string)
+	for _, key := range []string{
+		database.PrefIRCServer, database.PrefIRCTLS, database.PrefIRCNick, database.PrefIRCPassword, database.PrefIRCChannel,
+		database.PrefMatrixHomeserver, database.PrefMatrixToken, database.PrefMatrixRoom,
+	} {
+		value, err := database.GetPreference(appCtx, key)
+		if err != nil {
+			log.Printf("Failed to read bridge settings: %v", err)
+			return
+		}
+		prefs[key] = value
+	}
+
+	ctx, cancel := context.WithCancel(appCtx)
+	teamBridges.cancel = cancel
+
+	if prefs[database.PrefIRCServer] != "" && prefs[database.PrefIRCNick] != "" && prefs[database.PrefIRCChannel] != "" {
+		go runIRCBridge(ctx, &irc.Client{
+			Server:   prefs[database.PrefIRCServer],
+			TLS:      prefs[database.PrefIRCTLS] == "true",
+			Nick:     prefs[database.PrefIRCNick],
+			Password: prefs[database.PrefIRCPassword],
+			Channel:  prefs[database.PrefIRCChannel],
+		})
+	}
+	if prefs[database.PrefMatrixHomeserver] != "" && prefs[database.PrefMatrixToken] != "" && prefs[database.PrefMatrixRoom] != "" {
+		go runMatrixBridge(ctx, prefs[database.PrefMatrixHomeserver], prefs[database.PrefMatrixToken], prefs[database.PrefMatrixRoom])
+	}
+}
+
+// teamSystemPrompt composes the system prompt of a team bridge chat, using the
+// bridge persona as the persona layer
+func teamSystemPrompt(chat *Chat) string {
+	persona, err := database.GetPreference(appCtx, database.PrefBridgePersona)
+	if err != nil {
+		log.Printf("Failed to get bridge persona: %v", err)
+	}
+	return llm.ComposeSystemPrompt(append(instructionLayers(chat), llm.InstructionLayer{Level: llm.LayerPersona, Text: persona}))
+}
+
+// runIRCBridge answers mentions in the IRC channel, reconnecting until ctx is cancelled
+func runIRCBridge(ctx context.Context, client *irc.Client) {
+	log.Printf("IRC bridge started for %s on %s", client.Channel, client.Server)
+	for ctx.Err() == nil {
+		err := client.Run(ctx, func(sender, text string) string {
+			chat := bridgeChat(database.PrefIRCChatID, "IRC "+client.Channel)
+			return bridgeAnswer(chat, sender, text, teamSystemPrompt(chat))
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("IRC bridge: %v", err)
+			time.Sleep(teamRetryDelay)
+		}
+	}
+	log.Printf("IRC bridge stopped")
+}
+
+// runMatrixBridge answers mentions in the Matrix room until ctx is cancelled
+func runMatrixBridge(ctx context.Context, homeserver, token, room string) {
+	log.Printf("Matrix bridge started for %s", room)
+	for ctx.Err() == nil {
+		if err := followMatrixRoom(ctx, homeserver, token, room); err != nil && ctx.Err() == nil {
+			log.Printf("Matrix bridge: %v", err)
+			time.Sleep(teamRetryDelay)
+		}
+	}
+	log.Printf("Matrix bridge stopped")
+}
+
+// followMatrixRoom joins room and answers mentions until an error occurs
+func followMatrixRoom(ctx context.Context, homeserver, token, room string) error {
+	httpClient, err := llm.HTTPClient(appCtx)
+	if err != nil {
+		return err
+	}
+	client := &matrix.Client{Homeserver: homeserver, Token: token, RoomID: room, HTTP: httpClient}
+
+	self, err := client.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	if err := client.Join(ctx); err != nil {
+		return err
+	}
+
+	for {
+		messages, err := client.Messages(ctx)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			if msg.Sender == self {
+				continue
+			}
+			text, ok := matrix.Mention(msg.Body, self)
+			if !ok {
+				continue
+			}
+			chat := bridgeChat(database.PrefMatrixChatID, "Matrix "+room)
+			answer := bridgeAnswer(chat, msg.Sender, text, teamSystemPrompt(chat))
+			if err := client.Send(ctx, msg.Sender+": "+answer); err != nil {
+				return err
+			}
+		}
+	}
+}
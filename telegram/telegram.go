@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// apiURL is the Telegram Bot API endpoint
+const apiURL = "https://api.telegram.org/bot"
+
+// pollTimeout is how long, in seconds, a getUpdates call waits for messages
+const pollTimeout = 30
+
+// maxMessageLength is the longest text Telegram accepts in one message
+const maxMessageLength = 4096
+
+// User is the sender of a message
+type User struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+// Name returns the username of the user, or their first name
+func (u User) Name() string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	return u.FirstName
+}
+
+// Message is an incoming text message
+type Message struct {
+	MessageID int   `json:"message_id"`
+	From      *User `json:"from"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+// Update is one event received by the bot
+type Update struct {
+	UpdateID int      `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// Bot talks to the Telegram Bot API with long polling
+type Bot struct {
+	Token  string
+	HTTP   *http.Client
+	offset int
+}
+
+// Updates waits for new updates and acknowledges them, so each is returned once
+func (b *Bot) Updates(ctx context.Context) ([]Update, error) {
+	var updates []Update
+	err := b.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          b.offset,
+		"timeout":         pollTimeout,
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range updates {
+		if u.UpdateID >= b.offset {
+			b.offset = u.UpdateID + 1
+		}
+	}
+	return updates, nil
+}
+
+// Send sends text to a chat, split into several messages when it is too long
+func (b *Bot) Send(ctx context.Context, chatID int64, text string) error {
+	for _, part := range split(text, maxMessageLength) {
+		if err := b.call(ctx, "sendMessage", map[string]interface{}{
+			"chat_id": chatID,
+			"text":    part,
+		}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call invokes an API method and decodes its result into out
+func (b *Bot) call(ctx context.Context, method string, params map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+b.Token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Telegram request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.HTTP.Do(req)
+	if err != nil {
+		// Do not leak the token, which is part of the URL, into logs
+		return fmt.Errorf("Telegram %s failed: %v", method, strings.ReplaceAll(err.Error(), b.Token, "***"))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid Telegram response: %v", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("Telegram %s failed: %s", method, result.Description)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(result.Result, out)
+}
+
+// split breaks text into parts of at most limit bytes, preferring line breaks
+func split(text string, limit int) []string {
+	var parts []string
+	for len(text) > limit {
+		cut := strings.LastIndex(text[:limit], "\n")
+		if cut <= 0 {
+			cut = limit
+			// Do not cut a multi-byte character in half
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, text[:cut])
+		text = strings.TrimLeft(text[cut:], "\n")
+	}
+	return append(parts, text)
+}
@@ -0,0 +1,93 @@
+package templates
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Context carries the system values that built-in variables are resolved from
+type Context struct {
+	Clipboard string
+	Selection string
+	Workspace string
+}
+
+// variablePattern matches {{name}} placeholders, allowing spaces inside the braces
+var variablePattern = regexp.MustCompile(`{{\s*([a-zA-Z_][a-zA-Z0-9_ ]*?)\s*}}`)
+
+// Resolve replaces built-in variables in text with values taken from ctx.
+// Unknown variables are left untouched.
+func Resolve(text string, ctx Context) string {
+	return variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		if value, ok := builtin(name, ctx); ok {
+			return value
+		}
+		return match
+	})
+}
+
+// Variables returns the names of the placeholders in text that are not
+// built-in, in the order they first appear, so they can be asked for
+func Variables(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range variablePattern.FindAllStringSubmatch(text, -1) {
+		name := match[1]
+		if _, ok := builtin(name, Context{}); ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// Fill replaces the placeholders named in values. Built-in variables and
+// placeholders without a value are left for Resolve.
+func Fill(text string, values map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		if value, ok := values[variablePattern.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// builtin resolves a single built-in variable
+func builtin(name string, ctx Context) (string, bool) {
+	switch name {
+	case "date":
+		return time.Now().Format("2006-01-02"), true
+	case "time":
+		return time.Now().Format("15:04"), true
+	case "datetime":
+		return time.Now().Format("2006-01-02 15:04"), true
+	case "clipboard":
+		return ctx.Clipboard, true
+	case "selection":
+		return ctx.Selection, true
+	case "os":
+		return runtime.GOOS, true
+	case "workspace":
+		return ctx.Workspace, true
+	case "git_branch", "git_branch of workspace":
+		return gitBranch(ctx.Workspace), true
+	}
+	return "", false
+}
+
+// gitBranch returns the current branch of the repository at dir, or an empty string
+func gitBranch(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
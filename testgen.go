@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/gotest"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// testGenRounds bounds how many times the model may fix a test file that does
+// not compile
+const testGenRounds = 4
+
+// testOutputSize is how much of the go test output is shown and sent back,
+// keeping its end where the failures are summarized
+const testOutputSize = 4000
+
+// isGoSource reports whether an attachment is a Go file tests can be written for
+func isGoSource(a database.Attachment) bool {
+	return strings.HasSuffix(a.Name, ".go") && !strings.HasSuffix(a.Name, "_test.go")
+}
+
+// generateTests has the model write tests for an attached Go file, runs them
+// with go test in the workspace and sends the output back until they compile,
+// then shows the result with an offer to save the test file
+func generateTests(w fyne.Window, chatID int, a database.Attachment) {
+	workspace, err := database.GetPreference(appCtx, database.PrefWorkspaceDir)
+	if err != nil || workspace == "" {
+		dialog.ShowInformation("Generate tests", "Set the workspace folder in the settings first, the tests run against it.", w)
+		return
+	}
+	source, err := a.Data()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to read %s: %v", a.Name, err), w)
+		return
+	}
+	path, err := gotest.Locate(workspace, a.Name, source)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	status := widget.NewLabel("Writing tests...")
+	progress := dialog.NewCustomWithoutButtons("Generate tests", container.NewVBox(status, newBusyIndicator()), w)
+	progress.Show()
+
+	go func() {
+		var attempts []llm.TestAttempt
+		var code string
+		var result gotest.Result
+		for round := 1; round <= testGenRounds; round++ {
+			status.SetText(fmt.Sprintf("Writing tests, attempt %d of %d...", round, testGenRounds))
+			code, err = llm.GenerateTests(appCtx, currentModel, a.Name, string(source), attempts)
+			if err != nil {
+				break
+			}
+			status.SetText(fmt.Sprintf("Running go test, attempt %d of %d...", round, testGenRounds))
+			result, err = gotest.Run(appCtx, path, []byte(code))
+			if err != nil || result.Compiled {
+				break
+			}
+			attempts = append(attempts, llm.TestAttempt{Code: code, Output: tail(result.Output, testOutputSize)})
+		}
+		progress.Hide()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to generate tests: %v", err), w)
+			return
+		}
+
+		verdict := "The tests pass."
+		switch {
+		case !result.Compiled:
+			verdict = fmt.Sprintf("The tests still do not compile after %d attempts.", testGenRounds)
+		case !result.Passed:
+			verdict = "The tests compile but fail."
+		}
+		testPath := gotest.TestFile(path)
+		rel, _ := filepath.Rel(workspace, testPath)
+		AddMessage(chatID, fmt.Sprintf("Tests for %s, proposed as %s. %s\n\n```go\n%s```\n\n```\n%s\n```",
+			a.Name, rel, verdict, code, tail(result.Output, testOutputSize)), "Tests", true)
+		showTestProposal(w, testPath, rel, verdict, code)
+	}()
+}
+
+// showTestProposal shows a generated test file with a button writing it into
+// the workspace
+func showTestProposal(w fyne.Window, path, name, verdict, code string) {
+	text := widget.NewLabel(code)
+	text.TextStyle = fyne.TextStyle{Monospace: true}
+	var d dialog.Dialog
+	saveBtn := widget.NewButton("Save to workspace", func() {
+		if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save %s: %v", name, err), w)
+			return
+		}
+		d.Hide()
+		dialog.ShowInformation("Generate tests", fmt.Sprintf("Saved %s", path), w)
+	})
+	saveBtn.Importance = widget.HighImportance
+	d = dialog.NewCustom(name, "Close", container.NewBorder(widget.NewLabel(verdict), saveBtn, nil, nil, container.NewScroll(text)), w)
+	d.Resize(fyne.NewSize(800, 600))
+	d.Show()
+}
+
+// tail returns the last n bytes of s, cut at a line start
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return "…\n" + s
+}
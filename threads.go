@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// threadExcerptSize bounds how much of the message a thread is on is quoted
+// in its dialog and in the chat's context
+const threadExcerptSize = 300
+
+// newThreadButton returns a button opening the thread on a message, showing
+// how many messages the thread has
+func newThreadButton(item *messageItem) *widget.Button {
+	btn := widget.NewButtonWithIcon("", theme.QuestionIcon(), nil)
+	btn.OnTapped = func() {
+		showThread(item, btn)
+	}
+	btn.Importance = widget.LowImportance
+	if item.messageID != 0 {
+		thread, err := database.GetThread(appCtx, item.messageID)
+		if err != nil {
+			log.Printf("Failed to load thread: %v", err)
+		}
+		setThreadCount(btn, len(thread.Messages))
+	}
+	return btn
+}
+
+// setThreadCount shows the number of messages of a thread on its button
+func setThreadCount(btn *widget.Button, count int) {
+	if count == 0 {
+		btn.SetText("")
+		return
+	}
+	btn.SetText(fmt.Sprint(count))
+}
+
+// threadExcerpt shortens text to threadExcerptSize characters
+func threadExcerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > threadExcerptSize {
+		return string(runes[:threadExcerptSize]) + "…"
+	}
+	return text
+}
+
+// showThread opens the side discussion on a message: clarifying questions
+// answered about that message alone, stored with the chat and left out of its
+// context unless included
+func showThread(item *messageItem, btn *widget.Button) {
+	if item.messageID == 0 {
+		dialog.ShowInformation("Thread", "Wait until the answer is complete.", mainWindow)
+		return
+	}
+	thread, err := database.GetThread(appCtx, item.messageID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load thread: %v", err), mainWindow)
+		return
+	}
+
+	parent := widget.NewLabel(threadExcerpt(thread.Parent))
+	parent.Wrapping = fyne.TextWrapWord
+	parent.Importance = widget.LowImportance
+	messages := container.NewVBox()
+	scroll := container.NewVScroll(messages)
+	addMessage := func(sender, text string) {
+		_, label := newSenderLabel(sender, text)
+		messages.Add(container.NewVBox(label, newMarkdownView(text)))
+		scroll.ScrollToBottom()
+	}
+	for _, m := range thread.Messages {
+		addMessage(m.Sender, m.Text)
+	}
+
+	contextCheck := widget.NewCheck("Include this thread in the chat's context", func(checked bool) {
+		if err := database.SetThreadInContext(appCtx, item.messageID, checked); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save thread: %v", err), mainWindow)
+		}
+	})
+	contextCheck.Checked = thread.InContext
+
+	question := widget.NewMultiLineEntry()
+	question.Wrapping = fyne.TextWrapWord
+	question.SetPlaceHolder("Ask about this message")
+	question.SetMinRowsVisible(2)
+	var askBtn *widget.Button
+	askBtn = widget.NewButtonWithIcon("", theme.MailSendIcon(), func() {
+		text := strings.TrimSpace(question.Text)
+		if text == "" {
+			return
+		}
+		question.SetText("")
+		askBtn.Disable()
+		thread.Messages = append(thread.Messages, database.ThreadMessage{Sender: "You", Text: text})
+		if _, err := database.AddThreadMessage(appCtx, item.messageID, "You", text); err != nil {
+			log.Printf("Failed to save thread message: %v", err)
+		}
+		addMessage("You", text)
+		setThreadCount(btn, len(thread.Messages))
+
+		go func() {
+			defer askBtn.Enable()
+			transcript := make([]llm.Turn, len(thread.Messages))
+			for i, m := range thread.Messages {
+				transcript[i] = llm.Turn{Speaker: m.Sender, Text: m.Text}
+			}
+			reply, err := llm.ThreadReply(appCtx, currentModel, thread.Parent, transcript)
+			if err != nil {
+				addMessage("System", fmt.Sprintf("Error: %v", err))
+				return
+			}
+			thread.Messages = append(thread.Messages, database.ThreadMessage{Sender: "AI", Text: reply})
+			if _, err := database.AddThreadMessage(appCtx, item.messageID, "AI", reply); err != nil {
+				log.Printf("Failed to save thread message: %v", err)
+			}
+			addMessage("AI", reply)
+			setThreadCount(btn, len(thread.Messages))
+		}()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(parent, widget.NewSeparator()),
+		container.NewVBox(contextCheck, container.NewBorder(nil, nil, nil, askBtn, question)),
+		nil, nil,
+		scroll,
+	)
+	d := dialog.NewCustom("Thread", "Close", content, mainWindow)
+	d.Resize(fyne.NewSize(550, 500))
+	d.Show()
+}
+
+// threadPrompt gives the model the threads of a chat that were included in
+// its context
+func threadPrompt(chatID int) string {
+	threads, err := database.GetContextThreads(appCtx, chatID)
+	if err != nil {
+		log.Printf("Failed to load threads: %v", err)
+		return ""
+	}
+	if len(threads) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("The user had these side discussions on messages of this conversation:")
+	for _, thread := range threads {
+		fmt.Fprintf(&b, "\n\nOn the message %q:\n", threadExcerpt(thread.Parent))
+		for _, m := range thread.Messages {
+			fmt.Fprintf(&b, "%s: %s\n", m.Sender, m.Text)
+		}
+	}
+	return b.String()
+}
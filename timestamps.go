@@ -0,0 +1,103 @@
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// daySeparator is the row above the first message of a day in a chat
+type daySeparator struct {
+	widget.BaseWidget
+	label *widget.Label
+}
+
+func newDaySeparator(day time.Time) *daySeparator {
+	label := widget.NewLabel(dayName(day, time.Now()))
+	label.TextStyle = fyne.TextStyle{Bold: true}
+	label.Importance = widget.LowImportance
+	s := &daySeparator{label: label}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+func (s *daySeparator) CreateRenderer() fyne.WidgetRenderer {
+	line := func() fyne.CanvasObject {
+		return container.NewVBox(layout.NewSpacer(), widget.NewSeparator(), layout.NewSpacer())
+	}
+	return widget.NewSimpleRenderer(container.NewGridWithColumns(3, line(), container.NewCenter(s.label), line()))
+}
+
+// dayName names the day of t as Today, Yesterday or its date
+func dayName(t, now time.Time) string {
+	switch {
+	case sameDay(t, now):
+		return "Today"
+	case sameDay(t, now.AddDate(0, 0, -1)):
+		return "Yesterday"
+	case t.Year() == now.Year():
+		return t.Local().Format("Monday, January 2")
+	default:
+		return t.Local().Format("Monday, January 2, 2006")
+	}
+}
+
+// sameDay tells whether a and b are on the same local calendar day
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Local().Date()
+	by, bm, bd := b.Local().Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// newTimeLabel shows the time a message was sent; the day is shown by the
+// separator above the day's first message
+func newTimeLabel(t time.Time) *widget.Label {
+	label := widget.NewLabel(t.Local().Format("15:04"))
+	label.Importance = widget.LowImportance
+	return label
+}
+
+// addMessageItem adds a message to a chat's message container, after a day
+// separator when it is the first message of its day
+func addMessageItem(msgContainer *fyne.Container, item *messageItem) {
+	var last *messageItem
+	for i := len(msgContainer.Objects) - 1; i >= 0 && last == nil; i-- {
+		last, _ = msgContainer.Objects[i].(*messageItem)
+	}
+	if last == nil || !sameDay(last.createdAt, item.createdAt) {
+		msgContainer.Add(newDaySeparator(item.createdAt))
+	}
+	msgContainer.Add(item)
+}
+
+// removeMessageItem removes a message from a chat's message container, and
+// the day separator above it when no other message of that day is left
+func removeMessageItem(msgContainer *fyne.Container, item *messageItem) {
+	objects := msgContainer.Objects
+	for i, object := range objects {
+		if object != item {
+			continue
+		}
+		if i > 0 {
+			_, separated := objects[i-1].(*daySeparator)
+			last := true
+			for _, next := range objects[i+1:] {
+				if _, ok := next.(*daySeparator); ok {
+					break
+				}
+				if _, ok := next.(*messageItem); ok {
+					last = false
+					break
+				}
+			}
+			if separated && last {
+				msgContainer.Remove(objects[i-1])
+			}
+		}
+		break
+	}
+	msgContainer.Remove(item)
+}
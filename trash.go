@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// trashPurgeInterval is how often chats past the retention period are purged
+const trashPurgeInterval = 24 * time.Hour
+
+// runTrashPurge permanently deletes expired chats from the trash while the app is running
+func runTrashPurge() {
+	for {
+		if purged, err := database.PurgeTrash(appCtx); err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d chats from the trash", purged)
+		}
+		time.Sleep(trashPurgeInterval)
+	}
+}
+
+// trashChat moves a chat to the trash and removes it from the sidebar
+func trashChat(chatID int) {
+	if err := database.TrashChat(appCtx, chatID); err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to delete chat: %v", err), mainWindow)
+		return
+	}
+	chatsMu.Lock()
+	for i := range chats {
+		if chats[i].ID == chatID {
+			chats = append(chats[:i], chats[i+1:]...)
+			break
+		}
+	}
+	chatsMu.Unlock()
+	delete(chatContainers, chatID)
+	refreshTagFilter()
+
+	// Keep the selection on the open chat, or open another one
+	chatList.UnselectAll()
+	if currentChat != nil && currentChat.ID == chatID {
+		currentChat = nil
+		if len(chats) == 0 {
+			createNewChat()
+		} else {
+			selectChat(chats[len(chats)-1].ID)
+		}
+	} else if currentChat != nil {
+		selectChat(currentChat.ID)
+	}
+	chatList.Refresh()
+}
+
+// restoreChat takes a chat out of the trash and puts it back in the sidebar
+func restoreChat(record database.ChatRecord) error {
+	if err := database.RestoreChat(appCtx, record.ID); err != nil {
+		return err
+	}
+	title := record.Title
+	if title == "" {
+		title = fmt.Sprintf("Chat %d", record.ID)
+	}
+	tags, err := database.GetChatTags(appCtx)
+	if err != nil {
+		log.Printf("Failed to load chat tags: %v", err)
+	}
+	chatsMu.Lock()
+	chats = append(chats, &Chat{ID: record.ID, Title: title, Tags: tags[record.ID], FolderID: record.FolderID, ChatConfig: record.ChatConfig})
+	sort.Slice(chats, func(i, j int) bool { return chats[i].ID < chats[j].ID })
+	chatsMu.Unlock()
+
+	refreshTagFilter()
+	chatList.UnselectAll()
+	if currentChat != nil {
+		selectChat(currentChat.ID)
+	}
+	chatList.Refresh()
+	return nil
+}
+
+// selectChat selects the sidebar entry of a chat, opening the folders it is
+// in, or opens the chat when the tag filter hides it
+func selectChat(chatID int) {
+	chat := findChat(chatID)
+	if chat == nil {
+		return
+	}
+	if !chatListed(chat) {
+		switchToChat(chat)
+		return
+	}
+	for folder := findFolder(chat.FolderID); folder != nil; folder = findFolder(folder.ParentID) {
+		chatList.OpenBranch(folderNode(folder.ID))
+	}
+	chatList.Select(chatNode(chatID))
+}
+
+// showTrashDialog lists the deleted chats with buttons to restore or purge them
+func showTrashDialog(w fyne.Window) {
+	list := container.NewVBox()
+	var refreshList func()
+	refreshList = func() {
+		list.Objects = nil
+		trashed, err := database.GetTrashedChats(appCtx)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load trash: %v", err), w)
+			return
+		}
+		for _, t := range trashed {
+			record := t.ChatRecord
+			restoreBtn := widget.NewButtonWithIcon("", theme.ContentUndoIcon(), func() {
+				if err := restoreChat(record); err != nil {
+					dialog.ShowError(fmt.Errorf("Failed to restore chat: %v", err), w)
+					return
+				}
+				refreshList()
+			})
+			purgeBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Delete forever", fmt.Sprintf("Permanently delete %q and its messages?", record.Title), func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					if err := database.PurgeChat(appCtx, record.ID); err != nil {
+						dialog.ShowError(fmt.Errorf("Failed to delete chat: %v", err), w)
+						return
+					}
+					refreshList()
+				}, w)
+			})
+
+			daysLeft := int(time.Until(t.DeletedAt.Add(database.TrashRetention)).Hours()/24) + 1
+			title := widget.NewLabel(record.Title)
+			title.Truncation = fyne.TextTruncateEllipsis
+			info := widget.NewLabel(fmt.Sprintf("Deleted %s, %d days left", t.DeletedAt.Local().Format("2006-01-02"), daysLeft))
+			info.Importance = widget.LowImportance
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(restoreBtn, purgeBtn), container.NewVBox(title, info)))
+		}
+		if len(trashed) == 0 {
+			list.Add(widget.NewLabel("The trash is empty"))
+		}
+		list.Refresh()
+	}
+	refreshList()
+
+	d := dialog.NewCustom("Trash", "Close", container.NewVScroll(list), w)
+	d.Resize(fyne.NewSize(500, 400))
+	d.Show()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// draftHistoryLimit is how many states of the draft undo goes back
+const draftHistoryLimit = 200
+
+// draftMergeDelay is the pause in typing that starts a new undo step
+const draftMergeDelay = time.Second
+
+// draftState is the text of the input and where its cursor was
+type draftState struct {
+	text        string
+	row, column int
+}
+
+// draftHistory keeps the states of the input for undo and redo. Unlike the
+// entry's own history it survives text set by the app, like an inserted
+// prompt or the input cleared after sending.
+type draftHistory struct {
+	undo, redo []draftState
+	typing     bool // The last step was typing, which later typing joins
+	lastEdit   time.Time
+}
+
+func (e *CustomEntry) draftState() draftState {
+	return draftState{text: e.Text, row: e.CursorRow, column: e.CursorColumn}
+}
+
+// edit applies a change to the input and records the state before it as an
+// undo step. Typing within draftMergeDelay of the last keystroke joins its step.
+func (e *CustomEntry) edit(typing bool, apply func()) {
+	before := e.draftState()
+	apply()
+	if e.Text == before.text {
+		return
+	}
+	h := &e.history
+	if !typing || !h.typing || time.Since(h.lastEdit) > draftMergeDelay {
+		h.undo = append(h.undo, before)
+		if len(h.undo) > draftHistoryLimit {
+			h.undo = h.undo[1:]
+		}
+	}
+	h.redo = nil
+	h.typing = typing
+	h.lastEdit = time.Now()
+}
+
+// SetText replaces the text of the input; the old text can be restored with undo
+func (e *CustomEntry) SetText(text string) {
+	e.edit(false, func() { e.Entry.SetText(text) })
+}
+
+// undoDraft restores the input as it was before the last change
+func (e *CustomEntry) undoDraft() {
+	h := &e.history
+	if len(h.undo) == 0 {
+		return
+	}
+	h.redo = append(h.redo, e.draftState())
+	e.restoreDraft(h.undo[len(h.undo)-1])
+	h.undo = h.undo[:len(h.undo)-1]
+}
+
+// redoDraft applies the last change undone again
+func (e *CustomEntry) redoDraft() {
+	h := &e.history
+	if len(h.redo) == 0 {
+		return
+	}
+	h.undo = append(h.undo, e.draftState())
+	e.restoreDraft(h.redo[len(h.redo)-1])
+	h.redo = h.redo[:len(h.redo)-1]
+}
+
+func (e *CustomEntry) restoreDraft(state draftState) {
+	e.Entry.SetText(state.text)
+	e.CursorRow, e.CursorColumn = state.row, state.column
+	e.Refresh()
+	e.history.typing = false
+}
+
+// historyShortcut runs undo for Ctrl+Z and redo for Ctrl+Shift+Z and Ctrl+Y,
+// or Cmd on macOS, and reports whether shortcut was one of them
+func (e *CustomEntry) historyShortcut(shortcut fyne.Shortcut) bool {
+	switch s := shortcut.(type) {
+	case *fyne.ShortcutUndo:
+		e.undoDraft()
+	case *fyne.ShortcutRedo:
+		e.redoDraft()
+	case *desktop.CustomShortcut:
+		if s.KeyName != fyne.KeyZ || s.Modifier != fyne.KeyModifierShortcutDefault|fyne.KeyModifierShift {
+			return false
+		}
+		e.redoDraft()
+	default:
+		return false
+	}
+	return true
+}
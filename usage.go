@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/devalexandre/llmschat/database"
+)
+
+// usageSummaryDays is how many days the per-model totals cover
+const usageSummaryDays = 30
+
+// activityWeeks is how many weeks the activity calendar shows
+const activityWeeks = 53
+
+// What the activity calendar counts per day
+const (
+	activityMessages = "Messages"
+	activityTokens   = "Tokens"
+)
+
+// activityCell is a day of the activity calendar, shaded by how busy it was
+type activityCell struct {
+	widget.BaseWidget
+	rect     *canvas.Rectangle
+	onTapped func()
+}
+
+func newActivityCell(onTapped func()) *activityCell {
+	rect := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	rect.SetMinSize(fyne.NewSize(10, 10))
+	rect.CornerRadius = 2
+	cell := &activityCell{rect: rect, onTapped: onTapped}
+	cell.ExtendBaseWidget(cell)
+	return cell
+}
+
+func (c *activityCell) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(c.rect)
+}
+
+// Tapped opens the chats of the day
+func (c *activityCell) Tapped(*fyne.PointEvent) {
+	if c.onTapped != nil {
+		c.onTapped()
+	}
+}
+
+// setLevel shades the cell from 0, an idle day, to 4, the busiest days
+func (c *activityCell) setLevel(level int) {
+	if level == 0 {
+		c.rect.FillColor = theme.Color(theme.ColorNameInputBackground)
+	} else {
+		r, g, b, _ := theme.Color(theme.ColorNamePrimary).RGBA()
+		c.rect.FillColor = color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(255 * level / 4)}
+	}
+	c.rect.Refresh()
+}
+
+// activityLevel buckets a day's count into the shades of the calendar
+func activityLevel(count, busiest int) int {
+	if count == 0 || busiest == 0 {
+		return 0
+	}
+	return (count*4 + busiest - 1) / busiest
+}
+
+// showUsage shows the requests, tokens and cost per model of the last days and
+// a calendar of the messages or tokens per day; tapping a day lists its chats
+func showUsage(w fyne.Window) {
+	since := time.Now().AddDate(0, 0, -usageSummaryDays)
+	models, err := database.GetUsageByModel(appCtx, since)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load usage: %v", err), w)
+		return
+	}
+
+	// The calendar starts on the Sunday activityWeeks weeks back
+	today := time.Now()
+	start := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	start = start.AddDate(0, 0, -int(start.Weekday())-7*(activityWeeks-1))
+	messages, err := database.GetMessagesPerDay(appCtx, start)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load usage: %v", err), w)
+		return
+	}
+	days, err := database.GetUsageByDay(appCtx, start)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load usage: %v", err), w)
+		return
+	}
+	tokens := make(map[string]int, len(days))
+	for _, day := range days {
+		tokens[day.Key] = day.PromptTokens + day.CompletionTokens
+	}
+
+	summary := widget.NewTable(
+		func() (int, int) { return len(models) + 1, 5 },
+		func() fyne.CanvasObject { return widget.NewLabel("gpt-3.5-turbo-16k") },
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText([]string{"Model", "Requests", "Tokens", "Cost", "Avg latency"}[id.Col])
+				return
+			}
+			m := models[id.Row-1]
+			label.TextStyle = fyne.TextStyle{}
+			label.SetText([]string{
+				m.Key,
+				fmt.Sprintf("%d", m.Requests),
+				fmt.Sprintf("%d", m.PromptTokens+m.CompletionTokens),
+				fmt.Sprintf("$%.4f", m.Cost),
+				m.AvgLatency.Round(10 * time.Millisecond).String(),
+			}[id.Col])
+		},
+	)
+
+	var d dialog.Dialog
+	dayLabel := widget.NewLabel("Tap a day to list its chats")
+	dayLabel.Importance = widget.LowImportance
+	var cells []*activityCell
+	var dates []string
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		dates = append(dates, date)
+		cells = append(cells, newActivityCell(func() {
+			dayLabel.SetText(fmt.Sprintf("%s: %d messages, %d tokens", date, messages[date], tokens[date]))
+			showDayChats(w, d, date)
+		}))
+	}
+
+	metric := widget.NewRadioGroup([]string{activityMessages, activityTokens}, func(selected string) {
+		counts := messages
+		if selected == activityTokens {
+			counts = tokens
+		}
+		busiest := 0
+		for _, date := range dates {
+			busiest = max(busiest, counts[date])
+		}
+		for i, date := range dates {
+			cells[i].setLevel(activityLevel(counts[date], busiest))
+		}
+	})
+	metric.Horizontal = true
+	metric.SetSelected(activityMessages)
+
+	objects := make([]fyne.CanvasObject, len(cells))
+	for i, cell := range cells {
+		objects[i] = cell
+	}
+	calendar := container.NewHScroll(container.NewGridWithRows(7, objects...))
+
+	title := widget.NewLabel(fmt.Sprintf("Last %d days", usageSummaryDays))
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	reportBtn := widget.NewButtonWithIcon("Weekly report", theme.DocumentSaveIcon(), func() {
+		exportReport(w)
+	})
+	activity := widget.NewLabel("Activity")
+	activity.TextStyle = fyne.TextStyle{Bold: true}
+	content := container.NewBorder(
+		container.NewBorder(nil, nil, title, reportBtn),
+		container.NewVBox(widget.NewSeparator(), container.NewBorder(nil, nil, activity, metric), calendar, dayLabel),
+		nil, nil,
+		summary,
+	)
+
+	d = dialog.NewCustom("Usage", "Close", content, w)
+	d.Resize(fyne.NewSize(850, 550))
+	d.Show()
+}
+
+// showDayChats lists the chats with messages on a day; picking one closes the
+// usage screen and opens it
+func showDayChats(w fyne.Window, usage dialog.Dialog, date string) {
+	records, err := database.GetChatsOnDay(appCtx, date)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load chats: %v", err), w)
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	var d dialog.Dialog
+	list := widget.NewList(
+		func() int { return len(records) },
+		func() fyne.CanvasObject { return widget.NewLabel("Chat") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			title := records[id].Title
+			if chat := findChat(records[id].ID); chat != nil {
+				title = chat.Title
+			}
+			item.(*widget.Label).SetText(title)
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		d.Hide()
+		usage.Hide()
+		selectChat(records[id].ID)
+	}
+
+	d = dialog.NewCustom(fmt.Sprintf("Chats of %s", date), "Close", list, w)
+	d.Resize(fyne.NewSize(400, 300))
+	d.Show()
+}
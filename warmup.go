@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/devalexandre/llmschat/database"
+	"github.com/devalexandre/llmschat/llm"
+)
+
+// warmUpCheckInterval is how often the scheduler checks whether a warm-up is due
+const warmUpCheckInterval = time.Minute
+
+// warmUpAtStart reports whether the local model is loaded when the app
+// starts, which is off unless turned on in the settings
+func warmUpAtStart() bool {
+	value, _ := database.GetPreference(appCtx, database.PrefWarmUpAtStart)
+	return value == "true"
+}
+
+// warmUpDue reports whether the daily warm-up time of the settings has passed
+// today without a warm-up. It waits while saving power.
+func warmUpDue(now time.Time) bool {
+	value, err := database.GetPreference(appCtx, database.PrefWarmUpTime)
+	if err != nil || value == "" {
+		return false
+	}
+	at, err := time.Parse("15:04", value)
+	if err != nil {
+		return false
+	}
+	if now.Hour()*60+now.Minute() < at.Hour()*60+at.Minute() {
+		return false
+	}
+	last, _ := database.GetPreference(appCtx, database.PrefWarmUpLastRun)
+	return last != now.Format("2006-01-02") && !economyActive()
+}
+
+// runWarmUp loads the local model when the app starts and every day at the
+// warm-up time, so the first prompt does not wait for it
+func runWarmUp() {
+	if warmUpAtStart() {
+		warmUp()
+	}
+	for {
+		if now := time.Now(); warmUpDue(now) {
+			warmUp()
+			if err := database.SetPreference(appCtx, database.PrefWarmUpLastRun, now.Format("2006-01-02")); err != nil {
+				log.Printf("Failed to save the warm-up time: %v", err)
+			}
+		}
+		time.Sleep(warmUpCheckInterval)
+	}
+}
+
+// warmUp loads the model of the settings if it is served locally
+func warmUp() {
+	started := time.Now()
+	loaded, err := llm.WarmUp(appCtx)
+	switch {
+	case err != nil:
+		log.Printf("Failed to warm up the local model: %v", err)
+	case loaded:
+		log.Printf("Local model warmed up in %s", time.Since(started).Round(time.Second))
+	}
+}
@@ -0,0 +1,228 @@
+package workspace
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxFiles bounds how many files Files lists, so a huge folder does not
+// stall the completion
+const maxFiles = 20000
+
+// ignoreRule is a pattern of a .gitignore file
+type ignoreRule struct {
+	base     string // Folder of the .gitignore, relative to the workspace
+	pattern  *regexp.Regexp
+	negate   bool // Starts with !, re-including what earlier rules ignored
+	dirOnly  bool // Ends with /, matching folders only
+	anchored bool // Has a / before its end, matching from base instead of any name
+}
+
+// Files lists the files under root, relative to it with slashes, skipping
+// the .git folder and what the .gitignore files of the workspace ignore
+func Files(root string) ([]string, error) {
+	var rules []ignoreRule
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable folders are skipped, not fatal
+			if d != nil && d.IsDir() && p != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && (d.Name() == ".git" || ignored(rules, rel, true)) {
+				return fs.SkipDir
+			}
+			base := rel
+			if base == "." {
+				base = ""
+			}
+			rules = append(rules, readIgnore(filepath.Join(p, ".gitignore"), base)...)
+			return nil
+		}
+		if ignored(rules, rel, false) {
+			return nil
+		}
+		files = append(files, rel)
+		if len(files) >= maxFiles {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return files, err
+}
+
+// readIgnore reads the rules of a .gitignore file in the folder base; a
+// missing file has none
+func readIgnore(file, base string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if line, rule.negate = strings.CutPrefix(line, "!"); line == "" {
+			continue
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if line, rule.dirOnly = strings.CutSuffix(line, "/"); line == "" {
+			continue
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = globPattern(strings.TrimPrefix(line, "/"))
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globPattern turns a .gitignore glob into a regular expression matching a
+// whole path
+func globPattern(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**"):
+			b.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := strings.Replace(glob[i+1:i+end], "!", "^", 1)
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	pattern, err := regexp.Compile(b.String())
+	if err != nil {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(glob) + "$")
+	}
+	return pattern
+}
+
+// ignored reports whether the rules ignore a path relative to the workspace;
+// as in git, the last matching rule wins
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		local := rel
+		if rule.base != "" {
+			var ok bool
+			if local, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if !rule.anchored {
+			local = path.Base(local)
+		}
+		if rule.pattern.MatchString(local) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
+
+// Match returns up to limit of files whose path has the letters of query in
+// order, best first: matches in the file name, runs of consecutive letters
+// and short paths rank higher
+func Match(files []string, query string, limit int) []string {
+	query = strings.ToLower(query)
+	type scored struct {
+		file  string
+		score int
+	}
+	var matches []scored
+	for _, file := range files {
+		if score, ok := fuzzyScore(strings.ToLower(file), query); ok {
+			matches = append(matches, scored{file, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].file) < len(matches[j].file)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.file
+	}
+	return result
+}
+
+// fuzzyScore scores how well query matches file, both lower case, and
+// reports whether all its letters were found in order
+func fuzzyScore(file, query string) (int, bool) {
+	nameStart := strings.LastIndexByte(file, '/') + 1
+	score, last := 0, -2
+	q := []rune(query)
+	qi := 0
+	for i, r := range file {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if i == last+1 {
+			score += 3 // Consecutive letters
+		}
+		if i >= nameStart {
+			score += 2 // In the file name
+		}
+		if i == 0 || i == nameStart || !unicode.IsLetter(rune(file[i-1])) {
+			score += 2 // At the start of a word
+		}
+		last = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score - len(file)/16, true
+}
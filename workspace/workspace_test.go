@@ -0,0 +1,133 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeTree creates files under root, with slash separated paths
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "no gitignore",
+			files: map[string]string{"main.go": "", "pkg/a.go": ""},
+			want:  []string{"main.go", "pkg/a.go"},
+		},
+		{
+			name:  "git folder is skipped",
+			files: map[string]string{"main.go": "", ".git/HEAD": "", ".git/refs/heads/main": ""},
+			want:  []string{"main.go"},
+		},
+		{
+			name:  "name pattern matches at any depth",
+			files: map[string]string{".gitignore": "*.log\n", "a.log": "", "sub/b.log": "", "sub/c.go": ""},
+			want:  []string{".gitignore", "sub/c.go"},
+		},
+		{
+			name:  "comments and blank lines",
+			files: map[string]string{".gitignore": "# build output\n\n  \nbin/\n", "bin/app": "", "main.go": ""},
+			want:  []string{".gitignore", "main.go"},
+		},
+		{
+			name:  "directory only pattern skips files of that name",
+			files: map[string]string{".gitignore": "build/\n", "build/out": "", "docs/build": ""},
+			want:  []string{".gitignore", "docs/build"},
+		},
+		{
+			name:  "anchored pattern matches from the root only",
+			files: map[string]string{".gitignore": "/vendor\n", "vendor/x.go": "", "pkg/vendor/y.go": ""},
+			want:  []string{".gitignore", "pkg/vendor/y.go"},
+		},
+		{
+			name:  "pattern with a slash is anchored",
+			files: map[string]string{".gitignore": "docs/tmp\n", "docs/tmp/a": "", "x/docs/tmp/b": ""},
+			want:  []string{".gitignore", "x/docs/tmp/b"},
+		},
+		{
+			name:  "negation re-includes a file",
+			files: map[string]string{".gitignore": "*.env\n!example.env\n", "prod.env": "", "example.env": ""},
+			want:  []string{".gitignore", "example.env"},
+		},
+		{
+			name:  "last matching rule wins",
+			files: map[string]string{".gitignore": "!keep.txt\n*.txt\n", "keep.txt": "", "a.md": ""},
+			want:  []string{".gitignore", "a.md"},
+		},
+		{
+			name:  "double star matches any folders",
+			files: map[string]string{".gitignore": "**/cache/**\n", "cache/a": "", "x/y/cache/b": "", "x/c": ""},
+			want:  []string{".gitignore", "x/c"},
+		},
+		{
+			name:  "question mark and character class",
+			files: map[string]string{".gitignore": "file?.txt\nlog[0-9]\n", "file1.txt": "", "file10.txt": "", "log7": "", "logx": ""},
+			want:  []string{".gitignore", "file10.txt", "logx"},
+		},
+		{
+			name:  "nested gitignore applies to its folder",
+			files: map[string]string{"web/.gitignore": "*.map\n", "web/app.js.map": "", "web/app.js": "", "root.map": ""},
+			want:  []string{"root.map", "web/.gitignore", "web/app.js"},
+		},
+		{
+			name:  "escaped leading character",
+			files: map[string]string{".gitignore": "\\#notes\n", "#notes": "", "notes": ""},
+			want:  []string{".gitignore", "notes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			got, err := Files(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Files() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	files := []string{"main.go", "internal/maintenance.go", "docs/manual.md", "README.md"}
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		{"main", 10, []string{"main.go", "internal/maintenance.go"}},
+		{"mgo", 1, []string{"main.go"}},
+		{"readme", 10, []string{"README.md"}},
+		{"zzz", 10, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := Match(files, tt.query, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}